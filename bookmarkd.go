@@ -3,6 +3,7 @@ package bookmarkd
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 // Build version and commit SHA.
@@ -26,7 +27,44 @@ var (
 	ErrForbidden    = errors.New("you are not authorized to perform the requested action")
 	ErrBadRequest   = errors.New("your request is in a bad format")
 	ErrInvalidInput = errors.New("there is a problem with the data you submitted")
+	ErrUnavailable  = errors.New("the service is temporarily unavailable")
+	ErrConflict     = errors.New("the request conflicts with a concurrent change")
+
+	// conditional request errors.
+	ErrPreconditionFailed   = errors.New("the resource was modified since it was last read")
+	ErrPreconditionRequired = errors.New("the request must be conditional, e.g. using If-Match")
+
+	// bookmark errors.
+	ErrBookmarkDuplicate = errors.New("a bookmark with the same url already exists")
+
+	// idempotency errors.
+	ErrIdempotencyKeyReused     = errors.New("the idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with the same idempotency key is still in progress")
 
 	// sqlite specific errors.
 	ErrUsersUsernameConflict = errors.New("username is already in use")
+	ErrDatabaseNewer         = errors.New("the database was migrated by a newer version")
 )
+
+// ValidationError represents invalid input for a single field. It wraps
+// ErrInvalidInput so it can be matched with errors.Is().
+type ValidationError struct {
+	// Name of the offending field as it appears in the JSON representation.
+	Field string
+
+	// Human-readable description of the problem.
+	Message string
+}
+
+// NewValidationError returns a ValidationError for field with a formatted message.
+func NewValidationError(field, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidInput, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
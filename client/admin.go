@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateBackup takes a backup of the server's database. Requires AdminToken.
+func (c *Client) CreateBackup(ctx context.Context) (*Backup, error) {
+	var b Backup
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/admin/backups",
+		admin:  true,
+	}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// FindBackups lists the backups kept by the server, newest first. Requires
+// AdminToken.
+func (c *Client) FindBackups(ctx context.Context) ([]*Backup, error) {
+	var resp struct {
+		Backups []*Backup `json:"backups"`
+	}
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/admin/backups",
+		admin:  true,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Backups, nil
+}
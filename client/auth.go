@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cristalhq/otp"
+
+	"bookmarkd"
+)
+
+// Registration represents a started registration. The TOTP url should be
+// added to an authenticator app, or passed to Passcode(), to generate the
+// passcode confirming the registration.
+type Registration struct {
+	RegistrationID string `json:"registrationId"`
+	TotpUrl        string `json:"totpUrl"`
+}
+
+// tokenResponse is the response of a login, registration or refresh.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	Expires      int    `json:"expires"`
+}
+
+// Register starts the registration of a new user. Returns
+// ErrUsersUsernameConflict if the username is in use.
+func (c *Client) Register(ctx context.Context, username string) (*Registration, error) {
+	var reg Registration
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/register",
+		body: struct {
+			Username string `json:"username"`
+		}{username},
+	}, &reg); err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+// ConfirmRegistration completes a registration with a passcode generated from
+// its TOTP url. The new user is logged in.
+func (c *Client) ConfirmRegistration(ctx context.Context, registrationID, passcode string) error {
+	var resp tokenResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/register/confirm",
+		body: struct {
+			RegistrationID string `json:"registrationId"`
+			Totp           string `json:"totp"`
+		}{registrationID, passcode},
+	}, &resp); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setToken(newToken(resp))
+	return nil
+}
+
+// Login starts a new session for the user with a TOTP passcode.
+func (c *Client) Login(ctx context.Context, username, passcode string) error {
+	var resp tokenResponse
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/auth/login",
+		body: struct {
+			Username string `json:"username"`
+			Totp     string `json:"totp"`
+		}{username, passcode},
+	}, &resp); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setToken(newToken(resp))
+	return nil
+}
+
+// Logout deletes the current session & forgets its tokens.
+func (c *Client) Logout(ctx context.Context) error {
+	if _, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   "/auth/logout",
+	}, nil); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setToken(nil)
+	return nil
+}
+
+// Refresh exchanges the refresh token for new tokens. This happens
+// automatically when the access token expires.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.refresh(ctx, "")
+}
+
+// refresh exchanges the refresh token for new tokens unless the access token
+// has changed from stale, i.e. another request has already refreshed it.
+func (c *Client) refresh(ctx context.Context, stale string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == nil || c.token.RefreshToken == "" {
+		return bookmarkd.ErrUnauthorized
+	} else if stale != "" && c.token.AccessToken != stale {
+		return nil
+	}
+
+	// Refresh tokens are single use so the lock is held until the new tokens
+	// are set to prevent concurrent refreshes.
+	resp, err := c.send(ctx, request{method: http.MethodPost, path: "/auth/refresh"}, nil, c.token.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	var t tokenResponse
+	if _, err := decode(resp, &t, false); err != nil {
+		return fmt.Errorf("refresh session: %w", err)
+	}
+	c.setToken(newToken(t))
+	return nil
+}
+
+// accessToken returns the access token of the current session, refreshing it
+// first if it is about to expire. Returns a blank token if not logged in.
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+
+	if token == nil {
+		return "", nil
+	}
+
+	if !token.ExpiresAt.IsZero() && time.Until(token.ExpiresAt) < refreshMargin && token.RefreshToken != "" {
+		if err := c.refresh(ctx, token.AccessToken); err != nil {
+			return "", err
+		}
+		c.mu.Lock()
+		token = c.token
+		c.mu.Unlock()
+	}
+	return token.AccessToken, nil
+}
+
+// newToken returns the tokens of a login, registration or refresh response.
+func newToken(resp tokenResponse) *Token {
+	return &Token{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(resp.Expires) * time.Second),
+	}
+}
+
+// Passcode returns the TOTP passcode at a given time for the otpauth url
+// returned by Register().
+func Passcode(totpUrl string, at time.Time) (string, error) {
+	key, err := otp.ParseKeyFromURL(totpUrl)
+	if err != nil {
+		return "", fmt.Errorf("parse totp url: %w", err)
+	}
+
+	totp, err := otp.NewTOTP(otp.TOTPConfig{
+		Algo:   key.Algorithm(),
+		Digits: key.Digits(),
+		Issuer: key.Issuer(),
+		Period: key.Period(),
+		Skew:   1, // only used to validate passcodes but must be set
+	})
+	if err != nil {
+		return "", fmt.Errorf("totp: %w", err)
+	}
+	return totp.GenerateCode(key.Secret(), at)
+}
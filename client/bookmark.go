@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// FindBookmarkByID returns a single bookmark by id.
+func (c *Client) FindBookmarkByID(ctx context.Context, id int) (*Bookmark, error) {
+	var b Bookmark
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/bookmarks/%d", id),
+	}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// FindBookmarks returns a page of bookmarks matching filter.
+func (c *Client) FindBookmarks(ctx context.Context, filter BookmarkFilter) (*BookmarkList, error) {
+	var list BookmarkList
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/bookmarks",
+		query:  encodeQuery(filter),
+	}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CreateBookmark creates a new bookmark & updates it with the stored values.
+// The mode decides how an existing bookmark with the same url is handled.
+func (c *Client) CreateBookmark(ctx context.Context, bookmark *Bookmark, mode DuplicateMode) error {
+	var query url.Values
+	if mode != "" {
+		query = url.Values{"duplicates": {string(mode)}}
+	}
+
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/bookmarks",
+		query:  query,
+		body:   bookmark,
+	}, bookmark)
+	return err
+}
+
+// UpdateBookmark updates a bookmark. If upd.Version is set the update fails
+// with ErrPreconditionFailed unless the bookmark is still at that version.
+func (c *Client) UpdateBookmark(ctx context.Context, id int, upd BookmarkUpdate) (*Bookmark, error) {
+	var b Bookmark
+	if _, err := c.do(ctx, request{
+		method: http.MethodPatch,
+		path:   fmt.Sprintf("/bookmarks/%d", id),
+		header: ifMatch(upd.Version),
+		body:   upd,
+	}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// DeleteBookmark removes a bookmark & returns its last state. If version is
+// set the delete fails with ErrPreconditionFailed unless the bookmark is still
+// at that version.
+func (c *Client) DeleteBookmark(ctx context.Context, id int, version *int) (*Bookmark, error) {
+	var b Bookmark
+	if _, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/bookmarks/%d", id),
+		header: ifMatch(version),
+	}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// FindBookmarkRevisions returns the revision history of the bookmark set on
+// filter.BookmarkID.
+func (c *Client) FindBookmarkRevisions(ctx context.Context, filter BookmarkRevisionFilter) ([]*BookmarkRevision, int, error) {
+	if filter.BookmarkID == nil {
+		return nil, 0, fmt.Errorf("bookmark id required")
+	}
+
+	var resp struct {
+		Revisions []*BookmarkRevision `json:"revisions"`
+		N         int                 `json:"n"`
+	}
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/bookmarks/%d/revisions", *filter.BookmarkID),
+		query:  encodeQuery(filter),
+	}, &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp.Revisions, resp.N, nil
+}
+
+// RevertBookmark restores a bookmark to the values before a revision.
+func (c *Client) RevertBookmark(ctx context.Context, id int, revisionID int) (*Bookmark, error) {
+	var b Bookmark
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   fmt.Sprintf("/bookmarks/%d/revisions/%d/revert", id, revisionID),
+	}, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// FindDuplicateBookmarks lists groups of bookmarks sharing a canonical url.
+func (c *Client) FindDuplicateBookmarks(ctx context.Context) ([]*BookmarkDuplicates, error) {
+	var resp struct {
+		Duplicates []*BookmarkDuplicates `json:"duplicates"`
+	}
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/bookmarks/duplicates",
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Duplicates, nil
+}
+
+// UpdateBookmarksStatus sets the status of every bookmark matching filter.
+// Returns the number of bookmarks changed.
+func (c *Client) UpdateBookmarksStatus(ctx context.Context, filter BookmarkFilter, status BookmarkStatus) (int, error) {
+	var resp struct {
+		N int `json:"n"`
+	}
+	if _, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   "/bookmarks/status",
+		body: struct {
+			Status BookmarkStatus `json:"status"`
+			Filter BookmarkFilter `json:"filter"`
+		}{status, filter},
+	}, &resp); err != nil {
+		return 0, err
+	}
+	return resp.N, nil
+}
+
+// BatchBookmarks applies a list of operations in a single transaction. The
+// Err of a failed operation's result is set to an *Error.
+func (c *Client) BatchBookmarks(ctx context.Context, batch *BookmarkBatch) (*BookmarkBatchResult, error) {
+	var resp struct {
+		Committed bool `json:"committed"`
+		Results   []*struct {
+			*BookmarkOpResult
+
+			// Error of a failed operation & the status code it would have
+			// returned as an individual request.
+			Code  int `json:"code"`
+			Error *struct {
+				Error string `json:"error"`
+				Field string `json:"field"`
+			} `json:"error"`
+		} `json:"results"`
+	}
+	if _, err := c.do(ctx, request{
+		method:       http.MethodPost,
+		path:         "/bookmarks/batch",
+		body:         batch,
+		decodeErrors: true,
+	}, &resp); err != nil && len(resp.Results) == 0 {
+		return nil, err
+	}
+
+	// A rolled back batch responds with an error status but still holds the
+	// result of every operation.
+	result := &BookmarkBatchResult{Committed: resp.Committed}
+	for _, item := range resp.Results {
+		res := item.BookmarkOpResult
+		if res == nil {
+			res = &BookmarkOpResult{}
+		}
+		if item.Error != nil {
+			res.Err = newError(item.Code, item.Error.Error, item.Error.Field)
+		}
+		result.Results = append(result.Results, res)
+	}
+	return result, nil
+}
+
+// ifMatch returns the If-Match header requiring a bookmark version, if set.
+func ifMatch(version *int) http.Header {
+	if version == nil {
+		return nil
+	}
+	return http.Header{"If-Match": {fmt.Sprintf(`"%d"`, *version)}}
+}
@@ -0,0 +1,211 @@
+// Package client implements a Go client for the bookmarkd HTTP API.
+//
+// The client defines its own types for the JSON the API sends & receives so it
+// can be imported without the server. Requests are authenticated with the
+// tokens returned by Login() and access tokens are refreshed automatically.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before expiry an access token is refreshed.
+const refreshMargin = 10 * time.Second
+
+// Token represents the tokens of an authenticated session. It may be stored
+// & restored with SetToken() to resume a session.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Client represents a client to the bookmarkd HTTP API.
+type Client struct {
+	// Url of the API including the base path, e.g. "https://example.com/api".
+	URL string
+
+	// HTTP client used to send requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Called with the new tokens whenever they change, e.g. after a refresh,
+	// so they can be persisted. Called with nil after Logout(). Must not call
+	// back into the client.
+	OnToken func(token *Token)
+
+	// Token of the admin api, set with BOOKMARKD_ADMIN_TOKEN on the server.
+	// Only used by admin methods such as CreateBackup().
+	AdminToken string
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewClient returns a new instance of Client for the API at url.
+func NewClient(url string) *Client {
+	return &Client{URL: strings.TrimSuffix(url, "/")}
+}
+
+// Token returns the tokens of the current session or nil if not logged in.
+func (c *Client) Token() *Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == nil {
+		return nil
+	}
+	other := *c.token
+	return &other
+}
+
+// SetToken sets the tokens used to authenticate requests.
+func (c *Client) SetToken(token *Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setToken(token)
+}
+
+// setToken sets the tokens & notifies OnToken. Must hold mu.
+func (c *Client) setToken(token *Token) {
+	if token != nil {
+		other := *token
+		token = &other
+	}
+	c.token = token
+
+	if c.OnToken != nil {
+		c.OnToken(token)
+	}
+}
+
+// request describes a single API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   interface{}
+
+	// Decode the body of error responses into v as well. Batches respond
+	// with the result of every operation even if one of them failed.
+	decodeErrors bool
+
+	// Authenticate with the admin token instead of the session.
+	admin bool
+}
+
+// do sends req and decodes the JSON response body into v, if not nil. If the
+// access token is rejected it is refreshed once & the request is retried.
+// Error responses are returned as *Error. Returns the response headers.
+func (c *Client) do(ctx context.Context, req request, v interface{}) (http.Header, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("encode request body: %w", err)
+		}
+	}
+
+	// The admin token is static & never refreshed.
+	if req.admin {
+		resp, err := c.send(ctx, req, body, c.AdminToken)
+		if err != nil {
+			return nil, err
+		}
+		return decode(resp, v, req.decodeErrors)
+	}
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, req, body, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && token != "" {
+		resp.Body.Close()
+		if err := c.refresh(ctx, token); err != nil {
+			return nil, err
+		}
+		if token, err = c.accessToken(ctx); err != nil {
+			return nil, err
+		} else if resp, err = c.send(ctx, req, body, token); err != nil {
+			return nil, err
+		}
+	}
+	return decode(resp, v, req.decodeErrors)
+}
+
+// decode decodes the JSON body of resp into v, if not nil, and closes the body.
+// Error responses are returned as *Error and are only decoded into v if
+// decodeErrors is set. Returns the response headers.
+func decode(resp *http.Response, v interface{}, decodeErrors bool) (http.Header, error) {
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.Header, fmt.Errorf("read response body: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		if decodeErrors && v != nil {
+			json.Unmarshal(buf, v)
+		}
+		return resp.Header, errorFromBody(resp.StatusCode, buf)
+	}
+
+	if v != nil && resp.StatusCode != http.StatusNotModified {
+		if err := json.Unmarshal(buf, v); err != nil {
+			return resp.Header, fmt.Errorf("decode response body: %w", err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// send sends a single HTTP request authenticated with token, if not blank.
+func (c *Client) send(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	u := c.URL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, r)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range req.header {
+		httpReq.Header[name] = values
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return c.httpClient().Do(httpReq)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,330 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/httplog/v2"
+	"github.com/gorilla/websocket"
+
+	"bookmarkd"
+	"bookmarkd/client"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/inmem"
+	"bookmarkd/internal/server"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
+)
+
+func TestClient(t *testing.T) {
+	c := MustOpenClient(t)
+	ctx := context.Background()
+
+	MustRegister(t, ctx, c, "jane")
+
+	t.Run("CurrentUser", func(t *testing.T) {
+		u, err := c.CurrentUser(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, u.Username, "jane")
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		other := client.NewClient(c.URL)
+		other.SetToken(c.Token())
+
+		sessions, err := c.FindSessions(ctx, client.SessionFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, sessions.N, 1)
+
+		s, err := c.FindSessionByID(ctx, sessions.Sessions[0].ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, s.ID, sessions.Sessions[0].ID)
+
+		// Ensure a revoked session can no longer be used.
+		_, err = other.DeleteSession(ctx, s.ID)
+		require.Equal(t, err, nil)
+		_, err = c.CurrentUser(ctx)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+
+		MustLogin(t, ctx, c, "jane")
+	})
+
+	t.Run("Bookmarks", func(t *testing.T) {
+		b := &client.Bookmark{Name: "Go", Url: "https://go.dev", Status: client.BookmarkStatusUnread}
+		require.Equal(t, c.CreateBookmark(ctx, b, ""), nil)
+		require.Equal(t, b.Version, 1)
+
+		name := "Golang"
+		upd, err := c.UpdateBookmark(ctx, b.ID, client.BookmarkUpdate{Name: &name, Version: &b.Version})
+		require.Equal(t, err, nil)
+		require.Equal(t, upd.Name, "Golang")
+
+		// Ensure a stale version is rejected.
+		_, err = c.UpdateBookmark(ctx, b.ID, client.BookmarkUpdate{Name: &name, Version: &b.Version})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrPreconditionFailed), true)
+
+		starred := false
+		bookmarks, err := c.FindBookmarks(ctx, client.BookmarkFilter{Starred: &starred})
+		require.Equal(t, err, nil)
+		require.Equal(t, bookmarks.N, 1)
+		require.Equal(t, bookmarks.Bookmarks[0].Name, "Golang")
+
+		_, err = c.DeleteBookmark(ctx, b.ID, &upd.Version)
+		require.Equal(t, err, nil)
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		result, err := c.BatchBookmarks(ctx, &client.BookmarkBatch{Operations: []client.BookmarkOp{
+			{Op: client.BookmarkOpCreate, Bookmark: &client.Bookmark{Name: "A", Url: "https://a.com", Status: client.BookmarkStatusUnread}},
+			{Op: client.BookmarkOpDelete, ID: 999},
+		}})
+		require.Equal(t, err, nil)
+		require.Equal(t, result.Committed, false)
+		require.Equal(t, result.Results[0].Status, client.BookmarkOpStatusRolledBack)
+		require.Equal(t, errors.Is(result.Results[1].Err, bookmarkd.ErrNotFound), true)
+	})
+
+	// Ensure filters are encoded as the server expects & pages can be walked
+	// with the returned cursors.
+	t.Run("Pagination", func(t *testing.T) {
+		for _, name := range []string{"C", "D", "E"} {
+			b := &client.Bookmark{Name: name, Url: "https://" + name + ".com", Status: client.BookmarkStatusRead}
+			require.Equal(t, c.CreateBookmark(ctx, b, ""), nil)
+		}
+
+		status := client.BookmarkStatusRead
+		filter := client.BookmarkFilter{Status: &status, Sort: "name", Direction: "desc", Limit: 2}
+		page, err := c.FindBookmarks(ctx, filter)
+		require.Equal(t, err, nil)
+		require.Equal(t, page.N, 3)
+		require.Equal(t, len(page.Bookmarks), 2)
+		require.Equal(t, page.Bookmarks[0].Name, "E")
+		require.NotEqual(t, page.NextCursor, "")
+
+		filter.Cursor = page.NextCursor
+		page, err = c.FindBookmarks(ctx, filter)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(page.Bookmarks), 1)
+		require.Equal(t, page.Bookmarks[0].Name, "C")
+		require.Equal(t, page.NextCursor, "")
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		_, err := c.FindBookmarkByID(ctx, 999)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	t.Run("ErrInvalidInput", func(t *testing.T) {
+		err := c.CreateBookmark(ctx, &client.Bookmark{Url: "https://go.dev"}, "")
+
+		var verr *bookmarkd.ValidationError
+		require.Equal(t, errors.As(err, &verr), true)
+		require.Equal(t, verr.Field, "name")
+	})
+
+	t.Run("ErrUsersUsernameConflict", func(t *testing.T) {
+		_, err := c.Register(ctx, "jane")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUsersUsernameConflict), true)
+	})
+}
+
+// Ensure expired & rejected access tokens are refreshed automatically.
+func TestClient_Refresh(t *testing.T) {
+	c := MustOpenClient(t)
+	ctx := context.Background()
+
+	MustRegister(t, ctx, c, "jane")
+
+	t.Run("Expired", func(t *testing.T) {
+		token := c.Token()
+		c.SetToken(&client.Token{AccessToken: token.AccessToken, RefreshToken: token.RefreshToken, ExpiresAt: time.Now()})
+
+		_, err := c.CurrentUser(ctx)
+		require.Equal(t, err, nil)
+		require.NotEqual(t, c.Token().RefreshToken, token.RefreshToken)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		token := c.Token()
+		c.SetToken(&client.Token{AccessToken: "invalid", RefreshToken: token.RefreshToken, ExpiresAt: token.ExpiresAt})
+
+		_, err := c.CurrentUser(ctx)
+		require.Equal(t, err, nil)
+		require.NotEqual(t, c.Token().AccessToken, "invalid")
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		require.Equal(t, c.Logout(ctx), nil)
+		require.Equal(t, c.Token() == nil, true)
+
+		_, err := c.CurrentUser(ctx)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	c := MustOpenClient(t)
+	ctx := context.Background()
+
+	MustRegister(t, ctx, c, "jane")
+
+	sub, err := c.Subscribe(ctx)
+	require.Equal(t, err, nil)
+	defer sub.Close()
+
+	b := &client.Bookmark{Name: "Go", Url: "https://go.dev", Status: client.BookmarkStatusUnread}
+	require.Equal(t, c.CreateBookmark(ctx, b, ""), nil)
+
+	select {
+	case event := <-sub.C():
+		require.Equal(t, event.Type, client.EventTypeBookmarkAdded)
+		payload, ok := event.Payload.(*client.EventTypeBookmarkAddedPayload)
+		require.Equal(t, ok, true)
+		require.Equal(t, payload.Bookmark.ID, b.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	// Ensure the stream is closed with the subscription.
+	sub.Close()
+	for range sub.C() {
+	}
+}
+
+// Ensure subscribers are told the server is going away when it shuts down.
+func TestClient_Subscribe_Shutdown(t *testing.T) {
+	c, eventService := MustOpenClientEventService(t)
+	ctx := context.Background()
+
+	MustRegister(t, ctx, c, "jane")
+
+	sub, err := c.Subscribe(ctx)
+	require.Equal(t, err, nil)
+	defer sub.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.Equal(t, eventService.Shutdown(shutdownCtx), nil)
+
+	for range sub.C() {
+	}
+	require.Equal(t, websocket.IsCloseError(errors.Unwrap(sub.Err()), websocket.CloseGoingAway), true)
+}
+
+func TestClient_Backups(t *testing.T) {
+	c := MustOpenClient(t)
+	ctx := context.Background()
+
+	// Ensure the admin token is required.
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		c.AdminToken = "invalid"
+		_, err := c.CreateBackup(ctx)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		c.AdminToken = AdminToken
+		b, err := c.CreateBackup(ctx)
+		require.Equal(t, err, nil)
+		require.NotEqual(t, b.Size, int64(0))
+
+		backups, err := c.FindBackups(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(backups), 1)
+		require.Equal(t, backups[0].Name, b.Name)
+	})
+}
+
+// AdminToken is the admin token of the test server.
+const AdminToken = "admin"
+
+// MustOpenClient returns a client connected to a test server backed by an
+// in-memory database.
+func MustOpenClient(tb testing.TB) *client.Client {
+	tb.Helper()
+	c, _ := MustOpenClientEventService(tb)
+	return c
+}
+
+// MustOpenClientEventService returns a client like MustOpenClient() along
+// with the event service of the test server.
+func MustOpenClientEventService(tb testing.TB) (*client.Client, *inmem.EventService) {
+	tb.Helper()
+
+	config, err := core.NewConfig(func(string) string { return "" })
+	if err != nil {
+		tb.Fatal(err)
+	}
+	config.AdminToken = AdminToken
+
+	db := sqlite.NewDB(":memory:")
+	eventService := inmem.NewEventService()
+	db.EventService = eventService
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+	r := server.NewRouter(
+		logger,
+		config,
+		server.NewHealth(),
+		inmem.NewRegistrationStore(),
+		sqlite.NewBackupStore(db, tb.TempDir()),
+		sqlite.NewBookmarkStore(db),
+		eventService,
+		sqlite.NewIdempotencyKeyStore(db, time.Hour),
+		sqlite.NewSessionStore(db),
+		sqlite.NewUserStore(db),
+	)
+
+	s := httptest.NewServer(r)
+	tb.Cleanup(s.Close)
+
+	return client.NewClient(s.URL + config.HttpBasePath), eventService
+}
+
+// MustLogin logs in an existing user.
+func MustLogin(tb testing.TB, ctx context.Context, c *client.Client, username string) {
+	tb.Helper()
+
+	if err := c.Login(ctx, username, MustPasscode(tb, username)); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// MustPasscode returns the current passcode of a user registered with
+// MustRegister().
+func MustPasscode(tb testing.TB, username string) string {
+	tb.Helper()
+
+	passcode, err := client.Passcode(totpUrls[username], time.Now())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return passcode
+}
+
+// totpUrls holds the TOTP url of each user registered with MustRegister().
+var totpUrls = make(map[string]string)
+
+// MustRegister registers & logs in a new user.
+func MustRegister(tb testing.TB, ctx context.Context, c *client.Client, username string) {
+	tb.Helper()
+
+	reg, err := c.Register(ctx, username)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	totpUrls[username] = reg.TotpUrl
+
+	if err := c.ConfirmRegistration(ctx, reg.RegistrationID, MustPasscode(tb, username)); err != nil {
+		tb.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"bookmarkd"
+)
+
+// Error represents an error response of the API. It wraps the bookmarkd error
+// the server responded with so it can be matched with errors.Is(), e.g.
+// errors.Is(err, bookmarkd.ErrNotFound).
+type Error struct {
+	// HTTP status code of the response.
+	StatusCode int
+
+	// Error message & offending field as returned by the server.
+	Message string
+	Field   string
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// statusErrors lists the errors the server returns for each status code. If a
+// status has several, the one contained in the message is chosen.
+var statusErrors = map[int][]error{
+	http.StatusInternalServerError:  {bookmarkd.ErrInternal},
+	http.StatusNotFound:             {bookmarkd.ErrNotFound},
+	http.StatusUnauthorized:         {bookmarkd.ErrUnauthorized},
+	http.StatusForbidden:            {bookmarkd.ErrForbidden},
+	http.StatusBadRequest:           {bookmarkd.ErrBadRequest},
+	http.StatusNotAcceptable:        {bookmarkd.ErrInvalidInput, bookmarkd.ErrUsersUsernameConflict},
+	http.StatusPreconditionFailed:   {bookmarkd.ErrPreconditionFailed},
+	http.StatusPreconditionRequired: {bookmarkd.ErrPreconditionRequired},
+	http.StatusUnprocessableEntity:  {bookmarkd.ErrIdempotencyKeyReused},
+	http.StatusConflict:             {bookmarkd.ErrIdempotencyKeyInProgress, bookmarkd.ErrBookmarkDuplicate, bookmarkd.ErrConflict},
+}
+
+// errorFromBody returns the *Error of an error response with the given body.
+func errorFromBody(statusCode int, buf []byte) error {
+	var body struct {
+		Error string `json:"error"`
+		Field string `json:"field"`
+
+		// Unknown routes respond with a message instead.
+		Message string `json:"message"`
+	}
+	json.Unmarshal(buf, &body)
+
+	message := body.Error
+	if message == "" {
+		message = body.Message
+	}
+	if message == "" {
+		message = http.StatusText(statusCode)
+	}
+	return newError(statusCode, message, body.Field)
+}
+
+// newError returns an *Error wrapping the bookmarkd error matching the status
+// code & message of an error response.
+func newError(statusCode int, message, field string) *Error {
+	e := &Error{StatusCode: statusCode, Message: message, Field: field}
+
+	candidates := statusErrors[statusCode]
+	for _, err := range candidates {
+		if strings.Contains(message, err.Error()) {
+			e.err = err
+			break
+		}
+	}
+	if e.err == nil && len(candidates) > 0 {
+		e.err = candidates[0]
+	} else if e.err == nil && statusCode >= http.StatusInternalServerError {
+		e.err = bookmarkd.ErrInternal
+	}
+
+	// Restore validation errors so the field can be read with errors.As().
+	if errors.Is(e.err, bookmarkd.ErrInvalidInput) && field != "" {
+		e.err = &bookmarkd.ValidationError{
+			Field:   field,
+			Message: strings.TrimPrefix(message, bookmarkd.ErrInvalidInput.Error()+": "),
+		}
+	}
+	return e
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// eventPayloads returns a new payload for each event type.
+var eventPayloads = map[string]func() interface{}{
+	EventTypeBookmarkAdded:              func() interface{} { return &EventTypeBookmarkAddedPayload{} },
+	EventTypeBookmarkNameChanged:        func() interface{} { return &EventTypeBookmarkNameChangedPayload{} },
+	EventTypeBookmarkDescriptionChanged: func() interface{} { return &EventTypeBookmarkDescriptionChangedPayload{} },
+	EventTypeBookmarkUrlChanged:         func() interface{} { return &EventTypeBookmarkUrlChangedPayload{} },
+	EventTypeBookmarkStatusChanged:      func() interface{} { return &EventTypeBookmarkStatusChangedPayload{} },
+	EventTypeBookmarkStarredChanged:     func() interface{} { return &EventTypeBookmarkStarredChangedPayload{} },
+	EventTypeBookmarkRemoved:            func() interface{} { return &EventTypeBookmarkRemovedPayload{} },
+	EventTypeBookmarkReverted:           func() interface{} { return &EventTypeBookmarkRevertedPayload{} },
+}
+
+// Subscribe opens a websocket to the server & streams the current user's
+// events. The payload of each event is a pointer to the payload type of its
+// event type. Payloads of unknown types are left as json.RawMessage.
+//
+// The subscription ends when ctx is done, Close() is called or the
+// connection is lost. Caller must call Close() when done.
+func (c *Client) Subscribe(ctx context.Context) (*EventSubscription, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := c.dial(ctx, token)
+	if e := (*Error)(nil); errors.As(err, &e) && e.StatusCode == http.StatusUnauthorized && token != "" {
+		if err := c.refresh(ctx, token); err != nil {
+			return nil, err
+		}
+		if token, err = c.accessToken(ctx); err != nil {
+			return nil, err
+		}
+		conn, err = c.dial(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &EventSubscription{
+		c:    make(chan Event, 16),
+		conn: conn,
+	}
+	sub.ctx, sub.cancel = context.WithCancel(ctx)
+
+	go sub.monitor()
+	go sub.read()
+
+	return sub, nil
+}
+
+// dial opens the websocket of the events endpoint authenticated with token.
+func (c *Client) dial(ctx context.Context, token string) (*websocket.Conn, error) {
+	u := c.URL + "/events"
+	if rest, ok := strings.CutPrefix(u, "http"); ok {
+		u = "ws" + rest
+	}
+
+	header := make(http.Header)
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	dialer := *websocket.DefaultDialer
+	if c.HTTPClient != nil && c.HTTPClient.Jar != nil {
+		dialer.Jar = c.HTTPClient.Jar
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, u, header)
+	if err != nil && resp != nil {
+		defer resp.Body.Close()
+		buf, _ := io.ReadAll(resp.Body)
+		return nil, errorFromBody(resp.StatusCode, buf)
+	} else if err != nil {
+		return nil, fmt.Errorf("dial events: %w", err)
+	}
+	return conn, nil
+}
+
+// EventSubscription represents a stream of events received over a websocket.
+type EventSubscription struct {
+	c    chan Event
+	conn *websocket.Conn
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	once sync.Once
+	mu   sync.Mutex
+	err  error
+}
+
+// C returns the event stream. It is closed when the subscription ends.
+func (s *EventSubscription) C() <-chan Event {
+	return s.c
+}
+
+// Close disconnects from the server & closes the event stream.
+func (s *EventSubscription) Close() error {
+	s.cancel()
+	return nil
+}
+
+// Err returns the error that ended the subscription, if any. Returns nil if
+// the subscription is still open or was closed by the caller.
+func (s *EventSubscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// monitor closes the connection once the subscription ends.
+func (s *EventSubscription) monitor() {
+	<-s.ctx.Done()
+	s.once.Do(func() {
+		s.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		s.conn.Close()
+	})
+}
+
+// read decodes incoming messages onto the event stream until the connection
+// is closed.
+func (s *EventSubscription) read() {
+	defer close(s.c)
+	defer s.cancel()
+
+	for {
+		_, buf, err := s.conn.ReadMessage()
+		if err != nil {
+			if s.ctx.Err() == nil && !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				s.setErr(fmt.Errorf("read event: %w", err))
+			}
+			return
+		}
+
+		event, err := decodeEvent(buf)
+		if err != nil {
+			s.setErr(err)
+			return
+		}
+
+		select {
+		case s.c <- event:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *EventSubscription) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// decodeEvent decodes a JSON encoded event & its typed payload.
+func decodeEvent(buf []byte) (Event, error) {
+	var msg struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(buf, &msg); err != nil {
+		return Event{}, fmt.Errorf("decode event: %w", err)
+	}
+
+	newPayload, ok := eventPayloads[msg.Type]
+	if !ok {
+		return Event{Type: msg.Type, Payload: msg.Payload}, nil
+	}
+
+	payload := newPayload()
+	if err := json.Unmarshal(msg.Payload, payload); err != nil {
+		return Event{}, fmt.Errorf("decode %s event payload: %w", msg.Type, err)
+	}
+	return Event{Type: msg.Type, Payload: payload}, nil
+}
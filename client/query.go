@@ -0,0 +1,55 @@
+package client
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// encodeQuery encodes the fields of the struct v as query parameters named
+// after their json tags. Nil pointers & zero values are omitted.
+func encodeQuery(v interface{}) url.Values {
+	values := make(url.Values)
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := queryName(field)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		} else if fv.IsZero() {
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.String:
+			values.Set(name, fv.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			values.Set(name, strconv.FormatInt(fv.Int(), 10))
+		case reflect.Bool:
+			values.Set(name, strconv.FormatBool(fv.Bool()))
+		}
+	}
+	return values
+}
+
+// queryName returns the query parameter name of a struct field.
+func queryName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
@@ -0,0 +1,345 @@
+package client
+
+import (
+	"time"
+)
+
+// User represents a user of the API.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+
+	// Time the user was disabled by an operator. Disabled users cannot log in.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// List of active sessions.
+	Sessions []*Session `json:"sessions"`
+}
+
+// Session represents a logged in session of a user.
+type Session struct {
+	ID     int    `json:"id"`
+	UserID string `json:"userID"`
+	User   *User  `json:"user"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAd"`
+}
+
+// SessionFilter represents a filter passed to FindSessions().
+type SessionFilter struct {
+	// Filtering fields.
+	ID *int `json:"id"`
+
+	// Ordering of results. Sort is one of "created" or "updated" and
+	// defaults to "created". Direction defaults to ascending.
+	Sort      string `json:"sort"`
+	Direction string `json:"direction"`
+
+	// Cursor of a previous page, see SessionList.
+	Cursor string `json:"cursor"`
+
+	// Restrict to subset of range.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// SessionList represents a page of sessions returned by FindSessions().
+type SessionList struct {
+	Sessions []*Session `json:"sessions"`
+
+	// Total number of matching sessions.
+	N int `json:"n"`
+
+	// Cursors of the pages after & before this page. Blank if there is none.
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// Bookmark represents a bookmark of a user.
+type Bookmark struct {
+	ID     int    `json:"id"`
+	UserID string `json:"userID"`
+	User   *User  `json:"user"`
+
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Url         string `json:"url"`
+
+	// Canonical form of Url used by the server to detect duplicates.
+	CanonicalUrl string `json:"canonicalUrl"`
+
+	// Reading state of the bookmark. Defaults to unread.
+	Status  BookmarkStatus `json:"status"`
+	Starred bool           `json:"starred"`
+
+	// Timestamp the bookmark was marked as read. Zero while unread.
+	ReadAt time.Time `json:"readAt"`
+
+	// Incremented on every update. Used to detect conflicting changes.
+	Version int `json:"version"`
+
+	// How well the bookmark matches BookmarkFilter.Search. Only set when
+	// searching; a higher value is a better match.
+	Relevance int `json:"relevance,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BookmarkStatus represents the reading state of a bookmark.
+type BookmarkStatus string
+
+// Bookmark statuses.
+const (
+	BookmarkStatusUnread   BookmarkStatus = "unread"
+	BookmarkStatusRead     BookmarkStatus = "read"
+	BookmarkStatusArchived BookmarkStatus = "archived"
+)
+
+// DuplicateMode decides how CreateBookmark() handles an existing bookmark
+// with the same canonical url.
+type DuplicateMode string
+
+// Duplicate modes.
+const (
+	DuplicateModeAllow  DuplicateMode = "allow"
+	DuplicateModeReject DuplicateMode = "reject"
+	DuplicateModeMerge  DuplicateMode = "merge"
+)
+
+// BookmarkFilter represents a filter passed to FindBookmarks() &
+// UpdateBookmarksStatus().
+type BookmarkFilter struct {
+	// Filtering fields.
+	ID           *int            `json:"id"`
+	CanonicalUrl *string         `json:"canonicalUrl"`
+	Status       *BookmarkStatus `json:"status"`
+	Starred      *bool           `json:"starred"`
+
+	// Matches bookmarks whose name, description or url contain the text.
+	Search *string `json:"search"`
+
+	// Ordering of results. Sort is one of "created", "updated", "name" or
+	// "relevance" and defaults to "created". Direction defaults to ascending
+	// except for relevance which lists the best matches first.
+	Sort      string `json:"sort"`
+	Direction string `json:"direction"`
+
+	// Cursor of a previous page, see BookmarkList.
+	Cursor string `json:"cursor"`
+
+	// Restrict to subset of range.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// BookmarkList represents a page of bookmarks returned by FindBookmarks().
+type BookmarkList struct {
+	Bookmarks []*Bookmark `json:"bookmarks"`
+
+	// Total number of matching bookmarks.
+	N int `json:"n"`
+
+	// Cursors of the pages after & before this page. Blank if there is none.
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
+}
+
+// BookmarkUpdate represents a set of fields to update on a bookmark.
+type BookmarkUpdate struct {
+	Name        *string         `json:"name"`
+	Description *string         `json:"description"`
+	Url         *string         `json:"url"`
+	Status      *BookmarkStatus `json:"status"`
+	Starred     *bool           `json:"starred"`
+
+	// If set, the update fails with ErrPreconditionFailed unless the
+	// bookmark is still at this version.
+	Version *int `json:"-"`
+}
+
+// BookmarkDuplicates represents a group of bookmarks sharing a canonical url.
+type BookmarkDuplicates struct {
+	CanonicalUrl string      `json:"canonicalUrl"`
+	Bookmarks    []*Bookmark `json:"bookmarks"`
+}
+
+// BookmarkRevision represents a single update in the history of a bookmark.
+type BookmarkRevision struct {
+	ID         int    `json:"id"`
+	BookmarkID int    `json:"bookmarkID"`
+	UserID     string `json:"userID"`
+
+	// Old and new values of every field set on the update.
+	Changes []BookmarkFieldChange `json:"changes"`
+
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BookmarkFieldChange represents the old and new value of an updated field.
+type BookmarkFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// BookmarkRevisionFilter represents a filter passed to FindBookmarkRevisions().
+type BookmarkRevisionFilter struct {
+	// Bookmark whose history is listed. Required.
+	BookmarkID *int `json:"-"`
+
+	// Restrict to subset of range.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// BatchMode decides whether the operations of a batch succeed or fail together.
+type BatchMode string
+
+// Batch modes.
+const (
+	// Every operation must succeed or the batch is rolled back. Default.
+	BatchModeAtomic BatchMode = "atomic"
+
+	// Failed operations are skipped & the others are committed.
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// Batch operation types.
+const (
+	BookmarkOpCreate = "create"
+	BookmarkOpUpdate = "update"
+	BookmarkOpDelete = "delete"
+)
+
+// Batch operation result statuses.
+const (
+	BookmarkOpStatusOK         = "ok"
+	BookmarkOpStatusFailed     = "failed"
+	BookmarkOpStatusRolledBack = "rolled_back"
+	BookmarkOpStatusSkipped    = "skipped"
+)
+
+// BookmarkBatch represents a list of operations passed to BatchBookmarks().
+type BookmarkBatch struct {
+	Mode       BatchMode    `json:"mode"`
+	Operations []BookmarkOp `json:"operations"`
+}
+
+// BookmarkOp represents a single operation of a batch.
+type BookmarkOp struct {
+	// Operation type. One of "create", "update" or "delete".
+	Op string `json:"op"`
+
+	// Bookmark to update or delete. If version is set the operation fails
+	// with ErrPreconditionFailed unless the bookmark is still at that version.
+	ID      int  `json:"id,omitempty"`
+	Version *int `json:"version,omitempty"`
+
+	// Bookmark to create & how to handle an existing duplicate.
+	Bookmark   *Bookmark     `json:"bookmark,omitempty"`
+	Duplicates DuplicateMode `json:"duplicates,omitempty"`
+
+	// Fields to update.
+	Update *BookmarkUpdate `json:"update,omitempty"`
+}
+
+// BookmarkBatchResult represents the result of BatchBookmarks().
+type BookmarkBatchResult struct {
+	// True if the batch was committed.
+	Committed bool `json:"committed"`
+
+	// Result of each operation in the same order as the batch.
+	Results []*BookmarkOpResult `json:"results"`
+}
+
+// BookmarkOpResult represents the result of a single operation of a batch.
+type BookmarkOpResult struct {
+	Op     string `json:"op"`
+	Status string `json:"status"`
+
+	// State of the bookmark after the operation. For deletes this is the
+	// state before removal.
+	Bookmark *Bookmark `json:"bookmark,omitempty"`
+
+	// Error of a failed operation, an *Error.
+	Err error `json:"-"`
+}
+
+// Backup represents a backup of the server's database.
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Event represents an event streamed by Subscribe(). Payload is a pointer to
+// the payload type matching the event type.
+type Event struct {
+	Type    string      `json:"type"`
+	Payload interface{} `json:"payload"`
+}
+
+// Event types.
+const (
+	EventTypeBookmarkAdded              = "bookmark:added"
+	EventTypeBookmarkNameChanged        = "bookmark:name_changed"
+	EventTypeBookmarkDescriptionChanged = "bookmark:description_changed"
+	EventTypeBookmarkUrlChanged         = "bookmark:url_changed"
+	EventTypeBookmarkStatusChanged      = "bookmark:status_changed"
+	EventTypeBookmarkStarredChanged     = "bookmark:starred_changed"
+	EventTypeBookmarkRemoved            = "bookmark:removed"
+	EventTypeBookmarkReverted           = "bookmark:reverted"
+)
+
+// Event payload types.
+type (
+	EventTypeBookmarkAddedPayload struct {
+		Bookmark *Bookmark `json:"bookmark"`
+	}
+
+	EventTypeBookmarkNameChangedPayload struct {
+		ID        int       `json:"id"`
+		Name      string    `json:"name"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	EventTypeBookmarkDescriptionChangedPayload struct {
+		ID          int       `json:"id"`
+		Description string    `json:"description"`
+		UpdatedAt   time.Time `json:"updatedAt"`
+	}
+
+	EventTypeBookmarkUrlChangedPayload struct {
+		ID        int       `json:"id"`
+		Url       string    `json:"url"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	EventTypeBookmarkStatusChangedPayload struct {
+		ID        int            `json:"id"`
+		Status    BookmarkStatus `json:"status"`
+		ReadAt    time.Time      `json:"readAt"`
+		UpdatedAt time.Time      `json:"updatedAt"`
+	}
+
+	EventTypeBookmarkStarredChangedPayload struct {
+		ID        int       `json:"id"`
+		Starred   bool      `json:"starred"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	EventTypeBookmarkRemovedPayload struct {
+		ID int `json:"id"`
+	}
+
+	EventTypeBookmarkRevertedPayload struct {
+		ID         int       `json:"id"`
+		RevisionID int       `json:"revisionID"`
+		UpdatedAt  time.Time `json:"updatedAt"`
+	}
+)
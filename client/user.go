@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// userIDMe is the user id of the current user in API paths.
+const userIDMe = "me"
+
+// FindUserByID returns a single user by id. Use "me" for the current user.
+func (c *Client) FindUserByID(ctx context.Context, id string) (*User, error) {
+	var u User
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/users/" + url.PathEscape(id),
+	}, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// CurrentUser returns the user of the current session.
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	return c.FindUserByID(ctx, userIDMe)
+}
+
+// FindSessionByID returns a single session of the current user by id.
+func (c *Client) FindSessionByID(ctx context.Context, id int) (*Session, error) {
+	var s Session
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/users/%s/sessions/%d", userIDMe, id),
+	}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindSessions returns a page of the current user's sessions.
+func (c *Client) FindSessions(ctx context.Context, filter SessionFilter) (*SessionList, error) {
+	var list SessionList
+	if _, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/users/" + userIDMe + "/sessions",
+		query:  encodeQuery(filter),
+	}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// DeleteSession revokes a session of the current user & returns it.
+func (c *Client) DeleteSession(ctx context.Context, id int) (*Session, error) {
+	var s Session
+	if _, err := c.do(ctx, request{
+		method: http.MethodDelete,
+		path:   fmt.Sprintf("/users/%s/sessions/%d", userIDMe, id),
+	}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
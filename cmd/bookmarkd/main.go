@@ -2,21 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/inmem"
+	"bookmarkd/internal/postgres"
 	"bookmarkd/internal/server"
 	"bookmarkd/internal/sqlite"
+	"bookmarkd/internal/systemd"
 
 	"github.com/go-chi/httplog/v2"
 )
@@ -33,18 +39,72 @@ func main() {
 	bookmarkd.Commit = commit
 
 	ctx := context.Background()
-	if err := run(ctx, os.Getenv, os.Stdout); err != nil {
+	if err := run(ctx, os.Args[1:], os.Getenv, os.Stdout, os.Stderr); errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	} else if err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
 	}
 }
 
+// run parses the flags & runs the server, or the command named by args.
 func run(
 	ctx context.Context,
+	args []string,
 	getenv func(string) string,
 	stdout io.Writer,
+	stderr io.Writer,
 ) error {
-	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
+	fs := flag.NewFlagSet("bookmarkd", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", getenv("BOOKMARKD_CONFIG"), "config file path, defaults to $BOOKMARKD_CONFIG")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, strings.TrimSpace(`
+bookmarkd is a bookmark server.
+
+Usage:
+
+	bookmarkd [flags]                run the server
+	bookmarkd [flags] config print   print the effective config, secrets redacted
+
+Settings are read from the TOML config file & overridden by environment
+variables. Send SIGHUP to reload the config file.
+
+Flags:
+`))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := core.LoadConfig(*configPath, getenv)
+	if err != nil {
+		return err
+	}
+
+	switch cmd := strings.Join(fs.Args(), " "); cmd {
+	case "":
+		return serve(ctx, *configPath, config, getenv, stdout)
+	case "config print":
+		return config.Print(stdout)
+	case "help":
+		fs.Usage()
+		return flag.ErrHelp
+	default:
+		return fmt.Errorf("unknown command %q, see 'bookmarkd help'", cmd)
+	}
+}
+
+// serve runs the server until ctx is done or a signal stops it.
+func serve(
+	ctx context.Context,
+	configPath string,
+	config core.Config,
+	getenv func(string) string,
+	stdout io.Writer,
+) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	logger := httplog.NewLogger("bookmarkd", httplog.Options{
@@ -61,16 +121,13 @@ func run(
 		QuietDownRoutes: []string{
 			"/",
 			"/ping",
+			"/healthz",
+			"/readyz",
 		},
 		QuietDownPeriod: 10 * time.Second,
 		Writer:          stdout,
 	})
 
-	config, err := core.NewConfig(getenv)
-	if err != nil {
-		return err
-	}
-
 	// Initialize error tracking.
 	// if config.Rollbar.Token != "" {
 	// 	rollbar.SetToken(config.Rollbar.Token)
@@ -82,52 +139,301 @@ func run(
 	// 	log.Printf("rollbar error tracking enabled")
 	// }
 
-	db := sqlite.NewDB(config.DbDsn)
-	if err := db.Open(); err != nil {
-		return fmt.Errorf("cannot open db: %w", err)
-	}
-
 	// inmem
-	registrationStore := inmem.NewRegistrationStore()
 	eventService := inmem.NewEventService()
 
-	// sqlite
-	bookmarkStore := sqlite.NewBookmarkStore(db)
-	sessionService := sqlite.NewSessionStore(db)
-	userStore := sqlite.NewUserStore(db)
+	var (
+		registrationStore   core.RegistrationStore
+		backupStore         core.BackupStore
+		bookmarkStore       core.BookmarkStore
+		idempotencyKeyStore core.IdempotencyKeyStore
+		sessionService      core.SessionStore
+		userStore           core.UserStore
+
+		// dependencies checked by the readiness probe
+		checks []core.HealthCheck
+	)
+
+	switch config.DbScheme() {
+	case core.DbSchemePostgres:
+		db := postgres.NewDB(config.DbDsn)
+		db.EventService = eventService
+		db.UrlCanonicalizer = core.NewUrlCanonicalizer(config.UrlStripParams)
+		if err := db.Open(); err != nil {
+			return fmt.Errorf("cannot open db: %w", err)
+		}
+		defer db.Close()
+
+		// postgres, registrations are shared by all instances
+		registrationStore = postgres.NewRegistrationStore(db)
+		backupStore = postgres.NewBackupStore()
+		bookmarkStore = postgres.NewBookmarkStore(db)
+		idempotencyKeyStore = postgres.NewIdempotencyKeyStore(db, time.Duration(config.IdempotencyKeyTTLInSeconds)*time.Second)
+		sessionService = postgres.NewSessionStore(db)
+		userStore = postgres.NewUserStore(db)
 
-	db.EventService = eventService
+		checks = append(checks,
+			core.HealthCheck{Name: "db", Check: db.Ping},
+			core.HealthCheck{Name: "migrations", Check: db.CheckMigrations},
+		)
+
+	case core.DbSchemeInmem:
+		db := inmem.NewDB()
+		db.EventService = eventService
+		db.UrlCanonicalizer = core.NewUrlCanonicalizer(config.UrlStripParams)
+		if err := db.Open(); err != nil {
+			return fmt.Errorf("cannot open db: %w", err)
+		}
+		defer db.Close()
+		logger.Warn("using an in-memory database, all data is lost on exit")
+
+		// inmem, nothing is written to disk
+		registrationStore = inmem.NewRegistrationStore()
+		backupStore = inmem.NewBackupStore()
+		bookmarkStore = inmem.NewBookmarkStore(db)
+		idempotencyKeyStore = inmem.NewIdempotencyKeyStore(db, time.Duration(config.IdempotencyKeyTTLInSeconds)*time.Second)
+		sessionService = inmem.NewSessionStore(db)
+		userStore = inmem.NewUserStore(db)
+
+		checks = append(checks, core.HealthCheck{Name: "db", Check: db.Ping})
+
+	default:
+		db := sqlite.NewDB(config.DbDsn)
+		db.ManualCheckpoint = config.ReplicaDir != ""
+		db.EventService = eventService
+		db.UrlCanonicalizer = core.NewUrlCanonicalizer(config.UrlStripParams)
+		if err := db.Open(); err != nil {
+			return fmt.Errorf("cannot open db: %w", err)
+		}
+		defer db.Close()
+
+		// inmem
+		registrationStore = inmem.NewRegistrationStore()
+
+		// sqlite
+		bookmarkStore = sqlite.NewBookmarkStore(db)
+		idempotencyKeyStore = sqlite.NewIdempotencyKeyStore(db, time.Duration(config.IdempotencyKeyTTLInSeconds)*time.Second)
+		sessionService = sqlite.NewSessionStore(db)
+		userStore = sqlite.NewUserStore(db)
+
+		checks = append(checks,
+			core.HealthCheck{Name: "db", Check: db.Ping},
+			core.HealthCheck{Name: "migrations", Check: db.CheckMigrations},
+		)
+
+		// Take backups on demand & on a schedule, if enabled.
+		sqliteBackupStore := sqlite.NewBackupStore(db, config.BackupDir)
+		sqliteBackupStore.Retain = config.BackupRetain
+		sqliteBackupStore.Interval = time.Duration(config.BackupIntervalInSeconds) * time.Second
+		if err := sqliteBackupStore.Open(); err != nil {
+			return fmt.Errorf("cannot open backup store: %w", err)
+		}
+		defer sqliteBackupStore.Close()
+		backupStore = sqliteBackupStore
+
+		// Replicate the WAL continuously, if enabled.
+		if config.ReplicaDir != "" {
+			replicator := sqlite.NewReplicator(db, sqlite.NewFileReplicaClient(config.ReplicaDir))
+			replicator.SyncInterval = time.Duration(config.ReplicaSyncIntervalInSeconds) * time.Second
+			replicator.SnapshotInterval = time.Duration(config.ReplicaSnapshotIntervalInSeconds) * time.Second
+			replicator.Retention = time.Duration(config.ReplicaRetentionInSeconds) * time.Second
+			if err := replicator.Open(); err != nil {
+				return fmt.Errorf("cannot open replicator: %w", err)
+			}
+			defer replicator.Close()
+			logger.Info("replicating", "dir", config.ReplicaDir, "generation", replicator.Generation())
+		}
+	}
+
+	checks = append(checks, core.HealthCheck{Name: "events", Check: eventService.Ping})
+	health := server.NewHealth(checks...)
+	health.Timeout = time.Duration(config.HealthCheckTimeoutInSeconds) * time.Second
 
 	httpServer := server.NewServer(
 		logger,
 		config,
+		health,
 		registrationStore,
+		backupStore,
 		bookmarkStore,
 		eventService,
+		idempotencyKeyStore,
 		sessionService,
 		userStore,
 	)
 
-	go func() {
-		logger.Info("listening", "addr", httpServer.Addr)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			fmt.Fprintf(os.Stderr, "error listening and serving: %s\n", err)
+	// Serve over TLS, if enabled. Certificate files are reloaded when they
+	// change.
+	tlsConfig, err := server.NewTLSConfig(config)
+	if err != nil {
+		return fmt.Errorf("cannot configure tls: %w", err)
+	}
+	httpServer.TLSConfig = tlsConfig
+	if config.TlsSelfSigned {
+		logger.Warn("using a self-signed certificate, clients won't trust it")
+	}
+
+	// Redirect plain HTTP requests to HTTPS, if enabled.
+	var redirectServer *http.Server
+	if config.TlsRedirectPort != 0 {
+		redirectServer = server.NewRedirectServer(config)
+		go func() {
+			logger.Info("redirecting to https", "addr", redirectServer.Addr)
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "error listening and serving redirects: %s\n", err)
+			}
+		}()
+	}
+
+	// Serve through a handler which can be replaced by a router built from
+	// a reloaded config.
+	handler := server.NewHandler(httpServer.Handler)
+	httpServer.Handler = handler
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	// Listen on the sockets passed by systemd, a unix socket or the TCP
+	// address.
+	listeners, err := server.Listen(config, getenv)
+	if err != nil {
+		return fmt.Errorf("cannot listen: %w", err)
+	}
+	for _, l := range listeners {
+		go func() {
+			logger.Info("listening", "addr", l.Addr().String(), "tls", tlsConfig != nil)
+
+			// The certificates are provided by the TLS config.
+			serve := httpServer.Serve
+			if tlsConfig != nil {
+				serve = func(l net.Listener) error { return httpServer.ServeTLS(l, "", "") }
+			}
+			if err := serve(l); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintf(os.Stderr, "error serving: %s\n", err)
+			}
+		}()
+	}
+
+	// Tell systemd the server is ready, if it runs the server with
+	// Type=notify.
+	notify := func(state string) {
+		if _, err := systemd.Notify(getenv, state); err != nil {
+			logger.Warn("cannot notify systemd", "state", state, "err", err)
 		}
-	}()
+	}
+	notify(systemd.Ready)
+
+	// Ping the systemd watchdog, if enabled, until the server has stopped.
+	// Pings are sent at half the interval so a late one doesn't get the
+	// process killed. Pings are skipped while the server isn't ready so
+	// systemd restarts a server which stays unhealthy.
+	interval, err := systemd.WatchdogInterval(getenv)
+	if err != nil {
+		logger.Warn("systemd watchdog disabled", "err", err)
+	}
+	stopWatchdog := make(chan struct{})
+	defer close(stopWatchdog)
+	if interval > 0 {
+		go func() {
+			ticker := time.NewTicker(interval / 2)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if report := health.Check(ctx); !report.Ready() {
+						logger.Warn("skipping systemd watchdog ping", "status", report.Status)
+						continue
+					}
+					notify(systemd.Watchdog)
+				case <-stopWatchdog:
+					return
+				}
+			}
+		}()
+	}
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		<-ctx.Done()
-		// make a new context for the Shutdown (thanks Alessandro Rosetti)
-		// shutdownCtx := context.Background()
-		shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+
+		// Reload the config on SIGHUP until shutting down. Settings which
+		// can't be applied while running are kept until a restart.
+		for ctx.Err() == nil {
+			select {
+			case <-hup:
+				notify(systemd.Reloading)
+				next, err := core.LoadConfig(configPath, getenv)
+				if err != nil {
+					logger.Error("cannot reload config", "err", err)
+					notify(systemd.Ready)
+					continue
+				}
+
+				var reloaded, restart []string
+				config, reloaded, restart = config.Reload(next)
+				if len(restart) > 0 {
+					logger.Warn("config changes require a restart", "settings", restart)
+				}
+
+				handler.Store(server.NewRouter(
+					logger,
+					config,
+					health,
+					registrationStore,
+					backupStore,
+					bookmarkStore,
+					eventService,
+					idempotencyKeyStore,
+					sessionService,
+					userStore,
+				))
+				logger.Info("config reloaded", "settings", reloaded)
+				notify(systemd.Ready)
+
+			case <-ctx.Done():
+			}
+		}
+		logger.Info("shutting down")
+		notify(systemd.Stopping)
+
+		// Restore the default signal handling so a second signal kills the
+		// process if the shutdown hangs.
+		cancel()
+
+		// Fail the readiness probe first so load balancers stop sending
+		// traffic before the listener closes.
+		health.Drain()
+		if d := time.Duration(config.ShutdownDelayInSeconds) * time.Second; d > 0 {
+			logger.Info("draining", "delay", d)
+			time.Sleep(d)
+		}
+
+		// make a new context for the Shutdown (thanks Alessandro Rosetti),
+		// ctx is already done.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
+
+		// Stop accepting connections & wait for in-flight requests. Websocket
+		// connections are hijacked so the server doesn't wait for them.
 		if err := httpServer.Shutdown(shutdownCtx); err != nil {
 			fmt.Fprintf(os.Stderr, "error shutting down http server: %s\n", err)
 		}
+		if redirectServer != nil {
+			if err := redirectServer.Shutdown(shutdownCtx); err != nil {
+				fmt.Fprintf(os.Stderr, "error shutting down redirect server: %s\n", err)
+			}
+		}
+
+		// Close every event subscription so websocket clients are sent a
+		// going away message, then wait for them to disconnect.
+		if err := eventService.Shutdown(shutdownCtx); err != nil {
+			fmt.Fprintf(os.Stderr, "error shutting down event service: %s\n", err)
+		}
 	}()
 	wg.Wait()
+
+	// Background goroutines & the database are closed by the deferred calls.
 	return nil
 }
@@ -0,0 +1,706 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookmarkd/client"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/postgres"
+	"bookmarkd/internal/sqlite"
+)
+
+// Admin represents the state of an admin command. Admin commands operate on
+// the database file directly so they can be used while the server is down.
+// Migrations are also managed for postgres databases, in which case DB is nil.
+type Admin struct {
+	*Main
+
+	ServerConfig core.Config
+	DB           *sqlite.DB
+	Migrator     core.Migrator
+
+	UserStore    *sqlite.UserStore
+	SessionStore *sqlite.SessionStore
+}
+
+// runAdmin opens the database & executes an admin subcommand.
+func (m *Main) runAdmin(ctx context.Context, args []string) error {
+	config, err := core.NewConfig(os.Getenv)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	fs := flag.NewFlagSet("bookmarkdctl admin", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	dsn := fs.String("dsn", config.DbDsn, "database file or postgres url, defaults to $BOOKMARKD_DSN")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if config.DbDsn, err = core.ExpandDSN(*dsn); err != nil {
+		return fmt.Errorf("expand dsn: %w", err)
+	}
+
+	var cmd string
+	if args = fs.Args(); len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	// Only migrations are managed for postgres, the other commands work on
+	// the sqlite file.
+	if cmd == "migrations" && config.DbScheme() == core.DbSchemePostgres {
+		return m.runAdminPostgresMigrations(ctx, config, args)
+	} else if config.DbScheme() != core.DbSchemeSqlite {
+		return fmt.Errorf("admin commands require a sqlite database, not %s", config.DbScheme())
+	}
+	switch cmd {
+	case "users", "sessions", "migrations", "stats", "backup":
+	case "restore":
+		// Restoring replaces the database file so it must not be opened.
+		return m.runAdminRestore(ctx, config, args)
+	case "replica":
+		// The replica is read without touching the database.
+		return m.runAdminReplica(ctx, config, args)
+	default:
+		return fmt.Errorf("usage: bookmarkdctl admin [-dsn path] users|sessions|migrations|stats|backup|restore|replica")
+	}
+
+	// Refuse to create an empty database when the path is mistyped. Creating
+	// a user is allowed to set up a new database.
+	create := cmd == "users" && len(args) > 0 && args[0] == "create"
+	if config.DbDsn != ":memory:" && !create {
+		if _, err := os.Stat(config.DbDsn); err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+	}
+
+	a := &Admin{Main: m, ServerConfig: config}
+	a.DB = sqlite.NewDB(config.DbDsn)
+	a.DB.UrlCanonicalizer = core.NewUrlCanonicalizer(config.UrlStripParams)
+
+	// Showing the migration status must not apply pending migrations.
+	a.DB.SkipMigrations = cmd == "migrations"
+
+	if err := a.DB.Open(); err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer a.DB.Close()
+	a.Migrator = a.DB
+
+	a.UserStore = sqlite.NewUserStore(a.DB)
+	a.SessionStore = sqlite.NewSessionStore(a.DB)
+
+	switch cmd {
+	case "users":
+		return a.runUsers(ctx, args)
+	case "sessions":
+		return a.runSessions(ctx, args)
+	case "migrations":
+		return a.runMigrations(ctx, args)
+	case "backup":
+		return a.runBackup(ctx, args)
+	default:
+		return a.runStats(ctx, args)
+	}
+}
+
+// runUsers executes an admin users subcommand.
+func (a *Admin) runUsers(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "list", "ls":
+		return a.runUsersList(ctx, args)
+	case "create":
+		return a.runUsersCreate(ctx, args)
+	case "reset-totp":
+		return a.runUsersResetTotp(ctx, args)
+	case "disable":
+		return a.runUsersSetDisabled(ctx, args, true)
+	case "enable":
+		return a.runUsersSetDisabled(ctx, args, false)
+	case "rm":
+		return a.runUsersRemove(ctx, args)
+	default:
+		return fmt.Errorf("usage: bookmarkdctl admin users list|create|reset-totp|disable|enable|rm")
+	}
+}
+
+// runUsersList lists all users.
+func (a *Admin) runUsersList(ctx context.Context, args []string) error {
+	filter := core.UserFilter{Sort: core.SortUsername}
+	fs := flag.NewFlagSet("bookmarkdctl admin users list", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	fs.StringVar(&filter.Cursor, "cursor", "", "cursor of the page to list")
+	fs.IntVar(&filter.Limit, "limit", 100, "maximum number of users")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	users, n, err := a.UserStore.FindUsers(ctx, filter)
+	if err != nil {
+		return err
+	}
+	next, prev := filter.Cursors(users)
+
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, userRow(u))
+	}
+	return a.print(struct {
+		Users      []*core.User `json:"users"`
+		N          int          `json:"n"`
+		NextCursor string       `json:"nextCursor,omitempty"`
+		PrevCursor string       `json:"prevCursor,omitempty"`
+	}{users, n, next, prev}, userHeader, rows)
+}
+
+// runUsersCreate creates a user & prints the TOTP provisioning url which must
+// be added to the user's authenticator app.
+func (a *Admin) runUsersCreate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin users create", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl admin users create <username>")
+	}
+
+	seed, err := core.NewSeed()
+	if err != nil {
+		return fmt.Errorf("generate seed: %w", err)
+	}
+	totpUrl, err := a.ServerConfig.TotpURL(fs.Arg(0), seed)
+	if err != nil {
+		return err
+	}
+
+	user := &core.User{Username: fs.Arg(0), Seed: seed}
+	if err := a.UserStore.CreateUser(ctx, user); err != nil {
+		return err
+	}
+	return a.printTotpUrl(user, totpUrl)
+}
+
+// runUsersResetTotp replaces the TOTP seed of a user & prints the new
+// provisioning url. All sessions of the user are revoked.
+func (a *Admin) runUsersResetTotp(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin users reset-totp", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl admin users reset-totp <username>")
+	}
+
+	user, err := a.UserStore.FindUserByUsername(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("find user %q: %w", fs.Arg(0), err)
+	}
+
+	seed, err := core.NewSeed()
+	if err != nil {
+		return fmt.Errorf("generate seed: %w", err)
+	}
+	totpUrl, err := a.ServerConfig.TotpURL(user.Username, seed)
+	if err != nil {
+		return err
+	}
+
+	if user, err = a.UserStore.UpdateUser(asUser(ctx, user.ID), user.ID, core.UserUpdate{Seed: &seed}); err != nil {
+		return err
+	}
+	return a.printTotpUrl(user, totpUrl)
+}
+
+// runUsersSetDisabled disables or re-enables a user. Disabling a user revokes
+// all of their sessions.
+func (a *Admin) runUsersSetDisabled(ctx context.Context, args []string, disabled bool) error {
+	name := "bookmarkdctl admin users enable"
+	if disabled {
+		name = "bookmarkdctl admin users disable"
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: %s <username>", name)
+	}
+
+	user, err := a.UserStore.FindUserByUsername(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("find user %q: %w", fs.Arg(0), err)
+	}
+
+	if user, err = a.UserStore.UpdateUser(asUser(ctx, user.ID), user.ID, core.UserUpdate{Disabled: &disabled}); err != nil {
+		return err
+	}
+	return a.print(user, userHeader, [][]string{userRow(user)})
+}
+
+// runUsersRemove permanently deletes a user along with their bookmarks &
+// sessions.
+func (a *Admin) runUsersRemove(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin users rm", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl admin users rm <username>")
+	}
+
+	user, err := a.UserStore.FindUserByUsername(ctx, fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("find user %q: %w", fs.Arg(0), err)
+	}
+
+	if user, err = a.UserStore.DeleteUser(asUser(ctx, user.ID), user.ID); err != nil {
+		return err
+	}
+	return a.print(user, userHeader, [][]string{userRow(user)})
+}
+
+// runSessions executes an admin sessions subcommand.
+func (a *Admin) runSessions(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "list", "ls":
+		return a.runSessionsList(ctx, args)
+	case "revoke":
+		return a.runSessionsRevoke(ctx, args)
+	default:
+		return fmt.Errorf("usage: bookmarkdctl admin sessions list|revoke")
+	}
+}
+
+// runSessionsList lists the sessions of all users or of a single user.
+func (a *Admin) runSessionsList(ctx context.Context, args []string) error {
+	var filter core.SessionFilter
+	fs := flag.NewFlagSet("bookmarkdctl admin sessions list", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	username := fs.String("user", "", "only list the sessions of this user")
+	fs.StringVar(&filter.Cursor, "cursor", "", "cursor of the page to list")
+	fs.IntVar(&filter.Limit, "limit", 100, "maximum number of sessions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *username != "" {
+		user, err := a.UserStore.FindUserByUsername(ctx, *username)
+		if err != nil {
+			return fmt.Errorf("find user %q: %w", *username, err)
+		}
+		filter.UserID = &user.ID
+	}
+
+	sessions, n, err := a.SessionStore.FindSessions(ctx, filter)
+	if err != nil {
+		return err
+	}
+	next, prev := filter.Cursors(sessions)
+
+	rows := make([][]string, 0, len(sessions))
+	for _, s := range sessions {
+		rows = append(rows, adminSessionRow(s))
+	}
+	return a.print(struct {
+		Sessions   []*core.Session `json:"sessions"`
+		N          int             `json:"n"`
+		NextCursor string          `json:"nextCursor,omitempty"`
+		PrevCursor string          `json:"prevCursor,omitempty"`
+	}{sessions, n, next, prev}, adminSessionHeader, rows)
+}
+
+// runSessionsRevoke deletes sessions by ID, or all sessions of a user.
+func (a *Admin) runSessionsRevoke(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin sessions revoke", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	username := fs.String("user", "", "revoke all sessions of this user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if (*username == "") == (fs.NArg() == 0) {
+		return fmt.Errorf("usage: bookmarkdctl admin sessions revoke -user <username> | <id>...")
+	}
+
+	var sessions []*core.Session
+	if *username != "" {
+		user, err := a.UserStore.FindUserByUsername(ctx, *username)
+		if err != nil {
+			return fmt.Errorf("find user %q: %w", *username, err)
+		}
+		if sessions, _, err = a.SessionStore.FindSessions(ctx, core.SessionFilter{UserID: &user.ID}); err != nil {
+			return err
+		}
+	}
+	for _, arg := range fs.Args() {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid session id %q", arg)
+		}
+		s, err := a.SessionStore.FindSessionByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("find session %d: %w", id, err)
+		}
+		sessions = append(sessions, s)
+	}
+
+	rows := make([][]string, 0, len(sessions))
+	for _, s := range sessions {
+		if err := a.SessionStore.DeleteSession(asUser(ctx, s.UserID), s.ID); err != nil {
+			return fmt.Errorf("revoke session %d: %w", s.ID, err)
+		}
+		rows = append(rows, adminSessionRow(s))
+	}
+	return a.print(sessions, adminSessionHeader, rows)
+}
+
+// runAdminPostgresMigrations opens a postgres database without migrating it
+// & executes an admin migrations subcommand.
+func (m *Main) runAdminPostgresMigrations(ctx context.Context, config core.Config, args []string) error {
+	db := postgres.NewDB(config.DbDsn)
+	db.SkipMigrations = true
+	if err := db.Open(); err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	a := &Admin{Main: m, ServerConfig: config, Migrator: db}
+	return a.runMigrations(ctx, args)
+}
+
+// runMigrations prints the status of each migration or applies & reverts
+// migrations.
+func (a *Admin) runMigrations(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "", "status":
+		return a.runMigrationsStatus(ctx, args)
+	case "migrate":
+		return a.runMigrationsMigrate(ctx, args)
+	case "down":
+		return a.runMigrationsDown(ctx, args)
+	default:
+		return fmt.Errorf("usage: bookmarkdctl admin migrations status|migrate|down")
+	}
+}
+
+// runMigrationsStatus prints the status of each migration without applying any.
+func (a *Admin) runMigrationsStatus(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin migrations status", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	migrations, err := a.Migrator.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(migrations))
+	for _, m := range migrations {
+		var appliedAt string
+		if m.AppliedAt != nil {
+			appliedAt = formatTime(*m.AppliedAt)
+		}
+		rows = append(rows, []string{fmt.Sprintf("%07d", m.Version), m.Status, appliedAt, shortChecksum(m.Checksum)})
+	}
+	return a.print(migrations, []string{"VERSION", "STATUS", "APPLIED", "CHECKSUM"}, rows)
+}
+
+// runMigrationsMigrate migrates the database to the latest or a given version.
+func (a *Admin) runMigrationsMigrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin migrations migrate", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	to := fs.Int("to", 0, "version to migrate to, later migrations are reverted; -1 reverts all, defaults to the latest")
+	dryRun := fs.Bool("dry-run", false, "run the migrations in a transaction which is rolled back")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var opts core.MigrateOptions
+	opts.DryRun = *dryRun
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "to" {
+			opts.Target = to
+		}
+	})
+	return a.migrate(ctx, opts)
+}
+
+// runMigrationsDown reverts the latest applied migration.
+func (a *Admin) runMigrationsDown(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin migrations down", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	dryRun := fs.Bool("dry-run", false, "run the migration in a transaction which is rolled back")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	migrations, err := a.Migrator.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Migrate to the applied version before the latest applied version.
+	target, latest := -1, -1
+	for _, m := range migrations {
+		if m.Status != core.MigrationStatusPending {
+			target, latest = latest, m.Version
+		}
+	}
+	if latest == -1 {
+		return fmt.Errorf("no migrations applied")
+	}
+	return a.migrate(ctx, core.MigrateOptions{Target: &target, DryRun: *dryRun})
+}
+
+// migrate runs the migrations & prints the steps taken.
+func (a *Admin) migrate(ctx context.Context, opts core.MigrateOptions) error {
+	steps, err := a.Migrator.Migrate(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(steps))
+	for _, step := range steps {
+		rows = append(rows, []string{fmt.Sprintf("%07d", step.Version), step.Direction, step.Name})
+	}
+	if err := a.print(steps, []string{"VERSION", "DIRECTION", "FILE"}, rows); err != nil {
+		return err
+	}
+	if opts.DryRun && a.Output != OutputJSON {
+		fmt.Fprintln(a.Stdout, "\ndry run, no changes were made")
+	}
+	return nil
+}
+
+// shortChecksum returns the first characters of a checksum, enough to
+// tell checksums apart in a table.
+func shortChecksum(s string) string {
+	if len(s) > 12 {
+		return s[:12]
+	}
+	return s
+}
+
+// runStats prints row counts of the database.
+func (a *Admin) runStats(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin stats", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	stats, err := a.DB.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	return a.print(stats, []string{"USERS", "DISABLED", "SESSIONS", "BOOKMARKS", "REVISIONS"}, [][]string{{
+		strconv.Itoa(stats.Users),
+		strconv.Itoa(stats.DisabledUsers),
+		strconv.Itoa(stats.Sessions),
+		strconv.Itoa(stats.Bookmarks),
+		strconv.Itoa(stats.Revisions),
+	}})
+}
+
+// runBackup takes a backup of the database into the backup directory.
+func (a *Admin) runBackup(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin backup", flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+	dir := fs.String("dir", a.ServerConfig.BackupDir, "backup directory, defaults to $BOOKMARKD_BACKUP_DIR")
+	retain := fs.Int("retain", a.ServerConfig.BackupRetain, "number of backups to keep, 0 keeps all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s := sqlite.NewBackupStore(a.DB, *dir)
+	s.Retain = *retain
+	if err := s.Open(); err != nil {
+		return err
+	}
+	defer s.Close()
+
+	b, err := s.CreateBackup(ctx)
+	if err != nil {
+		return err
+	}
+	row := backupRow(&client.Backup{Name: b.Name, Size: b.Size, CreatedAt: b.CreatedAt})
+	return a.print(b, backupHeader, [][]string{row})
+}
+
+// runAdminRestore replaces the database with a backup after validating it.
+// The server must be stopped.
+func (m *Main) runAdminRestore(ctx context.Context, config core.Config, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl admin restore", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	dir := fs.String("dir", config.BackupDir, "backup directory searched for backup names")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl admin restore <backup file or name>")
+	}
+
+	// Backups may be given by their name within the backup directory.
+	src := fs.Arg(0)
+	if _, err := os.Stat(src); os.IsNotExist(err) && filepath.Base(src) == src {
+		src = filepath.Join(*dir, src)
+	}
+
+	if err := sqlite.RestoreBackup(ctx, src, config.DbDsn); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.Stdout, "restored %s to %s\nthe previous database was kept as %s.pre-restore\n", src, config.DbDsn, config.DbDsn)
+	return nil
+}
+
+// runAdminReplica lists the generations of a WAL replica or restores a
+// database from it.
+func (m *Main) runAdminReplica(ctx context.Context, config core.Config, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("bookmarkdctl admin replica "+cmd, flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	dir := fs.String("dir", config.ReplicaDir, "replica directory, defaults to $BOOKMARKD_REPLICA_DIR")
+
+	switch cmd {
+	case "list", "ls":
+		if err := fs.Parse(args); err != nil {
+			return err
+		} else if *dir == "" {
+			return fmt.Errorf("replica dir required")
+		}
+		return m.runAdminReplicaList(ctx, sqlite.NewFileReplicaClient(*dir))
+
+	case "restore":
+		timestamp := fs.String("timestamp", "", "restore the database as of this RFC 3339 time, defaults to the latest")
+		output := fs.String("o", config.DbDsn, "path of the restored database, must not exist")
+		if err := fs.Parse(args); err != nil {
+			return err
+		} else if *dir == "" {
+			return fmt.Errorf("replica dir required")
+		}
+
+		var target time.Time
+		if *timestamp != "" {
+			var err error
+			if target, err = time.Parse(time.RFC3339, *timestamp); err != nil {
+				return fmt.Errorf("parse timestamp: %w", err)
+			}
+		}
+
+		if err := sqlite.RestoreReplica(ctx, sqlite.NewFileReplicaClient(*dir), *output, target); err != nil {
+			return err
+		}
+		fmt.Fprintf(m.Stdout, "restored replica to %s\n", *output)
+		return nil
+
+	default:
+		return fmt.Errorf("usage: bookmarkdctl admin replica list|restore [-timestamp time] [-o path]")
+	}
+}
+
+// replicaGeneration summarizes a generation of a WAL replica.
+type replicaGeneration struct {
+	Generation  string    `json:"generation"`
+	Snapshots   int       `json:"snapshots"`
+	WALSegments int       `json:"walSegments"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
+
+// runAdminReplicaList lists the generations of a replica & the time range
+// each of them can be restored to.
+func (m *Main) runAdminReplicaList(ctx context.Context, client sqlite.ReplicaClient) error {
+	names, err := client.Generations(ctx)
+	if err != nil {
+		return err
+	}
+
+	generations := make([]*replicaGeneration, 0, len(names))
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		snapshots, err := client.Snapshots(ctx, name)
+		if err != nil {
+			return err
+		}
+		segments, err := client.WALSegments(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		g := &replicaGeneration{Generation: name, Snapshots: len(snapshots), WALSegments: len(segments)}
+		if len(snapshots) > 0 {
+			g.Start, g.End = snapshots[0].CreatedAt, snapshots[len(snapshots)-1].CreatedAt
+		}
+		if len(segments) > 0 && segments[len(segments)-1].CreatedAt.After(g.End) {
+			g.End = segments[len(segments)-1].CreatedAt
+		}
+		generations = append(generations, g)
+		rows = append(rows, []string{g.Generation, strconv.Itoa(g.Snapshots), strconv.Itoa(g.WALSegments), formatTime(g.Start), formatTime(g.End)})
+	}
+	return m.print(generations, replicaGenerationHeader, rows)
+}
+
+var replicaGenerationHeader = []string{"GENERATION", "SNAPSHOTS", "WAL SEGMENTS", "START", "END"}
+
+// printTotpUrl prints a user along with their TOTP provisioning url.
+func (a *Admin) printTotpUrl(user *core.User, totpUrl string) error {
+	if a.Output == OutputJSON {
+		return printJSON(a.Stdout, struct {
+			User    *core.User `json:"user"`
+			TotpUrl string     `json:"totpUrl"`
+		}{user, totpUrl})
+	}
+
+	if err := printTable(a.Stdout, userHeader, [][]string{userRow(user)}); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.Stdout, "\nAdd this url to the user's authenticator app:\n\n\t%s\n", totpUrl)
+	return nil
+}
+
+// asUser returns a context acting as the given user. The stores only allow
+// users to change their own objects.
+func asUser(ctx context.Context, userID string) context.Context {
+	return core.NewContextWithSession(ctx, core.SessionContext{UserID: userID})
+}
+
+var userHeader = []string{"ID", "USERNAME", "DISABLED", "CREATED", "UPDATED"}
+
+func userRow(u *core.User) []string {
+	var disabled string
+	if u.DisabledAt != nil {
+		disabled = formatTime(*u.DisabledAt)
+	}
+	return []string{u.ID, u.Username, disabled, formatTime(u.CreatedAt), formatTime(u.UpdatedAt)}
+}
+
+var adminSessionHeader = []string{"ID", "USER", "EXPIRES", "CREATED", "UPDATED"}
+
+func adminSessionRow(s *core.Session) []string {
+	return []string{strconv.Itoa(s.ID), s.UserID, formatTime(s.ExpiresAt), formatTime(s.CreatedAt), formatTime(s.UpdatedAt)}
+}
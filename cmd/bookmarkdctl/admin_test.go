@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"bookmarkd"
+	"bookmarkd/client"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
+)
+
+// Ensure users created offline can log in & disabling them revokes their
+// sessions.
+func TestAdmin_Users(t *testing.T) {
+	url, dsn := MustOpenServer(t)
+	configPath := filepath.Join(t.TempDir(), "bookmarkdctl.json")
+
+	stdout, _, err := RunMain(t, "", "-o", "json", "admin", "-dsn", dsn, "users", "create", "jane")
+	require.Equal(t, err, nil)
+
+	var created struct {
+		TotpUrl string `json:"totpUrl"`
+	}
+	require.Equal(t, json.Unmarshal([]byte(stdout), &created), nil)
+
+	_, _, err = RunMain(t, "", "-config", configPath, "-url", url, "login", "-totp-url", created.TotpUrl, "jane")
+	require.Equal(t, err, nil)
+	require.Equal(t, MustAdminSessionN(t, dsn, "jane"), 1)
+
+	for _, tt := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "Duplicate", args: []string{"users", "create", "jane"}, err: "username"},
+		{name: "NotFound", args: []string{"users", "disable", "john"}, err: `find user "john"`},
+		{name: "Usage", args: []string{"users", "disable"}, err: "usage: bookmarkdctl admin users disable <username>"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := RunMain(t, "", append([]string{"admin", "-dsn", dsn}, tt.args...)...)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	t.Run("Disable", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "admin", "-dsn", dsn, "users", "disable", "jane")
+		require.Equal(t, err, nil)
+		require.Equal(t, MustAdminSessionN(t, dsn, "jane"), 0)
+
+		// The revoked session can no longer be used or refreshed.
+		_, _, err = RunMain(t, "", "-config", configPath, "bookmarks", "list")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+
+		_, _, err = RunMain(t, "", "-config", configPath, "login", "-totp-url", created.TotpUrl, "jane")
+		require.NotEqual(t, err, nil)
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "admin", "-dsn", dsn, "users", "enable", "jane")
+		require.Equal(t, err, nil)
+
+		_, _, err = RunMain(t, "", "-config", configPath, "login", "-totp-url", created.TotpUrl, "jane")
+		require.Equal(t, err, nil)
+		_, _, err = RunMain(t, "", "-config", configPath, "bookmarks", "list")
+		require.Equal(t, err, nil)
+	})
+}
+
+// MustAdminSessionN returns the number of sessions of a user listed by the
+// admin sessions command.
+func MustAdminSessionN(tb testing.TB, dsn, username string) int {
+	tb.Helper()
+
+	stdout, _, err := RunMain(tb, "", "-o", "json", "admin", "-dsn", dsn, "sessions", "list", "-user", username)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var list struct {
+		N int `json:"n"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil {
+		tb.Fatal(err)
+	}
+	return list.N
+}
+
+// Ensure backups can be taken of a running server but not restored over it.
+func TestAdmin_Backup(t *testing.T) {
+	url, dsn := MustOpenServer(t)
+	dir := filepath.Join(t.TempDir(), "backups")
+	MustRegister(t, url, "jane")
+
+	stdout, _, err := RunMain(t, "", "-o", "json", "admin", "-dsn", dsn, "backup", "-dir", dir)
+	require.Equal(t, err, nil)
+
+	var backup client.Backup
+	require.Equal(t, json.Unmarshal([]byte(stdout), &backup), nil)
+	require.NotEqual(t, backup.Size, int64(0))
+
+	t.Run("ErrInUse", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "admin", "-dsn", dsn, "restore", "-dir", dir, backup.Name)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnavailable), true)
+
+		_, err = os.Stat(dsn + ".pre-restore")
+		require.Equal(t, os.IsNotExist(err), true)
+	})
+
+	// Ensure the restored database is usable by a new server.
+	t.Run("Restore", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "db.sqlite")
+		stdout, _, err := RunMain(t, "", "admin", "-dsn", dst, "restore", "-dir", dir, backup.Name)
+		require.Equal(t, err, nil)
+		require.Equal(t, strings.HasPrefix(stdout, "restored "), true)
+
+		require.Equal(t, MustAdminUsernames(t, dst), "jane")
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "admin", "-dsn", dsn, "restore")
+		require.NotEqual(t, err, nil)
+	})
+}
+
+// Ensure a WAL replica can be listed & restored.
+func TestAdmin_Replica(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "replica")
+
+	db := sqlite.NewDB(filepath.Join(t.TempDir(), "db.sqlite"))
+	db.ManualCheckpoint = true
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	r := sqlite.NewReplicator(db, sqlite.NewFileReplicaClient(dir))
+	r.SyncInterval, r.SnapshotInterval = time.Hour, 0
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := sqlite.NewUserStore(db).CreateUser(ctx, &core.User{Username: "jane", Seed: "seed"}); err != nil {
+		t.Fatal(err)
+	} else if err := r.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("List", func(t *testing.T) {
+		stdout, _, err := RunMain(t, "", "-o", "json", "admin", "replica", "list", "-dir", dir)
+		require.Equal(t, err, nil)
+
+		var generations []*replicaGeneration
+		require.Equal(t, json.Unmarshal([]byte(stdout), &generations), nil)
+		require.Equal(t, len(generations), 1)
+		require.Equal(t, generations[0].Generation, r.Generation())
+		require.Equal(t, generations[0].Snapshots, 1)
+		require.NotEqual(t, generations[0].WALSegments, 0)
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		dst := filepath.Join(t.TempDir(), "db.sqlite")
+		_, _, err := RunMain(t, "", "admin", "-dsn", dst, "replica", "restore", "-dir", dir)
+		require.Equal(t, err, nil)
+		require.Equal(t, MustAdminUsernames(t, dst), "jane")
+
+		// An existing database is never overwritten.
+		_, _, err = RunMain(t, "", "admin", "replica", "restore", "-dir", dir, "-o", dst)
+		require.NotEqual(t, err, nil)
+	})
+
+	for _, tt := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "DirRequired", args: []string{"list"}, err: "replica dir required"},
+		{name: "BadTimestamp", args: []string{"restore", "-dir", dir, "-timestamp", "yesterday"}, err: "parse timestamp"},
+		{name: "Usage", args: []string{"nope"}, err: "usage: bookmarkdctl admin replica"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := RunMain(t, "", append([]string{"admin", "replica"}, tt.args...)...)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// MustAdminUsernames returns the comma-separated usernames in the database at
+// dsn as listed by the admin users command.
+func MustAdminUsernames(tb testing.TB, dsn string) string {
+	tb.Helper()
+
+	stdout, _, err := RunMain(tb, "", "-o", "json", "admin", "-dsn", dsn, "users", "list")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var list struct {
+		Users []*client.User `json:"users"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &list); err != nil {
+		tb.Fatal(err)
+	}
+
+	usernames := make([]string, 0, len(list.Users))
+	for _, u := range list.Users {
+		usernames = append(usernames, u.Username)
+	}
+	return strings.Join(usernames, ",")
+}
+
+// Ensure showing the migration status & dry runs leave the database as is.
+func TestAdmin_Migrations(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "db.sqlite")
+
+	// Create a database missing the latest migrations.
+	db := sqlite.NewDB(dsn)
+	db.SkipMigrations = true
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	target := 5
+	if _, err := db.Migrate(ctx, core.MigrateOptions{Target: &target}); err != nil {
+		t.Fatal(err)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := MustAdminPendingMigrations(t, dsn)
+	require.Equal(t, strings.HasPrefix(pending, "6,"), true)
+
+	for _, tt := range []struct {
+		name  string
+		args  []string
+		steps int // number of steps printed, -1 if not a migration
+	}{
+		{name: "Status", args: []string{}, steps: -1},
+		{name: "StatusCommand", args: []string{"status"}, steps: -1},
+		{name: "MigrateDryRun", args: []string{"migrate", "-dry-run"}, steps: len(strings.Split(pending, ","))},
+		{name: "MigrateToDryRun", args: []string{"migrate", "-dry-run", "-to", "6"}, steps: 1},
+		{name: "DownDryRun", args: []string{"down", "-dry-run"}, steps: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, err := RunMain(t, "", append([]string{"-o", "json", "admin", "-dsn", dsn, "migrations"}, tt.args...)...)
+			require.Equal(t, err, nil)
+
+			if tt.steps != -1 {
+				var steps []*core.MigrationStep
+				require.Equal(t, json.Unmarshal([]byte(stdout), &steps), nil)
+				require.Equal(t, len(steps), tt.steps)
+			}
+			require.Equal(t, MustAdminPendingMigrations(t, dsn), pending)
+		})
+	}
+
+	t.Run("Migrate", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "admin", "-dsn", dsn, "migrations", "migrate", "-to", "6")
+		require.Equal(t, err, nil)
+		require.Equal(t, MustAdminPendingMigrations(t, dsn), strings.TrimPrefix(pending, "6,"))
+
+		_, _, err = RunMain(t, "", "admin", "-dsn", dsn, "migrations", "down")
+		require.Equal(t, err, nil)
+		require.Equal(t, MustAdminPendingMigrations(t, dsn), pending)
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "admin", "-dsn", dsn, "migrations", "nope")
+		require.NotEqual(t, err, nil)
+		require.Equal(t, MustAdminPendingMigrations(t, dsn), pending)
+	})
+}
+
+// Ensure migrations of a postgres database can be listed, reverted & applied.
+// Skipped unless $BOOKMARKD_TEST_POSTGRES_DSN is set.
+func TestAdmin_PostgresMigrations(t *testing.T) {
+	dsn := os.Getenv("BOOKMARKD_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("postgres not available, set BOOKMARKD_TEST_POSTGRES_DSN")
+	}
+
+	// Migrate a schema of its own so other tests sharing the server are
+	// not affected.
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(buf)
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+
+	if strings.Contains(dsn, "?") {
+		dsn += "&search_path=" + schema
+	} else {
+		dsn += "?search_path=" + schema
+	}
+
+	// Nothing is applied until the database is migrated.
+	pending := MustAdminPendingMigrations(t, dsn)
+	require.NotEqual(t, pending, "")
+
+	stdout, _, err := RunMain(t, "", "-o", "json", "admin", "-dsn", dsn, "migrations", "migrate", "-dry-run")
+	require.Equal(t, err, nil)
+	var steps []*core.MigrationStep
+	require.Equal(t, json.Unmarshal([]byte(stdout), &steps), nil)
+	require.Equal(t, len(steps), len(strings.Split(pending, ",")))
+	require.Equal(t, MustAdminPendingMigrations(t, dsn), pending)
+
+	_, _, err = RunMain(t, "", "admin", "-dsn", dsn, "migrations", "migrate")
+	require.Equal(t, err, nil)
+	require.Equal(t, MustAdminPendingMigrations(t, dsn), "")
+
+	_, _, err = RunMain(t, "", "admin", "-dsn", dsn, "migrations", "migrate", "-to", "-1")
+	require.Equal(t, err, nil)
+	require.Equal(t, MustAdminPendingMigrations(t, dsn), pending)
+
+	// Other admin commands still require a sqlite database.
+	_, _, err = RunMain(t, "", "admin", "-dsn", dsn, "stats")
+	require.NotEqual(t, err, nil)
+}
+
+// MustAdminPendingMigrations returns the comma-separated versions of the
+// pending migrations of the database at dsn.
+func MustAdminPendingMigrations(tb testing.TB, dsn string) string {
+	tb.Helper()
+
+	stdout, _, err := RunMain(tb, "", "-o", "json", "admin", "-dsn", dsn, "migrations", "status")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var migrations []*core.Migration
+	if err := json.Unmarshal([]byte(stdout), &migrations); err != nil {
+		tb.Fatal(err)
+	}
+
+	var versions []string
+	for _, m := range migrations {
+		if m.Status == core.MigrationStatusPending {
+			versions = append(versions, strconv.Itoa(m.Version))
+		}
+	}
+	return strings.Join(versions, ",")
+}
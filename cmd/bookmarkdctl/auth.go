@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"bookmarkd/client"
+)
+
+// runRegister registers a new user & logs in. The TOTP url must be added to an
+// authenticator app which then provides the passcode confirming registration.
+func (m *Main) runRegister(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl register", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl register <username>")
+	}
+
+	reg, err := m.Client.Register(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(m.Stderr, "Add this url to your authenticator app:\n\n%s\n\n", reg.TotpUrl)
+	passcode, err := m.prompt("Passcode: ")
+	if err != nil {
+		return err
+	}
+
+	if err := m.Client.ConfirmRegistration(ctx, reg.RegistrationID, passcode); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.Stderr, "Registered & logged in as %s\n", fs.Arg(0))
+	return nil
+}
+
+// runLogin starts a new session & stores its tokens in the config file.
+func (m *Main) runLogin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl login", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	passcode := fs.String("passcode", "", "TOTP passcode, prompted for if blank")
+	totpUrl := fs.String("totp-url", "", "generate the passcode from the TOTP url returned on registration")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl login [flags] <username>")
+	}
+
+	var err error
+	switch {
+	case *passcode != "":
+	case *totpUrl != "":
+		if *passcode, err = client.Passcode(*totpUrl, time.Now()); err != nil {
+			return err
+		}
+	default:
+		if *passcode, err = m.prompt("Passcode: "); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Client.Login(ctx, fs.Arg(0), *passcode); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.Stderr, "Logged in as %s\n", fs.Arg(0))
+	return nil
+}
+
+// runLogout deletes the current session & removes its tokens.
+func (m *Main) runLogout(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl logout", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if m.Client.Token() == nil {
+		return fmt.Errorf("not logged in")
+	}
+	return m.Client.Logout(ctx)
+}
+
+// prompt writes label to stderr & reads a line from stdin.
+func (m *Main) prompt(label string) (string, error) {
+	fmt.Fprint(m.Stderr, label)
+	line, err := bufio.NewReader(m.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read %s: %w", strings.TrimSuffix(strings.ToLower(label), ": "), err)
+	}
+	return strings.TrimSpace(line), nil
+}
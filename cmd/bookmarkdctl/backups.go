@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"bookmarkd/client"
+)
+
+// runBackups executes a backups subcommand. Backups are taken by the running
+// server and require the admin token.
+func (m *Main) runBackups(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("bookmarkdctl backups "+cmd, flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	fs.StringVar(&m.Client.AdminToken, "admin-token", os.Getenv("BOOKMARKD_ADMIN_TOKEN"), "admin token, defaults to $BOOKMARKD_ADMIN_TOKEN")
+
+	switch cmd {
+	case "create":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		b, err := m.Client.CreateBackup(ctx)
+		if err != nil {
+			return err
+		}
+		return m.print(b, backupHeader, [][]string{backupRow(b)})
+
+	case "list", "ls":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		backups, err := m.Client.FindBackups(ctx)
+		if err != nil {
+			return err
+		}
+		rows := make([][]string, 0, len(backups))
+		for _, b := range backups {
+			rows = append(rows, backupRow(b))
+		}
+		return m.print(backups, backupHeader, rows)
+
+	default:
+		return fmt.Errorf("usage: bookmarkdctl backups create|list")
+	}
+}
+
+var backupHeader = []string{"NAME", "SIZE", "CREATED"}
+
+func backupRow(b *client.Backup) []string {
+	return []string{b.Name, strconv.FormatInt(b.Size, 10), formatTime(b.CreatedAt)}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"bookmarkd/client"
+)
+
+// runBookmarks executes a bookmarks subcommand.
+func (m *Main) runBookmarks(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "list", "ls":
+		return m.runBookmarksList(ctx, args, false)
+	case "search":
+		return m.runBookmarksList(ctx, args, true)
+	case "add":
+		return m.runBookmarksAdd(ctx, args)
+	case "edit":
+		return m.runBookmarksEdit(ctx, args)
+	case "rm":
+		return m.runBookmarksRemove(ctx, args)
+	default:
+		return fmt.Errorf("usage: bookmarkdctl bookmarks list|search|add|edit|rm")
+	}
+}
+
+// runBookmarksList lists a page of bookmarks. Search requires a query and
+// orders results by relevance.
+func (m *Main) runBookmarksList(ctx context.Context, args []string, search bool) error {
+	name := "bookmarkdctl bookmarks list"
+	if search {
+		name = "bookmarkdctl bookmarks search"
+	}
+
+	var filter client.BookmarkFilter
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	status := fs.String("status", "", "only list bookmarks with status: unread, read or archived")
+	starred := fs.String("starred", "", "only list starred (true) or unstarred (false) bookmarks")
+	fs.StringVar(&filter.Sort, "sort", "", "sort by: created, updated, name or relevance")
+	fs.StringVar(&filter.Direction, "direction", "", "sort direction: asc or desc")
+	fs.StringVar(&filter.Cursor, "cursor", "", "cursor of the page to list")
+	fs.IntVar(&filter.Limit, "limit", 20, "maximum number of bookmarks")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if search {
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: %s [flags] <query>", name)
+		}
+		query := fs.Arg(0)
+		filter.Search = &query
+	}
+	if *status != "" {
+		s := client.BookmarkStatus(*status)
+		filter.Status = &s
+	}
+	if *starred != "" {
+		v, err := strconv.ParseBool(*starred)
+		if err != nil {
+			return fmt.Errorf("invalid -starred: %w", err)
+		}
+		filter.Starred = &v
+	}
+
+	list, err := m.Client.FindBookmarks(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(list.Bookmarks))
+	for _, b := range list.Bookmarks {
+		rows = append(rows, bookmarkRow(b))
+	}
+	if err := m.print(list, bookmarkHeader, rows); err != nil {
+		return err
+	}
+
+	if m.Output == OutputTable {
+		fmt.Fprintf(m.Stderr, "\n%d of %d bookmarks", len(list.Bookmarks), list.N)
+		if list.NextCursor != "" {
+			fmt.Fprintf(m.Stderr, ", next page: -cursor %s", list.NextCursor)
+		}
+		fmt.Fprintln(m.Stderr)
+	}
+	return nil
+}
+
+// runBookmarksAdd creates a bookmark.
+func (m *Main) runBookmarksAdd(ctx context.Context, args []string) error {
+	b := &client.Bookmark{Status: client.BookmarkStatusUnread}
+	fs := flag.NewFlagSet("bookmarkdctl bookmarks add", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	fs.StringVar(&b.Name, "name", "", "name, defaults to the url")
+	fs.StringVar(&b.Description, "description", "", "description")
+	fs.BoolVar(&b.Starred, "starred", false, "star the bookmark")
+	duplicates := fs.String("duplicates", "", "handle an existing bookmark with the same url: allow, reject or merge")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl bookmarks add [flags] <url>")
+	}
+
+	b.Url = fs.Arg(0)
+	if b.Name == "" {
+		b.Name = b.Url
+	}
+
+	if err := m.Client.CreateBookmark(ctx, b, client.DuplicateMode(*duplicates)); err != nil {
+		return err
+	}
+	return m.print(b, bookmarkHeader, [][]string{bookmarkRow(b)})
+}
+
+// runBookmarksEdit updates the fields of a bookmark set by flags.
+func (m *Main) runBookmarksEdit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl bookmarks edit", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	name := fs.String("name", "", "name")
+	description := fs.String("description", "", "description")
+	url := fs.String("url", "", "url")
+	status := fs.String("status", "", "status: unread, read or archived")
+	starred := fs.Bool("starred", false, "starred")
+	version := fs.Int("version", 0, "only edit if the bookmark is still at this version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl bookmarks edit [flags] <id>")
+	}
+
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid bookmark id %q", fs.Arg(0))
+	}
+
+	// Only update the fields whose flags were given.
+	var upd client.BookmarkUpdate
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			upd.Name = name
+		case "description":
+			upd.Description = description
+		case "url":
+			upd.Url = url
+		case "status":
+			s := client.BookmarkStatus(*status)
+			upd.Status = &s
+		case "starred":
+			upd.Starred = starred
+		case "version":
+			upd.Version = version
+		}
+	})
+
+	b, err := m.Client.UpdateBookmark(ctx, id, upd)
+	if err != nil {
+		return err
+	}
+	return m.print(b, bookmarkHeader, [][]string{bookmarkRow(b)})
+}
+
+// runBookmarksRemove deletes a bookmark.
+func (m *Main) runBookmarksRemove(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl bookmarks rm", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	version := fs.Int("version", 0, "only remove if the bookmark is still at this version")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl bookmarks rm [flags] <id>")
+	}
+
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid bookmark id %q", fs.Arg(0))
+	}
+
+	var v *int
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "version" {
+			v = version
+		}
+	})
+
+	b, err := m.Client.DeleteBookmark(ctx, id, v)
+	if err != nil {
+		return err
+	}
+	return m.print(b, bookmarkHeader, [][]string{bookmarkRow(b)})
+}
+
+var bookmarkHeader = []string{"ID", "NAME", "STATUS", "STARRED", "URL", "UPDATED"}
+
+func bookmarkRow(b *client.Bookmark) []string {
+	starred := ""
+	if b.Starred {
+		starred = "*"
+	}
+	return []string{
+		strconv.Itoa(b.ID),
+		truncate(b.Name, 40),
+		string(b.Status),
+		starred,
+		truncate(b.Url, 60),
+		formatTime(b.UpdatedAt),
+	}
+}
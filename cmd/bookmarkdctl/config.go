@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"bookmarkd/client"
+)
+
+// DefaultURL is the url of a server running with the default configuration.
+const DefaultURL = "http://localhost:8080/api"
+
+// Config represents the config file of the command line client.
+type Config struct {
+	// Url of the server including the base path.
+	URL string `json:"url"`
+
+	// Tokens of the current session, if logged in.
+	Token *client.Token `json:"token,omitempty"`
+}
+
+// DefaultConfigPath returns the path of the config file in the user's config
+// directory, e.g. ~/.config/bookmarkd/bookmarkdctl.json.
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "bookmarkdctl.json"
+	}
+	return filepath.Join(dir, "bookmarkd", "bookmarkdctl.json")
+}
+
+// ReadConfigFile returns the config stored at path. A missing file returns the
+// default config.
+func ReadConfigFile(path string) (*Config, error) {
+	config := &Config{URL: DefaultURL}
+
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, config); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// WriteConfigFile stores config at path. The file is only readable by the
+// current user as it holds session tokens.
+func WriteConfigFile(path string, config *Config) error {
+	buf, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(buf, '\n'), 0600)
+}
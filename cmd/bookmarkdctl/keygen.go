@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// runKeygen prints a new PASETO secret key for BOOKMARKD_PASETO_SECRET. Tokens
+// signed by a server only remain valid across restarts if the key is set.
+func (m *Main) runKeygen(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl keygen", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	public := fs.Bool("public", false, "also print the public key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	secretKey := paseto.NewV4AsymmetricSecretKey()
+
+	if m.Output == OutputJSON {
+		v := map[string]string{"secret": secretKey.ExportHex()}
+		if *public {
+			v["public"] = secretKey.Public().ExportHex()
+		}
+		return printJSON(m.Stdout, v)
+	}
+
+	fmt.Fprintf(m.Stdout, "BOOKMARKD_PASETO_SECRET=%s\n", secretKey.ExportHex())
+	if *public {
+		fmt.Fprintf(m.Stdout, "# public key: %s\n", secretKey.Public().ExportHex())
+	}
+	return nil
+}
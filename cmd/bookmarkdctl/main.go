@@ -1,17 +1,203 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"bookmarkd"
+	"bookmarkd/client"
+)
+
+// Build version, injected during build.
+var (
+	version string
+	commit  string
 )
 
 func main() {
-	var verbose bool
-	flag.BoolVar(&verbose, "verbose", false, "verbose output")
-	flag.Parse()
-	if verbose {
-		fmt.Println("verbose is on")
+	// Propagate build information to root package.
+	bookmarkd.Version = version
+	bookmarkd.Commit = commit
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	m := NewMain()
+	if err := m.Run(ctx, os.Args[1:]); errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+// Main represents the command line program.
+type Main struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Path of the config file holding the server url & session tokens.
+	ConfigPath string
+
+	// Url of the server. Overrides the config file if set.
+	URL string
+
+	// Output format, "table" or "json".
+	Output string
+
+	Config *Config
+	Client *client.Client
+}
+
+// NewMain returns a new instance of Main.
+func NewMain() *Main {
+	return &Main{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run parses the global flags and executes the subcommand named by args.
+func (m *Main) Run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	fs.StringVar(&m.ConfigPath, "config", DefaultConfigPath(), "config file path")
+	fs.StringVar(&m.URL, "url", "", "server url, e.g. "+DefaultURL)
+	fs.StringVar(&m.Output, "o", OutputTable, "output format: table or json")
+	fs.Usage = m.usage(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if m.Output != OutputTable && m.Output != OutputJSON {
+		return fmt.Errorf("unknown output format %q", m.Output)
+	}
+
+	cmd, args := fs.Arg(0), fs.Args()
+	if len(args) > 0 {
+		args = args[1:]
+	}
+
+	// Commands which do not talk to a server.
+	switch cmd {
+	case "", "help":
+		fs.Usage()
+		return flag.ErrHelp
+	case "keygen":
+		return m.runKeygen(ctx, args)
+	case "admin":
+		return m.runAdmin(ctx, args)
+	case "version":
+		fmt.Fprintf(m.Stdout, "bookmarkdctl %s %s\n", version, commit)
+		return nil
+	}
+
+	if err := m.open(); err != nil {
+		return err
 	}
 
-	fmt.Println(flag.Args())
+	switch cmd {
+	case "register":
+		return m.runRegister(ctx, args)
+	case "login":
+		return m.runLogin(ctx, args)
+	case "logout":
+		return m.runLogout(ctx, args)
+	case "bookmarks":
+		return m.runBookmarks(ctx, args)
+	case "sessions":
+		return m.runSessions(ctx, args)
+	case "backups":
+		return m.runBackups(ctx, args)
+	case "watch":
+		return m.runWatch(ctx, args)
+	default:
+		return fmt.Errorf("unknown command %q, see 'bookmarkdctl help'", cmd)
+	}
+}
+
+// open loads the config file & creates a client which saves refreshed tokens
+// back to it.
+func (m *Main) open() (err error) {
+	if m.Config, err = ReadConfigFile(m.ConfigPath); err != nil {
+		return err
+	}
+	if m.URL != "" {
+		m.Config.URL = m.URL
+	}
+
+	m.Client = client.NewClient(m.Config.URL)
+	m.Client.SetToken(m.Config.Token)
+	m.Client.OnToken = func(token *client.Token) {
+		m.Config.Token = token
+		if err := WriteConfigFile(m.ConfigPath, m.Config); err != nil {
+			fmt.Fprintf(m.Stderr, "save tokens: %s\n", err)
+		}
+	}
+	return nil
+}
+
+func (m *Main) usage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(m.Stderr, strings.TrimSpace(`
+bookmarkdctl is a command line client for bookmarkd.
+
+Usage:
+
+	bookmarkdctl [flags] <command> [arguments]
+
+Commands:
+
+	register <username>            register a new user & log in
+	login <username>               log in with a TOTP passcode
+	logout                         delete the current session
+
+	bookmarks list                 list bookmarks
+	bookmarks search <query>       search bookmarks
+	bookmarks add <url>            add a bookmark
+	bookmarks edit <id>            edit a bookmark
+	bookmarks rm <id>              remove a bookmark
+
+	sessions list                  list your sessions
+	sessions revoke <id>           revoke a session
+
+	watch                          print events as they happen
+
+	backups create                 back up the server's database
+	backups list                   list the server's backups
+
+Admin commands open the database file directly & work while the server is
+stopped. The file defaults to $BOOKMARKD_DSN and is set with -dsn. The
+migrations commands also accept a postgres url:
+
+	admin users list               list users
+	admin users create <username>  create a user & print the TOTP url
+	admin users reset-totp <user>  replace a user's TOTP seed
+	admin users disable <user>     disable a user & revoke their sessions
+	admin users enable <user>      re-enable a disabled user
+	admin users rm <user>          delete a user & their bookmarks
+	admin sessions list            list sessions of all users
+	admin sessions revoke <id>     revoke sessions by id or with -user
+	admin migrations status        show applied, pending & modified migrations
+	admin migrations migrate       migrate to the latest or the -to version, see -dry-run
+	admin migrations down          revert the latest applied migration
+	admin stats                    print database statistics
+	admin backup                   back up the database
+	admin restore <backup>         replace the database with a backup
+	admin replica list             list the generations of the wal replica
+	admin replica restore          restore the wal replica, optionally as of -timestamp
+
+	keygen                         generate a PASETO secret key
+	version                        print the version
+
+Flags:
+`))
+		fs.PrintDefaults()
+	}
 }
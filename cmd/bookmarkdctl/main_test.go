@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/httplog/v2"
+
+	"bookmarkd/client"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/inmem"
+	"bookmarkd/internal/server"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
+)
+
+func TestMain_Run(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "bookmarkdctl.json")
+
+	for _, tt := range []struct {
+		name   string
+		args   []string
+		err    string // error message contains, if set
+		stdout string // standard output contains, if set
+	}{
+		{name: "Help", args: []string{}, err: flag.ErrHelp.Error()},
+		{name: "HelpCommand", args: []string{"help"}, err: flag.ErrHelp.Error()},
+		{name: "Version", args: []string{"version"}, stdout: "bookmarkdctl "},
+		{name: "UnknownFlag", args: []string{"-nope", "version"}, err: "flag provided but not defined: -nope"},
+		{name: "UnknownOutput", args: []string{"-o", "xml", "version"}, err: `unknown output format "xml"`},
+		{name: "UnknownCommand", args: []string{"-config", configPath, "nope"}, err: `unknown command "nope"`},
+		{name: "BookmarksUsage", args: []string{"-config", configPath, "bookmarks"}, err: "usage: bookmarkdctl bookmarks"},
+		{name: "SessionsUsage", args: []string{"-config", configPath, "sessions", "nope"}, err: "usage: bookmarkdctl sessions"},
+		{name: "LoginUsage", args: []string{"-config", configPath, "login"}, err: "usage: bookmarkdctl login"},
+		{name: "LogoutNotLoggedIn", args: []string{"-config", configPath, "logout"}, err: "not logged in"},
+		{name: "AdminUsage", args: []string{"admin", "-dsn", filepath.Join(t.TempDir(), "db"), "nope"}, err: "usage: bookmarkdctl admin"},
+		{name: "AdminMissingDatabase", args: []string{"admin", "-dsn", filepath.Join(t.TempDir(), "db"), "stats"}, err: "open database"},
+		{name: "AdminPostgres", args: []string{"admin", "-dsn", "postgres://127.0.0.1:1/db", "stats"}, err: "admin commands require a sqlite database, not postgres"},
+		{name: "AdminPostgresMigrations", args: []string{"admin", "-dsn", "postgres://127.0.0.1:1/db?connect_timeout=1", "migrations"}, err: "open database: ping"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, err := RunMain(t, "", tt.args...)
+			if tt.err == "" {
+				require.Equal(t, err, nil)
+			} else if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+			require.Equal(t, strings.Contains(stdout, tt.stdout), true)
+		})
+	}
+
+	// Nothing is written by commands which fail before logging in.
+	_, err := os.Stat(configPath)
+	require.Equal(t, errors.Is(err, os.ErrNotExist), true)
+}
+
+// Ensure tokens are saved to the config file when logging in & used by
+// later invocations.
+func TestMain_Login(t *testing.T) {
+	url, _ := MustOpenServer(t)
+	configPath := filepath.Join(t.TempDir(), "bookmarkd", "bookmarkdctl.json")
+	totpUrl := MustRegister(t, url, "jane")
+
+	_, stderr, err := RunMain(t, "", "-config", configPath, "-url", url, "login", "-totp-url", totpUrl, "jane")
+	require.Equal(t, err, nil)
+	require.Equal(t, strings.Contains(stderr, "Logged in as jane"), true)
+
+	// Ensure the file holding the tokens is only accessible by the user.
+	fi, err := os.Stat(configPath)
+	require.Equal(t, err, nil)
+	require.Equal(t, fi.Mode().Perm(), os.FileMode(0600))
+	fi, err = os.Stat(filepath.Dir(configPath))
+	require.Equal(t, err, nil)
+	require.Equal(t, fi.Mode().Perm(), os.FileMode(0700))
+
+	config := MustReadConfigFile(t, configPath)
+	require.Equal(t, config.URL, url)
+	require.NotEqual(t, config.Token, nil)
+	require.NotEqual(t, config.Token.AccessToken, "")
+	require.NotEqual(t, config.Token.RefreshToken, "")
+
+	// Ensure the saved session & url are used without passing -url.
+	t.Run("Bookmarks", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "-config", configPath, "bookmarks", "add", "-name", "Go", "https://go.dev")
+		require.Equal(t, err, nil)
+
+		stdout, _, err := RunMain(t, "", "-config", configPath, "-o", "json", "bookmarks", "list")
+		require.Equal(t, err, nil)
+
+		var list client.BookmarkList
+		require.Equal(t, json.Unmarshal([]byte(stdout), &list), nil)
+		require.Equal(t, list.N, 1)
+		require.Equal(t, list.Bookmarks[0].Name, "Go")
+	})
+
+	// Ensure logging out removes the tokens from the config file.
+	t.Run("Logout", func(t *testing.T) {
+		_, _, err := RunMain(t, "", "-config", configPath, "logout")
+		require.Equal(t, err, nil)
+		require.Equal(t, MustReadConfigFile(t, configPath).Token == nil, true)
+
+		_, _, err = RunMain(t, "", "-config", configPath, "bookmarks", "list")
+		require.NotEqual(t, err, nil)
+	})
+}
+
+// Ensure tokens refreshed while running a command are saved to the config
+// file.
+func TestMain_Refresh(t *testing.T) {
+	url, _ := MustOpenServer(t)
+	configPath := filepath.Join(t.TempDir(), "bookmarkdctl.json")
+	totpUrl := MustRegister(t, url, "jane")
+
+	_, _, err := RunMain(t, "", "-config", configPath, "-url", url, "login", "-totp-url", totpUrl, "jane")
+	require.Equal(t, err, nil)
+
+	// Expire the access token so the next command refreshes it first.
+	config := MustReadConfigFile(t, configPath)
+	token := *config.Token
+	config.Token.ExpiresAt = time.Now().Add(-time.Minute)
+	require.Equal(t, WriteConfigFile(configPath, config), nil)
+
+	_, _, err = RunMain(t, "", "-config", configPath, "sessions", "list")
+	require.Equal(t, err, nil)
+
+	other := MustReadConfigFile(t, configPath).Token
+	require.NotEqual(t, other.RefreshToken, token.RefreshToken)
+	require.Equal(t, other.ExpiresAt.After(time.Now()), true)
+
+	// Ensure the refreshed tokens can be used by later invocations.
+	_, _, err = RunMain(t, "", "-config", configPath, "sessions", "list")
+	require.Equal(t, err, nil)
+}
+
+// Ensure a passcode is read from stdin if it isn't passed as a flag.
+func TestMain_LoginPrompt(t *testing.T) {
+	url, _ := MustOpenServer(t)
+	configPath := filepath.Join(t.TempDir(), "bookmarkdctl.json")
+	totpUrl := MustRegister(t, url, "jane")
+
+	passcode, err := client.Passcode(totpUrl, time.Now())
+	require.Equal(t, err, nil)
+
+	_, stderr, err := RunMain(t, passcode+"\n", "-config", configPath, "-url", url, "login", "jane")
+	require.Equal(t, err, nil)
+	require.Equal(t, strings.Contains(stderr, "Passcode: "), true)
+	require.NotEqual(t, MustReadConfigFile(t, configPath).Token, nil)
+
+	_, _, err = RunMain(t, "", "-config", configPath, "-url", url, "login", "jane")
+	require.NotEqual(t, err, nil)
+}
+
+// AdminToken is the admin token of the test server.
+const AdminToken = "admin"
+
+// MustOpenServer returns the url of a test server backed by a temporary
+// sqlite file & the path of the file.
+func MustOpenServer(tb testing.TB) (url, dsn string) {
+	tb.Helper()
+
+	config, err := core.NewConfig(func(string) string { return "" })
+	if err != nil {
+		tb.Fatal(err)
+	}
+	config.AdminToken = AdminToken
+
+	dsn = filepath.Join(tb.TempDir(), "db.sqlite")
+	db := sqlite.NewDB(dsn)
+	eventService := inmem.NewEventService()
+	db.EventService = eventService
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+	r := server.NewRouter(
+		logger,
+		config,
+		server.NewHealth(),
+		inmem.NewRegistrationStore(),
+		sqlite.NewBackupStore(db, filepath.Join(tb.TempDir(), "backups")),
+		sqlite.NewBookmarkStore(db),
+		eventService,
+		sqlite.NewIdempotencyKeyStore(db, time.Hour),
+		sqlite.NewSessionStore(db),
+		sqlite.NewUserStore(db),
+	)
+
+	s := httptest.NewServer(r)
+	tb.Cleanup(s.Close)
+
+	return s.URL + config.HttpBasePath, dsn
+}
+
+// MustRegister registers a new user with the server at url & returns the
+// TOTP url of the user.
+func MustRegister(tb testing.TB, url, username string) string {
+	tb.Helper()
+
+	ctx := context.Background()
+	c := client.NewClient(url)
+	reg, err := c.Register(ctx, username)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	passcode, err := client.Passcode(reg.TotpUrl, time.Now())
+	if err != nil {
+		tb.Fatal(err)
+	} else if err := c.ConfirmRegistration(ctx, reg.RegistrationID, passcode); err != nil {
+		tb.Fatal(err)
+	}
+	return reg.TotpUrl
+}
+
+// RunMain runs the program with args, reading stdin, & returns its output.
+func RunMain(tb testing.TB, stdin string, args ...string) (stdout, stderr string, err error) {
+	tb.Helper()
+
+	var outBuf, errBuf bytes.Buffer
+	m := NewMain()
+	m.Stdin = strings.NewReader(stdin)
+	m.Stdout, m.Stderr = &outBuf, &errBuf
+
+	err = m.Run(context.Background(), args)
+	return outBuf.String(), errBuf.String(), err
+}
+
+// MustReadConfigFile returns the config file at path. Fatal on error.
+func MustReadConfigFile(tb testing.TB, path string) *Config {
+	tb.Helper()
+
+	config, err := ReadConfigFile(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return config
+}
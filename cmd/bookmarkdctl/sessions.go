@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"bookmarkd/client"
+)
+
+// runSessions executes a sessions subcommand.
+func (m *Main) runSessions(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "list", "ls":
+		return m.runSessionsList(ctx, args)
+	case "revoke":
+		return m.runSessionsRevoke(ctx, args)
+	default:
+		return fmt.Errorf("usage: bookmarkdctl sessions list|revoke")
+	}
+}
+
+// runSessionsList lists the current user's sessions.
+func (m *Main) runSessionsList(ctx context.Context, args []string) error {
+	var filter client.SessionFilter
+	fs := flag.NewFlagSet("bookmarkdctl sessions list", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	fs.StringVar(&filter.Cursor, "cursor", "", "cursor of the page to list")
+	fs.IntVar(&filter.Limit, "limit", 20, "maximum number of sessions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	list, err := m.Client.FindSessions(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(list.Sessions))
+	for _, s := range list.Sessions {
+		rows = append(rows, sessionRow(s))
+	}
+	return m.print(list, sessionHeader, rows)
+}
+
+// runSessionsRevoke deletes a session of the current user.
+func (m *Main) runSessionsRevoke(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl sessions revoke", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() != 1 {
+		return fmt.Errorf("usage: bookmarkdctl sessions revoke <id>")
+	}
+
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid session id %q", fs.Arg(0))
+	}
+
+	s, err := m.Client.DeleteSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	return m.print(s, sessionHeader, [][]string{sessionRow(s)})
+}
+
+var sessionHeader = []string{"ID", "CREATED", "UPDATED"}
+
+func sessionRow(s *client.Session) []string {
+	return []string{strconv.Itoa(s.ID), formatTime(s.CreatedAt), formatTime(s.UpdatedAt)}
+}
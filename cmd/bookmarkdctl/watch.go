@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// runWatch prints the current user's events until interrupted. Events are
+// printed as one JSON object per line with the json output format.
+func (m *Main) runWatch(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bookmarkdctl watch", flag.ContinueOnError)
+	fs.SetOutput(m.Stderr)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	sub, err := m.Client.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	for event := range sub.C() {
+		if m.Output == OutputJSON {
+			if err := json.NewEncoder(m.Stdout).Encode(event); err != nil {
+				return err
+			}
+			continue
+		}
+
+		payload, err := json.Marshal(event.Payload)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(m.Stdout, "%s  %-28s %s\n", time.Now().Format(time.TimeOnly), event.Type, payload)
+	}
+
+	// The stream ends when interrupted or the connection is lost.
+	if ctx.Err() != nil {
+		return nil
+	} else if err := sub.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("event stream closed")
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Backup represents a snapshot of the database taken while the server runs.
+type Backup struct {
+	// File name of the backup within the backup directory.
+	Name string `json:"name"`
+
+	// Size of the backup file in bytes.
+	Size int64 `json:"size"`
+
+	// Time the backup was taken.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BackupStore represents a service for taking backups of the database.
+type BackupStore interface {
+	// Takes a consistent snapshot of the database. Older backups are removed
+	// once more than the configured number of backups are kept.
+	CreateBackup(ctx context.Context) (*Backup, error)
+
+	// Lists the kept backups, newest first.
+	FindBackups(ctx context.Context) ([]*Backup, error)
+}
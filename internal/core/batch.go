@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+
+	"bookmarkd"
+)
+
+// MaxBookmarkBatchLen is the maximum number of operations in a single batch.
+const MaxBookmarkBatchLen = 1000
+
+// Bookmark batch operation types.
+const (
+	BookmarkOpCreate = "create"
+	BookmarkOpUpdate = "update"
+	BookmarkOpDelete = "delete"
+)
+
+// BatchMode controls how a batch handles a failing operation.
+type BatchMode string
+
+// Batch modes.
+const (
+	// Every operation must succeed. A failure rolls back the whole batch and
+	// the remaining operations are skipped. This is the default.
+	BatchModeAtomic BatchMode = "atomic"
+
+	// Failing operations are rolled back individually and the remaining
+	// operations are still applied.
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// Validate returns ErrBadRequest if the mode is unknown. An empty mode
+// selects BatchModeAtomic.
+func (m BatchMode) Validate() error {
+	switch m {
+	case "", BatchModeAtomic, BatchModeBestEffort:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown batch mode %q", bookmarkd.ErrBadRequest, m)
+}
+
+// BookmarkBatch represents a list of bookmark operations applied within a
+// single transaction by BatchBookmarks().
+type BookmarkBatch struct {
+	Mode       BatchMode    `json:"mode"`
+	Operations []BookmarkOp `json:"operations"`
+}
+
+// Validate returns ErrBadRequest if the mode is unknown or the batch is empty
+// or too large. Individual operations are validated as they are applied.
+func (b *BookmarkBatch) Validate() error {
+	if err := b.Mode.Validate(); err != nil {
+		return err
+	} else if len(b.Operations) == 0 {
+		return fmt.Errorf("%w: batch operations required", bookmarkd.ErrBadRequest)
+	} else if len(b.Operations) > MaxBookmarkBatchLen {
+		return fmt.Errorf("%w: batch exceeds %d operations", bookmarkd.ErrBadRequest, MaxBookmarkBatchLen)
+	}
+	return nil
+}
+
+// BookmarkOp represents a single operation within a batch.
+type BookmarkOp struct {
+	// Operation type. One of "create", "update" or "delete".
+	Op string `json:"op"`
+
+	// Bookmark to update or delete. If version is set the operation fails
+	// with ErrPreconditionFailed unless the bookmark is still at that version.
+	ID      int  `json:"id,omitempty"`
+	Version *int `json:"version,omitempty"`
+
+	// Bookmark to create & how to handle an existing duplicate.
+	Bookmark   *Bookmark     `json:"bookmark,omitempty"`
+	Duplicates DuplicateMode `json:"duplicates,omitempty"`
+
+	// Fields to update.
+	Update *BookmarkUpdate `json:"update,omitempty"`
+}
+
+// Validate returns a *bookmarkd.ValidationError if the operation is unknown
+// or missing the fields it requires.
+func (op *BookmarkOp) Validate() error {
+	switch op.Op {
+	case BookmarkOpCreate:
+		if op.Bookmark == nil {
+			return bookmarkd.NewValidationError("bookmark", "bookmark required for create")
+		}
+	case BookmarkOpUpdate:
+		if op.Update == nil {
+			return bookmarkd.NewValidationError("update", "update required for update")
+		}
+	case BookmarkOpDelete:
+	default:
+		return bookmarkd.NewValidationError("op", "unknown batch operation %q", op.Op)
+	}
+	return nil
+}
+
+// Batch operation result statuses.
+const (
+	// The operation was applied.
+	BookmarkOpStatusOK = "ok"
+
+	// The operation failed. Err is set.
+	BookmarkOpStatusFailed = "failed"
+
+	// The operation was applied but undone because a later operation of an
+	// atomic batch failed.
+	BookmarkOpStatusRolledBack = "rolled_back"
+
+	// The operation was not attempted because an earlier operation of an
+	// atomic batch failed.
+	BookmarkOpStatusSkipped = "skipped"
+)
+
+// BookmarkBatchResult represents the outcome of BatchBookmarks().
+type BookmarkBatchResult struct {
+	// True if the transaction was committed. Always true for best effort
+	// batches and only true for atomic batches if every operation succeeded.
+	Committed bool `json:"committed"`
+
+	// Result of each operation in the same order as the batch.
+	Results []*BookmarkOpResult `json:"results"`
+}
+
+// BookmarkOpResult represents the outcome of a single batch operation.
+type BookmarkOpResult struct {
+	Op     string `json:"op"`
+	Status string `json:"status"`
+
+	// State of the bookmark after the operation. For deletes this is the
+	// state before removal.
+	Bookmark *Bookmark `json:"bookmark,omitempty"`
+
+	// Error of a failed operation.
+	Err error `json:"-"`
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 	"unicode/utf8"
 
@@ -13,7 +14,7 @@ import (
 const (
 	MaxBookmarkNameLen        = 255
 	MaxBookmarkDescriptionLen = 255
-	MaxBookmarkUrlLen         = 255
+	MaxBookmarkUrlLen         = 8192
 )
 
 type Bookmark struct {
@@ -32,29 +33,87 @@ type Bookmark struct {
 	// http url of  the bookmark.
 	Url string `json:"url"`
 
+	// Canonical form of Url used to detect duplicate bookmarks.
+	// Set by the store whenever the url changes.
+	CanonicalUrl string `json:"canonicalUrl"`
+
+	// Reading state of the bookmark. Defaults to unread.
+	Status  BookmarkStatus `json:"status"`
+	Starred bool           `json:"starred"`
+
+	// Timestamp the bookmark was marked as read. Zero while unread.
+	ReadAt time.Time `json:"readAt"`
+
+	// Incremented on every update. Used to detect conflicting changes.
+	Version int `json:"version"`
+
+	// How well the bookmark matches BookmarkFilter.Search. Only set when
+	// searching; a higher value is a better match.
+	Relevance int `json:"relevance,omitempty"`
+
 	// Timestamps for bookmark creation & last update.
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-// Validate returns an error if bookmark has invalid fields. Only performs basic validation.
+// Validate returns a *bookmarkd.ValidationError if bookmark has invalid fields.
 func (d *Bookmark) Validate() error {
+	if err := ValidateBookmarkUrl(d.Url); err != nil {
+		return err
+	}
+	return d.ValidateExceptUrl()
+}
+
+// ValidateExceptUrl validates all fields of bookmark but its url. Bookmarks
+// saved before the url rules were tightened can still be updated as long as
+// their url is left alone.
+func (d *Bookmark) ValidateExceptUrl() error {
 	if d.Name == "" {
-		return fmt.Errorf("%w: bookmark name required", bookmarkd.ErrInvalidInput)
+		return bookmarkd.NewValidationError("name", "bookmark name required")
 	} else if utf8.RuneCountInString(d.Name) > MaxBookmarkNameLen {
-		return fmt.Errorf("%w: bookmark name too long", bookmarkd.ErrInvalidInput)
+		return bookmarkd.NewValidationError("name", "bookmark name too long")
 	} else if utf8.RuneCountInString(d.Description) > MaxBookmarkDescriptionLen {
-		return fmt.Errorf("%w: bookmark description too long", bookmarkd.ErrInvalidInput)
-	} else if d.Url == "" {
-		return fmt.Errorf("%w: bookmark url required", bookmarkd.ErrInvalidInput)
-	} else if utf8.RuneCountInString(d.Url) > MaxBookmarkUrlLen {
-		return fmt.Errorf("%w: bookmark url too long", bookmarkd.ErrInvalidInput)
+		return bookmarkd.NewValidationError("description", "bookmark description too long")
+	} else if err := d.Status.Validate(); err != nil {
+		return err
 	} else if d.UserID == "" {
-		return fmt.Errorf("%w: bookmark creator required", bookmarkd.ErrInvalidInput)
+		return bookmarkd.NewValidationError("userID", "bookmark creator required")
 	}
 	return nil
 }
 
+// ValidateBookmarkUrl returns a *bookmarkd.ValidationError if url can't be
+// bookmarked.
+func ValidateBookmarkUrl(url string) error {
+	if url == "" {
+		return bookmarkd.NewValidationError("url", "bookmark url required")
+	} else if utf8.RuneCountInString(url) > MaxBookmarkUrlLen {
+		return bookmarkd.NewValidationError("url", "bookmark url too long")
+	} else if _, err := ParseBookmarkUrl(url); err != nil {
+		return err
+	}
+	return nil
+}
+
+// BookmarkStatus represents the reading state of a bookmark.
+type BookmarkStatus string
+
+// Bookmark statuses.
+const (
+	BookmarkStatusUnread   BookmarkStatus = "unread"
+	BookmarkStatusRead     BookmarkStatus = "read"
+	BookmarkStatusArchived BookmarkStatus = "archived"
+)
+
+// Validate returns a *bookmarkd.ValidationError if the status is unknown.
+func (s BookmarkStatus) Validate() error {
+	switch s {
+	case BookmarkStatusUnread, BookmarkStatusRead, BookmarkStatusArchived:
+		return nil
+	}
+	return bookmarkd.NewValidationError("status", "unknown bookmark status %q", s)
+}
+
 // CanEditBookmark returns true if the current user can edit the bookmark.
 // Only the bookmark owner can edit the bookmark.
 func CanEditBookmark(ctx context.Context, bookmark *Bookmark) bool {
@@ -65,24 +124,171 @@ func CanEditBookmark(ctx context.Context, bookmark *Bookmark) bool {
 type BookmarkStore interface {
 	FindBookmarkByID(ctx context.Context, id int) (*Bookmark, error)
 	FindBookmarks(ctx context.Context, filter BookmarkFilter) ([]*Bookmark, int, error)
-	CreateBookmark(ctx context.Context, bookmark *Bookmark) error
+	CreateBookmark(ctx context.Context, bookmark *Bookmark, mode DuplicateMode) error
 	UpdateBookmark(ctx context.Context, id int, update BookmarkUpdate) (*Bookmark, error)
-	DeleteBookmark(ctx context.Context, id int) (*Bookmark, error)
+	DeleteBookmark(ctx context.Context, id int, version *int) (*Bookmark, error)
+
+	// Revision history of a bookmark. Every update records a revision which
+	// can later be reverted.
+	FindBookmarkRevisions(ctx context.Context, filter BookmarkRevisionFilter) ([]*BookmarkRevision, int, error)
+	RevertBookmark(ctx context.Context, id int, revisionID int) (*Bookmark, error)
+
+	// Lists groups of the current user's bookmarks sharing a canonical url.
+	FindDuplicateBookmarks(ctx context.Context) ([]*BookmarkDuplicates, error)
+
+	// Sets the status of every bookmark matching filter, e.g. to mark all
+	// unread bookmarks as read. Returns the number of bookmarks changed.
+	UpdateBookmarksStatus(ctx context.Context, filter BookmarkFilter, status BookmarkStatus) (int, error)
+
+	// Applies a list of create, update & delete operations in a single
+	// transaction. See BookmarkBatch for how failures are handled.
+	BatchBookmarks(ctx context.Context, batch *BookmarkBatch) (*BookmarkBatchResult, error)
+}
+
+// DuplicateMode controls how CreateBookmark() handles a bookmark whose
+// canonical url matches an existing bookmark of the same user.
+type DuplicateMode string
+
+// Duplicate modes.
+const (
+	// Create the bookmark regardless of existing duplicates. This is the
+	// default when no mode is given.
+	DuplicateModeAllow DuplicateMode = "allow"
+
+	// Return ErrBookmarkDuplicate if a duplicate exists.
+	DuplicateModeReject DuplicateMode = "reject"
+
+	// Apply the new name & description to the existing bookmark instead of
+	// creating a new one.
+	DuplicateModeMerge DuplicateMode = "merge"
+)
+
+// Validate returns an error if the mode is unknown.
+func (m DuplicateMode) Validate() error {
+	switch m {
+	case "", DuplicateModeAllow, DuplicateModeReject, DuplicateModeMerge:
+		return nil
+	}
+	return fmt.Errorf("%w: unknown duplicate mode %q", bookmarkd.ErrBadRequest, m)
+}
+
+// BookmarkDuplicates represents a group of bookmarks sharing a canonical url.
+type BookmarkDuplicates struct {
+	CanonicalUrl string      `json:"canonicalUrl"`
+	Bookmarks    []*Bookmark `json:"bookmarks"`
 }
 
 // BookmarkFilter represents a filter used by FindBookmarks().
 type BookmarkFilter struct {
 	// Filtering fields.
-	ID *int `json:"id"`
+	ID           *int            `json:"id"`
+	CanonicalUrl *string         `json:"canonicalUrl"`
+	Status       *BookmarkStatus `json:"status"`
+	Starred      *bool           `json:"starred"`
+
+	// Matches bookmarks whose name, description or url contain the text.
+	Search *string `json:"search"`
+
+	// Ordering of results. Sort is one of "created", "updated", "name" or
+	// "relevance" and defaults to "created". Direction defaults to ascending
+	// except for relevance which lists the best matches first.
+	Sort      string `json:"sort"`
+	Direction string `json:"direction"`
+
+	// Opaque cursor of a previous page. Results continue after the row the
+	// cursor points at.
+	Cursor string `json:"cursor"`
 
 	// Restrict to subset of range.
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
 }
 
+// Validate returns ErrBadRequest if the status, sort, range or cursor are
+// invalid.
+func (f BookmarkFilter) Validate() error {
+	if f.Status != nil {
+		if err := f.Status.Validate(); err != nil {
+			return fmt.Errorf("%w: unknown bookmark status %q", bookmarkd.ErrBadRequest, *f.Status)
+		}
+	}
+
+	if err := validateSort(f.Sort, f.Direction, SortCreated, SortUpdated, SortName, SortRelevance); err != nil {
+		return err
+	} else if err := validateRange(f.Offset, f.Limit); err != nil {
+		return err
+	} else if f.Cursor != "" {
+		if _, err := DecodeCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Descending returns true if results are listed in descending order.
+func (f BookmarkFilter) Descending() bool {
+	if f.Direction == "" {
+		return f.Sort == SortRelevance
+	}
+	return f.Direction == SortDesc
+}
+
+// CursorOf returns the cursor pointing at bookmark under the filter's sort.
+func (f BookmarkFilter) CursorOf(bookmark *Bookmark) Cursor {
+	c := Cursor{ID: bookmark.ID}
+	switch f.Sort {
+	case SortUpdated:
+		c.Key = formatCursorTime(bookmark.UpdatedAt)
+	case SortName:
+		c.Key = bookmark.Name
+	case SortRelevance:
+		c.Key = bookmark.Relevance
+	default:
+		c.Key = formatCursorTime(bookmark.CreatedAt)
+	}
+	return c
+}
+
+// Cursors returns the cursors of the pages after and before a page of
+// bookmarks fetched with the filter.
+func (f BookmarkFilter) Cursors(bookmarks []*Bookmark) (next, prev string) {
+	return pageCursors(f.Cursor, f.Offset, f.Limit, len(bookmarks),
+		func() Cursor { return f.CursorOf(bookmarks[0]) },
+		func() Cursor { return f.CursorOf(bookmarks[len(bookmarks)-1]) },
+	)
+}
+
 // BookmarkUpdate represents a set of fields to update on a bookmark.
 type BookmarkUpdate struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	Url         *string `json:"url"`
+	Name        *string         `json:"name"`
+	Description *string         `json:"description"`
+	Url         *string         `json:"url"`
+	Status      *BookmarkStatus `json:"status"`
+	Starred     *bool           `json:"starred"`
+
+	// If set, the update fails with ErrPreconditionFailed unless the
+	// bookmark is still at this version.
+	Version *int `json:"-"`
+}
+
+// Changes returns the old and new value of every field set on the update.
+// Must be called before the update is applied to bookmark.
+func (u BookmarkUpdate) Changes(bookmark *Bookmark) []BookmarkFieldChange {
+	changes := make([]BookmarkFieldChange, 0)
+	if v := u.Name; v != nil {
+		changes = append(changes, BookmarkFieldChange{Field: BookmarkFieldName, Old: bookmark.Name, New: *v})
+	}
+	if v := u.Description; v != nil {
+		changes = append(changes, BookmarkFieldChange{Field: BookmarkFieldDescription, Old: bookmark.Description, New: *v})
+	}
+	if v := u.Url; v != nil {
+		changes = append(changes, BookmarkFieldChange{Field: BookmarkFieldUrl, Old: bookmark.Url, New: *v})
+	}
+	if v := u.Status; v != nil {
+		changes = append(changes, BookmarkFieldChange{Field: BookmarkFieldStatus, Old: string(bookmark.Status), New: string(*v)})
+	}
+	if v := u.Starred; v != nil {
+		changes = append(changes, BookmarkFieldChange{Field: BookmarkFieldStarred, Old: strconv.FormatBool(bookmark.Starred), New: strconv.FormatBool(*v)})
+	}
+	return changes
 }
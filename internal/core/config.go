@@ -1,20 +1,45 @@
 package core
 
 import (
+	"encoding/base32"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"aidanwoods.dev/go-paseto"
+	"github.com/BurntSushi/toml"
 	"github.com/cristalhq/otp"
 )
 
+// Minimum TLS versions.
+const (
+	TlsVersion12 = "1.2"
+	TlsVersion13 = "1.3"
+)
+
+// Client authentication modes of mutual TLS.
+const (
+	TlsClientAuthRequire       = "require"
+	TlsClientAuthVerifyIfGiven = "verify_if_given"
+)
+
+// Database backends, selected by the scheme of the DSN.
+const (
+	DbSchemeSqlite   = "sqlite"
+	DbSchemePostgres = "postgres"
+	DbSchemeInmem    = "inmem"
+)
+
 type Config struct {
 	Test bool
-	// sqlite
+	// database, a sqlite path or a postgres:// url
 	DbDsn string
 	// http
 	HttpAddress          string
@@ -22,6 +47,22 @@ type Config struct {
 	HttpDomain           string
 	HttpPort             string
 	HttpTimeoutInSeconds int
+	HttpRequireIfMatch   bool
+	// unix socket, if the address is a path
+	HttpSocketMode  string
+	HttpSocketGroup string
+	// tls, disabled unless a certificate is set or self-signed
+	TlsCertFile            string
+	TlsKeyFile             string
+	TlsSelfSigned          bool
+	TlsMinVersion          string
+	TlsClientCaFile        string
+	TlsClientAuth          string
+	TlsRedirectPort        int
+	TlsHstsMaxAgeInSeconds int
+	// health checks, readiness fails while shutting down for the delay
+	HealthCheckTimeoutInSeconds int
+	ShutdownDelayInSeconds      int
 	// jwt
 	PasetoSecretKey                       paseto.V4AsymmetricSecretKey
 	PasetoPublicKey                       paseto.V4AsymmetricPublicKey
@@ -29,15 +70,42 @@ type Config struct {
 	PasetoRefreshTokenExpirationInSeconds int
 	// error logging
 	RollbarToken string
+	// bookmarks
+	UrlStripParams []string
+	// idempotency keys
+	IdempotencyKeyTTLInSeconds int
+	// admin api, disabled if the token is blank
+	AdminToken string
+	// backups, scheduled backups are disabled if the interval is zero
+	BackupDir               string
+	BackupIntervalInSeconds int
+	BackupRetain            int
+	// wal replication, disabled if the dir is blank
+	ReplicaDir                       string
+	ReplicaSyncIntervalInSeconds     int
+	ReplicaSnapshotIntervalInSeconds int
+	ReplicaRetentionInSeconds        int
 	// totp settings
 	TotpAlgo   otp.Algorithm
 	TotpDigits uint
 	TotpIssuer string
 	TotpPeriod uint64
 	TotpSkew   uint
+
+	// true if no paseto secret was set & the key is random
+	generatedPasetoKey bool
 }
 
+// NewConfig returns the config read from the file at $BOOKMARKD_CONFIG, if
+// set, with settings overridden by environment variables.
 func NewConfig(getenv func(string) string) (Config, error) {
+	return LoadConfig(getenv("BOOKMARKD_CONFIG"), getenv)
+}
+
+// LoadConfig returns the default config with settings overridden by the TOML
+// file at path, if not blank, & then by environment variables. Returns an
+// error listing every unknown or invalid setting.
+func LoadConfig(path string, getenv func(string) string) (Config, error) {
 	sk := paseto.NewV4AsymmetricSecretKey()
 
 	c := Config{
@@ -48,90 +116,650 @@ func NewConfig(getenv func(string) string) (Config, error) {
 		HttpDomain:                            "http://localhost:8080",
 		HttpBasePath:                          "/api",
 		HttpTimeoutInSeconds:                  60,
+		HttpSocketMode:                        "0660",
+		TlsMinVersion:                         TlsVersion12,
+		TlsClientAuth:                         TlsClientAuthRequire,
+		HealthCheckTimeoutInSeconds:           2,
+		ShutdownDelayInSeconds:                5,
 		PasetoSecretKey:                       sk,
 		PasetoPublicKey:                       sk.Public(),
 		PasetoAccessTokenExpirationInSeconds:  300,
 		PasetoRefreshTokenExpirationInSeconds: 1200,
 		RollbarToken:                          "",
+		UrlStripParams:                        DefaultUrlStripParams,
+		IdempotencyKeyTTLInSeconds:            86400,
+		BackupRetain:                          7,
+		ReplicaSyncIntervalInSeconds:          1,
+		ReplicaSnapshotIntervalInSeconds:      86400,
+		ReplicaRetentionInSeconds:             604800,
 		TotpAlgo:                              otp.AlgorithmSHA1,
 		TotpDigits:                            8,
 		TotpIssuer:                            "bookmarkd",
 		TotpPeriod:                            30,
 		TotpSkew:                              1,
+
+		generatedPasetoKey: true,
 	}
 
-	// update config values from getenv function
+	var errs []error
 
-	if s := getenv("BOOKMARKD_TEST"); s != "" {
-		c.Test = true
+	// update config values from the config file
+	if path != "" {
+		errs = append(errs, c.readFile(path)...)
 	}
 
-	if s := getenv("BOOKMARKD_DSN"); s != "" {
-		if dsn, err := ExpandDSN(s); err != nil {
-			return c, err
-		} else {
-			c.DbDsn = dsn
+	// update config values from getenv function
+	for _, f := range configFields {
+		if s := getenv(f.Env); s != "" {
+			if err := f.set(&c, s); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.Env, err))
+			}
 		}
 	}
 
-	if s := getenv("BOOKMARKD_HTTP_ADDRESS"); s != "" {
-		c.HttpAddress = s
+	// Keep backups next to a sqlite database by default.
+	if c.BackupDir == "" && c.DbScheme() == DbSchemeSqlite {
+		c.BackupDir = filepath.Join(filepath.Dir(c.DbDsn), "backups")
 	}
 
-	if s := getenv("BOOKMARKD_HTTP_PORT"); s != "" {
-		c.HttpPort = s
+	if errs = append(errs, c.validate()...); len(errs) > 0 {
+		return c, newConfigError(errs)
 	}
+	return c, nil
+}
 
-	if s := getenv("BOOKMARKD_HTTP_DOMAIN"); s != "" {
-		c.HttpDomain = s
+// readFile sets the settings found in the TOML file at path. Returns an error
+// for each unknown setting & each value of the wrong type.
+func (c *Config) readFile(path string) []error {
+	var values map[string]interface{}
+	if _, err := toml.DecodeFile(path, &values); err != nil {
+		return []error{fmt.Errorf("read config file: %w", err)}
 	}
 
-	if s := getenv("BOOKMARKD_HTTP_BASE_PATH"); s != "" {
-		c.HttpBasePath = s
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
+	slices.Sort(keys)
+
+	var errs []error
+	for _, key := range keys {
+		f, ok := findConfigField(key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q, see 'bookmarkd config print' for every setting", path, key))
+			continue
+		}
 
-	if s := getenv("BOOKMARKD_PASETO_ACESS_TOKEN_EXPIRATION_IN_SECONDS"); s != "" {
-		if i, err := strconv.Atoi(s); err != nil {
-			return c, err
-		} else {
-			c.PasetoAccessTokenExpirationInSeconds = i
+		s, err := f.Kind.format(values[key])
+		if err == nil {
+			err = f.set(c, s)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
 		}
 	}
+	return errs
+}
 
-	if s := getenv("BOOKMARKD_PASETO_REFRESH_TOKEN_EXPIRATION_IN_SECONDS"); s != "" {
-		if i, err := strconv.Atoi(s); err != nil {
-			return c, err
-		} else {
-			c.PasetoRefreshTokenExpirationInSeconds = i
+// Validate returns an error listing every setting with an invalid value.
+func (c Config) Validate() error {
+	if errs := c.validate(); len(errs) > 0 {
+		return newConfigError(errs)
+	}
+	return nil
+}
+
+func (c Config) validate() (errs []error) {
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
 		}
 	}
 
-	if s := getenv("BOOKMARKD_PASETO_SECRET"); s != "" {
-		if h, err := paseto.NewV4AsymmetricSecretKeyFromHex(s); err != nil {
-			return c, err
-		} else {
-			c.PasetoSecretKey = h
-			c.PasetoPublicKey = h.Public()
+	check(c.DbDsn != "", "dsn", "required, e.g. a sqlite path, a postgres:// url or inmem://")
+
+	port, err := strconv.Atoi(c.HttpPort)
+	check(err == nil && port > 0 && port <= 65535, "http_port", "must be a port number between 1 and 65535, got %q", c.HttpPort)
+	u, err := url.Parse(c.HttpDomain)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && strings.Trim(u.Path, "/") == "",
+		"http_domain", "must be an http:// or https:// url without a path, e.g. https://bookmarks.example.com, got %q", c.HttpDomain)
+	check(c.HttpBasePath == "" || strings.HasPrefix(c.HttpBasePath, "/") && !strings.HasSuffix(c.HttpBasePath, "/"),
+		"http_base_path", "must be blank or start with a slash & not end with one, e.g. /api, got %q", c.HttpBasePath)
+	check(c.HttpTimeoutInSeconds > 0, "http_timeout_in_seconds", "must be greater than 0, got %d", c.HttpTimeoutInSeconds)
+	mode, err := strconv.ParseUint(c.HttpSocketMode, 8, 32)
+	check(err == nil && mode <= 0777, "http_socket_mode", "must be octal permissions, e.g. 0660, got %q", c.HttpSocketMode)
+
+	check((c.TlsCertFile == "") == (c.TlsKeyFile == ""), "tls_cert_file", "must be set together with tls_key_file")
+	check(c.TlsCertFile == "" || !c.TlsSelfSigned, "tls_self_signed", "must be false if tls_cert_file is set")
+	check(c.TlsMinVersion == TlsVersion12 || c.TlsMinVersion == TlsVersion13, "tls_min_version", "must be %s or %s, got %q", TlsVersion12, TlsVersion13, c.TlsMinVersion)
+	check(c.TlsClientCaFile == "" || c.TlsEnabled(), "tls_client_ca_file", "requires tls_cert_file or tls_self_signed")
+	check(c.TlsClientAuth == TlsClientAuthRequire || c.TlsClientAuth == TlsClientAuthVerifyIfGiven,
+		"tls_client_auth", "must be %s or %s, got %q", TlsClientAuthRequire, TlsClientAuthVerifyIfGiven, c.TlsClientAuth)
+	check(c.TlsRedirectPort >= 0 && c.TlsRedirectPort <= 65535, "tls_redirect_port", "must be 0 to disable the redirect or a port number up to 65535, got %d", c.TlsRedirectPort)
+	check(c.TlsRedirectPort == 0 || c.TlsEnabled(), "tls_redirect_port", "requires tls_cert_file or tls_self_signed")
+	check(c.TlsRedirectPort == 0 || c.HttpSocketPath() == "", "tls_redirect_port", "must be 0 if http_address is a unix socket")
+	check(c.TlsRedirectPort == 0 || strconv.Itoa(c.TlsRedirectPort) != c.HttpPort, "tls_redirect_port", "must differ from http_port %s", c.HttpPort)
+	check(c.TlsHstsMaxAgeInSeconds >= 0, "tls_hsts_max_age_in_seconds", "must be 0 to disable HSTS or more, got %d", c.TlsHstsMaxAgeInSeconds)
+
+	check(c.HealthCheckTimeoutInSeconds > 0, "health_check_timeout_in_seconds", "must be greater than 0, got %d", c.HealthCheckTimeoutInSeconds)
+	check(c.ShutdownDelayInSeconds >= 0, "shutdown_delay_in_seconds", "must be 0 or more, got %d", c.ShutdownDelayInSeconds)
+
+	check(c.PasetoAccessTokenExpirationInSeconds > 0, "paseto_access_token_expiration_in_seconds", "must be greater than 0, got %d", c.PasetoAccessTokenExpirationInSeconds)
+	check(c.PasetoRefreshTokenExpirationInSeconds >= c.PasetoAccessTokenExpirationInSeconds, "paseto_refresh_token_expiration_in_seconds",
+		"must be at least the access token expiration of %d, got %d", c.PasetoAccessTokenExpirationInSeconds, c.PasetoRefreshTokenExpirationInSeconds)
+
+	check(c.IdempotencyKeyTTLInSeconds > 0, "idempotency_key_ttl_in_seconds", "must be greater than 0, got %d", c.IdempotencyKeyTTLInSeconds)
+
+	check(c.BackupIntervalInSeconds >= 0, "backup_interval_in_seconds", "must be 0 to disable scheduled backups or more, got %d", c.BackupIntervalInSeconds)
+	check(c.BackupRetain >= 0, "backup_retain", "must be 0 to keep every backup or more, got %d", c.BackupRetain)
+
+	check(c.ReplicaSyncIntervalInSeconds > 0, "replica_sync_interval_in_seconds", "must be greater than 0, got %d", c.ReplicaSyncIntervalInSeconds)
+	check(c.ReplicaSnapshotIntervalInSeconds > 0, "replica_snapshot_interval_in_seconds", "must be greater than 0, got %d", c.ReplicaSnapshotIntervalInSeconds)
+	check(c.ReplicaRetentionInSeconds >= 0, "replica_retention_in_seconds", "must be 0 to keep every generation or more, got %d", c.ReplicaRetentionInSeconds)
+	check(c.ReplicaDir == "" || c.DbScheme() == DbSchemeSqlite, "replica_dir", "replication requires a sqlite database")
+
+	check(c.TotpDigits >= 6 && c.TotpDigits <= 8, "totp_digits", "must be between 6 and 8, got %d", c.TotpDigits)
+	check(c.TotpIssuer != "", "totp_issuer", "required, it names the account in authenticator apps")
+	check(c.TotpPeriod > 0, "totp_period", "must be greater than 0, got %d", c.TotpPeriod)
+
+	return errs
+}
+
+// newConfigError returns an error listing errs, one per line.
+func newConfigError(errs []error) error {
+	return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+}
+
+// Print writes the config as a TOML file. Each setting is preceded by its
+// description. Secrets are redacted & their settings commented out so the
+// output can be read back as a config file.
+func (c Config) Print(w io.Writer) error {
+	for i, f := range configFields {
+		s, prefix := f.get(&c), ""
+		if f.redact != nil && s != "" {
+			if s = f.redact(s); s == redacted {
+				prefix = "# "
+			}
+		}
+
+		buf, err := toml.Marshal(map[string]interface{}{f.Key: f.Kind.value(s)})
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# %s\n# Overridden by $%s.\n", f.Doc, f.Env)
+		if f.Reload {
+			fmt.Fprintln(w, "# Applied on SIGHUP without a restart.")
+		}
+		if _, err := io.WriteString(w, prefix+string(buf)); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// Reload returns c with the reloadable settings of next applied & the keys
+// of the settings which changed. Also returns the keys of the other settings
+// which changed but only take effect after a restart.
+func (c Config) Reload(next Config) (Config, []string, []string) {
+	var reloaded, restart []string
+	for _, f := range configFields {
+		// A random key is generated on each load if no secret is set.
+		if f.Key == "paseto_secret" && c.generatedPasetoKey && next.generatedPasetoKey {
+			continue
+		}
 
-	if s := getenv("BOOKMARKD_ROLLBAR_TOKEN"); s != "" {
-		c.RollbarToken = s
+		s := f.get(&next)
+		if f.get(&c) == s {
+			continue
+		} else if !f.Reload {
+			restart = append(restart, f.Key)
+			continue
+		}
+
+		// Values were validated when next was loaded.
+		if err := f.set(&c, s); err != nil {
+			restart = append(restart, f.Key)
+			continue
+		}
+		reloaded = append(reloaded, f.Key)
 	}
+	return c, reloaded, restart
+}
+
+// redacted replaces secrets when the config is printed.
+const redacted = "<redacted>"
+
+// configKind represents the type of a setting in the config file.
+type configKind int
+
+const (
+	configKindString configKind = iota
+	configKindInt
+	configKindBool
+	configKindList
+)
 
-	if s := getenv("BOOKMARKD_TOTP_DIGITS"); s != "" {
-		if i, err := strconv.Atoi(s); err != nil {
-			return c, err
-		} else {
-			c.TotpDigits = uint(i)
+// format returns v, a value decoded from the config file, in the form of an
+// environment variable. Returns an error if v is not of the kind.
+func (k configKind) format(v interface{}) (string, error) {
+	switch k {
+	case configKindInt:
+		if i, ok := v.(int64); ok {
+			return strconv.FormatInt(i, 10), nil
 		}
+		return "", fmt.Errorf("must be an integer, got %s", tomlType(v))
+	case configKindBool:
+		if b, ok := v.(bool); ok {
+			return strconv.FormatBool(b), nil
+		}
+		return "", fmt.Errorf("must be true or false, got %s", tomlType(v))
+	case configKindList:
+		a, ok := v.([]interface{})
+		if !ok {
+			return "", fmt.Errorf("must be an array of strings, got %s", tomlType(v))
+		}
+		items := make([]string, len(a))
+		for i := range a {
+			if items[i], ok = a[i].(string); !ok || strings.Contains(items[i], ",") {
+				return "", fmt.Errorf("must be an array of strings without commas, item %d is %s", i, tomlType(a[i]))
+			}
+		}
+		return strings.Join(items, ","), nil
+	default:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return "", fmt.Errorf("must be a string, got %s", tomlType(v))
 	}
+}
 
-	if s := getenv("BOOKMARKD_TOTP_ISSUER"); s != "" {
-		c.TotpIssuer = s
+// value returns s, a value in the form of an environment variable, as the
+// type it is written in the config file.
+func (k configKind) value(s string) interface{} {
+	switch k {
+	case configKindInt:
+		if i, err := strconv.Atoi(s); err == nil {
+			return i
+		}
+	case configKindBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case configKindList:
+		return splitList(s)
 	}
+	return s
+}
 
-	return c, nil
+// tomlType describes the type of a value decoded from a TOML file.
+func tomlType(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case int64:
+		return fmt.Sprintf("the integer %d", v)
+	case float64:
+		return fmt.Sprintf("the float %v", v)
+	case bool:
+		return fmt.Sprintf("the boolean %t", v)
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "a table"
+	default:
+		return fmt.Sprintf("a %T", v)
+	}
+}
+
+// configField represents a setting which can be set in the config file & by
+// an environment variable.
+type configField struct {
+	// Key in the config file.
+	Key string
+
+	// Environment variable overriding the config file.
+	Env string
+
+	// Type of the value in the config file.
+	Kind configKind
+
+	// Description printed with the setting.
+	Doc string
+
+	// If true, changes are applied on SIGHUP without a restart.
+	Reload bool
+
+	// Parses & sets the setting from the form of an environment variable.
+	set func(c *Config, s string) error
+
+	// Returns the setting in the form of an environment variable.
+	get func(c *Config) string
+
+	// Hides secrets when the setting is printed.
+	redact func(s string) string
+}
+
+func (f configField) reloadable() configField {
+	f.Reload = true
+	return f
+}
+
+func (f configField) secret() configField {
+	f.redact = func(string) string { return redacted }
+	return f
+}
+
+func stringField(key, env, doc string, p func(c *Config) *string) configField {
+	return configField{
+		Key: key, Env: env, Kind: configKindString, Doc: doc,
+		set: func(c *Config, s string) error { *p(c) = s; return nil },
+		get: func(c *Config) string { return *p(c) },
+	}
+}
+
+// pathField returns a field holding a file path. Paths starting with a
+// tilde are expanded to the home directory.
+func pathField(key, env, doc string, p func(c *Config) *string) configField {
+	f := stringField(key, env, doc, p)
+	f.set = func(c *Config, s string) (err error) {
+		*p(c), err = ExpandPath(s)
+		return err
+	}
+	return f
+}
+
+func intField(key, env, doc string, p func(c *Config) *int) configField {
+	return configField{
+		Key: key, Env: env, Kind: configKindInt, Doc: doc,
+		set: func(c *Config, s string) error {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("must be an integer, got %q", s)
+			}
+			*p(c) = i
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*p(c)) },
+	}
+}
+
+func uintField[T uint | uint64](key, env, doc string, p func(c *Config) *T) configField {
+	return configField{
+		Key: key, Env: env, Kind: configKindInt, Doc: doc,
+		set: func(c *Config, s string) error {
+			i, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return fmt.Errorf("must be a positive integer, got %q", s)
+			}
+			*p(c) = T(i)
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatUint(uint64(*p(c)), 10) },
+	}
+}
+
+func boolField(key, env, doc string, p func(c *Config) *bool) configField {
+	return configField{
+		Key: key, Env: env, Kind: configKindBool, Doc: doc,
+		set: func(c *Config, s string) error {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("must be true or false, got %q", s)
+			}
+			*p(c) = b
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatBool(*p(c)) },
+	}
+}
+
+// findConfigField returns the field of a config file key.
+func findConfigField(key string) (configField, bool) {
+	i := slices.IndexFunc(configFields, func(f configField) bool { return f.Key == key })
+	if i < 0 {
+		return configField{}, false
+	}
+	return configFields[i], true
+}
+
+// splitList splits a comma separated list & drops blank items.
+func splitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// totpAlgos are the TOTP hash algorithms by name.
+var totpAlgos = map[string]otp.Algorithm{
+	"SHA1":   otp.AlgorithmSHA1,
+	"SHA256": otp.AlgorithmSHA256,
+	"SHA512": otp.AlgorithmSHA512,
+}
+
+// configFields lists every setting in the order they are printed.
+var configFields = []configField{
+	boolField("test", "BOOKMARKD_TEST",
+		"Disables panic recovery & error reporting, for tests.",
+		func(c *Config) *bool { return &c.Test }),
+	{
+		Key: "dsn", Env: "BOOKMARKD_DSN", Kind: configKindString,
+		Doc: "Database, a sqlite path, a postgres:// url or inmem:// for an in-memory database.",
+		set: func(c *Config, s string) (err error) {
+			c.DbDsn, err = ExpandDSN(s)
+			return err
+		},
+		get:    func(c *Config) string { return c.DbDsn },
+		redact: redactDSN,
+	},
+
+	{
+		Key: "http_address", Env: "BOOKMARKD_HTTP_ADDRESS", Kind: configKindString,
+		Doc: "Address the server listens on, e.g. 0.0.0.0 for every interface, or the path of a unix socket. Ignored if systemd passes sockets.",
+		set: func(c *Config, s string) (err error) {
+			c.HttpAddress = s
+			if c.HttpSocketPath() != "" {
+				c.HttpAddress, err = ExpandPath(s)
+			}
+			return err
+		},
+		get: func(c *Config) string { return c.HttpAddress },
+	},
+	{
+		Key: "http_port", Env: "BOOKMARKD_HTTP_PORT", Kind: configKindInt,
+		Doc: "Port the server listens on.",
+		set: func(c *Config, s string) error {
+			if _, err := strconv.Atoi(s); err != nil {
+				return fmt.Errorf("must be an integer, got %q", s)
+			}
+			c.HttpPort = s
+			return nil
+		},
+		get: func(c *Config) string { return c.HttpPort },
+	},
+	stringField("http_domain", "BOOKMARKD_HTTP_DOMAIN",
+		"Public url of the server, used as the issuer of tokens.",
+		func(c *Config) *string { return &c.HttpDomain }),
+	stringField("http_base_path", "BOOKMARKD_HTTP_BASE_PATH",
+		"Path the api is served under.",
+		func(c *Config) *string { return &c.HttpBasePath }),
+	intField("http_timeout_in_seconds", "BOOKMARKD_HTTP_TIMEOUT_IN_SECONDS",
+		"Time a request may take before it is cancelled. Websockets are exempt.",
+		func(c *Config) *int { return &c.HttpTimeoutInSeconds }).reloadable(),
+	boolField("http_require_if_match", "BOOKMARKD_HTTP_REQUIRE_IF_MATCH",
+		"If true, updates must be conditional on an If-Match header.",
+		func(c *Config) *bool { return &c.HttpRequireIfMatch }).reloadable(),
+	stringField("http_socket_mode", "BOOKMARKD_HTTP_SOCKET_MODE",
+		"Octal permissions of the unix socket.",
+		func(c *Config) *string { return &c.HttpSocketMode }),
+	stringField("http_socket_group", "BOOKMARKD_HTTP_SOCKET_GROUP",
+		"Group name or id owning the unix socket, e.g. the group of the reverse proxy. Unchanged if blank.",
+		func(c *Config) *string { return &c.HttpSocketGroup }),
+
+	pathField("tls_cert_file", "BOOKMARKD_TLS_CERT_FILE",
+		"PEM certificate served over TLS, reloaded when the file changes. TLS is disabled if blank.",
+		func(c *Config) *string { return &c.TlsCertFile }),
+	pathField("tls_key_file", "BOOKMARKD_TLS_KEY_FILE",
+		"PEM private key of the certificate.",
+		func(c *Config) *string { return &c.TlsKeyFile }),
+	boolField("tls_self_signed", "BOOKMARKD_TLS_SELF_SIGNED",
+		"If true, TLS is served with a self-signed certificate generated on start, for development.",
+		func(c *Config) *bool { return &c.TlsSelfSigned }),
+	stringField("tls_min_version", "BOOKMARKD_TLS_MIN_VERSION",
+		"Minimum TLS version accepted, 1.2 or 1.3.",
+		func(c *Config) *string { return &c.TlsMinVersion }),
+	pathField("tls_client_ca_file", "BOOKMARKD_TLS_CLIENT_CA_FILE",
+		"PEM certificates of the CAs signing client certificates. Enables mutual TLS if set.",
+		func(c *Config) *string { return &c.TlsClientCaFile }),
+	stringField("tls_client_auth", "BOOKMARKD_TLS_CLIENT_AUTH",
+		"With mutual TLS, require to reject clients without a certificate or verify_if_given to only check certificates which are sent.",
+		func(c *Config) *string { return &c.TlsClientAuth }),
+	intField("tls_redirect_port", "BOOKMARKD_TLS_REDIRECT_PORT",
+		"Port of a plain HTTP listener redirecting to HTTPS, disabled if 0.",
+		func(c *Config) *int { return &c.TlsRedirectPort }),
+	intField("tls_hsts_max_age_in_seconds", "BOOKMARKD_TLS_HSTS_MAX_AGE_IN_SECONDS",
+		"Time browsers must only use HTTPS, sent in the Strict-Transport-Security header. Disabled if 0.",
+		func(c *Config) *int { return &c.TlsHstsMaxAgeInSeconds }).reloadable(),
+
+	intField("health_check_timeout_in_seconds", "BOOKMARKD_HEALTH_CHECK_TIMEOUT_IN_SECONDS",
+		"Time each readiness check may take before it fails.",
+		func(c *Config) *int { return &c.HealthCheckTimeoutInSeconds }),
+	intField("shutdown_delay_in_seconds", "BOOKMARKD_SHUTDOWN_DELAY_IN_SECONDS",
+		"Time the readiness probe fails before the server stops on shutdown.",
+		func(c *Config) *int { return &c.ShutdownDelayInSeconds }).reloadable(),
+
+	configField{
+		Key: "paseto_secret", Env: "BOOKMARKD_PASETO_SECRET", Kind: configKindString,
+		Doc: "Hex encoded key signing tokens, see 'bookmarkdctl keygen'. Random on each start if blank.",
+		set: func(c *Config, s string) error {
+			sk, err := paseto.NewV4AsymmetricSecretKeyFromHex(s)
+			if err != nil {
+				return fmt.Errorf("must be a hex encoded key, see 'bookmarkdctl keygen': %w", err)
+			}
+			c.PasetoSecretKey, c.PasetoPublicKey = sk, sk.Public()
+			c.generatedPasetoKey = false
+			return nil
+		},
+		get: func(c *Config) string { return c.PasetoSecretKey.ExportHex() },
+	}.secret(),
+	intField("paseto_access_token_expiration_in_seconds", "BOOKMARKD_PASETO_ACESS_TOKEN_EXPIRATION_IN_SECONDS",
+		"Lifetime of access tokens.",
+		func(c *Config) *int { return &c.PasetoAccessTokenExpirationInSeconds }).reloadable(),
+	intField("paseto_refresh_token_expiration_in_seconds", "BOOKMARKD_PASETO_REFRESH_TOKEN_EXPIRATION_IN_SECONDS",
+		"Lifetime of refresh tokens.",
+		func(c *Config) *int { return &c.PasetoRefreshTokenExpirationInSeconds }).reloadable(),
+
+	stringField("rollbar_token", "BOOKMARKD_ROLLBAR_TOKEN",
+		"Token of the Rollbar error tracking, disabled if blank.",
+		func(c *Config) *string { return &c.RollbarToken }).secret(),
+
+	{
+		Key: "url_strip_params", Env: "BOOKMARKD_URL_STRIP_PARAMS", Kind: configKindList,
+		Doc: "Query parameters ignored when detecting duplicate bookmarks. Comma separated in the environment.",
+		set: func(c *Config, s string) error {
+			c.UrlStripParams = splitList(s)
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(c.UrlStripParams, ",") },
+	},
+
+	intField("idempotency_key_ttl_in_seconds", "BOOKMARKD_IDEMPOTENCY_KEY_TTL_IN_SECONDS",
+		"Time the responses of requests with an Idempotency-Key are kept.",
+		func(c *Config) *int { return &c.IdempotencyKeyTTLInSeconds }),
+
+	stringField("admin_token", "BOOKMARKD_ADMIN_TOKEN",
+		"Token of the admin api, disabled if blank.",
+		func(c *Config) *string { return &c.AdminToken }).secret().reloadable(),
+
+	pathField("backup_dir", "BOOKMARKD_BACKUP_DIR",
+		"Directory of backups. Defaults to a backups directory next to the database.",
+		func(c *Config) *string { return &c.BackupDir }),
+	intField("backup_interval_in_seconds", "BOOKMARKD_BACKUP_INTERVAL_IN_SECONDS",
+		"Time between scheduled backups, disabled if 0.",
+		func(c *Config) *int { return &c.BackupIntervalInSeconds }),
+	intField("backup_retain", "BOOKMARKD_BACKUP_RETAIN",
+		"Number of backups kept, 0 keeps every backup.",
+		func(c *Config) *int { return &c.BackupRetain }),
+
+	pathField("replica_dir", "BOOKMARKD_REPLICA_DIR",
+		"Directory the WAL is replicated to, disabled if blank.",
+		func(c *Config) *string { return &c.ReplicaDir }),
+	intField("replica_sync_interval_in_seconds", "BOOKMARKD_REPLICA_SYNC_INTERVAL_IN_SECONDS",
+		"Time between shipping new WAL frames to the replica.",
+		func(c *Config) *int { return &c.ReplicaSyncIntervalInSeconds }),
+	intField("replica_snapshot_interval_in_seconds", "BOOKMARKD_REPLICA_SNAPSHOT_INTERVAL_IN_SECONDS",
+		"Time between snapshots starting a new generation of the replica.",
+		func(c *Config) *int { return &c.ReplicaSnapshotIntervalInSeconds }),
+	intField("replica_retention_in_seconds", "BOOKMARKD_REPLICA_RETENTION_IN_SECONDS",
+		"Time older generations of the replica are kept, 0 keeps every generation.",
+		func(c *Config) *int { return &c.ReplicaRetentionInSeconds }),
+
+	{
+		Key: "totp_algo", Env: "BOOKMARKD_TOTP_ALGO", Kind: configKindString,
+		Doc: "Hash algorithm of TOTP passcodes, SHA1, SHA256 or SHA512.",
+		set: func(c *Config, s string) error {
+			algo, ok := totpAlgos[strings.ToUpper(s)]
+			if !ok {
+				return fmt.Errorf("must be SHA1, SHA256 or SHA512, got %q", s)
+			}
+			c.TotpAlgo = algo
+			return nil
+		},
+		get: func(c *Config) string { return c.TotpAlgo.String() },
+	},
+	uintField("totp_digits", "BOOKMARKD_TOTP_DIGITS",
+		"Number of digits of TOTP passcodes.",
+		func(c *Config) *uint { return &c.TotpDigits }),
+	stringField("totp_issuer", "BOOKMARKD_TOTP_ISSUER",
+		"Issuer shown by authenticator apps.",
+		func(c *Config) *string { return &c.TotpIssuer }).reloadable(),
+	uintField("totp_period", "BOOKMARKD_TOTP_PERIOD",
+		"Seconds each TOTP passcode is valid for.",
+		func(c *Config) *uint64 { return &c.TotpPeriod }),
+	uintField("totp_skew", "BOOKMARKD_TOTP_SKEW",
+		"Number of periods before & after the current one whose passcodes are accepted.",
+		func(c *Config) *uint { return &c.TotpSkew }).reloadable(),
+}
+
+// redactDSN hides the password of a postgres url.
+func redactDSN(dsn string) string {
+	if DSNScheme(dsn) != DbSchemePostgres {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
+
+// TotpURL returns the provisioning url of a user's base32 encoded seed.
+func (c Config) TotpURL(username, seed string) (string, error) {
+	totp, err := otp.NewTOTP(otp.TOTPConfig{
+		Algo:   c.TotpAlgo,
+		Digits: c.TotpDigits,
+		Issuer: c.TotpIssuer,
+		Period: c.TotpPeriod,
+		Skew:   c.TotpSkew,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The seed is already base32 encoded. Decode it so the url carries the
+	// same secret the passcode is validated against.
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(seed)
+	if err != nil {
+		return "", fmt.Errorf("decode seed: %w", err)
+	}
+	return totp.GenerateURL(username, secret), nil
 }
 
 // expand returns path using tilde expansion. This means that a file path that
@@ -156,9 +784,41 @@ func ExpandPath(path string) (string, error) {
 	return filepath.Join(u.HomeDir, strings.TrimPrefix(path, "~"+string(os.PathSeparator))), nil
 }
 
-// expandDSN expands a datasource name. Ignores in-memory databases.
+// HttpSocketPath returns the path of the unix socket the server listens on,
+// or blank if it listens on a TCP address. Addresses containing a slash are
+// socket paths, e.g. /run/bookmarkd/bookmarkd.sock or ./bookmarkd.sock.
+func (c Config) HttpSocketPath() string {
+	if strings.ContainsRune(c.HttpAddress, '/') {
+		return c.HttpAddress
+	}
+	return ""
+}
+
+// TlsEnabled returns true if the server is served over TLS.
+func (c Config) TlsEnabled() bool {
+	return c.TlsCertFile != "" || c.TlsSelfSigned
+}
+
+// DbScheme returns the database backend selected by the DSN.
+func (c Config) DbScheme() string {
+	return DSNScheme(c.DbDsn)
+}
+
+// DSNScheme returns the database backend of a datasource name. Postgres is
+// selected by a postgres:// or postgresql:// url & the in-memory database by
+// inmem://, anything else is a sqlite path.
+func DSNScheme(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		return DbSchemePostgres
+	} else if strings.HasPrefix(dsn, "inmem://") {
+		return DbSchemeInmem
+	}
+	return DbSchemeSqlite
+}
+
+// expandDSN expands a datasource name. Ignores in-memory databases & urls.
 func ExpandDSN(dsn string) (string, error) {
-	if dsn == ":memory:" {
+	if dsn == ":memory:" || DSNScheme(dsn) != DbSchemeSqlite {
 		return dsn, nil
 	}
 	return ExpandPath(dsn)
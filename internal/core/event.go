@@ -11,7 +11,10 @@ const (
 	EventTypeBookmarkNameChanged        = "bookmark:name_changed"
 	EventTypeBookmarkDescriptionChanged = "bookmark:description_changed"
 	EventTypeBookmarkUrlChanged         = "bookmark:url_changed"
+	EventTypeBookmarkStatusChanged      = "bookmark:status_changed"
+	EventTypeBookmarkStarredChanged     = "bookmark:starred_changed"
 	EventTypeBookmarkRemoved            = "bookmark:removed"
+	EventTypeBookmarkReverted           = "bookmark:reverted"
 )
 
 // Event represents an event that occurs in the system. These events are
@@ -25,10 +28,14 @@ type Event struct {
 	Payload interface{} `json:"payload"`
 }
 
-// BookmarkNameChangedPayload represents the payload for an Event object with a
-// type of EventTypeBookmarkNameChanged.
-type EventTypeBookmarkAddedPayload = Event
+// EventTypeBookmarkAddedPayload represents the payload for an Event object with
+// a type of EventTypeBookmarkAdded.
+type EventTypeBookmarkAddedPayload struct {
+	Bookmark *Bookmark `json:"bookmark"`
+}
 
+// EventTypeBookmarkNameChangedPayload represents the payload for an Event
+// object with a type of EventTypeBookmarkNameChanged.
 type EventTypeBookmarkNameChangedPayload struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
@@ -47,10 +54,29 @@ type EventTypeBookmarkUrlChangedPayload struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+type EventTypeBookmarkStatusChangedPayload struct {
+	ID        int            `json:"id"`
+	Status    BookmarkStatus `json:"status"`
+	ReadAt    time.Time      `json:"readAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+type EventTypeBookmarkStarredChangedPayload struct {
+	ID        int       `json:"id"`
+	Starred   bool      `json:"starred"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
 type EventTypeBookmarkRemovedPayload struct {
 	ID int `json:"id"`
 }
 
+type EventTypeBookmarkRevertedPayload struct {
+	ID         int       `json:"id"`
+	RevisionID int       `json:"revisionID"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
 // EventService represents a service for managing event dispatch and event
 // listeners (aka subscriptions).
 //
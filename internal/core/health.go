@@ -0,0 +1,42 @@
+package core
+
+import "context"
+
+// Health statuses.
+const (
+	HealthStatusOK       = "ok"
+	HealthStatusReady    = "ready"
+	HealthStatusNotReady = "not ready"
+	HealthStatusDraining = "draining"
+	HealthStatusFailing  = "failing"
+)
+
+// HealthCheck represents a check of a dependency the server needs to serve
+// requests, e.g. the database. The server is only ready if every check passes.
+type HealthCheck struct {
+	// Name of the check, reported with its result.
+	Name string
+
+	// Returns an error if the dependency is unavailable. Should give up once
+	// ctx is done.
+	Check func(ctx context.Context) error
+}
+
+// HealthReport represents the readiness of the server & the result of each check.
+type HealthReport struct {
+	Status string               `json:"status"`
+	Checks []*HealthCheckResult `json:"checks"`
+}
+
+// Ready returns true if the server is ready to serve requests.
+func (r *HealthReport) Ready() bool {
+	return r.Status == HealthStatusReady
+}
+
+// HealthCheckResult represents the result of a single check.
+type HealthCheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// MaxIdempotencyKeyLen is the maximum length of a client supplied key.
+const MaxIdempotencyKeyLen = 255
+
+// IdempotencyKey represents a client supplied key identifying a mutating
+// request. The response to the first request with a key is stored so that
+// retries with the same key replay it instead of repeating the change.
+type IdempotencyKey struct {
+	// Owner of the key. Keys are scoped per user.
+	UserID string `json:"userID"`
+	Key    string `json:"key"`
+
+	// Hash of the method, path & body of the original request. Used to
+	// detect a key being reused for a different request.
+	Fingerprint string `json:"fingerprint"`
+
+	// Stored response. Only set once the original request has completed.
+	Completed  bool                `json:"completed"`
+	StatusCode int                 `json:"statusCode"`
+	Header     map[string][]string `json:"header"`
+	Body       []byte              `json:"body"`
+
+	// Timestamps of creation & when the key may be reused.
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// IdempotencyKeyStore represents a service for storing idempotency keys.
+type IdempotencyKeyStore interface {
+	// Reserves key.Key for the current user while the request is processed.
+	// Returns the existing key instead if it has been used & has not expired.
+	ReserveIdempotencyKey(ctx context.Context, key *IdempotencyKey) (*IdempotencyKey, error)
+
+	// Stores the response of a reserved key.
+	CompleteIdempotencyKey(ctx context.Context, key *IdempotencyKey) error
+
+	// Releases a reserved key so the request can be retried.
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+}
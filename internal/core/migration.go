@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Migration statuses.
+const (
+	// Applied to the database.
+	MigrationStatusApplied = "applied"
+
+	// Not yet applied to the database.
+	MigrationStatusPending = "pending"
+
+	// Applied, but the migration file was edited afterwards.
+	MigrationStatusModified = "modified"
+
+	// Applied by a newer version which knows migrations this version does not.
+	MigrationStatusUnknown = "unknown"
+)
+
+// Migration directions.
+const (
+	MigrationUp   = "up"
+	MigrationDown = "down"
+)
+
+// Migration represents a migration & its status in the database.
+type Migration struct {
+	Version int    `json:"version"`
+	Status  string `json:"status"`
+
+	// Checksum of the up file. For unknown migrations, the checksum recorded
+	// in the database.
+	Checksum string `json:"checksum"`
+
+	// Time the migration was applied. Nil if pending or if it was applied
+	// before the time was recorded.
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// MigrationStep represents a migration run in one direction.
+type MigrationStep struct {
+	Version   int    `json:"version"`
+	Direction string `json:"direction"`
+	Name      string `json:"name"`
+}
+
+// MigrateOptions represents options for Migrator.Migrate().
+type MigrateOptions struct {
+	// Version to migrate to. Later migrations are reverted & earlier ones
+	// applied. Nil migrates to the latest version, -1 reverts all migrations.
+	Target *int
+
+	// If true, the migrations run in a transaction which is rolled back, so
+	// errors are reported without changing the database.
+	DryRun bool
+}
+
+// Migrator represents a database whose schema is versioned by migrations.
+type Migrator interface {
+	// Returns the status of each migration, ordered by version. Migrations
+	// applied by a newer version are included with the unknown status.
+	Migrations(ctx context.Context) ([]*Migration, error)
+
+	// Applies & reverts migrations to reach the target version & returns the
+	// steps taken.
+	Migrate(ctx context.Context, opts MigrateOptions) ([]*MigrationStep, error)
+
+	// Returns an error if the database isn't at the latest version known to
+	// this build.
+	CheckMigrations(ctx context.Context) error
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"bookmarkd"
+)
+
+// Sort fields accepted by the various filters. Not every filter supports
+// every field.
+const (
+	SortCreated   = "created"
+	SortUpdated   = "updated"
+	SortName      = "name"
+	SortUsername  = "username"
+	SortRelevance = "relevance"
+)
+
+// Sort directions.
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// Cursor represents a position within a sorted list of results. Cursors are
+// handed out to clients as opaque strings and passed back to fetch the page
+// adjacent to the row they point at.
+type Cursor struct {
+	// Sort key & ID of the row the cursor points at.
+	Key interface{} `json:"k"`
+	ID  interface{} `json:"i"`
+
+	// If true, the cursor selects the rows before the row instead of after it.
+	Before bool `json:"b,omitempty"`
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c Cursor) Encode() string {
+	buf, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// DecodeCursor parses a cursor returned by Cursor.Encode().
+// Returns ErrBadRequest if s is malformed.
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: invalid cursor", bookmarkd.ErrBadRequest)
+	} else if err := json.Unmarshal(buf, &c); err != nil || c.Key == nil || c.ID == nil {
+		return c, fmt.Errorf("%w: invalid cursor", bookmarkd.ErrBadRequest)
+	}
+	return c, nil
+}
+
+// validateSort returns ErrBadRequest if sort is not one of allowed or the
+// direction is unknown. Empty values select the defaults.
+func validateSort(sort, direction string, allowed ...string) error {
+	if sort != "" && !slices.Contains(allowed, sort) {
+		return fmt.Errorf("%w: unknown sort %q", bookmarkd.ErrBadRequest, sort)
+	} else if direction != "" && direction != SortAsc && direction != SortDesc {
+		return fmt.Errorf("%w: unknown sort direction %q", bookmarkd.ErrBadRequest, direction)
+	}
+	return nil
+}
+
+// validateRange returns ErrBadRequest if offset or limit are negative.
+func validateRange(offset, limit int) error {
+	if offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", bookmarkd.ErrBadRequest)
+	} else if limit < 0 {
+		return fmt.Errorf("%w: limit must not be negative", bookmarkd.ErrBadRequest)
+	}
+	return nil
+}
+
+// pageCursors returns the cursors of the pages before and after a page of
+// count results fetched with the given cursor, offset & limit. first and last
+// are the cursors of the first and last row on the page.
+//
+// A next cursor is only returned when the page is full, so the final page
+// of a list that divides evenly by limit is followed by an empty page.
+func pageCursors(cursor string, offset, limit, count int, first, last func() Cursor) (next, prev string) {
+	if count == 0 {
+		return "", ""
+	}
+
+	before := false
+	if c, err := DecodeCursor(cursor); err == nil {
+		before = c.Before
+	}
+
+	// When paging backwards there is always a page after the current one.
+	// The page is full when there may be more rows beyond it.
+	full := limit > 0 && count >= limit
+	if before || full {
+		next = last().Encode()
+	}
+	if (before && full) || (!before && (cursor != "" || offset > 0)) {
+		c := first()
+		c.Before = true
+		prev = c.Encode()
+	}
+	return next, prev
+}
+
+// formatCursorTime returns the sort key of a timestamp. Matches the format
+// timestamps are stored in.
+func formatCursorTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
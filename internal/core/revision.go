@@ -0,0 +1,77 @@
+package core
+
+import (
+	"strconv"
+	"time"
+)
+
+// Bookmark field names used to identify changes within a revision.
+const (
+	BookmarkFieldName        = "name"
+	BookmarkFieldDescription = "description"
+	BookmarkFieldUrl         = "url"
+	BookmarkFieldStatus      = "status"
+	BookmarkFieldStarred     = "starred"
+)
+
+// BookmarkRevision represents a single call to UpdateBookmark(). A revision is
+// recorded for every update so previous values can be reviewed and restored.
+type BookmarkRevision struct {
+	ID int `json:"id"`
+
+	// Bookmark the revision belongs to.
+	BookmarkID int `json:"bookmarkID"`
+
+	// User that performed the update.
+	UserID string `json:"userID"`
+
+	// Old and new values of every field set on the update.
+	Changes []BookmarkFieldChange `json:"changes"`
+
+	// Timestamp of the update.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BookmarkFieldChange represents the value of a single bookmark field before
+// and after an update.
+type BookmarkFieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// RevertUpdate returns an update that restores every field changed by the
+// revision to its previous value.
+func (r *BookmarkRevision) RevertUpdate() BookmarkUpdate {
+	var upd BookmarkUpdate
+	for _, c := range r.Changes {
+		old := c.Old
+		switch c.Field {
+		case BookmarkFieldName:
+			upd.Name = &old
+		case BookmarkFieldDescription:
+			upd.Description = &old
+		case BookmarkFieldUrl:
+			upd.Url = &old
+		case BookmarkFieldStatus:
+			status := BookmarkStatus(old)
+			upd.Status = &status
+		case BookmarkFieldStarred:
+			if starred, err := strconv.ParseBool(old); err == nil {
+				upd.Starred = &starred
+			}
+		}
+	}
+	return upd
+}
+
+// BookmarkRevisionFilter represents a filter used by FindBookmarkRevisions().
+type BookmarkRevisionFilter struct {
+	// Filtering fields.
+	ID         *int `json:"id"`
+	BookmarkID *int `json:"bookmarkID"`
+
+	// Restrict to subset of range.
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
@@ -29,12 +29,61 @@ type SessionFilter struct {
 	UserID       *string `json:"userID"`
 	RefreshToken *string `json:"refreshToken"`
 
+	// Ordering of results. Sort is one of "created" or "updated" and
+	// defaults to "created". Direction defaults to ascending.
+	Sort      string `json:"sort"`
+	Direction string `json:"direction"`
+
+	// Opaque cursor of a previous page. Results continue after the row the
+	// cursor points at.
+	Cursor string `json:"cursor"`
+
 	// Restricts results to a subset of the total range.
 	// Can be used for pagination.
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
 }
 
+// Validate returns ErrBadRequest if the sort, range or cursor are invalid.
+func (f SessionFilter) Validate() error {
+	if err := validateSort(f.Sort, f.Direction, SortCreated, SortUpdated); err != nil {
+		return err
+	} else if err := validateRange(f.Offset, f.Limit); err != nil {
+		return err
+	} else if f.Cursor != "" {
+		if _, err := DecodeCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Descending returns true if results are listed in descending order.
+func (f SessionFilter) Descending() bool {
+	return f.Direction == SortDesc
+}
+
+// CursorOf returns the cursor pointing at session under the filter's sort.
+func (f SessionFilter) CursorOf(session *Session) Cursor {
+	c := Cursor{ID: session.ID}
+	switch f.Sort {
+	case SortUpdated:
+		c.Key = formatCursorTime(session.UpdatedAt)
+	default:
+		c.Key = formatCursorTime(session.CreatedAt)
+	}
+	return c
+}
+
+// Cursors returns the cursors of the pages after and before a page of
+// sessions fetched with the filter.
+func (f SessionFilter) Cursors(sessions []*Session) (next, prev string) {
+	return pageCursors(f.Cursor, f.Offset, f.Limit, len(sessions),
+		func() Cursor { return f.CursorOf(sessions[0]) },
+		func() Cursor { return f.CursorOf(sessions[len(sessions)-1]) },
+	)
+}
+
 type SessionUpdate struct {
 	RefreshToken string    `json:"-"`
 	ExpiresAt    time.Time `json:"-"`
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+
+	"bookmarkd"
+)
+
+// BookmarkUrlSchemes is the list of url schemes a bookmark may use. Schemes
+// such as "javascript:" or "data:" are rejected because they execute or embed
+// content instead of linking to a page.
+var BookmarkUrlSchemes = []string{"http", "https", "ftp"}
+
+// ParseBookmarkUrl parses raw and ensures it is an absolute url with an
+// allowed scheme and a valid host. Internationalized host names are accepted
+// in either unicode or punycode form.
+//
+// Returns a *bookmarkd.ValidationError for the "url" field on failure.
+func ParseBookmarkUrl(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, bookmarkd.NewValidationError("url", "bookmark url is malformed")
+	} else if u.Scheme == "" {
+		return nil, bookmarkd.NewValidationError("url", "bookmark url must be absolute")
+	} else if !slices.Contains(BookmarkUrlSchemes, strings.ToLower(u.Scheme)) {
+		return nil, bookmarkd.NewValidationError("url", "bookmark url scheme %q is not allowed", u.Scheme)
+	} else if u.Opaque != "" || u.Hostname() == "" {
+		return nil, bookmarkd.NewValidationError("url", "bookmark url host required")
+	}
+
+	// IP addresses are valid hosts but are rejected by the IDNA profile.
+	if host := u.Hostname(); !isIP(host) {
+		if _, err := idna.Lookup.ToASCII(host); err != nil {
+			return nil, bookmarkd.NewValidationError("url", "bookmark url host %q is invalid", host)
+		}
+	}
+
+	return u, nil
+}
+
+// isIP returns true if host is an IPv4 or IPv6 address.
+func isIP(host string) bool {
+	return net.ParseIP(host) != nil
+}
+
+// DefaultUrlStripParams is the list of query parameters removed from urls by
+// default when they are canonicalized. These are tracking parameters which do
+// not change the page that is served.
+var DefaultUrlStripParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"mc_cid",
+	"mc_eid",
+	"igshid",
+	"ref_src",
+	"_hsenc",
+	"_hsmi",
+}
+
+// defaultPorts maps url schemes to the port used when none is given.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// UrlCanonicalizer converts urls into a canonical form so that different
+// spellings of the same page can be detected as duplicates.
+type UrlCanonicalizer struct {
+	// Query parameters to remove. Supports path.Match patterns, e.g. "utm_*".
+	StripParams []string
+}
+
+// NewUrlCanonicalizer returns a new instance of UrlCanonicalizer that removes
+// the given query parameters.
+func NewUrlCanonicalizer(stripParams []string) *UrlCanonicalizer {
+	return &UrlCanonicalizer{StripParams: stripParams}
+}
+
+// Canonicalize returns the canonical form of raw. The scheme and host are
+// lowercased, internationalized hosts are converted to punycode and http is
+// treated as https. Default ports, trailing slashes, fragments and stripped
+// query parameters are removed, and the remaining query parameters are sorted.
+//
+// Urls that cannot be parsed are returned unchanged.
+func (c *UrlCanonicalizer) Canonicalize(raw string) string {
+	raw = strings.TrimSpace(raw)
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Opaque != "" {
+		// Non-hierarchical urls such as "mailto:" only have their scheme normalized.
+		return u.String()
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	host = strings.TrimSuffix(host, ".")
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil && !isIP(host) {
+		host = ascii
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port == "" || port == defaultPorts[u.Scheme] {
+		u.Host = host
+	} else {
+		u.Host = host + ":" + port
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	} else if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	u.RawPath = ""
+
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+
+	query := u.Query()
+	for name := range query {
+		if c.strip(name) {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	u.Fragment, u.RawFragment = "", ""
+
+	return u.String()
+}
+
+// strip returns true if the query parameter name matches a strip rule.
+func (c *UrlCanonicalizer) strip(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range c.StripParams {
+		if ok, _ := path.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
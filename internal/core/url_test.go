@@ -0,0 +1,71 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/utils/require"
+)
+
+func TestParseBookmarkUrl(t *testing.T) {
+	for _, tt := range []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/a?b=c", true},
+		{"HTTP://example.com", true},
+		{"ftp://files.example.com/pub", true},
+		{"https://127.0.0.1:8080/", true},
+		{"https://[::1]/", true},
+		{"https://bücher.de/", true},
+		{"https://xn--bcher-kva.de/", true},
+		{"javascript:alert(1)", false},
+		{"data:text/html,hi", false},
+		{"example.com/a", false},
+		{"https:///path", false},
+		{"https://exa mple.com/", false},
+		{"https://-example-.com/", false},
+	} {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := core.ParseBookmarkUrl(tt.url)
+			require.Equal(t, err == nil, tt.ok)
+			if err != nil {
+				var verr *bookmarkd.ValidationError
+				require.Equal(t, errors.As(err, &verr), true)
+				require.Equal(t, verr.Field, "url")
+			}
+		})
+	}
+}
+
+func TestUrlCanonicalizer_Canonicalize(t *testing.T) {
+	c := core.NewUrlCanonicalizer(core.DefaultUrlStripParams)
+
+	for _, tt := range []struct {
+		url      string
+		expected string
+	}{
+		{"https://example.com", "https://example.com/"},
+		{"HTTP://Example.COM:80/a/", "https://example.com/a"},
+		{"https://example.com:443/a//", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://example.com/a?utm_source=x&b=2&a=1&fbclid=y", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/a#section", "https://example.com/a"},
+		{"https://example.com./a?", "https://example.com/a"},
+		{"https://BÜCHER.de/", "https://xn--bcher-kva.de/"},
+		{"MAILTO:user@example.com", "mailto:user@example.com"},
+		{"  not a url  ", "not a url"},
+	} {
+		t.Run(tt.url, func(t *testing.T) {
+			require.Equal(t, c.Canonicalize(tt.url), tt.expected)
+		})
+	}
+
+	// Ensure the strip rules can be configured.
+	t.Run("StripParams", func(t *testing.T) {
+		c := core.NewUrlCanonicalizer([]string{"ref"})
+		require.Equal(t, c.Canonicalize("https://example.com/?ref=a&utm_source=b"), "https://example.com/?utm_source=b")
+	})
+}
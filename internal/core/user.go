@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"encoding/base32"
 	"fmt"
 	"time"
 
@@ -14,7 +15,10 @@ type User struct {
 	ID string `json:"id"`
 
 	Username string `json:"username"`
-	Seed     string
+	Seed     string `json:"-"`
+
+	// Time the user was disabled by an operator. Disabled users cannot log in.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
 
 	// Timestamps for user creation and last update.
 	CreatedAt time.Time `json:"createdAt"`
@@ -35,20 +39,90 @@ func (u User) Validate() error {
 	return nil
 }
 
+// Disabled returns true if the user has been disabled.
+func (u User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// NewSeed returns a new random base32 encoded TOTP seed.
+func NewSeed() (string, error) {
+	r, err := uuid.NewRandom()
+	if err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(r.String())), nil
+}
+
 // UserFilter represents a filter passed to FindUsers().
 type UserFilter struct {
 	// Filtering fields.
 	ID       *string `json:"id"`
 	Username *string `json:"username"`
 
+	// Ordering of results. Sort is one of "created", "updated" or "username"
+	// and defaults to "created". Direction defaults to ascending.
+	Sort      string `json:"sort"`
+	Direction string `json:"direction"`
+
+	// Opaque cursor of a previous page. Results continue after the row the
+	// cursor points at.
+	Cursor string `json:"cursor"`
+
 	// Restrict to subset of results.
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
 }
 
+// Validate returns ErrBadRequest if the sort, range or cursor are invalid.
+func (f UserFilter) Validate() error {
+	if err := validateSort(f.Sort, f.Direction, SortCreated, SortUpdated, SortUsername); err != nil {
+		return err
+	} else if err := validateRange(f.Offset, f.Limit); err != nil {
+		return err
+	} else if f.Cursor != "" {
+		if _, err := DecodeCursor(f.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Descending returns true if results are listed in descending order.
+func (f UserFilter) Descending() bool {
+	return f.Direction == SortDesc
+}
+
+// CursorOf returns the cursor pointing at user under the filter's sort.
+func (f UserFilter) CursorOf(user *User) Cursor {
+	c := Cursor{ID: user.ID}
+	switch f.Sort {
+	case SortUpdated:
+		c.Key = formatCursorTime(user.UpdatedAt)
+	case SortUsername:
+		c.Key = user.Username
+	default:
+		c.Key = formatCursorTime(user.CreatedAt)
+	}
+	return c
+}
+
+// Cursors returns the cursors of the pages after and before a page of users
+// fetched with the filter.
+func (f UserFilter) Cursors(users []*User) (next, prev string) {
+	return pageCursors(f.Cursor, f.Offset, f.Limit, len(users),
+		func() Cursor { return f.CursorOf(users[0]) },
+		func() Cursor { return f.CursorOf(users[len(users)-1]) },
+	)
+}
+
 // UserUpdate represents a set of fields to be updated via UpdateUser().
 type UserUpdate struct {
 	Username *string `json:"username"`
+
+	// Fields only updated by operators. Changing either revokes all sessions
+	// of the user.
+	Seed     *string `json:"-"`
+	Disabled *bool   `json:"-"`
 }
 
 type UserStore interface {
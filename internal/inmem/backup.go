@@ -0,0 +1,31 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.BackupStore = (*BackupStore)(nil)
+
+// BackupStore represents the backups of an in-memory database. Nothing is
+// kept on disk so there is nothing to back up.
+type BackupStore struct{}
+
+// NewBackupStore returns a new instance of BackupStore.
+func NewBackupStore() *BackupStore {
+	return &BackupStore{}
+}
+
+// CreateBackup returns ErrBadRequest as in-memory databases can't be backed up.
+func (s *BackupStore) CreateBackup(ctx context.Context) (*core.Backup, error) {
+	return nil, fmt.Errorf("%w: in-memory databases can't be backed up", bookmarkd.ErrBadRequest)
+}
+
+// FindBackups returns an empty list.
+func (s *BackupStore) FindBackups(ctx context.Context) ([]*core.Backup, error) {
+	return []*core.Backup{}, nil
+}
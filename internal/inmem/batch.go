@@ -0,0 +1,105 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"bookmarkd/internal/core"
+)
+
+// BatchBookmarks applies the operations of a batch in order within a single
+// transaction. Each operation runs inside a savepoint so a failure can be
+// undone without losing the operations before it.
+//
+// Atomic batches stop at the first failure & are rolled back entirely. Best
+// effort batches skip failing operations & commit the rest. Events are only
+// published for committed operations.
+func (s *BookmarkStore) BatchBookmarks(ctx context.Context, batch *core.BookmarkBatch) (*core.BookmarkBatchResult, error) {
+	if err := batch.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := batchBookmarks(ctx, tx, batch)
+	if err != nil {
+		return nil, err
+	} else if !result.Committed {
+		return result, nil
+	}
+	return result, tx.Commit()
+}
+
+// batchBookmarks applies each operation of batch within its own savepoint.
+// The returned result is marked committed if the caller should commit tx.
+func batchBookmarks(ctx context.Context, tx *Tx, batch *core.BookmarkBatch) (*core.BookmarkBatchResult, error) {
+	atomic := batch.Mode != core.BatchModeBestEffort
+
+	result := &core.BookmarkBatchResult{Committed: true}
+	for i := range batch.Operations {
+		op := &batch.Operations[i]
+		res := &core.BookmarkOpResult{Op: op.Op}
+		result.Results = append(result.Results, res)
+
+		// Once an atomic batch fails nothing else is attempted.
+		if !result.Committed {
+			res.Status = core.BookmarkOpStatusSkipped
+			continue
+		}
+
+		const savepoint = "bookmark_op"
+		if err := tx.Savepoint(ctx, savepoint); err != nil {
+			return nil, err
+		}
+
+		bookmark, err := applyBookmarkOp(ctx, tx, op)
+		if err != nil {
+			if err := tx.RollbackTo(ctx, savepoint); err != nil {
+				return nil, err
+			}
+			res.Status, res.Err = core.BookmarkOpStatusFailed, err
+
+			if atomic {
+				result.Committed = false
+				for _, prev := range result.Results[:i] {
+					prev.Status = core.BookmarkOpStatusRolledBack
+				}
+			}
+			continue
+		}
+
+		if err := tx.Release(ctx, savepoint); err != nil {
+			return nil, err
+		}
+		res.Status, res.Bookmark = core.BookmarkOpStatusOK, bookmark
+	}
+
+	return result, nil
+}
+
+// applyBookmarkOp performs a single batch operation & returns the affected bookmark.
+func applyBookmarkOp(ctx context.Context, tx *Tx, op *core.BookmarkOp) (*core.Bookmark, error) {
+	if err := op.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case core.BookmarkOpCreate:
+		bookmark := *op.Bookmark
+		if err := createBookmarkWithMode(ctx, tx, &bookmark, op.Duplicates); err != nil {
+			return nil, err
+		}
+		return &bookmark, nil
+	case core.BookmarkOpUpdate:
+		upd := *op.Update
+		upd.Version = op.Version
+		return updateBookmark(ctx, tx, op.ID, upd)
+	case core.BookmarkOpDelete:
+		return deleteBookmark(ctx, tx, op.ID, op.Version)
+	}
+	return nil, fmt.Errorf("unknown batch operation %q", op.Op)
+}
@@ -0,0 +1,646 @@
+package inmem
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.BookmarkStore = (*BookmarkStore)(nil)
+
+// BookmarkStore represents a service for managing bookmarks.
+type BookmarkStore struct {
+	db *DB
+}
+
+// NewBookmarkStore returns a new instance of BookmarkStore.
+func NewBookmarkStore(db *DB) *BookmarkStore {
+	return &BookmarkStore{db: db}
+}
+
+// FindBookmarkByID retrieves a single bookmark by ID. Only the owner can see
+// a bookmark. Returns ENOTFOUND if bookmark does not exist or user does not
+// have permission to view it.
+func (s *BookmarkStore) FindBookmarkByID(ctx context.Context, id int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findBookmarkByID(ctx, tx, id)
+}
+
+// FindBookmarks retrieves a list of bookmarks based on a filter. Only returns
+// bookmarks that the user owns.
+//
+// Also returns a count of total matching bookmarks which may different from the
+// number of returned bookmarks if the  "Limit" field is set.
+func (s *BookmarkStore) FindBookmarks(ctx context.Context, filter core.BookmarkFilter) ([]*core.Bookmark, int, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findBookmarks(ctx, tx, filter)
+}
+
+// CreateBookmark creates a new bookmark and assigns the current user as the owner.
+//
+// If the user already has a bookmark with the same canonical url then mode
+// decides whether the bookmark is created anyway, rejected with
+// ErrBookmarkDuplicate or merged into the existing bookmark. When merged,
+// bookmark is set to the state of the existing bookmark.
+func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookmark, mode core.DuplicateMode) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createBookmarkWithMode(ctx, tx, bookmark, mode); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateBookmark updates an existing bookmark by ID. Only the bookmark owner can update a bookmark.
+// Returns the new bookmark state even if there was an error during update.
+//
+// Returns ENOTFOUND if bookmark does not exist. Returns EUNAUTHORIZED if user
+// is not the bookmark owner.
+func (s *BookmarkStore) UpdateBookmark(ctx context.Context, id int, upd core.BookmarkUpdate) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookmark, err := updateBookmark(ctx, tx, id, upd)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// DeleteBookmark permanently removes a bookmark by ID. Only the bookmark owner may delete
+// a bookmark. Returns ENOTFOUND if bookmark does not exist. Returns EUNAUTHORIZED if
+// user is not the bookmark owner.
+//
+// If version is set the bookmark is only deleted if it is still at that
+// version, otherwise ErrPreconditionFailed is returned.
+func (s *BookmarkStore) DeleteBookmark(ctx context.Context, id int, version *int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookmark, err := deleteBookmark(ctx, tx, id, version)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// FindBookmarkRevisions retrieves the revision history of bookmarks, newest
+// first. Only returns revisions of bookmarks the current user owns.
+func (s *BookmarkStore) FindBookmarkRevisions(ctx context.Context, filter core.BookmarkRevisionFilter) ([]*core.BookmarkRevision, int, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findBookmarkRevisions(ctx, tx, filter)
+}
+
+// RevertBookmark restores the fields changed by a revision to their previous
+// values. The revert itself is recorded as a new revision.
+//
+// Returns ENOTFOUND if the bookmark or revision does not exist. Returns
+// EUNAUTHORIZED if user is not the bookmark owner.
+func (s *BookmarkStore) RevertBookmark(ctx context.Context, id int, revisionID int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookmark, err := revertBookmark(ctx, tx, id, revisionID)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// FindDuplicateBookmarks returns every group of the current user's bookmarks
+// that share a canonical url, ordered by canonical url.
+func (s *BookmarkStore) FindDuplicateBookmarks(ctx context.Context) ([]*core.BookmarkDuplicates, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findDuplicateBookmarks(ctx, tx)
+}
+
+// UpdateBookmarksStatus sets the status of every bookmark matching filter that
+// does not already have that status. Each change is recorded & published as if
+// the bookmark had been updated individually.
+//
+// Returns the number of bookmarks changed.
+func (s *BookmarkStore) UpdateBookmarksStatus(ctx context.Context, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := updateBookmarksStatus(ctx, tx, filter, status)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// findBookmarkByID is a helper function to retrieve a bookmark by ID.
+// Returns ENOTFOUND if bookmark doesn't exist.
+func findBookmarkByID(ctx context.Context, tx *Tx, id int) (*core.Bookmark, error) {
+	bookmarks, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{ID: &id})
+	if err != nil {
+		return nil, fmt.Errorf("find bookmarks: %w", err)
+	} else if len(bookmarks) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return bookmarks[0], nil
+}
+
+// findBookmarks retrieves a list of matching bookmarks of the current user.
+// Also returns a total matching count which may different from the number of
+// results if filter.Limit is set.
+func findBookmarks(ctx context.Context, tx *Tx, filter core.BookmarkFilter) ([]*core.Bookmark, int, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	userID := core.GetUserIDFromContext(ctx)
+
+	bookmarks := make([]*core.Bookmark, 0)
+	for _, row := range tx.db.bookmarks {
+		// Limit to bookmarks user owns.
+		if row.UserID != userID {
+			continue
+		}
+		if v := filter.ID; v != nil && row.ID != *v {
+			continue
+		}
+		if v := filter.CanonicalUrl; v != nil && row.CanonicalUrl != *v {
+			continue
+		}
+		if v := filter.Status; v != nil && row.Status != *v {
+			continue
+		}
+		if v := filter.Starred; v != nil && row.Starred != *v {
+			continue
+		}
+
+		bookmark := *row
+		if v := filter.Search; v != nil {
+			if bookmark.Relevance = bookmarkRelevance(&bookmark, *v); bookmark.Relevance == 0 {
+				continue
+			}
+		}
+		bookmarks = append(bookmarks, &bookmark)
+	}
+	n := len(bookmarks)
+
+	bookmarks, err := paginate(bookmarks, filter.CursorOf, filter.Descending(), filter.Cursor, filter.Offset, filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bookmarks, n, nil
+}
+
+// bookmarkRelevance scores how well bookmark matches a search. A match in the
+// name weighs more than one in the description which weighs more than one in
+// the url. Returns zero if nothing matches.
+func bookmarkRelevance(bookmark *core.Bookmark, search string) int {
+	search = strings.ToLower(search)
+
+	var relevance int
+	if strings.Contains(strings.ToLower(bookmark.Name), search) {
+		relevance += 4
+	}
+	if strings.Contains(strings.ToLower(bookmark.Description), search) {
+		relevance += 2
+	}
+	if strings.Contains(strings.ToLower(bookmark.Url), search) {
+		relevance += 1
+	}
+	return relevance
+}
+
+// createBookmarkWithMode creates a new bookmark unless the user already owns a
+// bookmark with the same canonical url and mode is reject or merge.
+func createBookmarkWithMode(ctx context.Context, tx *Tx, bookmark *core.Bookmark, mode core.DuplicateMode) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	} else if mode == "" || mode == core.DuplicateModeAllow {
+		return createBookmark(ctx, tx, bookmark)
+	}
+
+	// Look up an existing bookmark with the same canonical url.
+	canonicalUrl := tx.CanonicalizeUrl(bookmark.Url)
+	existing, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{CanonicalUrl: &canonicalUrl, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("find duplicate bookmarks: %w", err)
+	} else if len(existing) == 0 {
+		return createBookmark(ctx, tx, bookmark)
+	}
+
+	if mode == core.DuplicateModeReject {
+		return fmt.Errorf("%w: bookmark %d", bookmarkd.ErrBookmarkDuplicate, existing[0].ID)
+	}
+
+	// Merge the submitted fields into the existing bookmark. Only fields that
+	// differ are applied so an identical resubmission doesn't record a revision.
+	var upd core.BookmarkUpdate
+	if bookmark.Name != "" && bookmark.Name != existing[0].Name {
+		upd.Name = &bookmark.Name
+	}
+	if bookmark.Description != "" && bookmark.Description != existing[0].Description {
+		upd.Description = &bookmark.Description
+	}
+
+	merged := existing[0]
+	if upd.Name != nil || upd.Description != nil {
+		if merged, err = updateBookmark(ctx, tx, existing[0].ID, upd); err != nil {
+			return err
+		}
+	}
+	*bookmark = *merged
+
+	return nil
+}
+
+// createBookmark creates a new bookmark.
+func createBookmark(ctx context.Context, tx *Tx, bookmark *core.Bookmark) error {
+	// Assign bookmark to the current user.
+	// Return an error if the user is not currently logged in.
+	userID := core.GetUserIDFromContext(ctx)
+	if userID == "" {
+		return bookmarkd.ErrUnauthorized
+	}
+	bookmark.UserID = userID
+
+	// Set timestamps to current time.
+	bookmark.CreatedAt = tx.Now()
+	bookmark.UpdatedAt = bookmark.CreatedAt
+	bookmark.Version = 1
+
+	// New bookmarks are unread unless a status is given.
+	if bookmark.Status == "" {
+		bookmark.Status = core.BookmarkStatusUnread
+	}
+	bookmark.ReadAt = time.Time{}
+	if bookmark.Status == core.BookmarkStatusRead {
+		bookmark.ReadAt = bookmark.CreatedAt
+	}
+
+	// Perform basic field validation
+	if err := bookmark.Validate(); err != nil {
+		return err
+	}
+	bookmark.CanonicalUrl = tx.CanonicalizeUrl(bookmark.Url)
+
+	// The user may have been deleted since the session was created.
+	if _, ok := tx.db.users[userID]; !ok {
+		return fmt.Errorf("create bookmark: user %s: %w", userID, bookmarkd.ErrNotFound)
+	}
+
+	tx.db.bookmarkSeq++
+	bookmark.ID = tx.db.bookmarkSeq
+
+	row := *bookmark
+	row.User, row.Relevance = nil, 0
+	put(tx, tx.db.bookmarks, row.ID, &row)
+
+	other := *bookmark
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type:    core.EventTypeBookmarkAdded,
+		Payload: &core.EventTypeBookmarkAddedPayload{Bookmark: &other},
+	})
+
+	return nil
+}
+
+// updateBookmark updates a bookmark by ID. Returns the new state of the bookmark after update.
+func updateBookmark(ctx context.Context, tx *Tx, id int, upd core.BookmarkUpdate) (*core.Bookmark, error) {
+	// Fetch current object state. Return an error if current user is not owner.
+	bookmark, err := findBookmarkByID(ctx, tx, id)
+	if err != nil {
+		return bookmark, err
+	} else if !core.CanEditBookmark(ctx, bookmark) {
+		return bookmark, bookmarkd.ErrUnauthorized
+	} else if v := upd.Version; v != nil && *v != bookmark.Version {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// Capture previous values before they are overwritten.
+	changes := upd.Changes(bookmark)
+
+	// Update fields, if set.
+	if v := upd.Name; v != nil {
+		bookmark.Name = *v
+	}
+	if v := upd.Description; v != nil {
+		bookmark.Description = *v
+	}
+	if v := upd.Url; v != nil {
+		bookmark.Url = *v
+	}
+	if v := upd.Status; v != nil && *v != bookmark.Status {
+		// Track when the bookmark was read. Archiving keeps the read time.
+		switch bookmark.Status = *v; *v {
+		case core.BookmarkStatusRead:
+			bookmark.ReadAt = tx.Now()
+		case core.BookmarkStatusUnread:
+			bookmark.ReadAt = time.Time{}
+		}
+	}
+	if v := upd.Starred; v != nil {
+		bookmark.Starred = *v
+	}
+	bookmark.UpdatedAt = tx.Now()
+	bookmark.Version++
+
+	// Perform basic field validation. The url is only validated when it is
+	// changed so bookmarks with urls saved under older rules can be updated.
+	if err := bookmark.ValidateExceptUrl(); err != nil {
+		return bookmark, err
+	} else if upd.Url != nil {
+		if err := core.ValidateBookmarkUrl(bookmark.Url); err != nil {
+			return bookmark, err
+		}
+	}
+	bookmark.CanonicalUrl = tx.CanonicalizeUrl(bookmark.Url)
+
+	row := *bookmark
+	row.User, row.Relevance = nil, 0
+	put(tx, tx.db.bookmarks, id, &row)
+
+	// Record the update in the bookmark's revision history.
+	createBookmarkRevision(tx, &core.BookmarkRevision{
+		BookmarkID: bookmark.ID,
+		UserID:     core.GetUserIDFromContext(ctx),
+		Changes:    changes,
+	})
+
+	if upd.Name != nil {
+		tx.PublishEvent(bookmark.UserID, core.Event{
+			Type: core.EventTypeBookmarkNameChanged,
+			Payload: &core.EventTypeBookmarkNameChangedPayload{
+				ID:        bookmark.ID,
+				Name:      bookmark.Name,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		})
+	}
+
+	if upd.Description != nil {
+		tx.PublishEvent(bookmark.UserID, core.Event{
+			Type: core.EventTypeBookmarkDescriptionChanged,
+			Payload: &core.EventTypeBookmarkDescriptionChangedPayload{
+				ID:          bookmark.ID,
+				Description: bookmark.Description,
+				UpdatedAt:   bookmark.UpdatedAt,
+			},
+		})
+	}
+
+	if upd.Url != nil {
+		tx.PublishEvent(bookmark.UserID, core.Event{
+			Type: core.EventTypeBookmarkUrlChanged,
+			Payload: &core.EventTypeBookmarkUrlChangedPayload{
+				ID:        bookmark.ID,
+				Url:       bookmark.Url,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		})
+	}
+
+	if upd.Status != nil {
+		tx.PublishEvent(bookmark.UserID, core.Event{
+			Type: core.EventTypeBookmarkStatusChanged,
+			Payload: &core.EventTypeBookmarkStatusChangedPayload{
+				ID:        bookmark.ID,
+				Status:    bookmark.Status,
+				ReadAt:    bookmark.ReadAt,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		})
+	}
+
+	if upd.Starred != nil {
+		tx.PublishEvent(bookmark.UserID, core.Event{
+			Type: core.EventTypeBookmarkStarredChanged,
+			Payload: &core.EventTypeBookmarkStarredChangedPayload{
+				ID:        bookmark.ID,
+				Starred:   bookmark.Starred,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		})
+	}
+
+	return bookmark, nil
+}
+
+// updateBookmarksStatus sets the status of all bookmarks matching filter.
+// Bookmarks that already have the status are skipped.
+func updateBookmarksStatus(ctx context.Context, tx *Tx, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	if err := status.Validate(); err != nil {
+		return 0, err
+	}
+
+	bookmarks, _, err := findBookmarks(ctx, tx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("find bookmarks: %w", err)
+	}
+
+	var n int
+	for _, bookmark := range bookmarks {
+		if bookmark.Status == status {
+			continue
+		}
+		if _, err := updateBookmark(ctx, tx, bookmark.ID, core.BookmarkUpdate{Status: &status}); err != nil {
+			return 0, fmt.Errorf("update bookmark %d: %w", bookmark.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// deleteBookmark permanently deletes a bookmark by ID. Returns EUNAUTHORIZED if user
+// does not own the bookmark. Returns ErrPreconditionFailed if version is set
+// and does not match the bookmark.
+func deleteBookmark(ctx context.Context, tx *Tx, id int, version *int) (*core.Bookmark, error) {
+	// Verify object exists & the current user is the owner.
+	bookmark, err := findBookmarkByID(ctx, tx, id)
+	if err != nil {
+		return bookmark, err
+	} else if !core.CanEditBookmark(ctx, bookmark) {
+		return bookmark, bookmarkd.ErrUnauthorized
+	} else if version != nil && *version != bookmark.Version {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	deleteBookmarkRow(tx, id)
+
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type:    core.EventTypeBookmarkRemoved,
+		Payload: &core.EventTypeBookmarkRemovedPayload{ID: id},
+	})
+
+	return bookmark, nil
+}
+
+// deleteBookmarkRow removes a bookmark along with its revisions.
+func deleteBookmarkRow(tx *Tx, id int) {
+	for _, row := range tx.db.revisions {
+		if row.BookmarkID == id {
+			put(tx, tx.db.revisions, row.ID, nil)
+		}
+	}
+	put(tx, tx.db.bookmarks, id, nil)
+}
+
+// findDuplicateBookmarks returns groups of the current user's bookmarks that
+// share a canonical url.
+func findDuplicateBookmarks(ctx context.Context, tx *Tx) ([]*core.BookmarkDuplicates, error) {
+	userID := core.GetUserIDFromContext(ctx)
+
+	// Find canonical urls used by more than one bookmark.
+	counts := make(map[string]int)
+	for _, row := range tx.db.bookmarks {
+		if row.UserID == userID {
+			counts[row.CanonicalUrl]++
+		}
+	}
+	urls := make([]string, 0)
+	for canonicalUrl, n := range counts {
+		if n > 1 {
+			urls = append(urls, canonicalUrl)
+		}
+	}
+	slices.Sort(urls)
+
+	// Fetch the bookmarks of each group.
+	duplicates := make([]*core.BookmarkDuplicates, 0, len(urls))
+	for _, canonicalUrl := range urls {
+		bookmarks, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{CanonicalUrl: &canonicalUrl})
+		if err != nil {
+			return nil, fmt.Errorf("find bookmarks: %w", err)
+		}
+		duplicates = append(duplicates, &core.BookmarkDuplicates{
+			CanonicalUrl: canonicalUrl,
+			Bookmarks:    bookmarks,
+		})
+	}
+
+	return duplicates, nil
+}
+
+// revertBookmark restores the fields changed by a revision to their previous
+// values. Returns ENOTFOUND if the revision does not belong to the bookmark.
+func revertBookmark(ctx context.Context, tx *Tx, id int, revisionID int) (*core.Bookmark, error) {
+	revision, err := findBookmarkRevisionByID(ctx, tx, id, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the previous values as a regular update so the revert is checked
+	// for ownership, validated and recorded like any other change.
+	bookmark, err := updateBookmark(ctx, tx, id, revision.RevertUpdate())
+	if err != nil {
+		return bookmark, err
+	}
+
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type: core.EventTypeBookmarkReverted,
+		Payload: &core.EventTypeBookmarkRevertedPayload{
+			ID:         bookmark.ID,
+			RevisionID: revision.ID,
+			UpdatedAt:  bookmark.UpdatedAt,
+		},
+	})
+
+	return bookmark, nil
+}
+
+// findBookmarkRevisionByID is a helper function to retrieve a revision of a
+// bookmark. Returns ENOTFOUND if the revision doesn't exist.
+func findBookmarkRevisionByID(ctx context.Context, tx *Tx, bookmarkID, id int) (*core.BookmarkRevision, error) {
+	revisions, _, err := findBookmarkRevisions(ctx, tx, core.BookmarkRevisionFilter{ID: &id, BookmarkID: &bookmarkID})
+	if err != nil {
+		return nil, fmt.Errorf("find bookmark revisions: %w", err)
+	} else if len(revisions) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return revisions[0], nil
+}
+
+// findBookmarkRevisions retrieves a list of matching revisions of bookmarks
+// the current user owns, newest first. Also returns a total matching count
+// which may different from the number of results if filter.Limit is set.
+func findBookmarkRevisions(ctx context.Context, tx *Tx, filter core.BookmarkRevisionFilter) ([]*core.BookmarkRevision, int, error) {
+	userID := core.GetUserIDFromContext(ctx)
+
+	revisions := make([]*core.BookmarkRevision, 0)
+	for _, row := range tx.db.revisions {
+		if v := filter.ID; v != nil && row.ID != *v {
+			continue
+		}
+		if v := filter.BookmarkID; v != nil && row.BookmarkID != *v {
+			continue
+		}
+		if b, ok := tx.db.bookmarks[row.BookmarkID]; !ok || b.UserID != userID {
+			continue
+		}
+		revision := *row
+		revision.Changes = slices.Clone(row.Changes)
+		revisions = append(revisions, &revision)
+	}
+	n := len(revisions)
+
+	slices.SortFunc(revisions, func(a, b *core.BookmarkRevision) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	revisions = revisions[min(max(filter.Offset, 0), len(revisions)):]
+	if filter.Limit > 0 {
+		revisions = revisions[:min(filter.Limit, len(revisions))]
+	}
+
+	return revisions, n, nil
+}
+
+// createBookmarkRevision records a revision of a bookmark.
+func createBookmarkRevision(tx *Tx, revision *core.BookmarkRevision) {
+	revision.CreatedAt = tx.Now()
+
+	tx.db.revisionSeq++
+	revision.ID = tx.db.revisionSeq
+
+	row := *revision
+	row.Changes = slices.Clone(revision.Changes)
+	put(tx, tx.db.revisions, row.ID, &row)
+}
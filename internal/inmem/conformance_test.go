@@ -0,0 +1,37 @@
+package inmem_test
+
+import (
+	"testing"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/inmem"
+	"bookmarkd/internal/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.TestStores(t, func(tb testing.TB, events core.EventService, now func() time.Time) *storetest.Stores {
+		db := inmem.NewDB()
+		db.EventService = events
+		db.Now = now
+
+		return &storetest.Stores{
+			UserStore:           inmem.NewUserStore(db),
+			SessionStore:        inmem.NewSessionStore(db),
+			BookmarkStore:       inmem.NewBookmarkStore(db),
+			IdempotencyKeyStore: inmem.NewIdempotencyKeyStore(db, time.Hour),
+		}
+	})
+}
+
+func TestConformance_RegistrationStore(t *testing.T) {
+	storetest.TestRegistrationStore(t, func(tb testing.TB) core.RegistrationStore {
+		return inmem.NewRegistrationStore()
+	})
+}
+
+func TestConformance_EventService(t *testing.T) {
+	storetest.TestEventService(t, func(tb testing.TB) core.EventService {
+		return inmem.NewEventService()
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
+	"bookmarkd/internal/metrics"
 )
 
 // EventBufferSize is the buffer size of the channel for each subscription.
@@ -17,8 +18,12 @@ var _ core.EventService = (*EventService)(nil)
 
 // EventService represents a service for managing events in the system.
 type EventService struct {
-	mu sync.Mutex
-	m  map[string]map[*Subscription]struct{} // subscriptions by user ID
+	mu     sync.Mutex
+	m      map[string]map[*Subscription]struct{} // subscriptions by user ID
+	closed bool                                  // set once shut down
+
+	// Tracks subscriptions not yet closed by their subscriber.
+	wg sync.WaitGroup
 }
 
 // NewEventService returns a new instance of EventService.
@@ -46,14 +51,58 @@ func (s *EventService) PublishEvent(userID string, event core.Event) {
 	for sub := range subs {
 		select {
 		case sub.c <- event:
+			metrics.EventsPublished.Inc()
 		default:
+			metrics.EventsDropped.Inc()
+			s.unsubscribe(sub)
+		}
+	}
+}
+
+// Ping verifies events can still be published, i.e. that the service isn't
+// shut down & no publisher is stuck holding its lock.
+func (s *EventService) Ping(ctx context.Context) error {
+	if err := lockContext(ctx, &s.mu); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fmt.Errorf("event service is shut down: %w", bookmarkd.ErrUnavailable)
+	}
+	return nil
+}
+
+// Shutdown closes every subscription & refuses new ones, then waits until
+// each subscriber has closed its subscription or ctx is done. Subscribers see
+// their channel close, which lets them disconnect their clients cleanly.
+func (s *EventService) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	for _, subs := range s.m {
+		for sub := range subs {
 			s.unsubscribe(sub)
 		}
 	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Subscribe creates a new subscription for the currently logged in user.
-// Returns EUNAUTHORIZED if user is not logged in.
+// Returns EUNAUTHORIZED if user is not logged in & EUNAVAILABLE once the
+// service is shut down.
 func (s *EventService) Subscribe(ctx context.Context) (core.Subscription, error) {
 	// Fetch current user's ID.
 	userID := core.GetUserIDFromContext(ctx)
@@ -61,6 +110,13 @@ func (s *EventService) Subscribe(ctx context.Context) (core.Subscription, error)
 		return nil, fmt.Errorf("must be logged in to subscribe to events: %w", bookmarkd.ErrUnauthorized)
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, fmt.Errorf("event service is shut down: %w", bookmarkd.ErrUnavailable)
+	}
+
 	// Create new subscription for the user.
 	sub := &Subscription{
 		service: s,
@@ -76,6 +132,7 @@ func (s *EventService) Subscribe(ctx context.Context) (core.Subscription, error)
 		s.m[userID] = subs
 	}
 	subs[sub] = struct{}{}
+	s.wg.Add(1)
 
 	return sub, nil
 }
@@ -116,13 +173,15 @@ type Subscription struct {
 	service *EventService // service subscription was created from
 	userID  string        // subscribed user
 
-	c    chan core.Event // channel of events
-	once sync.Once       // ensures c only closed once
+	c         chan core.Event // channel of events
+	once      sync.Once       // ensures c only closed once
+	closeOnce sync.Once       // ensures the subscriber is only released once
 }
 
 // Close disconnects the subscription from the service it was created from.
 func (s *Subscription) Close() error {
 	s.service.Unsubscribe(s)
+	s.closeOnce.Do(s.service.wg.Done)
 	return nil
 }
 
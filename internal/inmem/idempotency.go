@@ -0,0 +1,132 @@
+package inmem
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.IdempotencyKeyStore = (*IdempotencyKeyStore)(nil)
+
+// idempotencyKeyID identifies a key. Keys are scoped per user.
+type idempotencyKeyID struct {
+	userID string
+	key    string
+}
+
+// IdempotencyKeyStore represents a service for storing idempotency keys.
+type IdempotencyKeyStore struct {
+	db *DB
+
+	// How long a key is kept after it is first used.
+	TTL time.Duration
+}
+
+// NewIdempotencyKeyStore returns a new instance of IdempotencyKeyStore that
+// keeps keys for ttl.
+func NewIdempotencyKeyStore(db *DB, ttl time.Duration) *IdempotencyKeyStore {
+	return &IdempotencyKeyStore{db: db, TTL: ttl}
+}
+
+// ReserveIdempotencyKey reserves key for the current user. If the user has
+// already used the key & it has not expired then the existing key is returned
+// and nothing is reserved. Expired keys are removed.
+func (s *IdempotencyKeyStore) ReserveIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) (*core.IdempotencyKey, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key.UserID = core.GetUserIDFromContext(ctx)
+	if key.UserID == "" {
+		return nil, bookmarkd.ErrUnauthorized
+	}
+
+	deleteExpiredIdempotencyKeys(tx)
+
+	id := idempotencyKeyID{userID: key.UserID, key: key.Key}
+	if row, ok := tx.db.idempotencyKeys[id]; ok {
+		// Keep the removal of expired keys.
+		return cloneIdempotencyKey(row), tx.Commit()
+	}
+
+	key.Completed, key.StatusCode, key.Header, key.Body = false, 0, nil, nil
+	key.CreatedAt = tx.Now()
+	key.ExpiresAt = key.CreatedAt.Add(s.TTL)
+
+	put(tx, tx.db.idempotencyKeys, id, cloneIdempotencyKey(key))
+
+	return nil, tx.Commit()
+}
+
+// CompleteIdempotencyKey stores the response of a key reserved by the current user.
+func (s *IdempotencyKeyStore) CompleteIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id := idempotencyKeyID{userID: core.GetUserIDFromContext(ctx), key: key.Key}
+	row, ok := tx.db.idempotencyKeys[id]
+	if !ok {
+		return bookmarkd.ErrNotFound
+	}
+
+	other := cloneIdempotencyKey(row)
+	other.Completed = true
+	other.StatusCode = key.StatusCode
+	other.Header = cloneHeader(key.Header)
+	other.Body = slices.Clone(key.Body)
+	put(tx, tx.db.idempotencyKeys, id, other)
+
+	key.Completed = true
+	return tx.Commit()
+}
+
+// DeleteIdempotencyKey removes a key of the current user.
+func (s *IdempotencyKeyStore) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	put(tx, tx.db.idempotencyKeys, idempotencyKeyID{userID: core.GetUserIDFromContext(ctx), key: key}, nil)
+	return tx.Commit()
+}
+
+// deleteExpiredIdempotencyKeys removes every key which has expired.
+func deleteExpiredIdempotencyKeys(tx *Tx) {
+	for id, row := range tx.db.idempotencyKeys {
+		if !row.ExpiresAt.After(tx.Now()) {
+			put(tx, tx.db.idempotencyKeys, id, nil)
+		}
+	}
+}
+
+// cloneIdempotencyKey returns a copy of key which shares no memory with it.
+func cloneIdempotencyKey(key *core.IdempotencyKey) *core.IdempotencyKey {
+	other := *key
+	other.Header = cloneHeader(key.Header)
+	other.Body = slices.Clone(key.Body)
+	return &other
+}
+
+// cloneHeader returns a deep copy of header.
+func cloneHeader(header map[string][]string) map[string][]string {
+	if header == nil {
+		return nil
+	}
+	other := maps.Clone(header)
+	for k, v := range other {
+		other[k] = slices.Clone(v)
+	}
+	return other
+}
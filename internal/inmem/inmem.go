@@ -0,0 +1,407 @@
+package inmem
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/metrics"
+)
+
+// DB represents a database held in memory. It behaves like the sqlite
+// database but nothing is written to disk so all data is lost on exit.
+//
+// Every transaction holds the lock of the database until it is committed or
+// rolled back so transactions are serializable.
+type DB struct {
+	mu sync.Mutex
+
+	// background context
+	ctx context.Context
+
+	// cancel background context
+	cancel func()
+
+	// tracks background goroutines, waited on by Close()
+	wg sync.WaitGroup
+
+	// Tables of the database. Rows are never handed out, callers always
+	// receive copies.
+	users           map[string]*core.User
+	sessions        map[int]*core.Session
+	bookmarks       map[int]*core.Bookmark
+	revisions       map[int]*core.BookmarkRevision
+	idempotencyKeys map[idempotencyKeyID]*core.IdempotencyKey
+
+	// Last IDs assigned to new rows.
+	sessionSeq  int
+	bookmarkSeq int
+	revisionSeq int
+
+	// Destination for events to be published.
+	EventService core.EventService
+
+	// Canonicalizes bookmark urls for duplicate detection.
+	UrlCanonicalizer *core.UrlCanonicalizer
+
+	// Returns the current time. Defaults to time.Now().
+	// Can be mocked for tests.
+	Now func() time.Time
+}
+
+// NewDB returns a new, empty database.
+func NewDB() *DB {
+	db := &DB{
+		users:           make(map[string]*core.User),
+		sessions:        make(map[int]*core.Session),
+		bookmarks:       make(map[int]*core.Bookmark),
+		revisions:       make(map[int]*core.BookmarkRevision),
+		idempotencyKeys: make(map[idempotencyKeyID]*core.IdempotencyKey),
+
+		Now: time.Now,
+
+		EventService:     core.NopEventService(),
+		UrlCanonicalizer: core.NewUrlCanonicalizer(core.DefaultUrlStripParams),
+	}
+
+	db.ctx, db.cancel = context.WithCancel(context.Background())
+
+	return db
+}
+
+// Open starts monitoring the stats of the database.
+func (db *DB) Open() error {
+	// Monitor stats in background goroutine.
+	db.wg.Add(1)
+	go func() { defer db.wg.Done(); db.monitor() }()
+
+	return nil
+}
+
+// Close stops the background goroutines. The data is kept until the
+// database is garbage collected.
+func (db *DB) Close() error {
+	db.cancel()
+	db.wg.Wait()
+	return nil
+}
+
+// BeginTx starts a transaction. Blocks until any other transaction ends.
+// The timestamp of the transaction is fixed when it starts, truncated to the
+// second like the timestamps stored by sqlite.
+func (db *DB) BeginTx(ctx context.Context) (*Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+
+	return &Tx{
+		db:  db,
+		now: db.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// Ping verifies the database can be locked before ctx is done, i.e. that no
+// transaction is stuck holding it.
+func (db *DB) Ping(ctx context.Context) error {
+	if err := lockContext(ctx, &db.mu); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	db.mu.Unlock()
+	return nil
+}
+
+// monitor runs in a goroutine and periodically calculates internal stats.
+func (db *DB) monitor() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := db.updateStats(db.ctx); err != nil {
+			log.Printf("stats error: %s", err)
+		}
+	}
+}
+
+// updateStats updates the metrics for the database.
+func (db *DB) updateStats(ctx context.Context) error {
+	stats, err := db.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	metrics.Users.Set(float64(stats.Users))
+	metrics.DisabledUsers.Set(float64(stats.DisabledUsers))
+	metrics.Sessions.Set(float64(stats.Sessions))
+	metrics.Bookmarks.Set(float64(stats.Bookmarks))
+	metrics.Revisions.Set(float64(stats.Revisions))
+
+	return nil
+}
+
+// Stats represents row counts of the database.
+type Stats struct {
+	Users         int `json:"users"`
+	DisabledUsers int `json:"disabledUsers"`
+	Sessions      int `json:"sessions"`
+	Bookmarks     int `json:"bookmarks"`
+	Revisions     int `json:"revisions"`
+}
+
+// Stats returns the current row counts of the database.
+func (db *DB) Stats(ctx context.Context) (*Stats, error) {
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stats := Stats{
+		Users:     len(db.users),
+		Sessions:  len(db.sessions),
+		Bookmarks: len(db.bookmarks),
+		Revisions: len(db.revisions),
+	}
+	for _, u := range db.users {
+		if u.Disabled() {
+			stats.DisabledUsers++
+		}
+	}
+
+	return &stats, nil
+}
+
+// Tx represents a transaction of the in-memory database. Changes are applied
+// directly & undone on rollback.
+//
+// Events published within the transaction are held back until it commits so
+// listeners never see changes which are later rolled back.
+type Tx struct {
+	db   *DB
+	now  time.Time
+	done bool
+
+	// Functions restoring the state changed by the transaction, in the
+	// order the changes were made.
+	undo []func()
+
+	// Events waiting for the transaction to commit.
+	events []txEvent
+
+	// Number of undo functions & buffered events when each open savepoint
+	// was created.
+	savepoints map[string]txSavepoint
+}
+
+// txEvent represents an event buffered by a transaction.
+type txEvent struct {
+	userID string
+	event  core.Event
+}
+
+// txSavepoint represents the state of a transaction when a savepoint was created.
+type txSavepoint struct {
+	undo   int
+	events int
+}
+
+func (t *Tx) Now() time.Time {
+	return t.now
+}
+
+func (t *Tx) CanonicalizeUrl(url string) string {
+	return t.db.UrlCanonicalizer.Canonicalize(url)
+}
+
+// PublishEvent queues event to be published to userID once the transaction
+// commits. The event is discarded if the transaction is rolled back.
+func (t *Tx) PublishEvent(userID string, event core.Event) {
+	t.events = append(t.events, txEvent{userID: userID, event: event})
+}
+
+// Commit keeps the changes of the transaction & publishes the events queued
+// within it.
+func (t *Tx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	t.db.mu.Unlock()
+
+	for _, e := range t.events {
+		t.db.EventService.PublishEvent(e.userID, e.event)
+	}
+	t.undo, t.events = nil, nil
+	return nil
+}
+
+// Rollback undoes the changes of the transaction. Like sql.Tx, returns
+// sql.ErrTxDone if the transaction has already ended so it can be deferred.
+func (t *Tx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	defer t.db.mu.Unlock()
+
+	t.rollbackTo(0)
+	t.undo, t.events = nil, nil
+	return nil
+}
+
+// Savepoint starts a savepoint within the transaction. Changes made after the
+// savepoint can be undone with RollbackTo() without aborting the transaction.
+func (t *Tx) Savepoint(ctx context.Context, name string) error {
+	if t.savepoints == nil {
+		t.savepoints = make(map[string]txSavepoint)
+	}
+	t.savepoints[name] = txSavepoint{undo: len(t.undo), events: len(t.events)}
+	return nil
+}
+
+// RollbackTo undoes the changes made since the savepoint was created,
+// including any events queued since then, and ends the savepoint.
+func (t *Tx) RollbackTo(ctx context.Context, name string) error {
+	sp, ok := t.savepoints[name]
+	if !ok {
+		return fmt.Errorf("no such savepoint: %s", name)
+	}
+	t.rollbackTo(sp.undo)
+	t.events = t.events[:sp.events]
+	return t.Release(ctx, name)
+}
+
+// Release ends the savepoint, keeping the changes made since it was created.
+func (t *Tx) Release(ctx context.Context, name string) error {
+	delete(t.savepoints, name)
+	return nil
+}
+
+// rollbackTo undoes every change after the first n in reverse order.
+func (t *Tx) rollbackTo(n int) {
+	for i := len(t.undo) - 1; i >= n; i-- {
+		t.undo[i]()
+	}
+	t.undo = t.undo[:n]
+}
+
+// put sets the row of table at key, or removes it if row is nil. The previous
+// row is restored if the transaction is rolled back.
+func put[K comparable, V any](t *Tx, table map[K]*V, key K, row *V) {
+	prev, ok := table[key]
+	t.undo = append(t.undo, func() {
+		if ok {
+			table[key] = prev
+		} else {
+			delete(table, key)
+		}
+	})
+
+	if row == nil {
+		delete(table, key)
+	} else {
+		table[key] = row
+	}
+}
+
+// paginate sorts rows by the key of the cursor returned by cursorOf & their
+// ID, then returns the rows after the cursor restricted by offset & limit.
+// Matches the pages returned by sqlite.FormatKeyset().
+func paginate[T any](rows []T, cursorOf func(T) core.Cursor, desc bool, cursor string, offset, limit int) ([]T, error) {
+	var c core.Cursor
+	if cursor != "" {
+		var err error
+		if c, err = core.DecodeCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rows before the cursor are listed in reverse so the rows nearest the
+	// cursor are returned first.
+	reverse := desc != c.Before
+	slices.SortFunc(rows, func(a, b T) int {
+		x, y := cursorOf(a), cursorOf(b)
+		n := cmp.Or(compareValues(x.Key, y.Key), compareValues(x.ID, y.ID))
+		if reverse {
+			return -n
+		}
+		return n
+	})
+
+	if cursor != "" {
+		rows = slices.DeleteFunc(rows, func(row T) bool {
+			x := cursorOf(row)
+			n := cmp.Or(compareValues(x.Key, c.Key), compareValues(x.ID, c.ID))
+			if reverse {
+				return n >= 0
+			}
+			return n <= 0
+		})
+	}
+
+	rows = rows[min(offset, len(rows)):]
+	if limit > 0 {
+		rows = rows[:min(limit, len(rows))]
+	}
+
+	if c.Before {
+		slices.Reverse(rows)
+	}
+	return rows, nil
+}
+
+// compareValues compares two sort keys. Numbers are compared by value, any
+// other values by their string form. Cursors decode numbers as float64 so
+// they must compare equal to the ints they were encoded from.
+func compareValues(a, b interface{}) int {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// toFloat returns v as a float64 if it is a number.
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// lockContext locks mu, giving up once ctx is done. If it gives up, mu is
+// unlocked again as soon as the pending lock is acquired.
+func lockContext(ctx context.Context, mu *sync.Mutex) error {
+	locked := make(chan struct{})
+	go func() {
+		mu.Lock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		go func() {
+			<-locked
+			mu.Unlock()
+		}()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package inmem_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/inmem"
+	"bookmarkd/utils/require"
+)
+
+// Ensure a failing atomic batch leaves no changes behind.
+func TestDB_Rollback(t *testing.T) {
+	db := inmem.NewDB()
+	ctx, _ := mustLogin(t, db, "jane")
+	b := inmem.NewBookmarkStore(db)
+
+	result, err := b.BatchBookmarks(ctx, &core.BookmarkBatch{Operations: []core.BookmarkOp{
+		{Op: core.BookmarkOpCreate, Bookmark: &core.Bookmark{Name: "NAME", Url: "https://example.com"}},
+		{Op: core.BookmarkOpDelete, ID: 100},
+	}})
+	require.Equal(t, err, nil)
+	require.Equal(t, result.Committed, false)
+
+	_, n, err := b.FindBookmarks(ctx, core.BookmarkFilter{})
+	require.Equal(t, err, nil)
+	require.Equal(t, n, 0)
+}
+
+// Ensure deleting a user removes the rows they own.
+func TestDB_DeleteUser(t *testing.T) {
+	db := inmem.NewDB()
+	ctx, user := mustLogin(t, db, "jane")
+	b := inmem.NewBookmarkStore(db)
+
+	bookmark := &core.Bookmark{Name: "NAME", Url: "https://example.com"}
+	require.Equal(t, b.CreateBookmark(ctx, bookmark, ""), nil)
+
+	_, err := inmem.NewUserStore(db).DeleteUser(ctx, user.ID)
+	require.Equal(t, err, nil)
+
+	_, err = b.FindBookmarkByID(ctx, bookmark.ID)
+	require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+	_, n, err := inmem.NewSessionStore(db).FindSessions(context.Background(), core.SessionFilter{UserID: &user.ID})
+	require.Equal(t, err, nil)
+	require.Equal(t, n, 0)
+}
+
+func TestDB_Stats(t *testing.T) {
+	db := inmem.NewDB()
+	ctx, _ := mustLogin(t, db, "jane")
+	require.Equal(t, inmem.NewBookmarkStore(db).CreateBookmark(ctx, &core.Bookmark{Name: "Go", Url: "https://go.dev", Status: core.BookmarkStatusUnread}, ""), nil)
+
+	stats, err := db.Stats(context.Background())
+	require.Equal(t, err, nil)
+	require.Equal(t, *stats, inmem.Stats{Users: 1, Sessions: 1, Bookmarks: 1})
+}
+
+// Ensure shutting down closes subscriptions & waits for their subscribers.
+func TestEventService_Shutdown(t *testing.T) {
+	s := inmem.NewEventService()
+	ctx := core.NewContextWithSession(context.Background(), core.SessionContext{UserID: "USER"})
+
+	sub, err := s.Subscribe(ctx)
+	require.Equal(t, err, nil)
+
+	// The subscriber hasn't closed its subscription yet.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.Equal(t, s.Shutdown(shutdownCtx), context.DeadlineExceeded)
+
+	_, ok := <-sub.C()
+	require.Equal(t, ok, false)
+	require.Equal(t, sub.Close(), nil)
+	require.Equal(t, s.Shutdown(context.Background()), nil)
+
+	// New subscriptions are refused & the service reports it is down.
+	_, err = s.Subscribe(ctx)
+	require.NotEqual(t, err, nil)
+	require.NotEqual(t, s.Ping(context.Background()), nil)
+}
+
+// mustLogin creates a user & a session for it. Returns a context
+// authenticated with the session. Fatal on error.
+func mustLogin(tb testing.TB, db *inmem.DB, username string) (context.Context, *core.User) {
+	tb.Helper()
+	ctx := context.Background()
+
+	user := &core.User{Username: username, Seed: "SEED"}
+	if err := inmem.NewUserStore(db).CreateUser(ctx, user); err != nil {
+		tb.Fatal(err)
+	}
+
+	session := &core.Session{UserID: user.ID}
+	if err := inmem.NewSessionStore(db).CreateSession(ctx, session); err != nil {
+		tb.Fatal(err)
+	}
+
+	return core.NewContextWithSession(ctx, core.SessionContext{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		ExpiresAt: session.ExpiresAt,
+	}), user
+}
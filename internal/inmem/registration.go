@@ -1,7 +1,6 @@
 package inmem
 
 import (
-	"encoding/base32"
 	"fmt"
 	"sync"
 	"time"
@@ -34,11 +33,10 @@ func (s *RegistrationStore) StartRegistration(username string) (*core.Registrati
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	r, err := uuid.NewRandom()
+	seed, err := core.NewSeed()
 	if err != nil {
 		return nil, err
 	}
-	seed := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte(r.String()))
 
 	id, err := uuid.NewRandom()
 	if err != nil {
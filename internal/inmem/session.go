@@ -0,0 +1,261 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.SessionStore = (*SessionStore)(nil)
+
+// SessionStore represents a service for managing user sessions.
+type SessionStore struct {
+	db *DB
+}
+
+// NewSessionStore returns a new instance of SessionStore.
+func NewSessionStore(db *DB) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+func (s *SessionStore) FindSessionByID(ctx context.Context, id int) (*core.Session, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := findSessionByID(tx, id)
+	if err != nil {
+		return nil, err
+	} else if err := attachSessionAssociations(tx, session); err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+func (s *SessionStore) FindSessionByUserID(ctx context.Context, id string) (*core.Session, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := findSessionByUserID(tx, id)
+	if err != nil {
+		return nil, err
+	} else if err := attachSessionAssociations(tx, session); err != nil {
+		return session, err
+	}
+	return session, nil
+}
+
+func (s *SessionStore) FindSessions(ctx context.Context, filter core.SessionFilter) ([]*core.Session, int, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findSessions(tx, filter)
+}
+
+func (s *SessionStore) CreateSession(ctx context.Context, session *core.Session) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createSession(tx, session); err != nil {
+		return err
+	} else if err := attachSessionAssociations(tx, session); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SessionStore) RefreshSession(ctx context.Context, refreshToken string) (*core.Session, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := refreshSession(tx, refreshToken)
+	if err != nil {
+		return session, err
+	} else if err := attachSessionAssociations(tx, session); err != nil {
+		return session, err
+	}
+	return session, tx.Commit()
+}
+
+func (s *SessionStore) DeleteSession(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteSession(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+//
+// helper functions
+//
+
+func findSessionByID(tx *Tx, id int) (*core.Session, error) {
+	sessions, _, err := findSessions(tx, core.SessionFilter{ID: &id})
+	if err != nil {
+		return nil, fmt.Errorf("find sessions: %w", err)
+	} else if len(sessions) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return sessions[0], nil
+}
+
+func findSessionByUserID(tx *Tx, id string) (*core.Session, error) {
+	sessions, _, err := findSessions(tx, core.SessionFilter{UserID: &id})
+	if err != nil {
+		return nil, fmt.Errorf("find sessions: %w", err)
+	} else if len(sessions) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return sessions[0], nil
+}
+
+func findSessionByRefreshToken(tx *Tx, token string) (*core.Session, error) {
+	sessions, _, err := findSessions(tx, core.SessionFilter{RefreshToken: &token})
+	if err != nil {
+		return nil, fmt.Errorf("find sessions: %w", err)
+	} else if len(sessions) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return sessions[0], nil
+}
+
+func findSessions(tx *Tx, filter core.SessionFilter) ([]*core.Session, int, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	sessions := make([]*core.Session, 0)
+	for _, row := range tx.db.sessions {
+		if v := filter.ID; v != nil && row.ID != *v {
+			continue
+		}
+		if v := filter.UserID; v != nil && row.UserID != *v {
+			continue
+		}
+		if v := filter.RefreshToken; v != nil && row.RefreshToken != *v {
+			continue
+		}
+		session := *row
+		sessions = append(sessions, &session)
+	}
+	n := len(sessions)
+
+	sessions, err := paginate(sessions, filter.CursorOf, filter.Descending(), filter.Cursor, filter.Offset, filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return sessions, n, nil
+}
+
+func createSession(tx *Tx, session *core.Session) error {
+	if session.UserID == "" {
+		return fmt.Errorf("unable to determine userID: %w", bookmarkd.ErrInternal)
+	} else if _, ok := tx.db.users[session.UserID]; !ok {
+		return fmt.Errorf("create session: user %s: %w", session.UserID, bookmarkd.ErrNotFound)
+	}
+
+	// Set timestamp fields to current time.
+	session.CreatedAt = tx.Now()
+	session.UpdatedAt = session.CreatedAt
+
+	// generate a random refresh Token
+	refreshToken, err := uuid.NewRandom()
+	if err != nil {
+		return fmt.Errorf("create refresh token: %w", err)
+	}
+	session.RefreshToken = refreshToken.String()
+
+	// set the refresh expiration
+	session.ExpiresAt = session.CreatedAt.Add(time.Duration(24*14) * time.Hour)
+
+	tx.db.sessionSeq++
+	session.ID = tx.db.sessionSeq
+
+	row := *session
+	row.User = nil
+	put(tx, tx.db.sessions, row.ID, &row)
+
+	return nil
+}
+
+func refreshSession(tx *Tx, refreshToken string) (*core.Session, error) {
+	s, err := findSessionByRefreshToken(tx, refreshToken)
+	if errors.Is(err, bookmarkd.ErrNotFound) {
+		return nil, bookmarkd.ErrUnauthorized
+	} else if err != nil {
+		return nil, fmt.Errorf("find session by refresh token: %w", err)
+	} else if !s.ExpiresAt.After(tx.Now()) {
+		return nil, fmt.Errorf("session expired: %w", bookmarkd.ErrUnauthorized)
+	}
+
+	rt, err := uuid.NewRandom()
+	if err != nil {
+		return nil, fmt.Errorf("new refresh token: %w", err)
+	}
+
+	s.ExpiresAt = tx.Now().Add(time.Duration(24*14) * time.Hour)
+	s.RefreshToken = rt.String()
+	s.UpdatedAt = tx.Now()
+
+	row := *s
+	put(tx, tx.db.sessions, row.ID, &row)
+
+	return s, nil
+}
+
+func deleteSession(ctx context.Context, tx *Tx, id int) error {
+	// Verify object exists & that the user is the owner of the session.
+	if session, err := findSessionByID(tx, id); err != nil {
+		return fmt.Errorf("find user session by id: %w", err)
+	} else if session.UserID != core.GetUserIDFromContext(ctx) {
+		return bookmarkd.ErrUnauthorized
+	}
+
+	put(tx, tx.db.sessions, id, nil)
+	return nil
+}
+
+// deleteUserSessions removes every session of a user.
+func deleteUserSessions(tx *Tx, userID string) {
+	for _, row := range tx.db.sessions {
+		if row.UserID == userID {
+			put(tx, tx.db.sessions, row.ID, nil)
+		}
+	}
+}
+
+//
+// Helpers
+//
+
+func attachSessionAssociations(tx *Tx, session *core.Session) (err error) {
+	if session.User, err = findUserByID(tx, session.UserID); err != nil {
+		return fmt.Errorf("attach user to session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.UserStore = (*UserStore)(nil)
+
+// UserStore represents a service for managing users.
+type UserStore struct {
+	db *DB
+}
+
+// NewUserStore returns a new instance of UserStore.
+func NewUserStore(db *DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+// FindUserByID retrieves a user by ID along with their sessions.
+// Returns ENOTFOUND if user does not exist.
+func (s *UserStore) FindUserByID(ctx context.Context, id string) (*core.User, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := findUserByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	attachUserAssociations(tx, user)
+	return user, nil
+}
+
+func (s *UserStore) FindUserByUsername(ctx context.Context, username string) (*core.User, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := findUserByUsername(tx, username)
+	if err != nil {
+		return nil, err
+	}
+	attachUserAssociations(tx, user)
+	return user, nil
+}
+
+// FindUsers retrieves a list of users by filter. Also returns total count of
+// matching users which may differ from returned results if filter.Limit is specified.
+func (s *UserStore) FindUsers(ctx context.Context, filter core.UserFilter) ([]*core.User, int, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findUsers(tx, filter)
+}
+
+// CreateUser creates a new user. Returns ErrUsersUsernameConflict if the
+// username is taken.
+func (s *UserStore) CreateUser(ctx context.Context, user *core.User) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createUser(tx, user); err != nil {
+		return err
+	}
+	attachUserAssociations(tx, user)
+	return tx.Commit()
+}
+
+// UpdateUser updates a user object. Returns EUNAUTHORIZED if current user is
+// not the user that is being updated. Returns ENOTFOUND if user does not exist.
+func (s *UserStore) UpdateUser(ctx context.Context, id string, upd core.UserUpdate) (*core.User, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := updateUser(ctx, tx, id, upd)
+	if err != nil {
+		return user, err
+	}
+	attachUserAssociations(tx, user)
+	return user, tx.Commit()
+}
+
+// DeleteUser permanently deletes a user along with their sessions, bookmarks
+// & idempotency keys. Returns EUNAUTHORIZED if current user is not the user
+// being deleted. Returns ENOTFOUND if user does not exist.
+func (s *UserStore) DeleteUser(ctx context.Context, id string) (*core.User, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := deleteUser(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
+
+//
+// Database actions
+//
+
+// findUserByID is a helper function to fetch a user by ID.
+// Returns ENOTFOUND if user does not exist.
+func findUserByID(tx *Tx, id string) (*core.User, error) {
+	row, ok := tx.db.users[id]
+	if !ok {
+		return nil, bookmarkd.ErrNotFound
+	}
+	user := *row
+	return &user, nil
+}
+
+func findUserByUsername(tx *Tx, username string) (*core.User, error) {
+	users, _, err := findUsers(tx, core.UserFilter{Username: &username})
+	if err != nil {
+		return nil, fmt.Errorf("find users: %w", err)
+	} else if len(users) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return users[0], nil
+}
+
+// findUsers returns a list of users matching a filter. Also returns a count of
+// total matching users which may differ if filter.Limit is set.
+func findUsers(tx *Tx, filter core.UserFilter) ([]*core.User, int, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]*core.User, 0)
+	for _, row := range tx.db.users {
+		if v := filter.ID; v != nil && row.ID != *v {
+			continue
+		}
+		if v := filter.Username; v != nil && row.Username != *v {
+			continue
+		}
+		user := *row
+		users = append(users, &user)
+	}
+	n := len(users)
+
+	users, err := paginate(users, filter.CursorOf, filter.Descending(), filter.Cursor, filter.Offset, filter.Limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, n, nil
+}
+
+// createUser creates a new user. Sets the new ID to user.ID and sets the
+// timestamps to the current time.
+func createUser(tx *Tx, user *core.User) error {
+	// Set timestamps to the current time.
+	user.CreatedAt = tx.Now()
+	user.UpdatedAt = user.CreatedAt
+
+	// Generate a new ID in uuidv7 format so users are ordered by creation.
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("generate a new userID: %w", err)
+	}
+	user.ID = id.String()
+
+	if err := user.Validate(); err != nil {
+		return fmt.Errorf("validate user: %w", err)
+	}
+
+	// Usernames are unique.
+	if _, err := findUserByUsername(tx, user.Username); err == nil {
+		return fmt.Errorf("insert user: %w", bookmarkd.ErrUsersUsernameConflict)
+	}
+
+	row := *user
+	row.Sessions = nil
+	put(tx, tx.db.users, row.ID, &row)
+
+	return nil
+}
+
+// updateUser updates fields on a user object. Returns EUNAUTHORIZED if current
+// user is not the user being updated.
+func updateUser(ctx context.Context, tx *Tx, id string, upd core.UserUpdate) (*core.User, error) {
+	// Fetch current object state.
+	user, err := findUserByID(tx, id)
+	if err != nil {
+		return user, fmt.Errorf("find user by id: %w", err)
+	} else if user.ID != core.GetUserIDFromContext(ctx) {
+		return nil, bookmarkd.ErrUnauthorized
+	}
+
+	// Update fields.
+	if v := upd.Username; v != nil && *v != user.Username {
+		if _, err := findUserByUsername(tx, *v); err == nil {
+			return user, fmt.Errorf("update user: %w", bookmarkd.ErrUsersUsernameConflict)
+		}
+		user.Username = *v
+	}
+
+	// Changing the seed or disabling the user ends all of their sessions.
+	revoke := false
+	if v := upd.Seed; v != nil {
+		if *v == "" {
+			return user, fmt.Errorf("%w: seed required", bookmarkd.ErrInvalidInput)
+		}
+		user.Seed, revoke = *v, true
+	}
+	if v := upd.Disabled; v != nil && *v != user.Disabled() {
+		if *v {
+			now := tx.Now()
+			user.DisabledAt, revoke = &now, true
+		} else {
+			user.DisabledAt = nil
+		}
+	}
+
+	// Set last updated date to current time.
+	user.UpdatedAt = tx.Now()
+
+	row := *user
+	put(tx, tx.db.users, id, &row)
+
+	if revoke {
+		deleteUserSessions(tx, id)
+	}
+
+	return user, nil
+}
+
+// deleteUser permanently removes a user by ID along with every row they own.
+// Returns EUNAUTHORIZED if current user is not the one being deleted.
+func deleteUser(ctx context.Context, tx *Tx, id string) (*core.User, error) {
+	// Verify object exists.
+	user, err := findUserByID(tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("find user by id: %w", err)
+	} else if user.ID != core.GetUserIDFromContext(ctx) {
+		return nil, bookmarkd.ErrUnauthorized
+	}
+
+	// Remove the rows which reference the user, like sqlite's cascading deletes.
+	deleteUserSessions(tx, id)
+	for _, row := range tx.db.bookmarks {
+		if row.UserID == id {
+			deleteBookmarkRow(tx, row.ID)
+		}
+	}
+	for _, row := range tx.db.revisions {
+		if row.UserID == id {
+			put(tx, tx.db.revisions, row.ID, nil)
+		}
+	}
+	for k := range tx.db.idempotencyKeys {
+		if k.userID == id {
+			put(tx, tx.db.idempotencyKeys, k, nil)
+		}
+	}
+
+	put(tx, tx.db.users, id, nil)
+	return user, nil
+}
+
+//
+// Helpers
+//
+
+func attachUserAssociations(tx *Tx, user *core.User) {
+	user.Sessions, _, _ = findSessions(tx, core.SessionFilter{UserID: &user.ID})
+}
@@ -0,0 +1,81 @@
+// Package metrics defines the Prometheus metrics of bookmarkd. The metrics are
+// registered with the default registry & exposed on /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Database row counts. Updated periodically by the database in use.
+var (
+	Users = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bookmarkd_db_users",
+		Help: "The total number of users.",
+	})
+
+	DisabledUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bookmarkd_db_disabled_users",
+		Help: "The total number of disabled users.",
+	})
+
+	Sessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bookmarkd_db_sessions",
+		Help: "The total number of sessions.",
+	})
+
+	Bookmarks = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bookmarkd_db_bookmarks",
+		Help: "The total number of bookmarks.",
+	})
+
+	Revisions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bookmarkd_db_bookmark_revisions",
+		Help: "The total number of bookmark revisions.",
+	})
+)
+
+// HTTP requests, labeled by the chi route pattern instead of the path so
+// the number of series stays bounded.
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bookmarkd_http_requests_total",
+		Help: "The total number of HTTP requests.",
+	}, []string{"method", "route", "code"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bookmarkd_http_request_duration_seconds",
+		Help:    "The time taken to serve HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Events streamed to subscribers.
+var (
+	WebsocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bookmarkd_websocket_connections",
+		Help: "The number of open websocket event subscriptions.",
+	})
+
+	EventsPublished = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bookmarkd_events_published_total",
+		Help: "The total number of events delivered to subscriptions.",
+	})
+
+	EventsDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bookmarkd_events_dropped_total",
+		Help: "The total number of events dropped because a subscription fell behind.",
+	})
+)
+
+// Login attempts, labeled by result.
+var Logins = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "bookmarkd_logins_total",
+	Help: "The total number of login attempts.",
+}, []string{"result"})
+
+// Login results.
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+)
@@ -0,0 +1,22 @@
+package mock
+
+import (
+	"context"
+
+	"bookmarkd/internal/core"
+)
+
+var _ core.BackupStore = (*BackupStore)(nil)
+
+type BackupStore struct {
+	CreateBackupFn func(ctx context.Context) (*core.Backup, error)
+	FindBackupsFn  func(ctx context.Context) ([]*core.Backup, error)
+}
+
+func (s *BackupStore) CreateBackup(ctx context.Context) (*core.Backup, error) {
+	return s.CreateBackupFn(ctx)
+}
+
+func (s *BackupStore) FindBackups(ctx context.Context) ([]*core.Backup, error) {
+	return s.FindBackupsFn(ctx)
+}
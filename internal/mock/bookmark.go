@@ -11,9 +11,16 @@ var _ core.BookmarkStore = (*BookmarkStore)(nil)
 type BookmarkStore struct {
 	FindBookmarkByIDFn func(ctx context.Context, id int) (*core.Bookmark, error)
 	FindBookmarksFn    func(ctx context.Context, filter core.BookmarkFilter) ([]*core.Bookmark, int, error)
-	CreateBookmarkFn   func(ctx context.Context, bookmark *core.Bookmark) error
+	CreateBookmarkFn   func(ctx context.Context, bookmark *core.Bookmark, mode core.DuplicateMode) error
 	UpdateBookmarkFn   func(ctx context.Context, id int, update core.BookmarkUpdate) (*core.Bookmark, error)
-	DeleteBookmarkFn   func(ctx context.Context, id int) (*core.Bookmark, error)
+	DeleteBookmarkFn   func(ctx context.Context, id int, version *int) (*core.Bookmark, error)
+
+	FindBookmarkRevisionsFn func(ctx context.Context, filter core.BookmarkRevisionFilter) ([]*core.BookmarkRevision, int, error)
+	RevertBookmarkFn        func(ctx context.Context, id int, revisionID int) (*core.Bookmark, error)
+
+	FindDuplicateBookmarksFn func(ctx context.Context) ([]*core.BookmarkDuplicates, error)
+	UpdateBookmarksStatusFn  func(ctx context.Context, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error)
+	BatchBookmarksFn         func(ctx context.Context, batch *core.BookmarkBatch) (*core.BookmarkBatchResult, error)
 }
 
 func (s *BookmarkStore) FindBookmarkByID(ctx context.Context, id int) (*core.Bookmark, error) {
@@ -24,14 +31,34 @@ func (s *BookmarkStore) FindBookmarks(ctx context.Context, filter core.BookmarkF
 	return s.FindBookmarksFn(ctx, filter)
 }
 
-func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookmark) error {
-	return s.CreateBookmarkFn(ctx, bookmark)
+func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookmark, mode core.DuplicateMode) error {
+	return s.CreateBookmarkFn(ctx, bookmark, mode)
 }
 
 func (s *BookmarkStore) UpdateBookmark(ctx context.Context, id int, update core.BookmarkUpdate) (*core.Bookmark, error) {
 	return s.UpdateBookmarkFn(ctx, id, update)
 }
 
-func (s *BookmarkStore) DeleteBookmark(ctx context.Context, id int) (*core.Bookmark, error) {
-	return s.DeleteBookmarkFn(ctx, id)
+func (s *BookmarkStore) DeleteBookmark(ctx context.Context, id int, version *int) (*core.Bookmark, error) {
+	return s.DeleteBookmarkFn(ctx, id, version)
+}
+
+func (s *BookmarkStore) FindBookmarkRevisions(ctx context.Context, filter core.BookmarkRevisionFilter) ([]*core.BookmarkRevision, int, error) {
+	return s.FindBookmarkRevisionsFn(ctx, filter)
+}
+
+func (s *BookmarkStore) RevertBookmark(ctx context.Context, id int, revisionID int) (*core.Bookmark, error) {
+	return s.RevertBookmarkFn(ctx, id, revisionID)
+}
+
+func (s *BookmarkStore) FindDuplicateBookmarks(ctx context.Context) ([]*core.BookmarkDuplicates, error) {
+	return s.FindDuplicateBookmarksFn(ctx)
+}
+
+func (s *BookmarkStore) UpdateBookmarksStatus(ctx context.Context, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	return s.UpdateBookmarksStatusFn(ctx, filter, status)
+}
+
+func (s *BookmarkStore) BatchBookmarks(ctx context.Context, batch *core.BookmarkBatch) (*core.BookmarkBatchResult, error) {
+	return s.BatchBookmarksFn(ctx, batch)
 }
@@ -0,0 +1,27 @@
+package mock
+
+import (
+	"context"
+
+	"bookmarkd/internal/core"
+)
+
+var _ core.IdempotencyKeyStore = (*IdempotencyKeyStore)(nil)
+
+type IdempotencyKeyStore struct {
+	ReserveIdempotencyKeyFn  func(ctx context.Context, key *core.IdempotencyKey) (*core.IdempotencyKey, error)
+	CompleteIdempotencyKeyFn func(ctx context.Context, key *core.IdempotencyKey) error
+	DeleteIdempotencyKeyFn   func(ctx context.Context, key string) error
+}
+
+func (s *IdempotencyKeyStore) ReserveIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) (*core.IdempotencyKey, error) {
+	return s.ReserveIdempotencyKeyFn(ctx, key)
+}
+
+func (s *IdempotencyKeyStore) CompleteIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) error {
+	return s.CompleteIdempotencyKeyFn(ctx, key)
+}
+
+func (s *IdempotencyKeyStore) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	return s.DeleteIdempotencyKeyFn(ctx, key)
+}
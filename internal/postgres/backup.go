@@ -0,0 +1,31 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.BackupStore = (*BackupStore)(nil)
+
+// BackupStore represents the backups of a Postgres database. Backups are
+// taken with the Postgres tools, e.g. pg_dump, so none are kept by bookmarkd.
+type BackupStore struct{}
+
+// NewBackupStore returns a new instance of BackupStore.
+func NewBackupStore() *BackupStore {
+	return &BackupStore{}
+}
+
+// CreateBackup returns ErrBadRequest as Postgres databases are backed up externally.
+func (s *BackupStore) CreateBackup(ctx context.Context) (*core.Backup, error) {
+	return nil, fmt.Errorf("%w: back up postgres databases with pg_dump", bookmarkd.ErrBadRequest)
+}
+
+// FindBackups returns an empty list.
+func (s *BackupStore) FindBackups(ctx context.Context) ([]*core.Backup, error) {
+	return []*core.Backup{}, nil
+}
@@ -0,0 +1,849 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.BookmarkStore = (*BookmarkStore)(nil)
+
+// BookmarkStore represents a service for managing bookmarks.
+type BookmarkStore struct {
+	db *DB
+}
+
+// NewBookmarkStore returns a new instance of BookmarkStore.
+func NewBookmarkStore(db *DB) *BookmarkStore {
+	return &BookmarkStore{db: db}
+}
+
+// FindBookmarkByID retrieves a single bookmark by ID along with associated memberships.
+// Only the bookmark owner & members can see a bookmark. Returns ENOTFOUND if bookmark does
+// not exist or user does not have permission to view it.
+func (s *BookmarkStore) FindBookmarkByID(ctx context.Context, id int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Fetch bookmark object and attach owner user.
+	bookmark, err := findBookmarkByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return bookmark, nil
+}
+
+// FindBookmarks retrieves a list of bookmarks based on a filter. Only returns bookmarks
+// that the user owns or is a member of.
+//
+// Also returns a count of total matching bookmarks which may different from the
+// number of returned bookmarks if the  "Limit" field is set.
+func (s *BookmarkStore) FindBookmarks(ctx context.Context, filter core.BookmarkFilter) ([]*core.Bookmark, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	// Fetch list of matching bookmark objects.
+	bookmarks, n, err := findBookmarks(ctx, tx, filter)
+	if err != nil {
+		return bookmarks, n, err
+	}
+
+	return bookmarks, n, nil
+}
+
+// CreateBookmark creates a new bookmark and assigns the current user as the owner.
+// The owner will automatically be added as a member of the new bookmark.
+//
+// If the user already has a bookmark with the same canonical url then mode
+// decides whether the bookmark is created anyway, rejected with
+// ErrBookmarkDuplicate or merged into the existing bookmark. When merged,
+// bookmark is set to the state of the existing bookmark.
+func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookmark, mode core.DuplicateMode) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Create bookmark and attach associated owner user.
+	if err := createBookmarkWithMode(ctx, tx, bookmark, mode); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateBookmark updates an existing bookmark by ID. Only the bookmark owner can update a bookmark.
+// Returns the new bookmark state even if there was an error during update.
+//
+// Returns ENOTFOUND if bookmark does not exist. Returns EUNAUTHORIZED if user
+// is not the bookmark owner.
+func (s *BookmarkStore) UpdateBookmark(ctx context.Context, id int, upd core.BookmarkUpdate) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Update the bookmark object and attach associated user to returned bookmark.
+	bookmark, err := updateBookmark(ctx, tx, id, upd)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// DeleteBookmark permanently removes a bookmark by ID. Only the bookmark owner may delete
+// a bookmark. Returns ENOTFOUND if bookmark does not exist. Returns EUNAUTHORIZED if
+// user is not the bookmark owner.
+//
+// If version is set the bookmark is only deleted if it is still at that
+// version, otherwise ErrPreconditionFailed is returned.
+func (s *BookmarkStore) DeleteBookmark(ctx context.Context, id int, version *int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookmark, err := deleteBookmark(ctx, tx, id, version)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// FindBookmarkRevisions retrieves the revision history of bookmarks, newest
+// first. Only returns revisions of bookmarks the current user owns.
+func (s *BookmarkStore) FindBookmarkRevisions(ctx context.Context, filter core.BookmarkRevisionFilter) ([]*core.BookmarkRevision, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findBookmarkRevisions(ctx, tx, filter)
+}
+
+// RevertBookmark restores the fields changed by a revision to their previous
+// values. The revert itself is recorded as a new revision.
+//
+// Returns ENOTFOUND if the bookmark or revision does not exist. Returns
+// EUNAUTHORIZED if user is not the bookmark owner.
+func (s *BookmarkStore) RevertBookmark(ctx context.Context, id int, revisionID int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookmark, err := revertBookmark(ctx, tx, id, revisionID)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// FindDuplicateBookmarks returns every group of the current user's bookmarks
+// that share a canonical url, ordered by canonical url.
+func (s *BookmarkStore) FindDuplicateBookmarks(ctx context.Context) ([]*core.BookmarkDuplicates, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findDuplicateBookmarks(ctx, tx)
+}
+
+// UpdateBookmarksStatus sets the status of every bookmark matching filter that
+// does not already have that status. Each change is recorded & published as if
+// the bookmark had been updated individually.
+//
+// Returns the number of bookmarks changed.
+func (s *BookmarkStore) UpdateBookmarksStatus(ctx context.Context, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := updateBookmarksStatus(ctx, tx, filter, status)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// findBookmarkByID is a helper function to retrieve a bookmark by ID.
+// Returns ENOTFOUND if bookmark doesn't exist.
+func findBookmarkByID(ctx context.Context, tx *Tx, id int) (*core.Bookmark, error) {
+	bookmarks, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{ID: &id})
+	if err != nil {
+		return nil, fmt.Errorf("find bookmarks: %w", err)
+	} else if len(bookmarks) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return bookmarks[0], nil
+}
+
+// findBookmarks retrieves a list of matching bookmarks. Also returns a total matching
+// count which may different from the number of results if filter.Limit is set.
+func findBookmarks(ctx context.Context, tx *Tx, filter core.BookmarkFilter) (_ []*core.Bookmark, n int, err error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	where, args := formatBookmarkFilter(ctx, filter)
+
+	// Score search matches. A match in the name weighs more than one in the
+	// description which weighs more than one in the url.
+	relevance, relevanceArgs := "0", []interface{}{}
+	if v := filter.Search; v != nil {
+		relevance = `(strpos(lower(name), lower(?)) > 0)::int * 4 + (strpos(lower(description), lower(?)) > 0)::int * 2 + (strpos(lower(url), lower(?)) > 0)::int`
+		relevanceArgs = []interface{}{*v, *v, *v}
+	}
+
+	// Page through the results using the sort column and cursor.
+	column := "created_at"
+	switch filter.Sort {
+	case core.SortUpdated:
+		column = "updated_at"
+	case core.SortName:
+		column = "name"
+	case core.SortRelevance:
+		column = "relevance"
+	}
+
+	// Relevance is computed, so the cursor is compared to its expression.
+	expr, exprArgs := column, []interface{}(nil)
+	if column == "relevance" {
+		expr, exprArgs = "("+relevance+")", relevanceArgs
+	}
+	keyset, keysetArgs, orderBy, reverse, err := FormatKeysetExpr(column, expr, exprArgs, filter.Descending(), filter.Cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Count the matching bookmarks separately, so the page itself is read
+	// from the index of the sort column starting at the cursor.
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks WHERE `+strings.Join(where, " AND "), args...).Scan(&n); err != nil {
+		return nil, 0, fmt.Errorf("db count bookmarks: %w", FormatError(err))
+	}
+
+	// Execute the query with the cursor & LIMIT/OFFSET injected.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		  id,
+		  user_id,
+		  name,
+		  description,
+		  url,
+		  canonical_url,
+		  status,
+		  starred,
+		  read_at,
+		  version,
+		  created_at,
+		  updated_at,
+		  `+relevance+` AS relevance
+		FROM bookmarks
+		WHERE `+strings.Join(append(where, keyset), " AND ")+`
+		`+orderBy+`
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		append(append(relevanceArgs, args...), keysetArgs...)...,
+	)
+	if err != nil {
+		return nil, n, fmt.Errorf("db select bookmarks: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	// Iterate over rows and deserialize into Bookmark objects.
+	bookmarks := make([]*core.Bookmark, 0)
+	for rows.Next() {
+		var bookmark core.Bookmark
+		if err := rows.Scan(
+			&bookmark.ID,
+			&bookmark.UserID,
+			&bookmark.Name,
+			&bookmark.Description,
+			&bookmark.Url,
+			&bookmark.CanonicalUrl,
+			&bookmark.Status,
+			&bookmark.Starred,
+			(*NullTime)(&bookmark.ReadAt),
+			&bookmark.Version,
+			(*NullTime)(&bookmark.CreatedAt),
+			(*NullTime)(&bookmark.UpdatedAt),
+			&bookmark.Relevance,
+		); err != nil {
+			return nil, 0, fmt.Errorf("db scan bookmark row: %w", FormatError(err))
+		}
+		bookmarks = append(bookmarks, &bookmark)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("db bookmark rows: %w", FormatError(err))
+	}
+
+	if reverse {
+		slices.Reverse(bookmarks)
+	}
+
+	return bookmarks, n, nil
+}
+
+// formatBookmarkFilter returns the WHERE clause parts & their args matching
+// the filter fields of filter to the current user's bookmarks. Each part is
+// AND-ed together. Values are appended to an arg list to avoid SQL injection.
+func formatBookmarkFilter(ctx context.Context, filter core.BookmarkFilter) (where []string, args []interface{}) {
+	where, args = []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.CanonicalUrl; v != nil {
+		where, args = append(where, "canonical_url = ?"), append(args, *v)
+	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, "status = ?"), append(args, *v)
+	}
+	if v := filter.Starred; v != nil {
+		where, args = append(where, "starred = ?"), append(args, *v)
+	}
+	if v := filter.Search; v != nil {
+		pattern := FormatLike(*v)
+		where = append(where, `(name ILIKE ? ESCAPE '\' OR description ILIKE ? ESCAPE '\' OR url ILIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	// Limit to bookmarks user owns.
+	userID := core.GetUserIDFromContext(ctx)
+	where, args = append(where, "user_id = ?"), append(args, UUID(userID))
+
+	return where, args
+}
+
+// createBookmarkWithMode creates a new bookmark unless the user already owns a
+// bookmark with the same canonical url and mode is reject or merge.
+func createBookmarkWithMode(ctx context.Context, tx *Tx, bookmark *core.Bookmark, mode core.DuplicateMode) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	} else if mode == "" || mode == core.DuplicateModeAllow {
+		return createBookmark(ctx, tx, bookmark)
+	}
+
+	// Serialize duplicate checks of the user so a concurrent request can't
+	// insert the same url between the lookup & the insert. Duplicates are
+	// allowed in other modes, so a unique index can't be used. The lock
+	// doesn't conflict with the key share lock taken by inserting bookmarks.
+	userID := core.GetUserIDFromContext(ctx)
+	if userID == "" {
+		return bookmarkd.ErrUnauthorized
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = ? FOR NO KEY UPDATE`, UUID(userID)); err != nil {
+		return fmt.Errorf("db lock user: %w", FormatError(err))
+	}
+
+	// Look up an existing bookmark with the same canonical url.
+	canonicalUrl := tx.CanonicalizeUrl(bookmark.Url)
+	existing, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{CanonicalUrl: &canonicalUrl, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("find duplicate bookmarks: %w", err)
+	} else if len(existing) == 0 {
+		return createBookmark(ctx, tx, bookmark)
+	}
+
+	if mode == core.DuplicateModeReject {
+		return fmt.Errorf("%w: bookmark %d", bookmarkd.ErrBookmarkDuplicate, existing[0].ID)
+	}
+
+	// Merge the submitted fields into the existing bookmark. Only fields that
+	// differ are applied so an identical resubmission doesn't record a revision.
+	var upd core.BookmarkUpdate
+	if bookmark.Name != "" && bookmark.Name != existing[0].Name {
+		upd.Name = &bookmark.Name
+	}
+	if bookmark.Description != "" && bookmark.Description != existing[0].Description {
+		upd.Description = &bookmark.Description
+	}
+
+	merged := existing[0]
+	if upd.Name != nil || upd.Description != nil {
+		if merged, err = updateBookmark(ctx, tx, existing[0].ID, upd); err != nil {
+			return err
+		}
+	}
+	*bookmark = *merged
+
+	return nil
+}
+
+// createBookmark creates a new bookmark.
+func createBookmark(ctx context.Context, tx *Tx, bookmark *core.Bookmark) error {
+	// Assign bookmark to the current user.
+	// Return an error if the user is not currently logged in.
+	userID := core.GetUserIDFromContext(ctx)
+	if userID == "" {
+		return bookmarkd.ErrUnauthorized
+	}
+	bookmark.UserID = userID
+
+	// Set timestamps to current time.
+	bookmark.CreatedAt = tx.Now()
+	bookmark.UpdatedAt = bookmark.CreatedAt
+	bookmark.Version = 1
+
+	// New bookmarks are unread unless a status is given.
+	if bookmark.Status == "" {
+		bookmark.Status = core.BookmarkStatusUnread
+	}
+	bookmark.ReadAt = time.Time{}
+	if bookmark.Status == core.BookmarkStatusRead {
+		bookmark.ReadAt = bookmark.CreatedAt
+	}
+
+	// Perform basic field validation
+	if err := bookmark.Validate(); err != nil {
+		return err
+	}
+	bookmark.CanonicalUrl = tx.CanonicalizeUrl(bookmark.Url)
+
+	// race condition, if user session was valid, then deleted
+	// we should check to ensure the user exists before adding a bookmark
+	// Actaully this should throw a foreign key constraint failed if the user
+	// was deleted from the database.
+	/*
+		if _, err := findUserByID(ctx, tx, bookmark.UserID); err != nil {
+			return fmt.Errorf("create bookmark error: %w", err)
+		}
+	*/
+
+	// Insert row into database & read back the new bookmark ID.
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO bookmarks (
+			user_id,
+		  name,
+		  description,
+		  url,
+		  canonical_url,
+		  status,
+		  starred,
+		  read_at,
+		  version,
+		  created_at,
+		  updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`,
+		bookmark.UserID,
+		bookmark.Name,
+		bookmark.Description,
+		bookmark.Url,
+		bookmark.CanonicalUrl,
+		bookmark.Status,
+		bookmark.Starred,
+		(*NullTime)(&bookmark.ReadAt),
+		bookmark.Version,
+		(*NullTime)(&bookmark.CreatedAt),
+		(*NullTime)(&bookmark.UpdatedAt),
+	).Scan(&bookmark.ID); err != nil {
+		return fmt.Errorf("db insert bookmark: %w", FormatError(err))
+	}
+
+	other := *bookmark
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type:    core.EventTypeBookmarkAdded,
+		Payload: &core.EventTypeBookmarkAddedPayload{Bookmark: &other},
+	})
+
+	return nil
+}
+
+// updateBookmark updates a bookmark by ID. Returns the new state of the bookmark after update.
+func updateBookmark(ctx context.Context, tx *Tx, id int, upd core.BookmarkUpdate) (*core.Bookmark, error) {
+	// Fetch current object state. Return an error if current user is not owner.
+	bookmark, err := findBookmarkByID(ctx, tx, id)
+	if err != nil {
+		return bookmark, err
+	} else if !core.CanEditBookmark(ctx, bookmark) {
+		return bookmark, bookmarkd.ErrUnauthorized
+	} else if v := upd.Version; v != nil && *v != bookmark.Version {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// Capture previous values before they are overwritten.
+	changes := upd.Changes(bookmark)
+
+	// Update fields, if set.
+	if v := upd.Name; v != nil {
+		bookmark.Name = *v
+	}
+	if v := upd.Description; v != nil {
+		bookmark.Description = *v
+	}
+	if v := upd.Url; v != nil {
+		bookmark.Url = *v
+	}
+	if v := upd.Status; v != nil && *v != bookmark.Status {
+		// Track when the bookmark was read. Archiving keeps the read time.
+		switch bookmark.Status = *v; *v {
+		case core.BookmarkStatusRead:
+			bookmark.ReadAt = tx.Now()
+		case core.BookmarkStatusUnread:
+			bookmark.ReadAt = time.Time{}
+		}
+	}
+	if v := upd.Starred; v != nil {
+		bookmark.Starred = *v
+	}
+	bookmark.UpdatedAt = tx.Now()
+	version := bookmark.Version
+	bookmark.Version++
+
+	// Perform basic field validation. The url is only validated when it is
+	// changed so bookmarks with urls saved under older rules can be updated.
+	if err := bookmark.ValidateExceptUrl(); err != nil {
+		return bookmark, err
+	} else if upd.Url != nil {
+		if err := core.ValidateBookmarkUrl(bookmark.Url); err != nil {
+			return bookmark, err
+		}
+	}
+	bookmark.CanonicalUrl = tx.CanonicalizeUrl(bookmark.Url)
+
+	// Execute update query. The version is checked again in case the
+	// bookmark changed since it was read.
+	result, err := tx.ExecContext(ctx, `
+		UPDATE bookmarks
+		SET name = ?,
+				description = ?,
+		  	url = ?,
+		    canonical_url = ?,
+		    status = ?,
+		    starred = ?,
+		    read_at = ?,
+		    version = ?,
+		    updated_at = ?
+		WHERE id = ? AND version = ?
+	`,
+		bookmark.Name,
+		bookmark.Description,
+		bookmark.Url,
+		bookmark.CanonicalUrl,
+		bookmark.Status,
+		bookmark.Starred,
+		(*NullTime)(&bookmark.ReadAt),
+		bookmark.Version,
+		(*NullTime)(&bookmark.UpdatedAt),
+		id,
+		version,
+	)
+	if err != nil {
+		// should we return inconsitent data or nil?
+		return bookmark, fmt.Errorf("db update bookmark: %w", FormatError(err))
+	} else if n, err := result.RowsAffected(); err != nil {
+		return bookmark, fmt.Errorf("db update bookmark: %w", FormatError(err))
+	} else if n == 0 {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// Record the update in the bookmark's revision history.
+	if err := createBookmarkRevision(ctx, tx, &core.BookmarkRevision{
+		BookmarkID: bookmark.ID,
+		UserID:     core.GetUserIDFromContext(ctx),
+		Changes:    changes,
+	}); err != nil {
+		return bookmark, fmt.Errorf("create bookmark revision: %w", err)
+	}
+
+	if upd.Name != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkNameChanged,
+			Payload: &core.EventTypeBookmarkNameChangedPayload{
+				ID:        bookmark.ID,
+				Name:      bookmark.Name,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark name event: %w", err)
+		}
+	}
+
+	if upd.Description != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkDescriptionChanged,
+			Payload: &core.EventTypeBookmarkDescriptionChangedPayload{
+				ID:          bookmark.ID,
+				Description: bookmark.Description,
+				UpdatedAt:   bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark description event: %w", err)
+		}
+	}
+
+	if upd.Url != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkUrlChanged,
+			Payload: &core.EventTypeBookmarkUrlChangedPayload{
+				ID:        bookmark.ID,
+				Url:       bookmark.Url,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark url event: %w", err)
+		}
+	}
+
+	if upd.Status != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkStatusChanged,
+			Payload: &core.EventTypeBookmarkStatusChangedPayload{
+				ID:        bookmark.ID,
+				Status:    bookmark.Status,
+				ReadAt:    bookmark.ReadAt,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark status event: %w", err)
+		}
+	}
+
+	if upd.Starred != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkStarredChanged,
+			Payload: &core.EventTypeBookmarkStarredChangedPayload{
+				ID:        bookmark.ID,
+				Starred:   bookmark.Starred,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark starred event: %w", err)
+		}
+	}
+
+	return bookmark, nil
+}
+
+// updateBookmarksStatus sets the status of all bookmarks matching filter.
+// Bookmarks that already have the status are skipped. The sort & paging
+// fields of filter are ignored.
+func updateBookmarksStatus(ctx context.Context, tx *Tx, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	if err := status.Validate(); err != nil {
+		return 0, err
+	}
+
+	userID := core.GetUserIDFromContext(ctx)
+	where, args := formatBookmarkFilter(ctx, filter)
+	where, args = append(where, "status != ?"), append(args, status)
+	now := tx.Now()
+
+	// Track when the bookmarks were read. Archiving keeps the read time.
+	readAt, readAtArgs := "read_at", []interface{}{}
+	switch status {
+	case core.BookmarkStatusRead:
+		readAt, readAtArgs = "?", []interface{}{(*NullTime)(&now)}
+	case core.BookmarkStatusUnread:
+		readAt = "NULL"
+	}
+
+	// Lock the matching rows & return their previous status along with the
+	// new state.
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE bookmarks
+		SET status = ?,
+		    read_at = `+readAt+`,
+		    version = bookmarks.version + 1,
+		    updated_at = ?
+		FROM (
+		  SELECT id, status
+		  FROM bookmarks
+		  WHERE `+strings.Join(where, " AND ")+`
+		  FOR UPDATE
+		) AS old
+		WHERE bookmarks.id = old.id
+		RETURNING bookmarks.id, old.status, bookmarks.read_at
+	`,
+		append(append(append([]interface{}{status}, readAtArgs...), (*NullTime)(&now)), args...)...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("db update bookmarks status: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	type change struct {
+		payload *core.EventTypeBookmarkStatusChangedPayload
+		old     core.BookmarkStatus
+	}
+	var changes []change
+	for rows.Next() {
+		c := change{payload: &core.EventTypeBookmarkStatusChangedPayload{Status: status, UpdatedAt: now}}
+		if err := rows.Scan(&c.payload.ID, &c.old, (*NullTime)(&c.payload.ReadAt)); err != nil {
+			return 0, fmt.Errorf("db scan bookmark status row: %w", FormatError(err))
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("db bookmark status rows: %w", FormatError(err))
+	}
+	rows.Close()
+
+	// Record each change in the revision history & publish it to the owner of
+	// the bookmark, the current user.
+	for _, c := range changes {
+		if err := createBookmarkRevision(ctx, tx, &core.BookmarkRevision{
+			BookmarkID: c.payload.ID,
+			UserID:     userID,
+			Changes:    core.BookmarkUpdate{Status: &status}.Changes(&core.Bookmark{Status: c.old}),
+		}); err != nil {
+			return 0, fmt.Errorf("create bookmark revision: %w", err)
+		}
+		tx.PublishEvent(userID, core.Event{Type: core.EventTypeBookmarkStatusChanged, Payload: c.payload})
+	}
+	return len(changes), nil
+}
+
+// deleteBookmark permanently deletes a bookmark by ID. Returns EUNAUTHORIZED if user
+// does not own the bookmark. Returns ErrPreconditionFailed if version is set
+// and does not match the bookmark.
+func deleteBookmark(ctx context.Context, tx *Tx, id int, version *int) (*core.Bookmark, error) {
+	// Verify object exists & the current user is the owner.
+	bookmark, err := findBookmarkByID(ctx, tx, id)
+	if err != nil {
+		return bookmark, err
+	} else if !core.CanEditBookmark(ctx, bookmark) {
+		return bookmark, bookmarkd.ErrUnauthorized
+	} else if version != nil && *version != bookmark.Version {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// Remove row from database.
+	if result, err := tx.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = ? AND version = ?`, id, bookmark.Version); err != nil {
+		return bookmark, fmt.Errorf("db delete bookmark: %w", FormatError(err))
+	} else if n, err := result.RowsAffected(); err != nil {
+		return bookmark, fmt.Errorf("db delete bookmark: %w", FormatError(err))
+	} else if n == 0 {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// The row is gone so the event is published to the owner directly.
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type:    core.EventTypeBookmarkRemoved,
+		Payload: &core.EventTypeBookmarkRemovedPayload{ID: id},
+	})
+
+	return bookmark, nil
+}
+
+// findDuplicateBookmarks returns groups of the current user's bookmarks that
+// share a canonical url.
+func findDuplicateBookmarks(ctx context.Context, tx *Tx) ([]*core.BookmarkDuplicates, error) {
+	userID := core.GetUserIDFromContext(ctx)
+
+	// Find canonical urls used by more than one bookmark.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT canonical_url
+		FROM bookmarks
+		WHERE user_id = ?
+		GROUP BY canonical_url
+		HAVING COUNT(*) > 1
+		ORDER BY canonical_url ASC
+	`, UUID(userID))
+	if err != nil {
+		return nil, fmt.Errorf("db select duplicate bookmarks: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	urls := make([]string, 0)
+	for rows.Next() {
+		var canonicalUrl string
+		if err := rows.Scan(&canonicalUrl); err != nil {
+			return nil, fmt.Errorf("db scan duplicate bookmark row: %w", FormatError(err))
+		}
+		urls = append(urls, canonicalUrl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db duplicate bookmark rows: %w", FormatError(err))
+	}
+
+	// Fetch the bookmarks of each group.
+	duplicates := make([]*core.BookmarkDuplicates, 0, len(urls))
+	for _, canonicalUrl := range urls {
+		bookmarks, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{CanonicalUrl: &canonicalUrl})
+		if err != nil {
+			return nil, fmt.Errorf("find bookmarks: %w", err)
+		}
+		duplicates = append(duplicates, &core.BookmarkDuplicates{
+			CanonicalUrl: canonicalUrl,
+			Bookmarks:    bookmarks,
+		})
+	}
+
+	return duplicates, nil
+}
+
+// revertBookmark restores the fields changed by a revision to their previous
+// values. Returns ENOTFOUND if the revision does not belong to the bookmark.
+func revertBookmark(ctx context.Context, tx *Tx, id int, revisionID int) (*core.Bookmark, error) {
+	revision, err := findBookmarkRevisionByID(ctx, tx, id, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the previous values as a regular update so the revert is checked
+	// for ownership, validated and recorded like any other change.
+	bookmark, err := updateBookmark(ctx, tx, id, revision.RevertUpdate())
+	if err != nil {
+		return bookmark, err
+	}
+
+	if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+		Type: core.EventTypeBookmarkReverted,
+		Payload: &core.EventTypeBookmarkRevertedPayload{
+			ID:         bookmark.ID,
+			RevisionID: revision.ID,
+			UpdatedAt:  bookmark.UpdatedAt,
+		},
+	}); err != nil {
+		return bookmark, fmt.Errorf("publish bookmark reverted event: %w", err)
+	}
+
+	return bookmark, nil
+}
+
+// publishBookmarkEvent publishes event to the bookmark members.
+func publishBookmarkEvent(ctx context.Context, tx *Tx, id int, event core.Event) error {
+	// Find owner of the bookmark.
+	rows, err := tx.QueryContext(ctx, `SELECT user_id FROM bookmarks WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("db select user_id: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	// Iterate over users and publish event.
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return fmt.Errorf("generate uuid for event: %w", (err))
+		}
+		tx.PublishEvent(userID.String(), event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("db select user_id rows: %w", FormatError(err))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/postgres"
+	"bookmarkd/utils/require"
+)
+
+// Ensure concurrent requests rejecting duplicates create the bookmark once.
+func Test_BookmarkService_ConcurrentDuplicates(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	s := postgres.NewBookmarkStore(db)
+	userCtx := MustLogin(t, context.Background(), db, "NAME0")
+
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.CreateBookmark(userCtx, &core.Bookmark{Name: "Go", Url: "https://go.dev/"}, core.DuplicateModeReject)
+		}()
+	}
+	wg.Wait()
+
+	var created int
+	for i := range n {
+		if errs[i] == nil {
+			created++
+		} else {
+			require.Equal(t, errors.Is(errs[i], bookmarkd.ErrBookmarkDuplicate), true)
+		}
+	}
+	require.Equal(t, created, 1)
+
+	_, total, err := s.FindBookmarks(userCtx, core.BookmarkFilter{})
+	require.Equal(t, err, nil)
+	require.Equal(t, total, 1)
+}
+
+// Ensure the length limits of the schema match the limits checked by
+// core.Bookmark.Validate(), so rows written through raw SQL are limited alike.
+func Test_BookmarkService_LengthLimits(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	ctx := context.Background()
+	userID := core.GetUserIDFromContext(MustLogin(t, ctx, db, "NAME0"))
+
+	// Writes a bookmark with a field of n characters & returns the error.
+	write := func(t *testing.T, query, field string, n int) error {
+		t.Helper()
+		values := map[string]string{"name": "NAME", "description": "", "url": "https://example.com"}
+		values[field] = strings.Repeat("é", n)
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.Equal(t, err, nil)
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, query, values["name"], values["description"], values["url"], userID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	for _, tt := range []struct {
+		field string
+		max   int
+	}{
+		{"name", core.MaxBookmarkNameLen},
+		{"description", core.MaxBookmarkDescriptionLen},
+		{"url", core.MaxBookmarkUrlLen},
+	} {
+		t.Run(tt.field, func(t *testing.T) {
+			insert := `INSERT INTO bookmarks (name, description, url, user_id, created_at, updated_at) VALUES (?, ?, ?, ?, now(), now())`
+			require.Equal(t, write(t, insert, tt.field, tt.max), nil)
+			require.NotEqual(t, write(t, insert, tt.field, tt.max+1), nil)
+
+			update := `UPDATE bookmarks SET name = ?, description = ?, url = ? WHERE user_id = ?`
+			require.Equal(t, write(t, update, tt.field, tt.max), nil)
+			require.NotEqual(t, write(t, update, tt.field, tt.max+1), nil)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/postgres"
+	"bookmarkd/internal/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.TestStores(t, func(tb testing.TB, events core.EventService, now func() time.Time) *storetest.Stores {
+		db := MustOpenDB(tb)
+		tb.Cleanup(func() { MustCloseDB(tb, db) })
+		db.EventService = events
+		db.Now = now
+
+		return &storetest.Stores{
+			UserStore:           postgres.NewUserStore(db),
+			SessionStore:        postgres.NewSessionStore(db),
+			BookmarkStore:       postgres.NewBookmarkStore(db),
+			IdempotencyKeyStore: postgres.NewIdempotencyKeyStore(db, time.Hour),
+		}
+	})
+}
+
+func TestConformance_RegistrationStore(t *testing.T) {
+	storetest.TestRegistrationStore(t, func(tb testing.TB) core.RegistrationStore {
+		db := MustOpenDB(tb)
+		tb.Cleanup(func() { MustCloseDB(tb, db) })
+		return postgres.NewRegistrationStore(db)
+	})
+}
@@ -0,0 +1,193 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.IdempotencyKeyStore = (*IdempotencyKeyStore)(nil)
+
+// IdempotencyKeyStore represents a service for storing idempotency keys.
+type IdempotencyKeyStore struct {
+	db *DB
+
+	// How long a key is kept after it is first used.
+	TTL time.Duration
+}
+
+// NewIdempotencyKeyStore returns a new instance of IdempotencyKeyStore that
+// keeps keys for ttl.
+func NewIdempotencyKeyStore(db *DB, ttl time.Duration) *IdempotencyKeyStore {
+	return &IdempotencyKeyStore{db: db, TTL: ttl}
+}
+
+// ReserveIdempotencyKey reserves key for the current user. If the user has
+// already used the key & it has not expired then the existing key is returned
+// and nothing is reserved. Expired keys are removed.
+func (s *IdempotencyKeyStore) ReserveIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) (*core.IdempotencyKey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key.UserID = core.GetUserIDFromContext(ctx)
+	if key.UserID == "" {
+		return nil, bookmarkd.ErrUnauthorized
+	}
+
+	if err := deleteExpiredIdempotencyKeys(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	key.Completed, key.StatusCode, key.Header, key.Body = false, 0, nil, nil
+	key.CreatedAt = tx.Now()
+	key.ExpiresAt = key.CreatedAt.Add(s.TTL)
+
+	// Insert the key unless the user already has it. A concurrent insert of
+	// the same key blocks until the other transaction commits, so the
+	// existing key can then be read instead.
+	var userID string
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (
+		  user_id,
+		  key,
+		  fingerprint,
+		  created_at,
+		  expires_at
+		)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, key) DO NOTHING
+		RETURNING user_id
+	`,
+		key.UserID,
+		key.Key,
+		key.Fingerprint,
+		(*NullTime)(&key.CreatedAt),
+		(*NullTime)(&key.ExpiresAt),
+	).Scan(&userID); errors.Is(err, sql.ErrNoRows) {
+		// Keep the removal of expired keys.
+		other, err := findIdempotencyKey(ctx, tx, key.UserID, key.Key)
+		if err != nil {
+			return nil, err
+		}
+		return other, tx.Commit()
+	} else if err != nil {
+		return nil, fmt.Errorf("db insert idempotency key: %w", FormatError(err))
+	}
+
+	return nil, tx.Commit()
+}
+
+// CompleteIdempotencyKey stores the response of a key reserved by the current user.
+func (s *IdempotencyKeyStore) CompleteIdempotencyKey(ctx context.Context, key *core.IdempotencyKey) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	header, err := json.Marshal(key.Header)
+	if err != nil {
+		return fmt.Errorf("encode idempotency key header: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET completed = TRUE,
+		    status_code = ?,
+		    header = ?,
+		    body = ?
+		WHERE user_id = ? AND key = ?
+	`,
+		key.StatusCode,
+		string(header),
+		key.Body,
+		UUID(core.GetUserIDFromContext(ctx)),
+		key.Key,
+	)
+	if err != nil {
+		return fmt.Errorf("db update idempotency key: %w", FormatError(err))
+	} else if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("db update idempotency key: %w", FormatError(err))
+	} else if n == 0 {
+		return bookmarkd.ErrNotFound
+	}
+	key.Completed = true
+
+	return tx.Commit()
+}
+
+// DeleteIdempotencyKey removes a key of the current user.
+func (s *IdempotencyKeyStore) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?
+	`, UUID(core.GetUserIDFromContext(ctx)), key); err != nil {
+		return fmt.Errorf("db delete idempotency key: %w", FormatError(err))
+	}
+	return tx.Commit()
+}
+
+// findIdempotencyKey returns a key of a user. Returns ErrNotFound if it does not exist.
+func findIdempotencyKey(ctx context.Context, tx *Tx, userID, key string) (*core.IdempotencyKey, error) {
+	var k core.IdempotencyKey
+	var header string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+		  user_id,
+		  key,
+		  fingerprint,
+		  completed,
+		  status_code,
+		  header,
+		  body,
+		  created_at,
+		  expires_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND key = ?
+	`, UUID(userID), key).Scan(
+		&k.UserID,
+		&k.Key,
+		&k.Fingerprint,
+		&k.Completed,
+		&k.StatusCode,
+		&header,
+		&k.Body,
+		(*NullTime)(&k.CreatedAt),
+		(*NullTime)(&k.ExpiresAt),
+	); err == sql.ErrNoRows {
+		return nil, bookmarkd.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("db select idempotency key: %w", FormatError(err))
+	}
+
+	if err := json.Unmarshal([]byte(header), &k.Header); err != nil {
+		return nil, fmt.Errorf("decode idempotency key header: %w", err)
+	}
+	return &k, nil
+}
+
+// deleteExpiredIdempotencyKeys removes every key which has expired.
+func deleteExpiredIdempotencyKeys(ctx context.Context, tx *Tx) error {
+	now := tx.Now()
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM idempotency_keys WHERE expires_at <= ?
+	`, (*NullTime)(&now)); err != nil {
+		return fmt.Errorf("db delete expired idempotency keys: %w", FormatError(err))
+	}
+	return nil
+}
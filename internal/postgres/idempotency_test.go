@@ -0,0 +1,45 @@
+package postgres_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/postgres"
+	"bookmarkd/utils/require"
+)
+
+// Ensure concurrent requests with the same key reserve it once & the others
+// get the reserved key instead of an error.
+func Test_IdempotencyKeyStore_ConcurrentReserve(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	k := postgres.NewIdempotencyKeyStore(db, time.Hour)
+	userCtx := MustLogin(t, context.Background(), db, "NAME0")
+
+	const n = 16
+	var wg sync.WaitGroup
+	others, errs := make([]*core.IdempotencyKey, n), make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			others[i], errs[i] = k.ReserveIdempotencyKey(userCtx, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP1"})
+		}()
+	}
+	wg.Wait()
+
+	var reserved int
+	for i := range n {
+		require.Equal(t, errs[i], nil)
+		if others[i] == nil {
+			reserved++
+		} else {
+			require.Equal(t, others[i].Fingerprint, "FP1")
+			require.Equal(t, others[i].Completed, false)
+		}
+	}
+	require.Equal(t, reserved, 1)
+}
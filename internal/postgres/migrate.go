@@ -0,0 +1,357 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+//go:embed migration/*.sql
+var migrationFS embed.FS
+
+// Ensure database implements interface.
+var _ core.Migrator = (*DB)(nil)
+
+// migrationLockID is the key of the advisory lock held while migrating so
+// instances started at the same time don't run the migrations twice.
+const migrationLockID = 0x626f6f6b6d61726b // "bookmark"
+
+// migrationFile represents a pair of embedded up & down migration files.
+//
+// Migration files are embedded in the postgres/migration folder and are named
+// by their version, e.g. 0000001.up.sql & 0000001.down.sql.
+type migrationFile struct {
+	Version  int
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// appliedMigration represents a migration recorded in the database.
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt *time.Time
+}
+
+// queryer is implemented by *sql.DB, *sql.Conn & *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// migrate applies pending migrations when the database is opened. It refuses
+// to run against a database migrated by a newer version.
+func (db *DB) migrate(ctx context.Context) error {
+	migrations, err := db.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if m.Status == core.MigrationStatusModified {
+			log.Printf("migration %07d was modified after it was applied", m.Version)
+		}
+	}
+
+	_, err = db.Migrate(ctx, core.MigrateOptions{})
+	return err
+}
+
+// Migrations returns the status of each migration, ordered by version.
+// Migrations applied by a newer version are included with the unknown status.
+func (db *DB) Migrations(ctx context.Context) ([]*core.Migration, error) {
+	files, err := migrationFiles()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db.db)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]*core.Migration, 0, len(files))
+	for _, f := range files {
+		m := &core.Migration{Version: f.Version, Status: core.MigrationStatusPending, Checksum: f.Checksum}
+		if a := applied[f.Version]; a != nil {
+			m.Status, m.AppliedAt = core.MigrationStatusApplied, a.AppliedAt
+			if a.Checksum != f.Checksum {
+				m.Status = core.MigrationStatusModified
+			}
+		}
+		migrations = append(migrations, m)
+	}
+
+	for version, a := range applied {
+		if !slices.ContainsFunc(files, func(f *migrationFile) bool { return f.Version == version }) {
+			migrations = append(migrations, &core.Migration{Version: version, Status: core.MigrationStatusUnknown, Checksum: a.Checksum, AppliedAt: a.AppliedAt})
+		}
+	}
+
+	slices.SortFunc(migrations, func(a, b *core.Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// CheckMigrations returns an error if the database isn't at the latest
+// version known to this build, e.g. because migrations were skipped or the
+// database was migrated by a newer version. Modified migrations are allowed.
+func (db *DB) CheckMigrations(ctx context.Context) error {
+	migrations, err := db.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		switch m.Status {
+		case core.MigrationStatusPending:
+			return fmt.Errorf("migration %07d is pending", m.Version)
+		case core.MigrationStatusUnknown:
+			return fmt.Errorf("%w: migration %07d is unknown to this version", bookmarkd.ErrDatabaseNewer, m.Version)
+		}
+	}
+	return nil
+}
+
+// Migrate applies & reverts migrations to reach the target version & returns
+// the steps taken. Each step runs in its own transaction so a failed step
+// leaves the database at the previous version. With DryRun set, all steps run
+// in a single transaction which is rolled back.
+//
+// An advisory lock is held throughout so concurrent instances wait for each
+// other & plan their steps once the other instance is done.
+func (db *DB) Migrate(ctx context.Context, opts core.MigrateOptions) ([]*core.MigrationStep, error) {
+	files, err := migrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	target := -1
+	if len(files) > 0 {
+		target = files[len(files)-1].Version
+	}
+	if opts.Target != nil {
+		target = *opts.Target
+		if target != -1 && !slices.ContainsFunc(files, func(f *migrationFile) bool { return f.Version == target }) {
+			return nil, fmt.Errorf("%w: unknown migration version %d", bookmarkd.ErrInvalidInput, target)
+		}
+	}
+
+	if opts.DryRun {
+		tx, err := db.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+			return nil, fmt.Errorf("lock migrations: %w", err)
+		} else if err := initMigrations(ctx, tx); err != nil {
+			return nil, err
+		}
+		steps, err := planMigrations(ctx, tx, files, target)
+		if err != nil {
+			return nil, err
+		}
+		for _, step := range steps {
+			if err := db.runMigrationStep(ctx, tx, step); err != nil {
+				return nil, fmt.Errorf("migration error: name=%q err=%w", step.Name, err)
+			}
+		}
+		return steps, nil
+	}
+
+	// Session level advisory locks belong to a connection, so every step
+	// runs on the connection holding the lock.
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("lock migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if err := withTx(ctx, conn, func(tx *sql.Tx) error { return initMigrations(ctx, tx) }); err != nil {
+		return nil, err
+	}
+	steps, err := planMigrations(ctx, conn, files, target)
+	if err != nil {
+		return nil, err
+	}
+	for _, step := range steps {
+		if err := withTx(ctx, conn, func(tx *sql.Tx) error { return db.runMigrationStep(ctx, tx, step) }); err != nil {
+			return nil, fmt.Errorf("migration error: name=%q err=%w", step.Name, err)
+		}
+	}
+	return steps, nil
+}
+
+// withTx runs fn in a transaction on conn & commits it if fn succeeds.
+func withTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// planMigrations returns the steps to reach the target version: applied
+// migrations after the target are reverted newest first, then pending
+// migrations up to the target are applied oldest first.
+func planMigrations(ctx context.Context, q queryer, files []*migrationFile, target int) ([]*core.MigrationStep, error) {
+	applied, err := appliedMigrations(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for version := range applied {
+		if !slices.ContainsFunc(files, func(f *migrationFile) bool { return f.Version == version }) {
+			return nil, fmt.Errorf("%w: migration %07d is unknown to this version", bookmarkd.ErrDatabaseNewer, version)
+		}
+	}
+
+	var steps []*core.MigrationStep
+	for i := len(files) - 1; i >= 0; i-- {
+		if f := files[i]; f.Version > target && applied[f.Version] != nil {
+			steps = append(steps, &core.MigrationStep{Version: f.Version, Direction: core.MigrationDown, Name: f.Down})
+		}
+	}
+	for _, f := range files {
+		if f.Version <= target && applied[f.Version] == nil {
+			steps = append(steps, &core.MigrationStep{Version: f.Version, Direction: core.MigrationUp, Name: f.Up})
+		}
+	}
+	return steps, nil
+}
+
+// runMigrationStep executes a migration file & records or removes the
+// migration in the 'migrations' table.
+func (db *DB) runMigrationStep(ctx context.Context, tx *sql.Tx, step *core.MigrationStep) error {
+	buf, err := fs.ReadFile(migrationFS, step.Name)
+	if err != nil {
+		return err
+	} else if _, err := tx.ExecContext(ctx, string(buf)); err != nil {
+		return err
+	}
+
+	if step.Direction == core.MigrationDown {
+		_, err := tx.ExecContext(ctx, `DELETE FROM migrations WHERE version = $1`, step.Version)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO migrations (version, checksum, applied_at) VALUES ($1, $2, $3)`,
+		step.Version, checksum(buf), db.Now().UTC())
+	return err
+}
+
+// initMigrations creates the 'migrations' table.
+func initMigrations(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS migrations (
+			version    INTEGER PRIMARY KEY,
+			checksum   TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("cannot create migrations table: %w", err)
+	}
+	return nil
+}
+
+// migrationFiles returns the embedded migrations ordered by version. Every
+// migration must have both an up & a down file.
+func migrationFiles() ([]*migrationFile, error) {
+	names, err := fs.Glob(migrationFS, "migration/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*migrationFile
+	byVersion := make(map[int]*migrationFile)
+	for _, name := range names {
+		base, direction, ok := strings.Cut(strings.TrimSuffix(path.Base(name), ".sql"), ".")
+		version, err := strconv.Atoi(base)
+		if !ok || err != nil || (direction != core.MigrationUp && direction != core.MigrationDown) {
+			return nil, fmt.Errorf("invalid migration file name: %q", name)
+		}
+
+		f := byVersion[version]
+		if f == nil {
+			f = &migrationFile{Version: version}
+			byVersion[version] = f
+			files = append(files, f)
+		}
+
+		if direction == core.MigrationUp {
+			buf, err := fs.ReadFile(migrationFS, name)
+			if err != nil {
+				return nil, err
+			}
+			f.Up, f.Checksum = name, checksum(buf)
+		} else {
+			f.Down = name
+		}
+	}
+
+	for _, f := range files {
+		if f.Up == "" || f.Down == "" {
+			return nil, fmt.Errorf("migration %07d requires an up & a down file", f.Version)
+		}
+	}
+	slices.SortFunc(files, func(a, b *migrationFile) int { return a.Version - b.Version })
+	return files, nil
+}
+
+// appliedMigrations returns the migrations recorded in the database by
+// version. The migrations table does not exist until the first migration runs.
+func appliedMigrations(ctx context.Context, q queryer) (map[int]*appliedMigration, error) {
+	applied := make(map[int]*appliedMigration)
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT to_regclass('migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("db select migrations table: %w", FormatError(err))
+	} else if !exists {
+		return applied, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT version, checksum, applied_at FROM migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("db select migrations: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		m := &appliedMigration{AppliedAt: &appliedAt}
+		if err := rows.Scan(&version, &m.Checksum, &appliedAt); err != nil {
+			return nil, fmt.Errorf("db scan migration: %w", FormatError(err))
+		}
+		applied[version] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db migrations rows: %w", FormatError(err))
+	}
+	return applied, nil
+}
+
+// checksum returns the hex encoded SHA-256 checksum of buf.
+func checksum(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,6 @@
+DROP TABLE registrations;
+DROP TABLE idempotency_keys;
+DROP TABLE bookmark_revisions;
+DROP TABLE bookmarks;
+DROP TABLE sessions;
+DROP TABLE users;
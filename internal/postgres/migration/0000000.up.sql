@@ -0,0 +1,87 @@
+-- The Postgres schema starts out equivalent to the sqlite schema after all
+-- of its migrations. Names & usernames use the "C" collation so they sort
+-- the same as in sqlite.
+CREATE TABLE users (
+	id          UUID PRIMARY KEY,
+	username    TEXT COLLATE "C" NOT NULL UNIQUE,
+	seed        TEXT NOT NULL,
+	disabled_at TIMESTAMPTZ,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+CREATE INDEX users_updated_at_idx ON users (updated_at, id);
+
+CREATE TABLE sessions (
+	id            BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	refresh_token TEXT NOT NULL,
+	expires_at    TIMESTAMPTZ NOT NULL,
+	created_at    TIMESTAMPTZ NOT NULL,
+	updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX sessions_refresh_token_idx ON sessions (refresh_token);
+CREATE INDEX sessions_user_id_created_at_idx ON sessions (user_id, created_at, id);
+CREATE INDEX sessions_user_id_updated_at_idx ON sessions (user_id, updated_at, id);
+
+CREATE TABLE bookmarks (
+	id            BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	user_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name          TEXT COLLATE "C" NOT NULL CONSTRAINT bookmarks_name_check CHECK (length(name) <= 255),
+	description   TEXT NOT NULL DEFAULT '' CONSTRAINT bookmarks_description_check CHECK (length(description) <= 255),
+	url           TEXT NOT NULL CONSTRAINT bookmarks_url_check CHECK (length(url) <= 8192),
+	canonical_url TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL DEFAULT 'unread',
+	starred       BOOLEAN NOT NULL DEFAULT FALSE,
+	read_at       TIMESTAMPTZ,
+	version       INTEGER NOT NULL DEFAULT 1,
+	created_at    TIMESTAMPTZ NOT NULL,
+	updated_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX bookmarks_user_id_idx ON bookmarks (user_id);
+CREATE INDEX bookmarks_user_id_canonical_url_idx ON bookmarks (user_id, canonical_url);
+CREATE INDEX bookmarks_user_id_status_idx ON bookmarks (user_id, status);
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks (user_id, created_at, id);
+CREATE INDEX bookmarks_user_id_updated_at_idx ON bookmarks (user_id, updated_at, id);
+CREATE INDEX bookmarks_user_id_name_idx ON bookmarks (user_id, name, id);
+
+CREATE TABLE bookmark_revisions (
+	id          BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	bookmark_id BIGINT NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+	user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	changes     JSONB NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX bookmark_revisions_bookmark_id_idx ON bookmark_revisions (bookmark_id);
+
+CREATE TABLE idempotency_keys (
+	user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	key         TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	completed   BOOLEAN NOT NULL DEFAULT FALSE,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	header      TEXT NOT NULL DEFAULT '{}',
+	body        BYTEA,
+	created_at  TIMESTAMPTZ NOT NULL,
+	expires_at  TIMESTAMPTZ NOT NULL,
+
+	PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+-- Registrations are kept in memory by the sqlite backend. They are stored
+-- here so a registration can be finished on any instance sharing the database.
+CREATE TABLE registrations (
+	id         UUID PRIMARY KEY,
+	username   TEXT NOT NULL,
+	seed       TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX registrations_expires_at_idx ON registrations (expires_at);
@@ -0,0 +1,291 @@
+package postgres_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/postgres"
+	"bookmarkd/utils/require"
+)
+
+// testDSN is the database the tests run against. Each test uses a schema of
+// its own. Tests are skipped if it is blank.
+//
+// The DSN is read from $BOOKMARKD_TEST_POSTGRES_DSN. Otherwise a temporary
+// server is started if the postgres binaries are found in $PATH.
+var testDSN string
+
+func TestMain(m *testing.M) {
+	testDSN = os.Getenv("BOOKMARKD_TEST_POSTGRES_DSN")
+
+	stop := func() {}
+	if testDSN == "" {
+		var err error
+		if testDSN, stop, err = startServer(); err != nil {
+			fmt.Fprintf(os.Stderr, "start postgres: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// startServer initializes & starts a temporary server listening on a unix
+// socket. Returns a blank DSN if initdb or pg_ctl are not installed.
+func startServer() (dsn string, stop func(), err error) {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		return "", func() {}, nil
+	}
+	pgctl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		return "", func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "bookmarkd-postgres-")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+
+	if out, err := exec.Command(initdb, "-D", data, "-U", "postgres", "-A", "trust").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %w: %s", err, out)
+	}
+
+	opts := fmt.Sprintf("-k %s -c listen_addresses=''", dir)
+	if out, err := exec.Command(pgctl, "-D", data, "-o", opts, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %w: %s", err, out)
+	}
+
+	stop = func() {
+		exec.Command(pgctl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+	return "postgres://postgres@/postgres?host=" + url.QueryEscape(dir), stop, nil
+}
+
+func TestDB(t *testing.T) {
+	db := MustOpenDB(t)
+	MustCloseDB(t, db)
+}
+
+func TestDB_Migrations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Applied", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.NotEqual(t, len(migrations), 0)
+		require.Equal(t, migrations[0].Version, 0)
+		for _, m := range migrations {
+			require.Equal(t, m.Status, core.MigrationStatusApplied)
+			require.NotEqual(t, m.AppliedAt, nil)
+		}
+		require.Equal(t, db.CheckMigrations(ctx), nil)
+	})
+
+	// Ensure all migrations can be reverted & applied again.
+	t.Run("Down", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+
+		target := -1
+		steps, err := db.Migrate(ctx, core.MigrateOptions{Target: &target})
+		require.Equal(t, err, nil)
+		require.Equal(t, len(steps), len(migrations))
+		require.Equal(t, steps[0].Direction, core.MigrationDown)
+		require.NotEqual(t, db.CheckMigrations(ctx), nil)
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.Equal(t, err, nil)
+		var exists bool
+		require.Equal(t, tx.QueryRowContext(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists), nil)
+		require.Equal(t, exists, false)
+		tx.Rollback()
+
+		steps, err = db.Migrate(ctx, core.MigrateOptions{})
+		require.Equal(t, err, nil)
+		require.Equal(t, len(steps), len(migrations))
+		require.Equal(t, steps[0].Direction, core.MigrationUp)
+		require.Equal(t, db.CheckMigrations(ctx), nil)
+	})
+
+	// Ensure a dry run reports the steps without changing the database.
+	t.Run("DryRun", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		target := -1
+		steps, err := db.Migrate(ctx, core.MigrateOptions{Target: &target, DryRun: true})
+		require.Equal(t, err, nil)
+		require.NotEqual(t, len(steps), 0)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		for _, m := range migrations {
+			require.Equal(t, m.Status, core.MigrationStatusApplied)
+		}
+
+		target = 9999999
+		_, err = db.Migrate(ctx, core.MigrateOptions{Target: &target})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+	})
+
+	// Ensure edited migration files are reported.
+	t.Run("Modified", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		MustExec(t, ctx, db, `UPDATE migrations SET checksum = 'x' WHERE version = 0`)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, migrations[0].Status, core.MigrationStatusModified)
+		require.Equal(t, db.CheckMigrations(ctx), nil)
+	})
+
+	// Ensure migrations are only applied once.
+	t.Run("Reopen", func(t *testing.T) {
+		db := MustOpenDB(t)
+		MustCloseDB(t, db)
+
+		db = postgres.NewDB(db.DSN)
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		MustCloseDB(t, db)
+	})
+
+	// Ensure a database migrated by a newer version is not opened.
+	t.Run("ErrDatabaseNewer", func(t *testing.T) {
+		db := MustOpenDB(t)
+		MustExec(t, ctx, db, `INSERT INTO migrations (version, checksum, applied_at) VALUES (9999999, '', now())`)
+		MustCloseDB(t, db)
+
+		db = postgres.NewDB(db.DSN)
+		err := db.Open()
+		require.Equal(t, errors.Is(err, bookmarkd.ErrDatabaseNewer), true)
+		db.Close()
+	})
+}
+
+func TestRebind(t *testing.T) {
+	require.Equal(t, postgres.Rebind(`SELECT * FROM t WHERE a = ? AND b IN (?, ?)`), `SELECT * FROM t WHERE a = $1 AND b IN ($2, $3)`)
+	require.Equal(t, postgres.Rebind(`SELECT 1`), `SELECT 1`)
+}
+
+// MustOpenDB returns a database using a new, empty schema. The schema is
+// dropped once the test completes. Skips the test if no server is available.
+func MustOpenDB(tb testing.TB) *postgres.DB {
+	tb.Helper()
+	if testDSN == "" {
+		tb.Skip("postgres not available, set BOOKMARKD_TEST_POSTGRES_DSN")
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		tb.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(buf)
+
+	admin, err := sql.Open("pgx", testDSN)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		admin.Close()
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		defer admin.Close()
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			tb.Error(err)
+		}
+	})
+
+	db := postgres.NewDB(withSearchPath(testDSN, schema))
+	if err := db.Open(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return db
+}
+
+// withSearchPath returns dsn with the search_path set to schema.
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	} else if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
+
+// MustExec executes query in a transaction. Fatal on error.
+func MustExec(tb testing.TB, ctx context.Context, db *postgres.DB, query string) {
+	tb.Helper()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tb.Fatal(err)
+	} else if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func MustCloseDB(tb testing.TB, db *postgres.DB) {
+	tb.Helper()
+	if err := db.Close(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// MustLogin creates a user with a session & returns a context acting as the
+// user. Fatal on error.
+func MustLogin(tb testing.TB, ctx context.Context, db *postgres.DB, username string) context.Context {
+	tb.Helper()
+
+	user := &core.User{Username: username, Seed: "random_seed"}
+	if err := postgres.NewUserStore(db).CreateUser(ctx, user); err != nil {
+		tb.Fatal(err)
+	}
+
+	session := &core.Session{UserID: user.ID}
+	if err := postgres.NewSessionStore(db).CreateSession(ctx, session); err != nil {
+		tb.Fatal(err)
+	}
+
+	return core.NewContextWithSession(ctx, core.SessionContext{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Ensure service implements interface.
+var _ core.RegistrationStore = (*RegistrationStore)(nil)
+
+// RegistrationStore represents a service for registration attempts. Unlike
+// the in-memory store, registrations are shared by every instance using the
+// database so a registration may be finished by another instance.
+type RegistrationStore struct {
+	db *DB
+
+	// How long a registration may be finished after it was started.
+	TTL time.Duration
+}
+
+// NewRegistrationStore returns a new instance of RegistrationStore.
+func NewRegistrationStore(db *DB) *RegistrationStore {
+	return &RegistrationStore{db: db, TTL: 5 * time.Minute}
+}
+
+// StartRegistration starts a registration for username with a new seed.
+// Expired registrations are removed.
+func (s *RegistrationStore) StartRegistration(username string) (*core.Registration, error) {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := tx.Now()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM registrations WHERE expires_at <= ?`, (*NullTime)(&now)); err != nil {
+		return nil, fmt.Errorf("db delete expired registrations: %w", FormatError(err))
+	}
+
+	seed, err := core.NewSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	r := core.Registration{
+		ID:        id,
+		Username:  username,
+		Seed:      seed,
+		ExpiresAt: now.Add(s.TTL),
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO registrations (
+		  id,
+		  username,
+		  seed,
+		  expires_at
+		)
+		VALUES (?, ?, ?, ?)
+	`,
+		r.ID,
+		r.Username,
+		r.Seed,
+		(*NullTime)(&r.ExpiresAt),
+	); err != nil {
+		return nil, fmt.Errorf("db insert registration: %w", FormatError(err))
+	}
+
+	return &r, tx.Commit()
+}
+
+// FindRegistrationByID returns a registration which has not expired.
+// Returns ENOTFOUND if it does not exist.
+func (s *RegistrationStore) FindRegistrationByID(id string) (*core.Registration, error) {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var r core.Registration
+	now := tx.Now()
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+		  id,
+		  username,
+		  seed,
+		  expires_at
+		FROM registrations
+		WHERE id = ? AND expires_at > ?
+	`, UUID(id), (*NullTime)(&now)).Scan(
+		&r.ID,
+		&r.Username,
+		&r.Seed,
+		(*NullTime)(&r.ExpiresAt),
+	); err == sql.ErrNoRows {
+		return nil, fmt.Errorf("find registration: %w", bookmarkd.ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("db select registration: %w", FormatError(err))
+	}
+
+	return &r, nil
+}
+
+// DeleteRegistration removes a registration.
+func (s *RegistrationStore) DeleteRegistration(id string) error {
+	ctx := context.Background()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM registrations WHERE id = ?`, UUID(id)); err != nil {
+		return fmt.Errorf("db delete registration: %w", FormatError(err))
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// findBookmarkRevisionByID is a helper function to retrieve a revision of a
+// bookmark. Returns ENOTFOUND if the revision doesn't exist.
+func findBookmarkRevisionByID(ctx context.Context, tx *Tx, bookmarkID, id int) (*core.BookmarkRevision, error) {
+	revisions, _, err := findBookmarkRevisions(ctx, tx, core.BookmarkRevisionFilter{ID: &id, BookmarkID: &bookmarkID})
+	if err != nil {
+		return nil, fmt.Errorf("find bookmark revisions: %w", err)
+	} else if len(revisions) == 0 {
+		return nil, bookmarkd.ErrNotFound
+	}
+	return revisions[0], nil
+}
+
+// findBookmarkRevisions retrieves a list of matching revisions, newest first.
+// Also returns a total matching count which may different from the number of
+// results if filter.Limit is set.
+func findBookmarkRevisions(ctx context.Context, tx *Tx, filter core.BookmarkRevisionFilter) (_ []*core.BookmarkRevision, n int, err error) {
+	// Build WHERE clause. Each part of the WHERE clause is AND-ed together.
+	// Values are appended to an arg list to avoid SQL injection.
+	where, args := []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "r.id = ?"), append(args, *v)
+	}
+	if v := filter.BookmarkID; v != nil {
+		where, args = append(where, "r.bookmark_id = ?"), append(args, *v)
+	}
+
+	// Limit to revisions of bookmarks the user owns.
+	userID := core.GetUserIDFromContext(ctx)
+	where, args = append(where, "b.user_id = ?"), append(args, UUID(userID))
+
+	// Count the matching revisions separately, so the page itself is read
+	// from the index in id order.
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM bookmark_revisions r
+		INNER JOIN bookmarks b ON b.id = r.bookmark_id
+		WHERE `+strings.Join(where, " AND "),
+		args...,
+	).Scan(&n); err != nil {
+		return nil, 0, fmt.Errorf("db count bookmark revisions: %w", FormatError(err))
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+		  r.id,
+		  r.bookmark_id,
+		  r.user_id,
+		  r.changes,
+		  r.created_at
+		FROM bookmark_revisions r
+		INNER JOIN bookmarks b ON b.id = r.bookmark_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY r.id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, fmt.Errorf("db select bookmark revisions: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	// Iterate over rows and deserialize into BookmarkRevision objects.
+	revisions := make([]*core.BookmarkRevision, 0)
+	for rows.Next() {
+		var revision core.BookmarkRevision
+		var changes string
+		if err := rows.Scan(
+			&revision.ID,
+			&revision.BookmarkID,
+			&revision.UserID,
+			&changes,
+			(*NullTime)(&revision.CreatedAt),
+		); err != nil {
+			return nil, 0, fmt.Errorf("db scan bookmark revision row: %w", FormatError(err))
+		}
+
+		if err := json.Unmarshal([]byte(changes), &revision.Changes); err != nil {
+			return nil, 0, fmt.Errorf("decode bookmark revision changes: %w", err)
+		}
+
+		revisions = append(revisions, &revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("db bookmark revision rows: %w", FormatError(err))
+	}
+
+	return revisions, n, nil
+}
+
+// createBookmarkRevision records a revision of a bookmark.
+func createBookmarkRevision(ctx context.Context, tx *Tx, revision *core.BookmarkRevision) error {
+	revision.CreatedAt = tx.Now()
+
+	changes, err := json.Marshal(revision.Changes)
+	if err != nil {
+		return fmt.Errorf("encode bookmark revision changes: %w", err)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO bookmark_revisions (
+		  bookmark_id,
+		  user_id,
+		  changes,
+		  created_at
+		)
+		VALUES (?, ?, ?, ?)
+		RETURNING id
+	`,
+		revision.BookmarkID,
+		revision.UserID,
+		string(changes),
+		(*NullTime)(&revision.CreatedAt),
+	).Scan(&revision.ID); err != nil {
+		return fmt.Errorf("db insert bookmark revision: %w", FormatError(err))
+	}
+
+	return nil
+}
@@ -12,34 +12,63 @@ import (
 // ErrorResponse is the response that represents an error.
 type ErrorResponse struct {
 	Error string `json:"error"`
+
+	// Name of the offending field for validation errors.
+	Field string `json:"field,omitempty"`
 }
 
 // Error prints & optionally logs an error message.
 func EncodeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := NewErrorResponse(r, err)
+	EncodeJson(w, status, resp)
+}
+
+// NewErrorResponse returns the status code & response body representing err.
+// Internal & unexpected errors are logged and reported.
+func NewErrorResponse(r *http.Request, err error) (int, *ErrorResponse) {
 	oplog := httplog.LogEntry(r.Context())
 
 	switch {
 	case errors.Is(err, bookmarkd.ErrInternal):
 		oplog.Error("internal error", "err", err)
 		bookmarkd.ReportError(r.Context(), err, r)
-		EncodeJson(w, http.StatusInternalServerError, &ErrorResponse{Error: "Internal error"})
+		return http.StatusInternalServerError, &ErrorResponse{Error: "Internal error"}
 	case errors.Is(err, bookmarkd.ErrNotFound):
-		EncodeJson(w, http.StatusNotFound, &ErrorResponse{Error: err.Error()})
+		return http.StatusNotFound, &ErrorResponse{Error: err.Error()}
 	case errors.Is(err, bookmarkd.ErrUnauthorized):
-		EncodeJson(w, http.StatusUnauthorized, &ErrorResponse{Error: err.Error()})
+		return http.StatusUnauthorized, &ErrorResponse{Error: err.Error()}
 	case errors.Is(err, bookmarkd.ErrForbidden):
-		EncodeJson(w, http.StatusForbidden, &ErrorResponse{Error: err.Error()})
+		return http.StatusForbidden, &ErrorResponse{Error: err.Error()}
 	case errors.Is(err, bookmarkd.ErrBadRequest):
-		EncodeJson(w, http.StatusBadRequest, &ErrorResponse{Error: err.Error()})
+		return http.StatusBadRequest, &ErrorResponse{Error: err.Error()}
 	case errors.Is(err, bookmarkd.ErrInvalidInput):
-		EncodeJson(w, http.StatusNotAcceptable, &ErrorResponse{Error: err.Error()})
+		resp := &ErrorResponse{Error: err.Error()}
+		var verr *bookmarkd.ValidationError
+		if errors.As(err, &verr) {
+			resp.Field = verr.Field
+		}
+		return http.StatusNotAcceptable, resp
+	case errors.Is(err, bookmarkd.ErrUnavailable):
+		return http.StatusServiceUnavailable, &ErrorResponse{Error: err.Error()}
+	case errors.Is(err, bookmarkd.ErrPreconditionFailed):
+		return http.StatusPreconditionFailed, &ErrorResponse{Error: err.Error()}
+	case errors.Is(err, bookmarkd.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired, &ErrorResponse{Error: err.Error()}
+	case errors.Is(err, bookmarkd.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, &ErrorResponse{Error: err.Error()}
+	case errors.Is(err, bookmarkd.ErrIdempotencyKeyInProgress):
+		return http.StatusConflict, &ErrorResponse{Error: err.Error()}
+	case errors.Is(err, bookmarkd.ErrBookmarkDuplicate):
+		return http.StatusConflict, &ErrorResponse{Error: err.Error()}
+	case errors.Is(err, bookmarkd.ErrConflict):
+		return http.StatusConflict, &ErrorResponse{Error: err.Error()}
 	case errors.Is(err, bookmarkd.ErrUsersUsernameConflict):
-		EncodeJson(w, http.StatusNotAcceptable, &ErrorResponse{Error: err.Error()})
+		return http.StatusNotAcceptable, &ErrorResponse{Error: err.Error()}
 
 	// not one of "our" errors, most likely an unhanded package related error
 	default:
 		oplog.Error("unhandled error", "err", err)
 		bookmarkd.ReportError(r.Context(), err, r)
-		EncodeJson(w, http.StatusInternalServerError, &ErrorResponse{Error: "Internal error"})
+		return http.StatusInternalServerError, &ErrorResponse{Error: "Internal error"}
 	}
 }
@@ -0,0 +1,73 @@
+package encoder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EncodeJsonETag writes v as JSON with an ETag header. If etag is empty a
+// weak tag is derived from the encoded body.
+//
+// Responds with 304 Not Modified instead if the tag matches the request's
+// If-None-Match header.
+func EncodeJsonETag[T any](w http.ResponseWriter, r *http.Request, status int, v T, etag string) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+
+	if etag == "" {
+		sum := sha256.Sum256(buf.Bytes())
+		etag = fmt.Sprintf(`W/"%x"`, sum[:16])
+	}
+	w.Header().Set("ETag", etag)
+
+	if ETagMatch(r.Header.Get("If-None-Match"), etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write json: %w", err)
+	}
+	return nil
+}
+
+// ETagMatch returns true if etag is listed in header, the value of an
+// If-Match or If-None-Match header. "*" matches any tag. Weak tags only match
+// when weak is true, as required for If-None-Match.
+func ETagMatch(header, etag string, weak bool) bool {
+	for _, tag := range ParseETags(header) {
+		if tag == "*" {
+			return true
+		}
+
+		a, b := tag, etag
+		if weak {
+			a, b = strings.TrimPrefix(a, "W/"), strings.TrimPrefix(b, "W/")
+		} else if strings.HasPrefix(a, "W/") || strings.HasPrefix(b, "W/") {
+			continue
+		}
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseETags splits a comma separated list of entity tags.
+func ParseETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,51 @@
+package encoder_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bookmarkd/internal/server/encoder"
+	"bookmarkd/utils/require"
+)
+
+func Test_ETagMatch(t *testing.T) {
+	require.Equal(t, encoder.ETagMatch(`"1", "2"`, `"2"`, false), true)
+	require.Equal(t, encoder.ETagMatch(`"1"`, `"2"`, false), false)
+	require.Equal(t, encoder.ETagMatch(`*`, `"2"`, false), true)
+	require.Equal(t, encoder.ETagMatch(`W/"2"`, `"2"`, false), false)
+	require.Equal(t, encoder.ETagMatch(`W/"2"`, `"2"`, true), true)
+	require.Equal(t, encoder.ETagMatch(``, `"2"`, true), false)
+}
+
+func Test_EncodeJsonETag(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks/1", nil)
+		require.Equal(t, encoder.EncodeJsonETag(w, r, http.StatusOK, map[string]int{"version": 2}, `"2"`), nil)
+		require.Equal(t, w.Code, http.StatusOK)
+		require.Equal(t, w.Header().Get("ETag"), `"2"`)
+	})
+
+	t.Run("NotModified", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks/1", nil)
+		r.Header.Set("If-None-Match", `"2"`)
+		require.Equal(t, encoder.EncodeJsonETag(w, r, http.StatusOK, map[string]int{"version": 2}, `"2"`), nil)
+		require.Equal(t, w.Code, http.StatusNotModified)
+		require.Equal(t, w.Body.Len(), 0)
+	})
+
+	// Ensure the derived tag is stable for the same body.
+	t.Run("Derived", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks", nil)
+		require.Equal(t, encoder.EncodeJsonETag(w, r, http.StatusOK, []int{1, 2}, ""), nil)
+		etag := w.Header().Get("ETag")
+
+		w = httptest.NewRecorder()
+		r.Header.Set("If-None-Match", etag)
+		require.Equal(t, encoder.EncodeJsonETag(w, r, http.StatusOK, []int{1, 2}, ""), nil)
+		require.Equal(t, w.Code, http.StatusNotModified)
+	})
+}
@@ -0,0 +1,121 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"bookmarkd"
+)
+
+// DecodeQuery decodes the query string of r into a struct of type T. Query
+// parameters are matched against the json tag of each field so filters keep
+// the same names whether they are sent in the url or a body.
+//
+// Supported field types are strings, integers & booleans, types derived from
+// them and pointers to either. Unknown parameters are ignored. Returns
+// ErrBadRequest naming the parameter if a value cannot be parsed.
+func DecodeQuery[T any](r *http.Request) (T, error) {
+	var v T
+	err := decodeQuery(r.URL.Query(), &v)
+	return v, err
+}
+
+// DecodeFilter decodes a list filter of type T from the query string of r.
+//
+// Filters used to be sent as a JSON body on GET requests. A non-empty body is
+// still decoded for backwards compatibility and a "Deprecation" header is set
+// on the response. Query parameters take precedence over the body.
+func DecodeFilter[T any](w http.ResponseWriter, r *http.Request) (T, error) {
+	var v T
+	if r.Body != nil {
+		buf, err := io.ReadAll(r.Body)
+		if err != nil {
+			return v, fmt.Errorf("%w: invalid body format", bookmarkd.ErrBadRequest)
+		}
+
+		if len(bytes.TrimSpace(buf)) > 0 {
+			if err := json.Unmarshal(buf, &v); err != nil {
+				return v, fmt.Errorf("%w: invalid body format", bookmarkd.ErrBadRequest)
+			}
+			w.Header().Set("Deprecation", "true")
+		}
+	}
+
+	if err := decodeQuery(r.URL.Query(), &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// decodeQuery sets the fields of the struct pointed to by dst from values.
+func decodeQuery(values url.Values, dst interface{}) error {
+	rv := reflect.ValueOf(dst).Elem()
+	rt := rv.Type()
+	if rt.Kind() != reflect.Struct {
+		return fmt.Errorf("decode query: %s is not a struct", rt)
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := queryName(field)
+		if name == "" || !field.IsExported() || !values.Has(name) {
+			continue
+		}
+
+		if err := setQueryValue(rv.Field(i), values.Get(name)); err != nil {
+			return fmt.Errorf("%w: invalid query parameter %q: %s", bookmarkd.ErrBadRequest, name, err)
+		}
+	}
+	return nil
+}
+
+// queryName returns the query parameter name of a struct field.
+func queryName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// setQueryValue parses s into v, allocating v if it is a pointer.
+func setQueryValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setQueryValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected integer")
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected boolean")
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package encoder_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+	"bookmarkd/utils/require"
+)
+
+func Test_DecodeQuery(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/bookmarks?id=4&status=read&starred=true&search=go+lang&sort=name&direction=desc&limit=10&unknown=1", nil)
+		filter, err := encoder.DecodeQuery[core.BookmarkFilter](r)
+		require.Equal(t, err, nil)
+		require.Equal(t, *filter.ID, 4)
+		require.Equal(t, *filter.Status, core.BookmarkStatusRead)
+		require.Equal(t, *filter.Starred, true)
+		require.Equal(t, *filter.Search, "go lang")
+		require.Equal(t, filter.Sort, core.SortName)
+		require.Equal(t, filter.Direction, core.SortDesc)
+		require.Equal(t, filter.Limit, 10)
+		require.Equal(t, filter.CanonicalUrl == nil, true)
+	})
+
+	t.Run("ErrInvalidInteger", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/bookmarks?limit=ten", nil)
+		_, err := encoder.DecodeQuery[core.BookmarkFilter](r)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+		require.Equal(t, strings.Contains(err.Error(), `"limit"`), true)
+	})
+
+	t.Run("ErrInvalidBoolean", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/bookmarks?starred=maybe", nil)
+		_, err := encoder.DecodeQuery[core.BookmarkFilter](r)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+}
+
+func Test_DecodeFilter(t *testing.T) {
+	t.Run("Query", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks?limit=5", nil)
+		filter, err := encoder.DecodeFilter[core.BookmarkFilter](w, r)
+		require.Equal(t, err, nil)
+		require.Equal(t, filter.Limit, 5)
+		require.Equal(t, w.Header().Get("Deprecation"), "")
+	})
+
+	// Ensure the deprecated body form still works & is overridden by the query.
+	t.Run("Body", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks?limit=5", strings.NewReader(`{"limit":2,"offset":3}`))
+		filter, err := encoder.DecodeFilter[core.BookmarkFilter](w, r)
+		require.Equal(t, err, nil)
+		require.Equal(t, filter.Limit, 5)
+		require.Equal(t, filter.Offset, 3)
+		require.Equal(t, w.Header().Get("Deprecation"), "true")
+	})
+
+	t.Run("ErrInvalidBody", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks", strings.NewReader(`{`))
+		_, err := encoder.DecodeFilter[core.BookmarkFilter](w, r)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+}
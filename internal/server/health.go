@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+// DefaultHealthTimeout is the default time each readiness check may take.
+const DefaultHealthTimeout = 2 * time.Second
+
+// Health reports whether the server is alive & ready to serve requests.
+//
+// The server is alive as long as it answers requests. It is ready while every
+// check passes and it isn't shutting down, so load balancers stop routing
+// traffic to it before its listeners close.
+type Health struct {
+	checks   []core.HealthCheck
+	draining atomic.Bool
+
+	// Time each check may take before it fails.
+	Timeout time.Duration
+}
+
+// NewHealth returns a new instance of Health running the given checks.
+func NewHealth(checks ...core.HealthCheck) *Health {
+	return &Health{
+		checks:  checks,
+		Timeout: DefaultHealthTimeout,
+	}
+}
+
+// Drain marks the server as not ready. Called when the server starts to shut
+// down. It can't be undone.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+// Draining returns true once Drain() has been called.
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Check runs every check concurrently, each within the timeout, & returns
+// their results in the order the checks were given.
+func (h *Health) Check(ctx context.Context) *core.HealthReport {
+	report := &core.HealthReport{
+		Status: core.HealthStatusReady,
+		Checks: make([]*core.HealthCheckResult, len(h.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = h.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != core.HealthStatusOK {
+			report.Status = core.HealthStatusNotReady
+		}
+	}
+	if h.Draining() {
+		report.Status = core.HealthStatusDraining
+	}
+	return report
+}
+
+func (h *Health) runCheck(ctx context.Context, check core.HealthCheck) *core.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+
+	// Report checks which ignore the context as timed out all the same.
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	result := &core.HealthCheckResult{
+		Name:       check.Name,
+		Status:     core.HealthStatusOK,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status, result.Error = core.HealthStatusFailing, err.Error()
+	}
+	return result
+}
+
+// handleHealthz handles the liveness probe. Dependencies aren't checked so an
+// unavailable database doesn't get the process restarted.
+func (h *Health) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Status string `json:"status"`
+	}
+
+	if err := encoder.EncodeJson(w, http.StatusOK, response{Status: core.HealthStatusOK}); err != nil {
+		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+	}
+}
+
+// handleReadyz handles the readiness probe. Responds with 503 if any check
+// fails or the server is shutting down.
+func (h *Health) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := encoder.EncodeJson(w, status, report); err != nil {
+		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
+	}
+}
@@ -46,7 +46,7 @@ func CreateJWT(config core.Config, sessionID int, refreshToken string) JwtRespon
 	at.SetNotBefore(now)
 	at.SetIssuer(config.HttpDomain)
 	at.SetAudience(config.HttpDomain + config.HttpBasePath)
-	at.SetExpiration(now.Add(time.Second * expiration))
+	at.SetExpiration(now.Add(expiration))
 	at.SetSubject(strconv.Itoa(sessionID))
 
 	// create a refresh token
@@ -55,7 +55,7 @@ func CreateJWT(config core.Config, sessionID int, refreshToken string) JwtRespon
 	rt.SetNotBefore(now)
 	rt.SetAudience(config.HttpDomain + config.HttpBasePath)
 	rt.SetIssuer(config.HttpDomain)
-	rt.SetExpiration(now.Add(time.Second * refreshExpiration))
+	rt.SetExpiration(now.Add(refreshExpiration))
 	rt.SetSubject(refreshToken)
 
 	return JwtResponse{
@@ -3,6 +3,7 @@ package jwt_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"aidanwoods.dev/go-paseto"
 
@@ -31,6 +32,27 @@ func TestCreateJWT(t *testing.T) {
 	require.Equal(t, err, nil)
 }
 
+// Ensure tokens expire after the configured number of seconds.
+func TestCreateJWT_Expiration(t *testing.T) {
+	now := time.Now()
+	r := jwt.CreateJWT(c, 1, "opaque")
+
+	for _, tt := range []struct {
+		token string
+		want  time.Duration
+	}{
+		{r.AccessToken, time.Duration(c.PasetoAccessTokenExpirationInSeconds) * time.Second},
+		{r.RefreshToken, time.Duration(c.PasetoRefreshTokenExpirationInSeconds) * time.Second},
+	} {
+		token, err := jwt.ValidateJWT(c, tt.token)
+		require.Equal(t, err, nil)
+
+		exp, err := token.GetExpiration()
+		require.Equal(t, err, nil)
+		require.Equal(t, exp.Sub(now).Round(time.Minute), tt.want.Round(time.Minute))
+	}
+}
+
 func TestValidateJWT(t *testing.T) {
 	response := jwt.CreateJWT(c, 1, "opaque")
 
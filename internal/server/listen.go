@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/systemd"
+)
+
+// Listen returns the listeners the server accepts connections on: the sockets
+// passed by systemd socket activation if any, else a unix socket if the
+// address is a path, else a TCP socket.
+func Listen(config core.Config, getenv func(string) string) ([]net.Listener, error) {
+	if listeners, err := systemd.Listeners(getenv); err != nil {
+		return nil, fmt.Errorf("socket activation: %w", err)
+	} else if len(listeners) > 0 {
+		return listeners, nil
+	}
+
+	if path := config.HttpSocketPath(); path != "" {
+		l, err := listenUnix(path, config.HttpSocketMode, config.HttpSocketGroup)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort(config.HttpAddress, config.HttpPort))
+	if err != nil {
+		return nil, err
+	}
+	return []net.Listener{l}, nil
+}
+
+// listenUnix listens on a unix socket at path with the given octal permissions
+// & group. The socket file is removed when the listener is closed.
+func listenUnix(path, mode, group string) (net.Listener, error) {
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid socket mode %q", mode)
+	}
+
+	gid := -1
+	if group != "" {
+		if gid, err = lookupGroup(group); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clients can connect between the listen & these calls, restrict the
+	// directory of the socket if that matters.
+	if err := os.Chmod(path, os.FileMode(perm)); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	if gid != -1 {
+		if err := os.Chown(path, -1, gid); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("chown socket: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes the socket at path left behind by a server which
+// didn't exit cleanly. Returns an error if a server still listens on it or
+// the file isn't a socket.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	} else if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists & is not a socket", path)
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
+
+// lookupGroup returns the id of a group given by name or id.
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(g.Gid)
+}
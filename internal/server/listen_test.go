@@ -0,0 +1,87 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server"
+	"bookmarkd/utils/require"
+)
+
+// Ensure the server listens on a unix socket with the configured permissions.
+func TestListen_Unix(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	config.HttpAddress = filepath.Join(t.TempDir(), "bookmarkd.sock")
+	config.HttpSocketMode = "0600"
+	require.Equal(t, config.HttpSocketPath(), config.HttpAddress)
+
+	listeners, err := server.Listen(config, func(string) string { return "" })
+	require.Equal(t, err, nil)
+	require.Equal(t, len(listeners), 1)
+
+	fi, err := os.Stat(config.HttpAddress)
+	require.Equal(t, err, nil)
+	require.Equal(t, fi.Mode().Perm(), os.FileMode(0600))
+
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})}
+	go s.Serve(listeners[0])
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", config.HttpAddress)
+		},
+	}}
+	resp, err := client.Get("http://bookmarkd/")
+	require.Equal(t, err, nil)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, string(body), "ok")
+
+	// A socket in use isn't replaced.
+	_, err = server.Listen(config, func(string) string { return "" })
+	require.NotEqual(t, err, nil)
+
+	// The socket file is removed on shutdown.
+	require.Equal(t, s.Shutdown(context.Background()), nil)
+	_, err = os.Stat(config.HttpAddress)
+	require.Equal(t, os.IsNotExist(err), true)
+}
+
+// Ensure a socket left behind by a crashed server is replaced.
+func TestListen_StaleUnix(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	config.HttpAddress = filepath.Join(t.TempDir(), "bookmarkd.sock")
+
+	l, err := net.Listen("unix", config.HttpAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+
+	listeners, err := server.Listen(config, func(string) string { return "" })
+	require.Equal(t, err, nil)
+	listeners[0].Close()
+}
+
+// Ensure files which aren't sockets are never removed.
+func TestListen_NotSocket(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	config.HttpAddress = filepath.Join(t.TempDir(), "bookmarkd.sock")
+	if err := os.WriteFile(config.HttpAddress, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := server.Listen(config, func(string) string { return "" })
+	require.NotEqual(t, err, nil)
+	_, err = os.Stat(config.HttpAddress)
+	require.Equal(t, err, nil)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+// AdminMiddleware only allows requests carrying the admin token as a bearer
+// token. Admin routes are disabled if no admin token is configured.
+func AdminMiddleware(config core.Config) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.AdminToken == "" {
+				encoder.EncodeError(w, r, fmt.Errorf("%w: admin api disabled", bookmarkd.ErrForbidden))
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken)) != 1 {
+				encoder.EncodeError(w, r, bookmarkd.ErrUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -35,8 +35,8 @@ func AuthMiddleware(config core.Config, sessionStore core.SessionStore) func(nex
 				return
 			}
 
-			// decode the bearerToken and take sessionID from payuload
-			idString, err := token.GetString("sessionID")
+			// decode the bearerToken and take the sessionID from the subject
+			idString, err := token.GetSubject()
 			if err != nil {
 				encoder.EncodeError(w, r, bookmarkd.ErrUnauthorized)
 				return
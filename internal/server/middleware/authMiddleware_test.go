@@ -0,0 +1,55 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server/jwt"
+	"bookmarkd/internal/server/middleware"
+	"bookmarkd/utils/require"
+)
+
+func Test_AuthMiddleware(t *testing.T) {
+	config, _ := core.NewConfig(func(string) string { return "" })
+	store := &mock.SessionStore{
+		FindSessionByIDFn: func(ctx context.Context, id int) (*core.Session, error) {
+			if id != 7 {
+				return nil, bookmarkd.ErrNotFound
+			}
+			return &core.Session{ID: 7, UserID: "USER"}, nil
+		},
+	}
+
+	var userID string
+	h := middleware.AuthMiddleware(config, store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = core.GetUserIDFromContext(r.Context())
+	}))
+
+	do := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/bookmarks", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// Ensure the session is found by the subject of the access token.
+	t.Run("OK", func(t *testing.T) {
+		w := do(jwt.CreateJWT(config, 7, "opaque").AccessToken)
+		require.Equal(t, w.Code, http.StatusOK)
+		require.Equal(t, userID, "USER")
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		w := do(jwt.CreateJWT(config, 8, "opaque").AccessToken)
+		require.Equal(t, w.Code, http.StatusUnauthorized)
+
+		w = do("v4.public.garbage")
+		require.Equal(t, w.Code, http.StatusUnauthorized)
+	})
+}
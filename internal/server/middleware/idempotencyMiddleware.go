@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/httplog/v2"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+// IdempotencyKeyHeader is the request header carrying a client supplied key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed from a stored key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// idempotencyKeyTimeout bounds storing or releasing a key once the handler
+// has returned.
+const idempotencyKeyTimeout = 5 * time.Second
+
+// IdempotencyMiddleware makes POST, PATCH & DELETE requests carrying an
+// Idempotency-Key header safe to retry. The response to the first request
+// with a key is stored & replayed for later requests with the same key.
+//
+// Reusing a key with a different request returns 422. A retry while the first
+// request is still running returns 409. Server errors are not stored so the
+// request can be retried. Must run after AuthMiddleware as keys are per user.
+func IdempotencyMiddleware(idempotencyKeyStore core.IdempotencyKeyStore) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || !idempotentMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			} else if len(key) > core.MaxIdempotencyKeyLen {
+				encoder.EncodeError(w, r, fmt.Errorf("%w: idempotency key too long", bookmarkd.ErrBadRequest))
+				return
+			}
+
+			// Read the body to fingerprint the request & restore it for the handler.
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				encoder.EncodeError(w, r, fmt.Errorf("%w: invalid body format", bookmarkd.ErrBadRequest))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			k := &core.IdempotencyKey{Key: key, Fingerprint: fingerprint(r, body)}
+			other, err := idempotencyKeyStore.ReserveIdempotencyKey(r.Context(), k)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			} else if other != nil {
+				replayIdempotencyKey(w, r, k, other)
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			completed := false
+			defer func() {
+				// Release the key if the handler failed or panicked so the
+				// client may retry.
+				if !completed {
+					ctx, cancel := idempotencyKeyContext(r)
+					defer cancel()
+					if err := idempotencyKeyStore.DeleteIdempotencyKey(ctx, key); err != nil {
+						httplog.LogEntry(r.Context()).Error("release idempotency key", "err", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.statusCode >= http.StatusInternalServerError {
+				return
+			}
+
+			k.StatusCode = rec.statusCode
+			k.Header = rec.Header().Clone()
+			k.Body = rec.body.Bytes()
+			ctx, cancel := idempotencyKeyContext(r)
+			defer cancel()
+			if err := idempotencyKeyStore.CompleteIdempotencyKey(ctx, k); err != nil {
+				httplog.LogEntry(r.Context()).Error("complete idempotency key", "err", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// idempotencyKeyContext returns a context for storing the outcome of r. It is
+// not cancelled when the client goes away as the changes made by the handler
+// are already committed & a retry must see them.
+func idempotencyKeyContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyKeyTimeout)
+}
+
+// replayIdempotencyKey writes the stored response of other, or an error if
+// the key cannot be replayed for the request k.
+func replayIdempotencyKey(w http.ResponseWriter, r *http.Request, k, other *core.IdempotencyKey) {
+	switch {
+	case other.Fingerprint != k.Fingerprint:
+		encoder.EncodeError(w, r, bookmarkd.ErrIdempotencyKeyReused)
+	case !other.Completed:
+		encoder.EncodeError(w, r, bookmarkd.ErrIdempotencyKeyInProgress)
+	default:
+		for name, values := range other.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(other.StatusCode)
+		w.Write(other.Body)
+	}
+}
+
+// idempotentMethod returns true if requests with method use idempotency keys.
+func idempotentMethod(method string) bool {
+	return method == http.MethodPost || method == http.MethodPatch || method == http.MethodDelete
+}
+
+// fingerprint returns a hash identifying the method, path & body of r.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through to the client while keeping a
+// copy of the status code & body.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	if !rec.wroteHeader {
+		rec.statusCode, rec.wroteHeader = statusCode, true
+	}
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *responseRecorder) Write(p []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(p)
+	return rec.ResponseWriter.Write(p)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
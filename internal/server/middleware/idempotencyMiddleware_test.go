@@ -0,0 +1,153 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server/middleware"
+	"bookmarkd/utils/require"
+)
+
+func Test_IdempotencyMiddleware(t *testing.T) {
+	keys := make(map[string]*core.IdempotencyKey)
+	store := &mock.IdempotencyKeyStore{
+		ReserveIdempotencyKeyFn: func(ctx context.Context, key *core.IdempotencyKey) (*core.IdempotencyKey, error) {
+			if other, ok := keys[key.Key]; ok {
+				return other, nil
+			}
+			other := *key
+			keys[key.Key] = &other
+			return nil, nil
+		},
+		CompleteIdempotencyKeyFn: func(ctx context.Context, key *core.IdempotencyKey) error {
+			other := *key
+			other.Completed = true
+			keys[key.Key] = &other
+			return nil
+		},
+		DeleteIdempotencyKeyFn: func(ctx context.Context, key string) error {
+			delete(keys, key)
+			return nil
+		},
+	}
+
+	var calls int
+	status := http.StatusCreated
+	h := middleware.IdempotencyMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"id":1}`))
+	}))
+
+	do := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "/bookmarks", strings.NewReader(body))
+		r.Header.Set(middleware.IdempotencyKeyHeader, key)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// Ensure a retry replays the stored response without calling the handler.
+	t.Run("Replay", func(t *testing.T) {
+		w := do("KEY1", `{"name":"NAME"}`)
+		require.Equal(t, w.Code, http.StatusCreated)
+		require.Equal(t, calls, 1)
+
+		w = do("KEY1", `{"name":"NAME"}`)
+		require.Equal(t, w.Code, http.StatusCreated)
+		require.Equal(t, w.Body.String(), `{"id":1}`)
+		require.Equal(t, w.Header().Get(middleware.IdempotentReplayedHeader), "true")
+		require.Equal(t, calls, 1)
+	})
+
+	// Ensure a key reused with a different body is rejected.
+	t.Run("ErrReused", func(t *testing.T) {
+		w := do("KEY1", `{"name":"OTHER"}`)
+		require.Equal(t, w.Code, http.StatusUnprocessableEntity)
+		require.Equal(t, calls, 1)
+	})
+
+	// Ensure server errors are not stored so the request can be retried.
+	t.Run("ServerError", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		do("KEY2", `{}`)
+		require.Equal(t, calls, 2)
+
+		status = http.StatusCreated
+		w := do("KEY2", `{}`)
+		require.Equal(t, w.Code, http.StatusCreated)
+		require.Equal(t, calls, 3)
+	})
+
+	// Ensure requests without a key are passed through.
+	t.Run("NoKey", func(t *testing.T) {
+		do("", `{}`)
+		do("", `{}`)
+		require.Equal(t, calls, 5)
+	})
+}
+
+// Ensure the outcome of a request is stored even if the client goes away once
+// the handler has returned.
+func Test_IdempotencyMiddleware_Canceled(t *testing.T) {
+	keys := make(map[string]*core.IdempotencyKey)
+	store := &mock.IdempotencyKeyStore{
+		ReserveIdempotencyKeyFn: func(ctx context.Context, key *core.IdempotencyKey) (*core.IdempotencyKey, error) {
+			if other, ok := keys[key.Key]; ok {
+				return other, nil
+			}
+			other := *key
+			keys[key.Key] = &other
+			return nil, nil
+		},
+		CompleteIdempotencyKeyFn: func(ctx context.Context, key *core.IdempotencyKey) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			other := *key
+			other.Completed = true
+			keys[key.Key] = &other
+			return nil
+		},
+		DeleteIdempotencyKeyFn: func(ctx context.Context, key string) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			delete(keys, key)
+			return nil
+		},
+	}
+
+	status := http.StatusCreated
+	var cancel context.CancelFunc
+	h := middleware.IdempotencyMiddleware(store)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer cancel()
+		w.WriteHeader(status)
+	}))
+
+	do := func(key string) {
+		ctx, c := context.WithCancel(context.Background())
+		cancel = c
+		r := httptest.NewRequest("POST", "/bookmarks", strings.NewReader(`{}`)).WithContext(ctx)
+		r.Header.Set(middleware.IdempotencyKeyHeader, key)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	t.Run("Complete", func(t *testing.T) {
+		do("KEY1")
+		require.Equal(t, keys["KEY1"].Completed, true)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		do("KEY2")
+		_, ok := keys["KEY2"]
+		require.Equal(t, ok, false)
+	})
+}
@@ -0,0 +1,360 @@
+// Package openapi builds an OpenAPI 3.1 document describing the HTTP API.
+// Request & response schemas are derived from the Go types used by the
+// handlers so the document stays in sync with the code.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version of the OpenAPI specification the document conforms to.
+const Version = "3.1.0"
+
+// Document represents the root of an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server represents the url the API is served from.
+type Server struct {
+	Url string `json:"url"`
+}
+
+// PathItem maps the lowercase HTTP methods of a path to their operations.
+type PathItem map[string]*Operation
+
+// Operation describes a single route.
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+// Parameter describes a path, query or header parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the JSON body of a request.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes a response of an operation.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a request or response body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas & security schemes of a document.
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme describes how requests are authenticated.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Schema represents a JSON schema.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Op describes a route registered with Document.Add().
+type Op struct {
+	Summary string
+	Tag     string
+
+	// Requires an authenticated session.
+	Secure bool
+
+	// Name of a security scheme required instead of a session, e.g. the
+	// admin token. Overrides Secure.
+	Scheme string
+
+	// Values whose types describe the path parameters by name. Parameters
+	// not listed are strings.
+	Params map[string]interface{}
+
+	// Optional request headers understood by the route.
+	Headers []string
+
+	// Values whose types describe the query parameters, JSON request body &
+	// JSON response body. Nil values are omitted.
+	Query    interface{}
+	Body     interface{}
+	Response interface{}
+
+	// Status code of a successful response. Defaults to 200.
+	Status int
+
+	// Status codes of error responses.
+	Errors []int
+
+	// Value whose type describes the body of error responses. Defaults to
+	// the ErrorResponse schema.
+	ErrorBody interface{}
+}
+
+// New returns a new, empty document.
+func New(title, version, serverUrl string) *Document {
+	d := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+	if serverUrl != "" {
+		d.Servers = []Server{{Url: serverUrl}}
+	}
+	return d
+}
+
+// pathParamRegex matches the "{name}" parameters of a route pattern.
+var pathParamRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// Add describes the route method & path, where path uses the same "{name}"
+// parameter syntax as chi.
+func (d *Document) Add(method, path string, op Op) {
+	o := &Operation{
+		OperationID: operationID(method, path),
+		Summary:     op.Summary,
+		Responses:   make(map[string]*Response),
+	}
+	if op.Tag != "" {
+		o.Tags = []string{op.Tag}
+	}
+	if op.Scheme != "" {
+		o.Security = []map[string][]string{{op.Scheme: {}}}
+	} else if op.Secure {
+		o.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	for _, m := range pathParamRegex.FindAllStringSubmatch(path, -1) {
+		schema := &Schema{Type: "string"}
+		if v, ok := op.Params[m[1]]; ok {
+			schema = d.SchemaOf(v)
+		}
+		o.Parameters = append(o.Parameters, &Parameter{Name: m[1], In: "path", Required: true, Schema: schema})
+	}
+	if op.Query != nil {
+		o.Parameters = append(o.Parameters, d.queryParameters(reflect.TypeOf(op.Query))...)
+	}
+	for _, name := range op.Headers {
+		o.Parameters = append(o.Parameters, &Parameter{Name: name, In: "header", Schema: &Schema{Type: "string"}})
+	}
+
+	if op.Body != nil {
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(d.SchemaOf(op.Body)),
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := &Response{Description: http.StatusText(status)}
+	if op.Response != nil {
+		resp.Content = jsonContent(d.SchemaOf(op.Response))
+	}
+	o.Responses[fmt.Sprint(status)] = resp
+
+	errorSchema := &Schema{Ref: "#/components/schemas/ErrorResponse"}
+	if op.ErrorBody != nil {
+		errorSchema = d.SchemaOf(op.ErrorBody)
+	}
+	for _, code := range op.Errors {
+		o.Responses[fmt.Sprint(code)] = &Response{
+			Description: http.StatusText(code),
+			Content:     jsonContent(errorSchema),
+		}
+	}
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(PathItem)
+	}
+	d.Paths[path][strings.ToLower(method)] = o
+}
+
+// Has returns true if the document describes the route method & path.
+func (d *Document) Has(method, path string) bool {
+	return d.Paths[path][strings.ToLower(method)] != nil
+}
+
+// SchemaOf returns the schema of the type of v. Named struct types are added
+// to the document's components & referenced.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		if _, ok := d.Components.Schemas[t.Name()]; !ok {
+			// Register before recursing so self-referencing types terminate.
+			d.Components.Schemas[t.Name()] = &Schema{}
+			*d.Components.Schemas[t.Name()] = *d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+
+	// Interfaces & other types accept any value.
+	return &Schema{}
+}
+
+// structSchema returns an object schema with a property for each JSON field.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fields(t) {
+		s.Properties[f.name] = d.schema(f.typ)
+	}
+	return s
+}
+
+// queryParameters returns a query parameter for each JSON field of t.
+func (d *Document) queryParameters(t reflect.Type) []*Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []*Parameter
+	for _, f := range fields(t) {
+		params = append(params, &Parameter{Name: f.name, In: "query", Schema: d.schema(f.typ)})
+	}
+	return params
+}
+
+// field represents a JSON encoded struct field.
+type field struct {
+	name string
+	typ  reflect.Type
+}
+
+// fields returns the JSON encoded fields of t in declaration order. Fields of
+// embedded structs are promoted as encoding/json does.
+func fields(t reflect.Type) []field {
+	var a []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				a = append(a, fields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		// Outer fields hide promoted fields of the same name.
+		if i := slices.IndexFunc(a, func(other field) bool { return other.name == name }); i >= 0 {
+			a = slices.Delete(a, i, i+1)
+		}
+		a = append(a, field{name: name, typ: f.Type})
+	}
+	return a
+}
+
+// jsonContent returns the content of a JSON body with the given schema.
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
+
+// operationID returns a unique name of a route, e.g. "getBookmarksID" for
+// "GET /bookmarks/{id}".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '_' || r == '-' }) {
+		if part == "id" || strings.HasSuffix(part, "id") && len(part) <= 3 {
+			part = strings.ToUpper(part)
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
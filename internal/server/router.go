@@ -2,15 +2,19 @@ package server
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/httplog/v2"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
+	"bookmarkd/internal/metrics"
 	"bookmarkd/internal/server/encoder"
 	"bookmarkd/internal/server/routes"
 )
@@ -19,11 +23,14 @@ import (
 func NewRouter(
 	logger *httplog.Logger,
 	config core.Config,
+	health *Health,
 
 	// stores and services
 	registrationStore core.RegistrationStore,
+	backupStore core.BackupStore,
 	bookmarkStore core.BookmarkStore,
 	eventService core.EventService,
+	idempotencyKeyStore core.IdempotencyKeyStore,
 	sessionStore core.SessionStore,
 	userStore core.UserStore,
 ) *chi.Mux {
@@ -35,6 +42,15 @@ func NewRouter(
 	r.Use(middleware.RealIP)
 	r.Use(httplog.RequestLogger(logger))
 
+	// Tell browsers to keep using HTTPS, if it is served.
+	if config.TlsEnabled() && config.TlsHstsMaxAgeInSeconds > 0 {
+		r.Use(hsts(config.TlsHstsMaxAgeInSeconds))
+	}
+
+	// Count requests & their latency. Websocket connections are long lived
+	// and tracked by their own gauge instead.
+	r.Use(skipWebsocket(trackMetrics))
+
 	// Dont report errors or attempt to recover in test
 	if !config.Test {
 		r.Use(handleReportPanic)
@@ -43,9 +59,10 @@ func NewRouter(
 
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
-	// processing should be stopped.
+	// processing should be stopped. Websocket connections are long lived
+	// and are exempt.
 	t := time.Second * time.Duration(config.HttpTimeoutInSeconds)
-	r.Use(middleware.Timeout(t))
+	r.Use(skipWebsocket(middleware.Timeout(t)))
 
 	r.Use(middleware.Heartbeat(config.HttpBasePath + "/ping"))
 
@@ -59,20 +76,29 @@ func NewRouter(
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Idempotency-Key", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "Idempotent-Replayed", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
+	// Expose metrics in the Prometheus text format.
+	r.Method(http.MethodGet, config.HttpBasePath+"/metrics", promhttp.Handler())
+
+	// Liveness & readiness probes.
+	r.Get(config.HttpBasePath+"/healthz", health.handleHealthz)
+	r.Get(config.HttpBasePath+"/readyz", health.handleReadyz)
+
 	r.Route(config.HttpBasePath, func(r chi.Router) {
 		routes.AddRoutes(
 			r,
 			config,
 			registrationStore,
+			backupStore,
 			bookmarkStore,
 			eventService,
+			idempotencyKeyStore,
 			sessionStore,
 			userStore,
 		)
@@ -94,6 +120,45 @@ func handleReportPanic(next http.Handler) http.Handler {
 	})
 }
 
+// skipWebsocket applies the middleware mw to every request except websocket
+// upgrades.
+func skipWebsocket(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// trackMetrics counts requests & records their latency by the route pattern
+// matched by the router.
+func trackMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		// Requests answered before routing, e.g. the heartbeat, share a label.
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
 // handleNotFound handles requests to routes that don't exist.
 func handleNotFound(w http.ResponseWriter, r *http.Request) {
 
@@ -1,16 +1,27 @@
 package server_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/httplog/v2"
+	"github.com/gorilla/websocket"
 
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server"
+	"bookmarkd/internal/server/jwt"
 	"bookmarkd/internal/server/routes"
+	"bookmarkd/utils/require"
 )
 
 func TestServerRuns(t *testing.T) {
@@ -30,13 +41,15 @@ func Test_Paths(t *testing.T) {
 	config, _ := core.NewConfig(os.Getenv)
 
 	mockRegistrationStore := mock.RegistrationStore{}
+	mockBackupStore := mock.BackupStore{}
 	mockEventService := mock.EventService{}
 	mockBookmarkStore := mock.BookmarkStore{}
+	mockIdempotencyKeyStore := mock.IdempotencyKeyStore{}
 	mockSessionStore := mock.SessionStore{}
 	mockUserStore := mock.UserStore{}
 
 	r := chi.NewRouter()
-	routes.AddRoutes(r, config, &mockRegistrationStore, &mockBookmarkStore, &mockEventService, &mockSessionStore, &mockUserStore)
+	routes.AddRoutes(r, config, &mockRegistrationStore, &mockBackupStore, &mockBookmarkStore, &mockEventService, &mockIdempotencyKeyStore, &mockSessionStore, &mockUserStore)
 
 	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
 		route = strings.Replace(route, "/*/", "/", -1)
@@ -50,3 +63,185 @@ func Test_Paths(t *testing.T) {
 
 	//t.Fatal()
 }
+
+// Ensure every registered route is described by the OpenAPI document and
+// every described route is registered.
+func Test_OpenAPI(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+
+	r := server.NewRouter(logger, config, server.NewHealth(), &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &mock.SessionStore{}, &mock.UserStore{})
+
+	doc := routes.OpenAPI(config)
+
+	// Document paths are relative to the base path.
+	registered := make(map[string]bool)
+	walkFunc := func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = strings.Replace(route, "/*/", "/", -1)
+		route = strings.TrimPrefix(route, config.HttpBasePath)
+		registered[method+" "+route] = true
+		if !doc.Has(method, route) {
+			t.Errorf("route has no OpenAPI entry: %s %s", method, route)
+		}
+		return nil
+	}
+	if err := chi.Walk(r, walkFunc); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, item := range doc.Paths {
+		for method := range item {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("OpenAPI entry has no route: %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	// Ensure the document encodes.
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Ensure websocket connections outlive the request timeout.
+func Test_WebsocketTimeout(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	config.HttpTimeoutInSeconds = 1
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+
+	events := make(chan core.Event, 1)
+	sessionStore := mock.SessionStore{
+		FindSessionByIDFn: func(ctx context.Context, id int) (*core.Session, error) {
+			return &core.Session{ID: id, UserID: "USER"}, nil
+		},
+	}
+	eventService := mock.EventService{
+		SubscribeFn: func(ctx context.Context) (core.Subscription, error) {
+			return &mock.Subscription{
+				CFn:     func() <-chan core.Event { return events },
+				CloseFn: func() error { return nil },
+			}, nil
+		},
+	}
+
+	r := server.NewRouter(logger, config, server.NewHealth(), &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &eventService, &mock.IdempotencyKeyStore{}, &sessionStore, &mock.UserStore{})
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	token := jwt.CreateJWT(config, 1, "opaque").AccessToken
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+config.HttpBasePath+"/events?token="+token, nil)
+	require.Equal(t, err, nil)
+	defer conn.Close()
+
+	time.Sleep(time.Duration(config.HttpTimeoutInSeconds)*time.Second + 200*time.Millisecond)
+	events <- core.Event{Type: core.EventTypeBookmarkAdded}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, buf, err := conn.ReadMessage()
+	require.Equal(t, err, nil)
+	require.Equal(t, strings.Contains(string(buf), core.EventTypeBookmarkAdded), true)
+}
+
+// Ensure requests are counted by route pattern & exposed on /metrics.
+func Test_Metrics(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+
+	r := server.NewRouter(logger, config, server.NewHealth(), &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &mock.SessionStore{}, &mock.UserStore{})
+
+	for _, path := range []string{"/bookmarks/1", "/bookmarks/2", "/no/such/path"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", config.HttpBasePath+path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/metrics", nil))
+	require.Equal(t, w.Code, http.StatusOK)
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`bookmarkd_http_requests_total{code="401",method="GET",route="` + config.HttpBasePath + `/bookmarks/{id}"} 2`,
+		`bookmarkd_http_requests_total{code="404",method="GET",route="` + config.HttpBasePath + `/*"} 1`,
+		`bookmarkd_http_request_duration_seconds_count{method="GET",route="` + config.HttpBasePath + `/bookmarks/{id}"} 2`,
+		`bookmarkd_db_users `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+// Ensure the readiness probe reports each check & fails while draining.
+func Test_Health(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+
+	var dbErr error
+	health := server.NewHealth(
+		core.HealthCheck{Name: "db", Check: func(ctx context.Context) error { return dbErr }},
+		core.HealthCheck{Name: "events", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	health.Timeout = 10 * time.Millisecond
+
+	r := server.NewRouter(logger, config, health, &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &mock.SessionStore{}, &mock.UserStore{})
+
+	readyz := func() (int, core.HealthReport) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/readyz", nil))
+		var report core.HealthReport
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, report
+	}
+
+	t.Run("Healthz", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/healthz", nil))
+		require.Equal(t, w.Code, http.StatusOK)
+	})
+
+	// Ensure a check which doesn't finish in time fails the probe.
+	t.Run("Timeout", func(t *testing.T) {
+		code, report := readyz()
+		require.Equal(t, code, http.StatusServiceUnavailable)
+		require.Equal(t, report.Status, core.HealthStatusNotReady)
+		require.Equal(t, len(report.Checks), 2)
+		require.Equal(t, report.Checks[0].Name, "db")
+		require.Equal(t, report.Checks[0].Status, core.HealthStatusOK)
+		require.Equal(t, report.Checks[1].Status, core.HealthStatusFailing)
+		require.Equal(t, report.Checks[1].Error, context.DeadlineExceeded.Error())
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		health := server.NewHealth(core.HealthCheck{Name: "db", Check: func(ctx context.Context) error { return dbErr }})
+		r := server.NewRouter(logger, config, health, &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &mock.SessionStore{}, &mock.UserStore{})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/readyz", nil))
+		require.Equal(t, w.Code, http.StatusOK)
+
+		dbErr = errors.New("ping: database is locked")
+		defer func() { dbErr = nil }()
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/readyz", nil))
+		require.Equal(t, w.Code, http.StatusServiceUnavailable)
+		require.Equal(t, strings.Contains(w.Body.String(), `"error":"ping: database is locked"`), true)
+
+		// Liveness doesn't depend on the checks.
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/healthz", nil))
+		require.Equal(t, w.Code, http.StatusOK)
+
+		// Once draining, the probe fails even though every check passes.
+		dbErr = nil
+		health.Drain()
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", config.HttpBasePath+"/readyz", nil))
+		require.Equal(t, w.Code, http.StatusServiceUnavailable)
+		require.Equal(t, strings.Contains(w.Body.String(), `"status":"draining"`), true)
+	})
+}
@@ -1 +1,102 @@
 package routes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server/jwt"
+	"bookmarkd/internal/server/routes"
+	"bookmarkd/utils/require"
+)
+
+// Ensure the session is refreshed by the opaque token signed into the
+// refresh token & never by an unsigned or expired one.
+func Test_refresh(t *testing.T) {
+	config, _ := core.NewConfig(func(string) string { return "" })
+
+	var refreshed []string
+	sessionStore := mock.SessionStore{
+		RefreshSessionFn: func(ctx context.Context, refreshToken string) (*core.Session, error) {
+			refreshed = append(refreshed, refreshToken)
+			if refreshToken != "opaque" {
+				return nil, bookmarkd.ErrUnauthorized
+			}
+			return &core.Session{ID: 1, UserID: UserID, RefreshToken: "next"}, nil
+		},
+	}
+
+	r := chi.NewRouter()
+	routes.AddRoutes(r, config, &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &sessionStore, &mock.UserStore{})
+
+	refresh := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/auth/refresh", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		refreshed = nil
+		require.Equal(t, refresh(jwt.CreateJWT(config, 1, "opaque").RefreshToken), http.StatusOK)
+		require.Equal(t, len(refreshed), 1)
+		require.Equal(t, refreshed[0], "opaque")
+	})
+
+	t.Run("ErrUnsigned", func(t *testing.T) {
+		refreshed = nil
+		require.Equal(t, refresh("opaque"), http.StatusUnauthorized)
+		require.Equal(t, len(refreshed), 0)
+	})
+
+	t.Run("ErrExpired", func(t *testing.T) {
+		expired := config
+		expired.PasetoRefreshTokenExpirationInSeconds = -60
+
+		refreshed = nil
+		require.Equal(t, refresh(jwt.CreateJWT(expired, 1, "opaque").RefreshToken), http.StatusUnauthorized)
+		require.Equal(t, len(refreshed), 0)
+	})
+
+	// Ensure a token signed by another server is rejected.
+	t.Run("ErrOtherKey", func(t *testing.T) {
+		other, _ := core.NewConfig(func(string) string { return "" })
+
+		refreshed = nil
+		require.Equal(t, refresh(jwt.CreateJWT(other, 1, "opaque").RefreshToken), http.StatusUnauthorized)
+		require.Equal(t, len(refreshed), 0)
+	})
+}
+
+// Ensure event subscriptions require a session.
+func Test_events(t *testing.T) {
+	config, _ := core.NewConfig(func(string) string { return "" })
+
+	var subscribed bool
+	eventService := mock.EventService{
+		SubscribeFn: func(ctx context.Context) (core.Subscription, error) {
+			subscribed = true
+			return nil, bookmarkd.ErrUnauthorized
+		},
+	}
+
+	r := chi.NewRouter()
+	routes.AddRoutes(r, config, &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &eventService, &mock.IdempotencyKeyStore{}, &mock.SessionStore{}, &mock.UserStore{})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	r.ServeHTTP(w, req)
+	require.Equal(t, w.Code, http.StatusUnauthorized)
+	require.Equal(t, subscribed, false)
+}
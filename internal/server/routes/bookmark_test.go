@@ -0,0 +1,52 @@
+package routes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/inmem"
+	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server/jwt"
+	"bookmarkd/internal/server/routes"
+	"bookmarkd/utils/require"
+)
+
+// Ensure invalid filter values in the query string are rejected.
+func Test_bookmarksGet(t *testing.T) {
+	config, _ := core.NewConfig(func(string) string { return "" })
+
+	sessionStore := mock.SessionStore{
+		FindSessionByIDFn: func(ctx context.Context, id int) (*core.Session, error) {
+			return &core.Session{ID: id, UserID: User.ID}, nil
+		},
+	}
+	bookmarkStore := inmem.NewBookmarkStore(inmem.NewDB())
+
+	r := chi.NewRouter()
+	routes.AddRoutes(r, config, &mock.RegistrationStore{}, &mock.BackupStore{}, bookmarkStore, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &sessionStore, &mock.UserStore{})
+
+	for _, tt := range []struct {
+		query string
+		code  int
+	}{
+		{query: "", code: http.StatusOK},
+		{query: "?status=read", code: http.StatusOK},
+		{query: "?status=bogus", code: http.StatusBadRequest},
+		{query: "?sort=bogus", code: http.StatusBadRequest},
+		{query: "?direction=bogus", code: http.StatusBadRequest},
+		{query: "?limit=nope", code: http.StatusBadRequest},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/bookmarks"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer "+jwt.CreateJWT(config, 1, "opaque").AccessToken)
+			r.ServeHTTP(w, req)
+			require.Equal(t, w.Code, tt.code)
+		})
+	}
+}
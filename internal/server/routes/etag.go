@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+// bookmarkETag returns the entity tag of a bookmark. The tag is derived from
+// the version so it changes whenever the bookmark is updated.
+func bookmarkETag(b *core.Bookmark) string {
+	return fmt.Sprintf(`"%d"`, b.Version)
+}
+
+// ifMatchVersion returns the bookmark version required by the If-Match header
+// of r or nil if any version is acceptable. If the header lists several tags
+// the current bookmark is looked up to choose the one that applies.
+//
+// Returns ErrPreconditionRequired if the header is missing & config requires
+// it, and ErrPreconditionFailed if no listed tag can match.
+func ifMatchVersion(r *http.Request, config core.Config, bookmarkStore core.BookmarkStore, id int) (*int, error) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if config.HttpRequireIfMatch {
+			return nil, bookmarkd.ErrPreconditionRequired
+		}
+		return nil, nil
+	}
+
+	var versions []int
+	for _, tag := range encoder.ParseETags(header) {
+		if tag == "*" {
+			return nil, nil
+		}
+
+		// Weak tags never match If-Match & tags not issued by us are ignored.
+		if s, ok := strings.CutPrefix(tag, `"`); ok {
+			if v, err := strconv.Atoi(strings.TrimSuffix(s, `"`)); err == nil {
+				versions = append(versions, v)
+			}
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return nil, bookmarkd.ErrPreconditionFailed
+	case 1:
+		return &versions[0], nil
+	}
+
+	b, err := bookmarkStore.FindBookmarkByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	} else if !encoder.ETagMatch(header, bookmarkETag(b), false) {
+		return nil, bookmarkd.ErrPreconditionFailed
+	}
+	return &b.Version, nil
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+type AdminBackupsGetResponse struct {
+	Backups []*core.Backup `json:"backups"`
+}
+
+func handleAdminBackupsGet(
+	backupStore core.BackupStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			backups, err := backupStore.FindBackups(r.Context())
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, AdminBackupsGetResponse{Backups: backups}); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
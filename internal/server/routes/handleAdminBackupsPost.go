@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+func handleAdminBackupsPost(
+	backupStore core.BackupStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			backup, err := backupStore.CreateBackup(r.Context())
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, backup); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"bookmarkd/internal/core"
+	"bookmarkd/internal/metrics"
 	"bookmarkd/internal/server/encoder"
 	"bookmarkd/internal/server/jwt"
 
@@ -58,7 +59,8 @@ func handleAuthLoginPost(
 			}
 
 			user, err := userStore.FindUserByUsername(r.Context(), input.Username)
-			if err != nil {
+			if err != nil || user.Disabled() {
+				metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
 				encoder.EncodeError(w, r, bookmarkd.ErrUnauthorized)
 				return
 			}
@@ -76,6 +78,7 @@ func handleAuthLoginPost(
 			}
 
 			if err := totp.Validate(input.Totp, time.Now(), user.Seed); err != nil {
+				metrics.Logins.WithLabelValues(metrics.LoginFailure).Inc()
 				encoder.EncodeError(w, r, bookmarkd.ErrUnauthorized)
 				return
 			}
@@ -86,6 +89,7 @@ func handleAuthLoginPost(
 				encoder.EncodeError(w, r, fmt.Errorf("create session: %w", err))
 				return
 			}
+			metrics.Logins.WithLabelValues(metrics.LoginSuccess).Inc()
 
 			encoder.EncodeJson(w, http.StatusOK, jwt.CreateJWT(config, s.ID, s.RefreshToken))
 		})
@@ -3,6 +3,7 @@ package routes
 import (
 	"net/http"
 
+	"bookmarkd"
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/server/encoder"
 	"bookmarkd/internal/server/jwt"
@@ -15,10 +16,22 @@ func handleAuthRefreshPost(
 
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			token := jwt.GetJwtTokenFromRequest(r)
+			// The signed refresh token carries the session's opaque refresh
+			// token in its subject.
+			token, err := jwt.ValidateJWT(config, jwt.GetJwtTokenFromRequest(r))
+			if err != nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrUnauthorized)
+				return
+			}
+
+			refreshToken, err := token.GetSubject()
+			if err != nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrUnauthorized)
+				return
+			}
 
 			// create a session object for the auth
-			s, err := sessionStore.RefreshSession(r.Context(), token)
+			s, err := sessionStore.RefreshSession(r.Context(), refreshToken)
 			if err != nil {
 				encoder.EncodeError(w, r, err)
 				return
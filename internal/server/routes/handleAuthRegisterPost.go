@@ -1,14 +1,13 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/server/encoder"
-
-	"github.com/cristalhq/otp"
 )
 
 type AuthRegisterPostPayload struct {
@@ -47,21 +46,12 @@ func handleAuthRegisterPost(
 				return
 			}
 
-			totp, err := otp.NewTOTP(otp.TOTPConfig{
-				Algo:   config.TotpAlgo,
-				Digits: config.TotpDigits,
-				Issuer: config.TotpIssuer,
-				Period: config.TotpPeriod,
-				Skew:   config.TotpSkew,
-			})
-			if err != nil {
-				encoder.EncodeError(w, r, err)
-				return
-			}
-
 			// ensure username is not already in use
-			if _, err := userStore.FindUserByUsername(r.Context(), input.Username); err != nil {
-				encoder.EncodeError(w, r, fmt.Errorf("%w: username in use", bookmarkd.ErrBadRequest))
+			if _, err := userStore.FindUserByUsername(r.Context(), input.Username); err == nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrUsersUsernameConflict)
+				return
+			} else if !errors.Is(err, bookmarkd.ErrNotFound) {
+				encoder.EncodeError(w, r, err)
 				return
 			}
 
@@ -71,11 +61,18 @@ func handleAuthRegisterPost(
 			reg, err := registrationStore.StartRegistration(input.Username)
 			if err != nil {
 				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			totpUrl, err := config.TotpURL(input.Username, reg.Seed)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
 			}
 
 			encoder.EncodeJson(w, http.StatusOK, &AuthRegisterPostPayload{
 				RegistrationID: reg.ID.String(),
-				TotpUrl:        totp.GenerateURL(input.Username, []byte(reg.Seed)),
+				TotpUrl:        totpUrl,
 			})
 		})
 }
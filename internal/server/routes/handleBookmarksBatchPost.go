@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+type BookmarksBatchPostResponse struct {
+	Committed bool                        `json:"committed"`
+	Results   []*BookmarksBatchPostResult `json:"results"`
+}
+
+type BookmarksBatchPostResult struct {
+	*core.BookmarkOpResult
+
+	// Error of a failed operation & the status code it would have returned
+	// as an individual request.
+	Code  int                    `json:"code,omitempty"`
+	Error *encoder.ErrorResponse `json:"error,omitempty"`
+}
+
+func handleBookmarksBatchPost(
+	bookmarkStore core.BookmarkStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			batch, err := encoder.DecodeJson[core.BookmarkBatch](r)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			result, err := bookmarkStore.BatchBookmarks(r.Context(), &batch)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			// A rolled back atomic batch responds with the status of the
+			// operation that caused it.
+			status := http.StatusOK
+
+			resp := &BookmarksBatchPostResponse{Committed: result.Committed}
+			for _, res := range result.Results {
+				item := &BookmarksBatchPostResult{BookmarkOpResult: res}
+				if res.Err != nil {
+					item.Code, item.Error = encoder.NewErrorResponse(r, res.Err)
+					if !result.Committed {
+						status = item.Code
+					}
+				}
+				resp.Results = append(resp.Results, item)
+			}
+
+			if err := encoder.EncodeJson(w, status, resp); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
@@ -20,7 +20,14 @@ func handleBookmarksCreate(
 				return
 			}
 
-			if err := bookmarkStore.CreateBookmark(r.Context(), &b); err != nil {
+			// Decide how to handle an existing bookmark with the same url.
+			mode := core.DuplicateMode(r.URL.Query().Get("duplicates"))
+			if err := mode.Validate(); err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := bookmarkStore.CreateBookmark(r.Context(), &b, mode); err != nil {
 				encoder.EncodeError(w, r, err)
 				return
 			}
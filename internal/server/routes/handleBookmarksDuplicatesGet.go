@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+type BookmarksDuplicatesGetResponse struct {
+	Duplicates []*core.BookmarkDuplicates `json:"duplicates"`
+}
+
+func handleBookmarksDuplicatesGet(
+	bookmarkStore core.BookmarkStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			duplicates, err := bookmarkStore.FindDuplicateBookmarks(r.Context())
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, &BookmarksDuplicatesGetResponse{
+				Duplicates: duplicates,
+			}); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
@@ -8,8 +8,10 @@ import (
 )
 
 type BookmarksGetResponse struct {
-	Bookmarks []*core.Bookmark `json:"bookmarks"`
-	N         int              `json:"n"`
+	Bookmarks  []*core.Bookmark `json:"bookmarks"`
+	N          int              `json:"n"`
+	NextCursor string           `json:"nextCursor,omitempty"`
+	PrevCursor string           `json:"prevCursor,omitempty"`
 }
 
 func handleBookmarksGet(
@@ -18,8 +20,8 @@ func handleBookmarksGet(
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		filter, err := encoder.DecodeJson[core.BookmarkFilter](r)
-		if r.Body != nil && err != nil {
+		filter, err := encoder.DecodeFilter[core.BookmarkFilter](w, r)
+		if err != nil {
 			encoder.EncodeError(w, r, err)
 			return
 		}
@@ -30,10 +32,15 @@ func handleBookmarksGet(
 			return
 		}
 
-		if err := encoder.EncodeJson(w, http.StatusOK, &BookmarksGetResponse{
-			Bookmarks: bookmarks,
-			N:         n,
-		}); err != nil {
+		next, prev := filter.Cursors(bookmarks)
+		setLinkHeader(w, r, next, prev)
+
+		if err := encoder.EncodeJsonETag(w, r, http.StatusOK, &BookmarksGetResponse{
+			Bookmarks:  bookmarks,
+			N:          n,
+			NextCursor: next,
+			PrevCursor: prev,
+		}, ""); err != nil {
 			encoder.EncodeError(w, r, err)
 		}
 	})
@@ -12,6 +12,7 @@ import (
 )
 
 func handleBookmarksIDDelete(
+	config core.Config,
 	bookmarkStore core.BookmarkStore,
 ) http.HandlerFunc {
 
@@ -22,9 +23,16 @@ func handleBookmarksIDDelete(
 			id, err := strconv.Atoi(p)
 			if err != nil {
 				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			version, err := ifMatchVersion(r, config, bookmarkStore, id)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
 			}
 
-			b, err := bookmarkStore.DeleteBookmark(r.Context(), id)
+			b, err := bookmarkStore.DeleteBookmark(r.Context(), id, version)
 			if err != nil {
 				encoder.EncodeError(w, r, err)
 				return
@@ -22,6 +22,7 @@ func handleBookmarksIDGet(
 			id, err := strconv.Atoi(p)
 			if err != nil {
 				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
 			}
 
 			b, err := bookmarkStore.FindBookmarkByID(r.Context(), id)
@@ -30,8 +31,7 @@ func handleBookmarksIDGet(
 				return
 			}
 
-			encoder.EncodeJson(w, http.StatusOK, &b)
-			if err := encoder.EncodeJson(w, http.StatusOK, &b); err != nil {
+			if err := encoder.EncodeJsonETag(w, r, http.StatusOK, &b, bookmarkETag(b)); err != nil {
 				encoder.EncodeError(w, r, err)
 			}
 		})
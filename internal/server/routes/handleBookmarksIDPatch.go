@@ -12,6 +12,7 @@ import (
 )
 
 func handleBookmarksIDPatch(
+	config core.Config,
 	bookmarkStore core.BookmarkStore,
 ) http.HandlerFunc {
 
@@ -22,6 +23,7 @@ func handleBookmarksIDPatch(
 			id, err := strconv.Atoi(p)
 			if err != nil {
 				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
 			}
 
 			upd, err := encoder.DecodeJson[core.BookmarkUpdate](r)
@@ -30,12 +32,18 @@ func handleBookmarksIDPatch(
 				return
 			}
 
+			if upd.Version, err = ifMatchVersion(r, config, bookmarkStore, id); err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
 			b, err := bookmarkStore.UpdateBookmark(r.Context(), id, upd)
 			if err != nil {
 				encoder.EncodeError(w, r, err)
 				return
 			}
 
+			w.Header().Set("ETag", bookmarkETag(b))
 			if err := encoder.EncodeJson(w, http.StatusOK, &b); err != nil {
 				encoder.EncodeError(w, r, err)
 			}
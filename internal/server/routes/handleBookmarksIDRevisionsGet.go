@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+type BookmarksIDRevisionsGetResponse struct {
+	Revisions []*core.BookmarkRevision `json:"revisions"`
+	N         int                      `json:"n"`
+}
+
+func handleBookmarksIDRevisionsGet(
+	bookmarkStore core.BookmarkStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			p := chi.URLParam(r, "id")
+
+			id, err := strconv.Atoi(p)
+			if err != nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			// Ensure the bookmark exists & is visible to the user so an unknown
+			// bookmark is not reported as an empty history.
+			if _, err := bookmarkStore.FindBookmarkByID(r.Context(), id); err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			filter, err := encoder.DecodeQuery[core.BookmarkRevisionFilter](r)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+			filter.BookmarkID = &id
+
+			revisions, n, err := bookmarkStore.FindBookmarkRevisions(r.Context(), filter)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, &BookmarksIDRevisionsGetResponse{
+				Revisions: revisions,
+				N:         n,
+			}); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
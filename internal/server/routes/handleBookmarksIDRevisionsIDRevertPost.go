@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+func handleBookmarksIDRevisionsIDRevertPost(
+	bookmarkStore core.BookmarkStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			id, err := strconv.Atoi(chi.URLParam(r, "id"))
+			if err != nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			rid, err := strconv.Atoi(chi.URLParam(r, "rid"))
+			if err != nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			b, err := bookmarkStore.RevertBookmark(r.Context(), id, rid)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, &b); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+type BookmarksStatusPostInput struct {
+	// Status to set on every matching bookmark.
+	Status core.BookmarkStatus `json:"status"`
+
+	// Restricts the bookmarks that are changed. An empty filter matches all
+	// of the user's bookmarks.
+	Filter core.BookmarkFilter `json:"filter"`
+}
+
+type BookmarksStatusPostResponse struct {
+	N int `json:"n"`
+}
+
+func handleBookmarksStatusPost(
+	bookmarkStore core.BookmarkStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			input, err := encoder.DecodeJson[BookmarksStatusPostInput](r)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			n, err := bookmarkStore.UpdateBookmarksStatus(r.Context(), input.Filter, input.Status)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, &BookmarksStatusPostResponse{N: n}); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
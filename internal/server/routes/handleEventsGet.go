@@ -2,14 +2,20 @@ package routes
 
 import (
 	"bookmarkd/internal/core"
+	"bookmarkd/internal/metrics"
+	"bookmarkd/internal/server/encoder"
 	"context"
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/httplog/v2"
 	"github.com/gorilla/websocket"
 )
 
+// closeWriteTimeout is the time allowed to send the close message to a client.
+const closeWriteTimeout = time.Second
+
 // upgrader is used to upgrade an HTTP connection to a Websocket connection.
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
@@ -24,8 +30,17 @@ func handleEventsGet(
 		func(w http.ResponseWriter, r *http.Request) {
 			oplog := httplog.LogEntry(r.Context())
 
-			// websocketConnections.Inc()
-			// defer websocketConnections.Dec()
+			metrics.WebsocketConnections.Inc()
+			defer metrics.WebsocketConnections.Dec()
+
+			// Subscribe to all events for the current user. Subscribe before
+			// upgrading so errors are reported as a regular response.
+			sub, err := eventService.Subscribe(r.Context())
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+			defer sub.Close()
 
 			// Upgrade HTTP connection to use websockets.
 			conn, err := upgrader.Upgrade(w, r, nil)
@@ -49,14 +64,6 @@ func handleEventsGet(
 			// Ignore all incoming messages.
 			go ignoreWebSocketReaders(conn)
 
-			// Subscribe to all events for the current user.
-			sub, err := eventService.Subscribe(r.Context())
-			if err != nil {
-				oplog.Error("eventservice subscribe", "err", err)
-				return
-			}
-			defer sub.Close()
-
 			// Stream all events to outgoing websocket writer.
 			for {
 				select {
@@ -64,8 +71,13 @@ func handleEventsGet(
 					return // disconnect when HTTP connection disconnects
 
 				case event, ok := <-sub.C():
-					// If subscription is closed then exit.
+					// If subscription is closed, e.g. because the server is
+					// shutting down, tell the client to reconnect later & exit.
 					if !ok {
+						msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "")
+						if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeWriteTimeout)); err != nil {
+							oplog.Debug("write close message to websocket conn", "err", err)
+						}
 						return
 					}
 
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+func handleOpenAPIGet(
+	config core.Config,
+) http.HandlerFunc {
+
+	// The document only depends on config so it is built once.
+	doc := OpenAPI(config)
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if err := encoder.EncodeJson(w, http.StatusOK, doc); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
@@ -3,8 +3,6 @@ package routes
 import (
 	"net/http"
 
-	"github.com/go-chi/chi/v5"
-
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/server/encoder"
 )
@@ -15,7 +13,7 @@ func handleUsersIDGet(
 
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			uid := chi.URLParam(r, "uid")
+			uid := userIDParam(r)
 
 			u, err := userStore.FindUserByID(r.Context(), uid)
 			if err != nil {
@@ -3,27 +3,55 @@ package routes
 import (
 	"net/http"
 
-	"github.com/go-chi/chi/v5"
-
+	"bookmarkd"
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/server/encoder"
 )
 
+type UsersIDSessionsGetResponse struct {
+	Sessions   []*core.Session `json:"sessions"`
+	N          int             `json:"n"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+	PrevCursor string          `json:"prevCursor,omitempty"`
+}
+
 func handleUsersIDSessionsGet(
-	userStore core.UserStore,
+	sessionStore core.SessionStore,
 ) http.HandlerFunc {
 
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			uid := chi.URLParam(r, "uid")
+			uid := userIDParam(r)
 
-			u, err := userStore.FindUserByID(r.Context(), uid)
+			// Users may only list their own sessions.
+			if uid != core.GetUserIDFromContext(r.Context()) {
+				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			filter, err := encoder.DecodeQuery[core.SessionFilter](r)
 			if err != nil {
 				encoder.EncodeError(w, r, err)
 				return
 			}
+			filter.UserID, filter.RefreshToken = &uid, nil
 
-			encoder.EncodeJson(w, http.StatusOK, u.Sessions)
+			sessions, n, err := sessionStore.FindSessions(r.Context(), filter)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
 
+			next, prev := filter.Cursors(sessions)
+			setLinkHeader(w, r, next, prev)
+
+			if err := encoder.EncodeJson(w, http.StatusOK, &UsersIDSessionsGetResponse{
+				Sessions:   sessions,
+				N:          n,
+				NextCursor: next,
+				PrevCursor: prev,
+			}); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
 		})
 }
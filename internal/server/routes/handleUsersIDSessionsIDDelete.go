@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+)
+
+func handleUsersIDSessionsIDDelete(
+	sessionStore core.SessionStore,
+) http.HandlerFunc {
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			uid := userIDParam(r)
+			id, err := strconv.Atoi(chi.URLParam(r, "sid"))
+			if err != nil {
+				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			// Users may only revoke their own sessions.
+			s, err := sessionStore.FindSessionByID(r.Context(), id)
+			if err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			} else if s.UserID != uid || uid != core.GetUserIDFromContext(r.Context()) {
+				encoder.EncodeError(w, r, bookmarkd.ErrNotFound)
+				return
+			}
+
+			if err := sessionStore.DeleteSession(r.Context(), id); err != nil {
+				encoder.EncodeError(w, r, err)
+				return
+			}
+
+			if err := encoder.EncodeJson(w, http.StatusOK, s); err != nil {
+				encoder.EncodeError(w, r, err)
+			}
+		})
+}
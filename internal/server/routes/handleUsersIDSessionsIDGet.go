@@ -17,7 +17,7 @@ func handleUsersIDSessionsIDGet(
 
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			uid := chi.URLParam(r, "uid")
+			uid := userIDParam(r)
 			sid := chi.URLParam(r, "sid")
 			id, err := strconv.Atoi(sid)
 			if err != nil {
@@ -0,0 +1,187 @@
+package routes
+
+import (
+	"net/http"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/server/encoder"
+	"bookmarkd/internal/server/jwt"
+	"bookmarkd/internal/server/openapi"
+)
+
+// OpenAPI returns the OpenAPI document describing every route registered by
+// AddRoutes() & the metrics & health routes the server registers next to them.
+// Paths are relative to the server url, which includes the base path.
+//
+// Every route must have an entry here. This is enforced by Test_OpenAPI.
+func OpenAPI(config core.Config) *openapi.Document {
+	version := bookmarkd.Version
+	if version == "" {
+		version = "dev"
+	}
+
+	d := openapi.New("bookmarkd", version, config.HttpDomain+config.HttpBasePath)
+	d.Components.SecuritySchemes = map[string]*openapi.SecurityScheme{
+		"bearerAuth": {
+			Type:         "http",
+			Scheme:       "bearer",
+			BearerFormat: "PASETO",
+			Description:  "Access token returned by /auth/login. May also be passed in the \"token\" query parameter.",
+		},
+		"adminAuth": {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "Admin token set with BOOKMARKD_ADMIN_TOKEN.",
+		},
+	}
+	d.SchemaOf(encoder.ErrorResponse{})
+
+	// Bookmark, revision & session IDs are integers. User IDs are UUIDs.
+	params := map[string]interface{}{"id": 0, "rid": 0, "sid": 0}
+
+	errs := []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError}
+	mutation := []string{"Idempotency-Key"}
+	conditional := []string{"Idempotency-Key", "If-Match"}
+
+	// Auth
+	d.Add("POST", "/auth/register", openapi.Op{
+		Summary: "Start a register flow", Tag: "auth",
+		Body: AuthRegisterPostInput{}, Response: AuthRegisterPostPayload{},
+		Errors: []int{http.StatusNotAcceptable},
+	})
+	d.Add("POST", "/auth/register/confirm", openapi.Op{
+		Summary: "Complete register", Tag: "auth",
+		Body: AuthRegisterConfirmPostInput{}, Response: jwt.JwtResponse{},
+		Errors: []int{http.StatusNotAcceptable, http.StatusUnauthorized},
+	})
+	d.Add("POST", "/auth/login", openapi.Op{
+		Summary: "Start a login flow", Tag: "auth",
+		Body: AuthLoginPostInput{}, Response: jwt.JwtResponse{},
+		Errors: []int{http.StatusNotAcceptable, http.StatusUnauthorized},
+	})
+	d.Add("POST", "/auth/refresh", openapi.Op{
+		Summary: "Exchange a refresh token for new refresh token and access token", Tag: "auth",
+		Secure: true, Response: jwt.JwtResponse{},
+		Errors: []int{http.StatusUnauthorized},
+	})
+	d.Add("DELETE", "/auth/logout", openapi.Op{
+		Summary: "Delete the current user session", Tag: "auth",
+		Secure: true, Response: AuthLogoutDeleteResponse{},
+		Errors: []int{http.StatusUnauthorized},
+	})
+
+	// Users
+	d.Add("GET", "/users/{uid}", openapi.Op{
+		Summary: "Get a single user", Tag: "users",
+		Secure: true, Params: params, Response: core.User{}, Errors: errs,
+	})
+	d.Add("GET", "/users/{uid}/sessions", openapi.Op{
+		Summary: "List all sessions belonging to user", Tag: "users",
+		Secure: true, Params: params, Query: core.SessionFilter{}, Response: UsersIDSessionsGetResponse{}, Errors: errs,
+	})
+	d.Add("GET", "/users/{uid}/sessions/{sid}", openapi.Op{
+		Summary: "Get a single user session", Tag: "users",
+		Secure: true, Params: params, Response: core.Session{}, Errors: errs,
+	})
+	d.Add("DELETE", "/users/{uid}/sessions/{sid}", openapi.Op{
+		Summary: "Revoke a user session", Tag: "users",
+		Secure: true, Params: params, Headers: mutation, Response: core.Session{}, Errors: errs,
+	})
+
+	// Bookmarks
+	d.Add("GET", "/bookmarks", openapi.Op{
+		Summary: "List all bookmarks", Tag: "bookmarks",
+		Secure: true, Headers: []string{"If-None-Match"},
+		Query: core.BookmarkFilter{}, Response: BookmarksGetResponse{}, Errors: errs,
+	})
+	d.Add("POST", "/bookmarks", openapi.Op{
+		Summary: "Create a bookmark", Tag: "bookmarks",
+		Secure: true, Headers: mutation,
+		Query: struct {
+			Duplicates core.DuplicateMode `json:"duplicates"`
+		}{},
+		Body: core.Bookmark{}, Response: core.Bookmark{},
+		Errors: append(errs, http.StatusNotAcceptable, http.StatusConflict, http.StatusUnprocessableEntity),
+	})
+	d.Add("POST", "/bookmarks/status", openapi.Op{
+		Summary: "Set the status of many bookmarks at once", Tag: "bookmarks",
+		Secure: true, Headers: mutation,
+		Body: BookmarksStatusPostInput{}, Response: BookmarksStatusPostResponse{},
+		Errors: append(errs, http.StatusNotAcceptable),
+	})
+	d.Add("POST", "/bookmarks/batch", openapi.Op{
+		Summary: "Apply many create, update & delete operations in one transaction", Tag: "bookmarks",
+		Secure: true, Headers: mutation,
+		Body: core.BookmarkBatch{}, Response: BookmarksBatchPostResponse{},
+		Errors: append(errs, http.StatusNotAcceptable, http.StatusConflict, http.StatusPreconditionFailed),
+	})
+	d.Add("GET", "/bookmarks/duplicates", openapi.Op{
+		Summary: "List bookmarks sharing the same canonical url", Tag: "bookmarks",
+		Secure: true, Response: BookmarksDuplicatesGetResponse{}, Errors: errs,
+	})
+	d.Add("GET", "/bookmarks/{id}", openapi.Op{
+		Summary: "View a single bookmark", Tag: "bookmarks",
+		Secure: true, Params: params, Headers: []string{"If-None-Match"},
+		Response: core.Bookmark{}, Errors: errs,
+	})
+	d.Add("PATCH", "/bookmarks/{id}", openapi.Op{
+		Summary: "Update a bookmark", Tag: "bookmarks",
+		Secure: true, Params: params, Headers: conditional,
+		Body: core.BookmarkUpdate{}, Response: core.Bookmark{},
+		Errors: append(errs, http.StatusNotAcceptable, http.StatusPreconditionFailed, http.StatusPreconditionRequired),
+	})
+	d.Add("DELETE", "/bookmarks/{id}", openapi.Op{
+		Summary: "Remove a bookmark", Tag: "bookmarks",
+		Secure: true, Params: params, Headers: conditional,
+		Response: core.Bookmark{},
+		Errors:   append(errs, http.StatusPreconditionFailed, http.StatusPreconditionRequired),
+	})
+	d.Add("GET", "/bookmarks/{id}/revisions", openapi.Op{
+		Summary: "List the revision history of a bookmark", Tag: "bookmarks",
+		Secure: true, Params: params, Query: core.BookmarkRevisionFilter{}, Response: BookmarksIDRevisionsGetResponse{}, Errors: errs,
+	})
+	d.Add("POST", "/bookmarks/{id}/revisions/{rid}/revert", openapi.Op{
+		Summary: "Restore a bookmark to the values before a revision", Tag: "bookmarks",
+		Secure: true, Params: params, Headers: mutation, Response: core.Bookmark{}, Errors: errs,
+	})
+
+	// Events
+	d.Add("GET", "/events", openapi.Op{
+		Summary: "Initiate a websocket events subscription", Tag: "events",
+		Secure: true, Status: http.StatusSwitchingProtocols,
+		Errors: []int{http.StatusUnauthorized, http.StatusServiceUnavailable, http.StatusInternalServerError},
+	})
+
+	// Admin
+	d.Add("GET", "/admin/backups", openapi.Op{
+		Summary: "List backups of the database", Tag: "admin",
+		Scheme: "adminAuth", Response: AdminBackupsGetResponse{},
+		Errors: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError},
+	})
+	d.Add("POST", "/admin/backups", openapi.Op{
+		Summary: "Take a backup of the database", Tag: "admin",
+		Scheme: "adminAuth", Response: core.Backup{},
+		Errors: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError},
+	})
+
+	// Meta
+	d.Add("GET", "/openapi.json", openapi.Op{
+		Summary: "This document", Tag: "meta",
+	})
+	d.Add("GET", "/metrics", openapi.Op{
+		Summary: "Metrics in the Prometheus text format", Tag: "meta",
+	})
+	d.Add("GET", "/healthz", openapi.Op{
+		Summary: "Liveness probe, does not check dependencies", Tag: "meta",
+		Response: struct {
+			Status string `json:"status"`
+		}{},
+	})
+	d.Add("GET", "/readyz", openapi.Op{
+		Summary: "Readiness probe, fails while a dependency is unavailable or the server shuts down", Tag: "meta",
+		Response: core.HealthReport{}, Errors: []int{http.StatusServiceUnavailable}, ErrorBody: core.HealthReport{},
+	})
+
+	return d
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// setLinkHeader adds a Link header pointing at the next & previous pages of a
+// list response. Each link is the request url with the "cursor" query
+// parameter replaced. Empty cursors are skipped.
+func setLinkHeader(w http.ResponseWriter, r *http.Request, next, prev string) {
+	var links []string
+	for _, l := range []struct{ rel, cursor string }{{"next", next}, {"prev", prev}} {
+		if l.cursor == "" {
+			continue
+		}
+
+		u := *r.URL
+		q := u.Query()
+		q.Set("cursor", l.cursor)
+		q.Del("offset")
+		u.RawQuery = q.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), l.rel))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
@@ -11,17 +11,34 @@ func AddRoutes(
 	mux chi.Router,
 	config core.Config,
 	registrationStore core.RegistrationStore,
+	backupStore core.BackupStore,
 	bookmarkStore core.BookmarkStore,
 	eventService core.EventService,
+	idempotencyKeyStore core.IdempotencyKeyStore,
 	sessionStore core.SessionStore,
 	userStore core.UserStore,
 ) {
 
+	// Admin Routes
+	mux.Group(func(r chi.Router) {
+		// Ensure all paths that follow carry the admin token
+		r.Use(middleware.AdminMiddleware(config))
+
+		// List backups of the database
+		r.Get("/admin/backups", handleAdminBackupsGet(backupStore))
+
+		// Take a backup of the database
+		r.Post("/admin/backups", handleAdminBackupsPost(backupStore))
+	})
+
 	// Protected Routes
 	mux.Group(func(r chi.Router) {
 		// Ensure all paths that follow have a valid user session
 		r.Use(middleware.AuthMiddleware(config, sessionStore))
 
+		// Replay the stored response of retried mutating requests
+		r.Use(middleware.IdempotencyMiddleware(idempotencyKeyStore))
+
 		// Delete the current user session
 		r.Delete("/auth/logout", handleAuthLogoutDelete(sessionStore))
 
@@ -29,33 +46,50 @@ func AddRoutes(
 		r.Get("/users/{uid}", handleUsersIDGet(userStore))
 
 		// List all sessions belonging to user
-		r.Get("/users/{uid}/sessions", handleUsersIDSessionsGet(userStore))
+		r.Get("/users/{uid}/sessions", handleUsersIDSessionsGet(sessionStore))
 
 		// Get a single user session
 		r.Get("/users/{uid}/sessions/{sid}", handleUsersIDSessionsIDGet(sessionStore))
 
+		// Revoke a user session
+		r.Delete("/users/{uid}/sessions/{sid}", handleUsersIDSessionsIDDelete(sessionStore))
+
 		// List all bookmarks.
 		r.Get("/bookmarks", handleBookmarksGet(bookmarkStore))
 
 		// Create a bookmark.
 		r.Post("/bookmarks", handleBookmarksCreate(bookmarkStore))
 
+		// Set the status of many bookmarks at once, e.g. mark all as read.
+		r.Post("/bookmarks/status", handleBookmarksStatusPost(bookmarkStore))
+
+		// Apply many create, update & delete operations in one transaction.
+		r.Post("/bookmarks/batch", handleBookmarksBatchPost(bookmarkStore))
+
+		// List bookmarks sharing the same canonical url.
+		r.Get("/bookmarks/duplicates", handleBookmarksDuplicatesGet(bookmarkStore))
+
 		// View a single bookmark.
 		r.Get("/bookmarks/{id}", handleBookmarksIDGet(bookmarkStore))
 
 		// Update a bookmark.
-		r.Patch("/bookmarks/{id}", handleBookmarksIDPatch(bookmarkStore))
+		r.Patch("/bookmarks/{id}", handleBookmarksIDPatch(config, bookmarkStore))
 
 		// Remove a bookmark.
-		r.Delete("/bookmarks/{id}", handleBookmarksIDDelete(bookmarkStore))
-	})
+		r.Delete("/bookmarks/{id}", handleBookmarksIDDelete(config, bookmarkStore))
 
-	// Public Routes
-	mux.Group(func(r chi.Router) {
+		// List the revision history of a bookmark.
+		r.Get("/bookmarks/{id}/revisions", handleBookmarksIDRevisionsGet(bookmarkStore))
+
+		// Restore a bookmark to the values before a revision.
+		r.Post("/bookmarks/{id}/revisions/{rid}/revert", handleBookmarksIDRevisionsIDRevertPost(bookmarkStore))
 
 		// Initiate a websocket events subscription
 		r.Get("/events", handleEventsGet(eventService))
+	})
 
+	// Public Routes
+	mux.Group(func(r chi.Router) {
 		// Start a register flow
 		r.Post("/auth/register", handleAuthRegisterPost(config, userStore, registrationStore))
 
@@ -67,5 +101,8 @@ func AddRoutes(
 
 		// Exchange a refresh token for new refresh token and access token
 		r.Post("/auth/refresh", handleAuthRefreshPost(config, sessionStore))
+
+		// OpenAPI document describing every route
+		r.Get("/openapi.json", handleOpenAPIGet(config))
 	})
 }
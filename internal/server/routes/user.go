@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"bookmarkd/internal/core"
+)
+
+// UserIDMe may be used in place of the "uid" URL parameter to refer to the
+// current user, e.g. "/users/me/sessions".
+const UserIDMe = "me"
+
+// userIDParam returns the "uid" URL parameter with UserIDMe resolved to the
+// ID of the current user.
+func userIDParam(r *http.Request) string {
+	uid := chi.URLParam(r, "uid")
+	if uid == UserIDMe {
+		return core.GetUserIDFromContext(r.Context())
+	}
+	return uid
+}
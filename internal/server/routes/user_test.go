@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,8 +16,10 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"bookmarkd"
 	"bookmarkd/internal/core"
 	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server/jwt"
 	"bookmarkd/internal/server/routes"
 	"bookmarkd/utils/require"
 )
@@ -47,11 +50,12 @@ func Test_login(t *testing.T) {
 	registrationStore := mock.RegistrationStore{}
 	mockEventService := mock.EventService{}
 	mockBookmarkStore := mock.BookmarkStore{}
+	mockIdempotencyKeyStore := mock.IdempotencyKeyStore{}
 	mockSessionStore := mock.SessionStore{}
 	mockUserStore := mock.UserStore{}
 
 	r := chi.NewRouter()
-	routes.AddRoutes(r, config, &registrationStore, &mockBookmarkStore, &mockEventService, &mockSessionStore, &mockUserStore)
+	routes.AddRoutes(r, config, &registrationStore, &mock.BackupStore{}, &mockBookmarkStore, &mockEventService, &mockIdempotencyKeyStore, &mockSessionStore, &mockUserStore)
 
 	// setup server mocks
 	registrationStore.StartRegistrationSessionFn = func(username string) (*core.Registration, error) {
@@ -73,7 +77,10 @@ func Test_login(t *testing.T) {
 	registrationStore.DeleteRegistrationSessionFn = func(id string) error {
 		return nil
 	}
+	// The user only exists once registration is confirmed.
+	registered := false
 	mockUserStore.CreateUserFn = func(ctx context.Context, user *core.User) error {
+		registered = true
 		user.ID = User.ID
 		user.Seed = User.Seed
 		user.Username = User.Username
@@ -84,6 +91,9 @@ func Test_login(t *testing.T) {
 	}
 
 	mockUserStore.FindUserByUsernameFn = func(ctx context.Context, username string) (*core.User, error) {
+		if !registered {
+			return nil, bookmarkd.ErrNotFound
+		}
 		return &User, nil
 	}
 
@@ -112,17 +122,15 @@ func Test_login(t *testing.T) {
 
 	require.Equal(t, err, nil)
 
-	str := u.Query().Get("secret")
-	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(str)
-	if err != nil {
-		t.Fatal(err)
-	}
+	// The url carries the seed the server validates passcodes against.
+	secret := u.Query().Get("secret")
+	require.Equal(t, secret, seed)
 
 	now := time.Now().UTC()
-	passcode, err := totp.GenerateCode(string(secret), now)
+	passcode, err := totp.GenerateCode(secret, now)
 	require.Equal(t, err, nil)
 
-	if err := totp.Validate(passcode, time.Now(), string(secret)); err != nil {
+	if err := totp.Validate(passcode, time.Now(), secret); err != nil {
 		t.Fatal(err)
 	}
 
@@ -141,6 +149,11 @@ func Test_login(t *testing.T) {
 	require.Equal(t, len(loginResponse.RefreshToken) > 0, true)
 	require.Equal(t, loginResponse.TokenType, "bearer")
 	require.Equal(t, loginResponse.Expires, 300)
+
+	// Ensure the username can't be registered again.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/auth/register", strings.NewReader(`{"username":"`+username+`"}`)))
+	require.Equal(t, w.Code, http.StatusNotAcceptable)
 }
 
 // Register
@@ -225,3 +238,40 @@ func login(t *testing.T, m *chi.Mux, username string, totp string) (*routes.Auth
 
 	return &resp, nil
 }
+
+// Ensure the TOTP seed of a user is never served.
+func Test_usersIDGet(t *testing.T) {
+	config, _ := core.NewConfig(func(string) string { return "" })
+
+	sessionStore := mock.SessionStore{
+		FindSessionByIDFn: func(ctx context.Context, id int) (*core.Session, error) {
+			return &core.Session{ID: id, UserID: User.ID}, nil
+		},
+	}
+	userStore := mock.UserStore{
+		FindUserByIDFn: func(ctx context.Context, id string) (*core.User, error) {
+			return &User, nil
+		},
+	}
+
+	r := chi.NewRouter()
+	routes.AddRoutes(r, config, &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &sessionStore, &userStore)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/users/"+User.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+jwt.CreateJWT(config, 1, "opaque").AccessToken)
+	r.ServeHTTP(w, req)
+	require.Equal(t, w.Code, http.StatusOK)
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	require.Equal(t, body["id"], interface{}(User.ID))
+	for key := range body {
+		if strings.EqualFold(key, "seed") {
+			t.Fatalf("user response contains %q", key)
+		}
+	}
+	require.Equal(t, strings.Contains(w.Body.String(), User.Seed), false)
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"net"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/go-chi/httplog/v2"
 
@@ -11,11 +12,14 @@ import (
 
 func NewServer(logger *httplog.Logger,
 	config core.Config,
+	health *Health,
 
 	// stores and services
 	registrationStore core.RegistrationStore,
+	backupStore core.BackupStore,
 	bookmarkStore core.BookmarkStore,
 	eventService core.EventService,
+	idempotencyKeyStore core.IdempotencyKeyStore,
 	sessionStore core.SessionStore,
 	userStore core.UserStore,
 ) *http.Server {
@@ -23,15 +27,48 @@ func NewServer(logger *httplog.Logger,
 	r := NewRouter(
 		logger,
 		config,
+		health,
 		registrationStore,
+		backupStore,
 		bookmarkStore,
 		eventService,
+		idempotencyKeyStore,
 		sessionStore,
 		userStore,
 	)
 
+	// The address is only informational if the server is given listeners,
+	// see Listen().
+	addr := net.JoinHostPort(config.HttpAddress, config.HttpPort)
+	if path := config.HttpSocketPath(); path != "" {
+		addr = path
+	}
+
 	return &http.Server{
-		Addr:    net.JoinHostPort(config.HttpAddress, config.HttpPort),
+		Addr:    addr,
 		Handler: r,
 	}
 }
+
+// Handler is an http.Handler whose underlying handler can be replaced while
+// the server is running, e.g. by a router built from a reloaded config.
+type Handler struct {
+	v atomic.Value // *http.Handler, a pointer so handlers of any type can be stored
+}
+
+// NewHandler returns a new instance of Handler serving h.
+func NewHandler(h http.Handler) *Handler {
+	handler := &Handler{}
+	handler.Store(h)
+	return handler
+}
+
+// Store replaces the underlying handler. Requests in flight finish with the
+// previous handler.
+func (h *Handler) Store(handler http.Handler) {
+	h.v.Store(&handler)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.v.Load().(*http.Handler)).ServeHTTP(w, r)
+}
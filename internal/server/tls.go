@@ -0,0 +1,229 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"bookmarkd/internal/core"
+)
+
+// NewTLSConfig returns the TLS config of the server. Returns nil if TLS is
+// disabled.
+func NewTLSConfig(config core.Config) (*tls.Config, error) {
+	if !config.TlsEnabled() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.TlsMinVersion == core.TlsVersion13 {
+		tlsConfig.MinVersion = tls.VersionTLS13
+	}
+
+	if config.TlsSelfSigned {
+		cert, err := SelfSignedCertificate(selfSignedHosts(config), time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("generate self-signed certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		r := newCertReloader(config.TlsCertFile, config.TlsKeyFile)
+		if err := r.reload(); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = r.GetCertificate
+	}
+
+	if config.TlsClientCaFile != "" {
+		buf, err := os.ReadFile(config.TlsClientCaFile)
+		if err != nil {
+			return nil, fmt.Errorf("read client ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return nil, fmt.Errorf("no certificates found in client ca file %s", config.TlsClientCaFile)
+		}
+		tlsConfig.ClientCAs = pool
+
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		if config.TlsClientAuth == core.TlsClientAuthVerifyIfGiven {
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// certReloader serves a certificate & its key from files. The files are
+// loaded again whenever either is modified so renewed certificates are
+// served without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time // latest modification time of the files when loaded
+}
+
+func newCertReloader(certFile, keyFile string) *certReloader {
+	return &certReloader{certFile: certFile, keyFile: keyFile}
+}
+
+// GetCertificate returns the current certificate. Implements the callback of
+// tls.Config. If the files were modified but can't be loaded, e.g. because
+// only one of them was replaced so far, the previous certificate is served.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if err := r.reload(); err != nil {
+		log.Printf("tls certificate reload error: %s", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// reload loads the files if they were modified since they were last loaded.
+func (r *certReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls certificate: %w", err)
+	}
+	r.cert, r.modTime = &cert, modTime
+	return nil
+}
+
+// latestModTime returns the modification time of the file modified last.
+func (r *certReloader) latestModTime() (time.Time, error) {
+	var modTime time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return modTime, err
+		} else if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	return modTime, nil
+}
+
+// SelfSignedCertificate returns a new certificate for hosts, signed by its
+// own key & valid for a year from now. Hosts may be names or IP addresses.
+func SelfSignedCertificate(hosts []string, now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"bookmarkd self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// selfSignedHosts returns the hosts a self-signed certificate is valid for:
+// the loopback addresses, the listen address & the host of the domain.
+func selfSignedHosts(config core.Config) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if config.HttpAddress != "" && config.HttpSocketPath() == "" {
+		hosts = append(hosts, config.HttpAddress)
+	}
+	if u, err := url.Parse(config.HttpDomain); err == nil && u.Hostname() != "" {
+		hosts = append(hosts, u.Hostname())
+	}
+	return hosts
+}
+
+// NewRedirectServer returns a plain HTTP server redirecting every request to
+// the same url over HTTPS.
+func NewRedirectServer(config core.Config) *http.Server {
+	return &http.Server{
+		Addr:              net.JoinHostPort(config.HttpAddress, strconv.Itoa(config.TlsRedirectPort)),
+		Handler:           redirectToHTTPS(config.HttpPort),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// redirectToHTTPS redirects requests to the HTTPS server listening on port of
+// the requested host.
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		u := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
+
+// hsts tells browsers to only connect over HTTPS for maxAge seconds.
+func hsts(maxAge int) func(http.Handler) http.Handler {
+	value := "max-age=" + strconv.Itoa(maxAge)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil {
+				w.Header().Set("Strict-Transport-Security", value)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package server_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/httplog/v2"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/mock"
+	"bookmarkd/internal/server"
+	"bookmarkd/utils/require"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Run("Disabled", func(t *testing.T) {
+		config, _ := core.NewConfig(os.Getenv)
+		tlsConfig, err := server.NewTLSConfig(config)
+		require.Equal(t, err, nil)
+		require.Equal(t, tlsConfig == nil, true)
+	})
+
+	t.Run("SelfSigned", func(t *testing.T) {
+		config, _ := core.NewConfig(os.Getenv)
+		config.TlsSelfSigned = true
+		config.TlsMinVersion = core.TlsVersion13
+		config.HttpDomain = "https://bookmarkd.test"
+
+		tlsConfig, err := server.NewTLSConfig(config)
+		require.Equal(t, err, nil)
+		require.Equal(t, tlsConfig.MinVersion, uint16(tls.VersionTLS13))
+		require.Equal(t, len(tlsConfig.Certificates), 1)
+
+		leaf := tlsConfig.Certificates[0].Leaf
+		for _, host := range []string{"localhost", "127.0.0.1", "bookmarkd.test"} {
+			require.Equal(t, leaf.VerifyHostname(host), nil)
+		}
+	})
+
+	// Ensure renewed certificates are served without a restart & a broken
+	// renewal keeps the previous certificate.
+	t.Run("Reload", func(t *testing.T) {
+		dir := t.TempDir()
+		config, _ := core.NewConfig(os.Getenv)
+		config.TlsCertFile = filepath.Join(dir, "cert.pem")
+		config.TlsKeyFile = filepath.Join(dir, "key.pem")
+
+		first := mustWriteCertificate(t, config.TlsCertFile, config.TlsKeyFile, time.Now().Add(-time.Minute))
+		tlsConfig, err := server.NewTLSConfig(config)
+		require.Equal(t, err, nil)
+		require.Equal(t, tlsConfig.MinVersion, uint16(tls.VersionTLS12))
+
+		cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+		require.Equal(t, err, nil)
+		require.Equal(t, string(cert.Certificate[0]), string(first.Certificate[0]))
+
+		second := mustWriteCertificate(t, config.TlsCertFile, config.TlsKeyFile, time.Now())
+		cert, err = tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+		require.Equal(t, err, nil)
+		require.Equal(t, string(cert.Certificate[0]), string(second.Certificate[0]))
+
+		if err := os.WriteFile(config.TlsKeyFile, []byte("not a key"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(config.TlsKeyFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		cert, err = tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+		require.Equal(t, err, nil)
+		require.Equal(t, string(cert.Certificate[0]), string(second.Certificate[0]))
+	})
+
+	t.Run("ClientAuth", func(t *testing.T) {
+		dir := t.TempDir()
+		config, _ := core.NewConfig(os.Getenv)
+		config.TlsSelfSigned = true
+		config.TlsClientCaFile = filepath.Join(dir, "ca.pem")
+		mustWriteCertificate(t, config.TlsClientCaFile, filepath.Join(dir, "ca.key"), time.Now())
+
+		tlsConfig, err := server.NewTLSConfig(config)
+		require.Equal(t, err, nil)
+		require.Equal(t, tlsConfig.ClientAuth, tls.RequireAndVerifyClientCert)
+
+		config.TlsClientAuth = core.TlsClientAuthVerifyIfGiven
+		tlsConfig, err = server.NewTLSConfig(config)
+		require.Equal(t, err, nil)
+		require.Equal(t, tlsConfig.ClientAuth, tls.VerifyClientCertIfGiven)
+	})
+
+	t.Run("ErrNoCertificate", func(t *testing.T) {
+		dir := t.TempDir()
+		config, _ := core.NewConfig(os.Getenv)
+		config.TlsCertFile = filepath.Join(dir, "cert.pem")
+		config.TlsKeyFile = filepath.Join(dir, "key.pem")
+
+		_, err := server.NewTLSConfig(config)
+		require.NotEqual(t, err, nil)
+	})
+}
+
+// Ensure HSTS is only sent over TLS.
+func Test_HSTS(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	config.TlsSelfSigned = true
+	config.TlsHstsMaxAgeInSeconds = 31536000
+	logger := httplog.NewLogger("bookmarkd", httplog.Options{Writer: io.Discard})
+
+	r := server.NewRouter(logger, config, server.NewHealth(), &mock.RegistrationStore{}, &mock.BackupStore{}, &mock.BookmarkStore{}, &mock.EventService{}, &mock.IdempotencyKeyStore{}, &mock.SessionStore{}, &mock.UserStore{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "https://localhost"+config.HttpBasePath+"/healthz", nil))
+	require.Equal(t, w.Header().Get("Strict-Transport-Security"), "max-age=31536000")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "http://localhost"+config.HttpBasePath+"/healthz", nil))
+	require.Equal(t, w.Header().Get("Strict-Transport-Security"), "")
+}
+
+func TestNewRedirectServer(t *testing.T) {
+	config, _ := core.NewConfig(os.Getenv)
+	config.TlsSelfSigned = true
+	config.TlsRedirectPort = 8081
+
+	for _, tt := range []struct {
+		port, target, location string
+	}{
+		{"8443", "http://bookmarkd.test:8081/api/bookmarks?limit=10", "https://bookmarkd.test:8443/api/bookmarks?limit=10"},
+		{"443", "http://bookmarkd.test/a%2Fb", "https://bookmarkd.test/a%2Fb"},
+	} {
+		config.HttpPort = tt.port
+		s := server.NewRedirectServer(config)
+		require.Equal(t, s.Addr, config.HttpAddress+":8081")
+
+		w := httptest.NewRecorder()
+		s.Handler.ServeHTTP(w, httptest.NewRequest("POST", tt.target, nil))
+		require.Equal(t, w.Code, http.StatusPermanentRedirect)
+		require.Equal(t, w.Header().Get("Location"), tt.location)
+	}
+}
+
+// mustWriteCertificate writes a new self-signed certificate & its key as PEM
+// files, modified at modTime.
+func mustWriteCertificate(tb testing.TB, certFile, keyFile string, modTime time.Time) tls.Certificate {
+	tb.Helper()
+	cert, err := server.SelfSignedCertificate([]string{"localhost"}, time.Now())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	for path, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: cert.Certificate[0]},
+		keyFile:  {Type: "PRIVATE KEY", Bytes: key},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			tb.Fatal(err)
+		} else if err := os.Chtimes(path, modTime, modTime); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return cert
+}
@@ -0,0 +1,359 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Backup file names are the prefix & the UTC time the backup was taken.
+const (
+	backupPrefix     = "bookmarkd-"
+	backupSuffix     = ".sqlite"
+	backupTimeFormat = "20060102T150405.000Z"
+)
+
+// Time RestoreBackup waits for the lock on the database it replaces.
+const restoreLockTimeout = 100 * time.Millisecond
+
+// Ensure service implements interface.
+var _ core.BackupStore = (*BackupStore)(nil)
+
+// BackupStore represents a service for taking backups of a running database.
+//
+// Backups are written with VACUUM INTO which produces a consistent, compacted
+// copy of the database without blocking writers. Copying the database file
+// itself is unsafe in WAL mode as committed pages may still be in the WAL.
+type BackupStore struct {
+	db *DB
+
+	// Serializes backups & pruning.
+	mu sync.Mutex
+
+	// Background scheduler.
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	// Directory the backups are written to.
+	Dir string
+
+	// Number of backups to keep. Older backups are removed after each backup.
+	// Zero keeps all backups.
+	Retain int
+
+	// Interval between scheduled backups. Zero disables scheduled backups.
+	Interval time.Duration
+}
+
+// NewBackupStore returns a new instance of BackupStore writing to dir.
+func NewBackupStore(db *DB, dir string) *BackupStore {
+	s := &BackupStore{db: db, Dir: dir}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Open creates the backup directory & starts scheduled backups, if enabled.
+func (s *BackupStore) Open() error {
+	if s.Dir == "" {
+		return fmt.Errorf("backup dir required")
+	} else if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	if s.Interval > 0 {
+		s.wg.Add(1)
+		go func() { defer s.wg.Done(); s.schedule() }()
+	}
+	return nil
+}
+
+// Close stops scheduled backups & waits for a running backup to finish.
+func (s *BackupStore) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// schedule runs in a goroutine and takes a backup every interval.
+func (s *BackupStore) schedule() {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if backup, err := s.CreateBackup(s.ctx); err != nil {
+			log.Printf("backup error: %s", err)
+		} else {
+			log.Printf("backup created: %s", backup.Name)
+		}
+	}
+}
+
+// CreateBackup takes a backup of the database. Older backups are removed
+// afterwards so at most Retain backups are kept.
+func (s *BackupStore) CreateBackup(ctx context.Context) (*core.Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	createdAt := s.db.Now().UTC()
+	name := backupPrefix + createdAt.Format(backupTimeFormat) + backupSuffix
+	path := filepath.Join(s.Dir, name)
+
+	// Write to a temporary file first so a failed backup never leaves a
+	// partial file behind that looks like a valid backup.
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if _, err := s.db.db.ExecContext(ctx, `VACUUM INTO ?`, tmp); err != nil {
+		os.Remove(tmp)
+		return nil, fmt.Errorf("db vacuum into: %w", FormatError(err))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	backup := &core.Backup{Name: name, Size: fi.Size(), CreatedAt: createdAt.Truncate(time.Millisecond)}
+
+	if err := s.prune(); err != nil {
+		return backup, fmt.Errorf("prune backups: %w", err)
+	}
+	return backup, nil
+}
+
+// FindBackups returns the backups in the backup directory, newest first.
+func (s *BackupStore) FindBackups(ctx context.Context) ([]*core.Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findBackups()
+}
+
+func (s *BackupStore) findBackups() ([]*core.Backup, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return []*core.Backup{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	backups := make([]*core.Backup, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+
+		// Ignore files which are not named like a backup.
+		createdAt, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+		if err != nil {
+			continue
+		}
+
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, &core.Backup{Name: name, Size: fi.Size(), CreatedAt: createdAt})
+	}
+
+	// Names sort by time, so the newest backup sorts last.
+	slices.SortFunc(backups, func(a, b *core.Backup) int { return strings.Compare(b.Name, a.Name) })
+	return backups, nil
+}
+
+// prune removes the oldest backups until at most Retain are left.
+func (s *BackupStore) prune() error {
+	if s.Retain <= 0 {
+		return nil
+	}
+
+	backups, err := s.findBackups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > s.Retain {
+		if err := os.Remove(filepath.Join(s.Dir, backups[len(backups)-1].Name)); err != nil {
+			return err
+		}
+		backups = backups[:len(backups)-1]
+	}
+	return nil
+}
+
+// ValidateBackup checks that the database file at path is intact & was
+// written by a compatible version. Backups missing migrations are valid as the
+// migrations run when the database is opened. Backups with migrations unknown
+// to this version were written by a newer version and are rejected.
+func ValidateBackup(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	dsn, err := fileURI(path, url.Values{"mode": {"ro"}})
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, `PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: not a database: %s", bookmarkd.ErrBadRequest, err)
+	} else if result != "ok" {
+		return fmt.Errorf("%w: integrity check failed: %s", bookmarkd.ErrBadRequest, result)
+	}
+
+	files, err := migrationFiles()
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	} else if len(applied) == 0 {
+		return fmt.Errorf("%w: no migrations applied, not a bookmarkd database", bookmarkd.ErrBadRequest)
+	}
+	for version := range applied {
+		if !slices.ContainsFunc(files, func(f *migrationFile) bool { return f.Version == version }) {
+			return fmt.Errorf("%w: unknown migration %07d, backup was written by a newer version", bookmarkd.ErrBadRequest, version)
+		}
+	}
+	return nil
+}
+
+// RestoreBackup replaces the database at dst with the backup at src after
+// validating it. The server must be stopped; the restore is refused while
+// any other connection has dst open. The replaced database & its WAL are kept
+// next to dst with a ".pre-restore" suffix.
+func RestoreBackup(ctx context.Context, src, dst string) error {
+	if err := ValidateBackup(ctx, src); err != nil {
+		return fmt.Errorf("validate backup: %w", err)
+	}
+
+	// Hold an exclusive lock on the current database until it is replaced.
+	if _, err := os.Stat(dst); err == nil {
+		unlock, err := lockDatabase(ctx, dst)
+		if err != nil {
+			return fmt.Errorf("%w: database is in use, stop the server first: %s", bookmarkd.ErrUnavailable, err)
+		}
+		defer unlock()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// Copy the backup next to the destination so the final rename is atomic.
+	tmp := dst + ".restore"
+	if err := copyFile(src, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("copy backup: %w", err)
+	}
+
+	// Move the current database & its WAL aside. A leftover WAL would
+	// otherwise be replayed into the restored database.
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(dst+suffix, dst+".pre-restore"+suffix); err != nil && !os.IsNotExist(err) {
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	return os.Rename(tmp, dst)
+}
+
+// lockDatabase takes an exclusive lock on the database at path & returns a
+// function releasing it. Fails if another connection has the database open.
+//
+// In WAL mode an exclusive transaction only excludes other writers, so the
+// connection also uses the exclusive locking mode. That requires an exclusive
+// lock on the database file itself which conflicts with the shared lock every
+// open WAL connection holds, even while idle.
+func lockDatabase(ctx context.Context, path string) (unlock func(), err error) {
+	dsn, err := fileURI(path, url.Values{
+		"_busy_timeout": {strconv.Itoa(int(restoreLockTimeout.Milliseconds()))},
+		"_locking_mode": {"EXCLUSIVE"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `BEGIN EXCLUSIVE`); err != nil {
+		conn.Close()
+		db.Close()
+		return nil, FormatError(err)
+	}
+
+	return func() {
+		conn.ExecContext(context.Background(), `ROLLBACK`)
+		conn.Close()
+		db.Close()
+	}, nil
+}
+
+// fileURI returns the SQLite URI of the database file at path with params.
+// The path is escaped so names containing "?" or "#" are not cut short.
+func fileURI(path string, params url.Values) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: params.Encode()}
+	return u.String(), nil
+}
+
+// copyFile copies the file at src to dst & syncs it to disk.
+func copyFile(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	} else if err := w.Sync(); err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,164 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
+)
+
+func Test_BackupStore(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+
+	ctx := context.Background()
+	user := MustCreateUser(t, ctx, sqlite.NewUserStore(db), &core.User{Username: "jane"})
+
+	// Take each backup a minute apart so their names differ.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { now = now.Add(time.Minute); return now }
+
+	s := sqlite.NewBackupStore(db, t.TempDir())
+	s.Retain = 2
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var first *core.Backup
+	t.Run("CreateBackup", func(t *testing.T) {
+		var err error
+		first, err = s.CreateBackup(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, first.Name, "bookmarkd-20240101T000100.000Z.sqlite")
+		require.NotEqual(t, first.Size, int64(0))
+
+		backups, err := s.FindBackups(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(backups), 1)
+		require.Equal(t, *backups[0], *first)
+	})
+
+	// Ensure only the newest backups are kept.
+	t.Run("Retain", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if _, err := s.CreateBackup(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		backups, err := s.FindBackups(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(backups), 2)
+		require.Equal(t, backups[0].Name, "bookmarkd-20240101T000300.000Z.sqlite")
+		require.Equal(t, backups[1].Name, "bookmarkd-20240101T000200.000Z.sqlite")
+	})
+
+	t.Run("RestoreBackup", func(t *testing.T) {
+		backups, err := s.FindBackups(ctx)
+		require.Equal(t, err, nil)
+
+		dst := filepath.Join(t.TempDir(), "db")
+		require.Equal(t, sqlite.RestoreBackup(ctx, filepath.Join(s.Dir, backups[0].Name), dst), nil)
+
+		other := sqlite.NewDB(dst)
+		if err := other.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer MustCloseDB(t, other)
+
+		u, err := sqlite.NewUserStore(other).FindUserByID(ctx, user.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, u.Username, "jane")
+	})
+
+	// Ensure a database which is still open is not replaced.
+	t.Run("ErrInUse", func(t *testing.T) {
+		backups, err := s.FindBackups(ctx)
+		require.Equal(t, err, nil)
+
+		dst := filepath.Join(t.TempDir(), "db")
+		other := sqlite.NewDB(dst)
+		if err := other.Open(); err != nil {
+			t.Fatal(err)
+		}
+		MustCreateUser(t, ctx, sqlite.NewUserStore(other), &core.User{Username: "john"})
+
+		err = sqlite.RestoreBackup(ctx, filepath.Join(s.Dir, backups[0].Name), dst)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnavailable), true)
+		_, err = os.Stat(dst + ".pre-restore")
+		require.Equal(t, os.IsNotExist(err), true)
+
+		// The restore succeeds once the database is closed & the previous
+		// database is kept intact.
+		MustCloseDB(t, other)
+		require.Equal(t, sqlite.RestoreBackup(ctx, filepath.Join(s.Dir, backups[0].Name), dst), nil)
+
+		prev := sqlite.NewDB(dst + ".pre-restore")
+		if err := prev.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer MustCloseDB(t, prev)
+		_, err = sqlite.NewUserStore(prev).FindUserByUsername(ctx, "john")
+		require.Equal(t, err, nil)
+	})
+
+	// Ensure paths containing URI characters are not cut short.
+	t.Run("SpecialPath", func(t *testing.T) {
+		backups, err := s.FindBackups(ctx)
+		require.Equal(t, err, nil)
+
+		path := filepath.Join(t.TempDir(), "back?up#1 %41.sqlite")
+		data, err := os.ReadFile(filepath.Join(s.Dir, backups[0].Name))
+		require.Equal(t, err, nil)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, sqlite.ValidateBackup(ctx, path), nil)
+
+		_, err = os.Stat(filepath.Join(filepath.Dir(path), "back"))
+		require.Equal(t, os.IsNotExist(err), true)
+	})
+
+	// Ensure files which are not databases are rejected.
+	t.Run("ErrNotDatabase", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "db")
+		if err := os.WriteFile(path, []byte("not a database"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		err := sqlite.ValidateBackup(ctx, path)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+
+	// Ensure backups written by a newer version are rejected.
+	t.Run("ErrNewerVersion", func(t *testing.T) {
+		backups, err := s.FindBackups(ctx)
+		require.Equal(t, err, nil)
+		path := filepath.Join(s.Dir, backups[0].Name)
+
+		other, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+		if _, err := other.Exec(`INSERT INTO migrations (version, checksum) VALUES (9999999, '')`); err != nil {
+			t.Fatal(err)
+		}
+
+		dst := filepath.Join(t.TempDir(), "db")
+		err = sqlite.RestoreBackup(ctx, path, dst)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+
+		_, err = os.Stat(dst)
+		require.Equal(t, os.IsNotExist(err), true)
+	})
+}
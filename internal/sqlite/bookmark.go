@@ -2,8 +2,11 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -65,7 +68,12 @@ func (s *BookmarkStore) FindBookmarks(ctx context.Context, filter core.BookmarkF
 
 // CreateBookmark creates a new bookmark and assigns the current user as the owner.
 // The owner will automatically be added as a member of the new bookmark.
-func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookmark) error {
+//
+// If the user already has a bookmark with the same canonical url then mode
+// decides whether the bookmark is created anyway, rejected with
+// ErrBookmarkDuplicate or merged into the existing bookmark. When merged,
+// bookmark is set to the state of the existing bookmark.
+func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookmark, mode core.DuplicateMode) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -73,7 +81,7 @@ func (s *BookmarkStore) CreateBookmark(ctx context.Context, bookmark *core.Bookm
 	defer tx.Rollback()
 
 	// Create bookmark and attach associated owner user.
-	if err := createBookmark(ctx, tx, bookmark); err != nil {
+	if err := createBookmarkWithMode(ctx, tx, bookmark, mode); err != nil {
 		return err
 	}
 	return tx.Commit()
@@ -102,20 +110,85 @@ func (s *BookmarkStore) UpdateBookmark(ctx context.Context, id int, upd core.Boo
 // DeleteBookmark permanently removes a bookmark by ID. Only the bookmark owner may delete
 // a bookmark. Returns ENOTFOUND if bookmark does not exist. Returns EUNAUTHORIZED if
 // user is not the bookmark owner.
-func (s *BookmarkStore) DeleteBookmark(ctx context.Context, id int) (*core.Bookmark, error) {
+//
+// If version is set the bookmark is only deleted if it is still at that
+// version, otherwise ErrPreconditionFailed is returned.
+func (s *BookmarkStore) DeleteBookmark(ctx context.Context, id int, version *int) (*core.Bookmark, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
 
-	bookmark, err := deleteBookmark(ctx, tx, id)
+	bookmark, err := deleteBookmark(ctx, tx, id, version)
 	if err != nil {
 		return bookmark, err
 	}
 	return bookmark, tx.Commit()
 }
 
+// FindBookmarkRevisions retrieves the revision history of bookmarks, newest
+// first. Only returns revisions of bookmarks the current user owns.
+func (s *BookmarkStore) FindBookmarkRevisions(ctx context.Context, filter core.BookmarkRevisionFilter) ([]*core.BookmarkRevision, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	return findBookmarkRevisions(ctx, tx, filter)
+}
+
+// RevertBookmark restores the fields changed by a revision to their previous
+// values. The revert itself is recorded as a new revision.
+//
+// Returns ENOTFOUND if the bookmark or revision does not exist. Returns
+// EUNAUTHORIZED if user is not the bookmark owner.
+func (s *BookmarkStore) RevertBookmark(ctx context.Context, id int, revisionID int) (*core.Bookmark, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	bookmark, err := revertBookmark(ctx, tx, id, revisionID)
+	if err != nil {
+		return bookmark, err
+	}
+	return bookmark, tx.Commit()
+}
+
+// FindDuplicateBookmarks returns every group of the current user's bookmarks
+// that share a canonical url, ordered by canonical url.
+func (s *BookmarkStore) FindDuplicateBookmarks(ctx context.Context) ([]*core.BookmarkDuplicates, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return findDuplicateBookmarks(ctx, tx)
+}
+
+// UpdateBookmarksStatus sets the status of every bookmark matching filter that
+// does not already have that status. Each change is recorded & published as if
+// the bookmark had been updated individually.
+//
+// Returns the number of bookmarks changed.
+func (s *BookmarkStore) UpdateBookmarksStatus(ctx context.Context, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := updateBookmarksStatus(ctx, tx, filter, status)
+	if err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
 // findBookmarkByID is a helper function to retrieve a bookmark by ID.
 // Returns ENOTFOUND if bookmark doesn't exist.
 func findBookmarkByID(ctx context.Context, tx *Tx, id int) (*core.Bookmark, error) {
@@ -131,33 +204,68 @@ func findBookmarkByID(ctx context.Context, tx *Tx, id int) (*core.Bookmark, erro
 // findBookmarks retrieves a list of matching bookmarks. Also returns a total matching
 // count which may different from the number of results if filter.Limit is set.
 func findBookmarks(ctx context.Context, tx *Tx, filter core.BookmarkFilter) (_ []*core.Bookmark, n int, err error) {
-	// Build WHERE clause. Each part of the WHERE clause is AND-ed together.
-	// Values are appended to an arg list to avoid SQL injection.
-	where, args := []string{"1 = 1"}, []interface{}{}
-	if v := filter.ID; v != nil {
-		where, args = append(where, "id = ?"), append(args, *v)
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
 	}
 
-	// Limit to bookmarks user owns.
-	userID := core.GetUserIDFromContext(ctx)
-	where, args = append(where, "user_id = ?"), append(args, userID)
+	where, args := formatBookmarkFilter(ctx, filter)
 
-	// Execue query with limiting WHERE clause and LIMIT/OFFSET injected.
+	// Score search matches. A match in the name weighs more than one in the
+	// description which weighs more than one in the url.
+	relevance, relevanceArgs := "0", []interface{}{}
+	if v := filter.Search; v != nil {
+		relevance = `(instr(lower(name), lower(?)) > 0) * 4 + (instr(lower(description), lower(?)) > 0) * 2 + (instr(lower(url), lower(?)) > 0)`
+		relevanceArgs = []interface{}{*v, *v, *v}
+	}
+
+	// Page through the results using the sort column and cursor.
+	column := "created_at"
+	switch filter.Sort {
+	case core.SortUpdated:
+		column = "updated_at"
+	case core.SortName:
+		column = "name"
+	case core.SortRelevance:
+		column = "relevance"
+	}
+
+	// Relevance is computed, so the cursor is compared to its expression.
+	expr, exprArgs := column, []interface{}(nil)
+	if column == "relevance" {
+		expr, exprArgs = "("+relevance+")", relevanceArgs
+	}
+	keyset, keysetArgs, orderBy, reverse, err := FormatKeysetExpr(column, expr, exprArgs, filter.Descending(), filter.Cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Count the matching bookmarks separately, so the page itself is read
+	// from the index of the sort column starting at the cursor.
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks WHERE `+strings.Join(where, " AND "), args...).Scan(&n); err != nil {
+		return nil, 0, fmt.Errorf("db count bookmarks: %w", FormatError(err))
+	}
+
+	// Execute the query with the cursor & LIMIT/OFFSET injected.
 	rows, err := tx.QueryContext(ctx, `
-		SELECT 
+		SELECT
 		  id,
 		  user_id,
 		  name,
 		  description,
 		  url,
+		  canonical_url,
+		  status,
+		  starred,
+		  read_at,
+		  version,
 		  created_at,
 		  updated_at,
-		  COUNT(*) OVER()
+		  `+relevance+` AS relevance
 		FROM bookmarks
-		WHERE `+strings.Join(where, " AND ")+`
-		ORDER BY id ASC
+		WHERE `+strings.Join(append(where, keyset), " AND ")+`
+		`+orderBy+`
 		`+FormatLimitOffset(filter.Limit, filter.Offset),
-		args...,
+		append(append(relevanceArgs, args...), keysetArgs...)...,
 	)
 	if err != nil {
 		return nil, n, fmt.Errorf("db select bookmarks: %w", FormatError(err))
@@ -174,9 +282,14 @@ func findBookmarks(ctx context.Context, tx *Tx, filter core.BookmarkFilter) (_ [
 			&bookmark.Name,
 			&bookmark.Description,
 			&bookmark.Url,
+			&bookmark.CanonicalUrl,
+			&bookmark.Status,
+			&bookmark.Starred,
+			(*NullTime)(&bookmark.ReadAt),
+			&bookmark.Version,
 			(*NullTime)(&bookmark.CreatedAt),
 			(*NullTime)(&bookmark.UpdatedAt),
-			&n,
+			&bookmark.Relevance,
 		); err != nil {
 			return nil, 0, fmt.Errorf("db scan bookmark row: %w", FormatError(err))
 		}
@@ -186,9 +299,86 @@ func findBookmarks(ctx context.Context, tx *Tx, filter core.BookmarkFilter) (_ [
 		return nil, 0, fmt.Errorf("db bookmark rows: %w", FormatError(err))
 	}
 
+	if reverse {
+		slices.Reverse(bookmarks)
+	}
+
 	return bookmarks, n, nil
 }
 
+// formatBookmarkFilter returns the WHERE clause parts & their args matching
+// the filter fields of filter to the current user's bookmarks. Each part is
+// AND-ed together. Values are appended to an arg list to avoid SQL injection.
+func formatBookmarkFilter(ctx context.Context, filter core.BookmarkFilter) (where []string, args []interface{}) {
+	where, args = []string{"1 = 1"}, []interface{}{}
+	if v := filter.ID; v != nil {
+		where, args = append(where, "id = ?"), append(args, *v)
+	}
+	if v := filter.CanonicalUrl; v != nil {
+		where, args = append(where, "canonical_url = ?"), append(args, *v)
+	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, "status = ?"), append(args, *v)
+	}
+	if v := filter.Starred; v != nil {
+		where, args = append(where, "starred = ?"), append(args, *v)
+	}
+	if v := filter.Search; v != nil {
+		pattern := FormatLike(*v)
+		where = append(where, `(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR url LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	// Limit to bookmarks user owns.
+	userID := core.GetUserIDFromContext(ctx)
+	where, args = append(where, "user_id = ?"), append(args, userID)
+
+	return where, args
+}
+
+// createBookmarkWithMode creates a new bookmark unless the user already owns a
+// bookmark with the same canonical url and mode is reject or merge.
+func createBookmarkWithMode(ctx context.Context, tx *Tx, bookmark *core.Bookmark, mode core.DuplicateMode) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	} else if mode == "" || mode == core.DuplicateModeAllow {
+		return createBookmark(ctx, tx, bookmark)
+	}
+
+	// Look up an existing bookmark with the same canonical url.
+	canonicalUrl := tx.CanonicalizeUrl(bookmark.Url)
+	existing, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{CanonicalUrl: &canonicalUrl, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("find duplicate bookmarks: %w", err)
+	} else if len(existing) == 0 {
+		return createBookmark(ctx, tx, bookmark)
+	}
+
+	if mode == core.DuplicateModeReject {
+		return fmt.Errorf("%w: bookmark %d", bookmarkd.ErrBookmarkDuplicate, existing[0].ID)
+	}
+
+	// Merge the submitted fields into the existing bookmark. Only fields that
+	// differ are applied so an identical resubmission doesn't record a revision.
+	var upd core.BookmarkUpdate
+	if bookmark.Name != "" && bookmark.Name != existing[0].Name {
+		upd.Name = &bookmark.Name
+	}
+	if bookmark.Description != "" && bookmark.Description != existing[0].Description {
+		upd.Description = &bookmark.Description
+	}
+
+	merged := existing[0]
+	if upd.Name != nil || upd.Description != nil {
+		if merged, err = updateBookmark(ctx, tx, existing[0].ID, upd); err != nil {
+			return err
+		}
+	}
+	*bookmark = *merged
+
+	return nil
+}
+
 // createBookmark creates a new bookmark.
 func createBookmark(ctx context.Context, tx *Tx, bookmark *core.Bookmark) error {
 	// Assign bookmark to the current user.
@@ -202,11 +392,22 @@ func createBookmark(ctx context.Context, tx *Tx, bookmark *core.Bookmark) error
 	// Set timestamps to current time.
 	bookmark.CreatedAt = tx.Now()
 	bookmark.UpdatedAt = bookmark.CreatedAt
+	bookmark.Version = 1
+
+	// New bookmarks are unread unless a status is given.
+	if bookmark.Status == "" {
+		bookmark.Status = core.BookmarkStatusUnread
+	}
+	bookmark.ReadAt = time.Time{}
+	if bookmark.Status == core.BookmarkStatusRead {
+		bookmark.ReadAt = bookmark.CreatedAt
+	}
 
 	// Perform basic field validation
 	if err := bookmark.Validate(); err != nil {
 		return err
 	}
+	bookmark.CanonicalUrl = tx.CanonicalizeUrl(bookmark.Url)
 
 	// race condition, if user session was valid, then deleted
 	// we should check to ensure the user exists before adding a bookmark
@@ -225,15 +426,25 @@ func createBookmark(ctx context.Context, tx *Tx, bookmark *core.Bookmark) error
 		  name,
 		  description,
 		  url,
+		  canonical_url,
+		  status,
+		  starred,
+		  read_at,
+		  version,
 		  created_at,
 		  updated_at
 		)
-		VALUES (?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		bookmark.UserID,
 		bookmark.Name,
 		bookmark.Description,
 		bookmark.Url,
+		bookmark.CanonicalUrl,
+		bookmark.Status,
+		bookmark.Starred,
+		(*NullTime)(&bookmark.ReadAt),
+		bookmark.Version,
 		(*NullTime)(&bookmark.CreatedAt),
 		(*NullTime)(&bookmark.UpdatedAt),
 	)
@@ -248,6 +459,12 @@ func createBookmark(ctx context.Context, tx *Tx, bookmark *core.Bookmark) error
 	}
 	bookmark.ID = int(id)
 
+	other := *bookmark
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type:    core.EventTypeBookmarkAdded,
+		Payload: &core.EventTypeBookmarkAddedPayload{Bookmark: &other},
+	})
+
 	return nil
 }
 
@@ -259,8 +476,13 @@ func updateBookmark(ctx context.Context, tx *Tx, id int, upd core.BookmarkUpdate
 		return bookmark, err
 	} else if !core.CanEditBookmark(ctx, bookmark) {
 		return bookmark, bookmarkd.ErrUnauthorized
+	} else if v := upd.Version; v != nil && *v != bookmark.Version {
+		return bookmark, bookmarkd.ErrPreconditionFailed
 	}
 
+	// Capture previous values before they are overwritten.
+	changes := upd.Changes(bookmark)
+
 	// Update fields, if set.
 	if v := upd.Name; v != nil {
 		bookmark.Name = *v
@@ -271,30 +493,76 @@ func updateBookmark(ctx context.Context, tx *Tx, id int, upd core.BookmarkUpdate
 	if v := upd.Url; v != nil {
 		bookmark.Url = *v
 	}
+	if v := upd.Status; v != nil && *v != bookmark.Status {
+		// Track when the bookmark was read. Archiving keeps the read time.
+		switch bookmark.Status = *v; *v {
+		case core.BookmarkStatusRead:
+			bookmark.ReadAt = tx.Now()
+		case core.BookmarkStatusUnread:
+			bookmark.ReadAt = time.Time{}
+		}
+	}
+	if v := upd.Starred; v != nil {
+		bookmark.Starred = *v
+	}
 	bookmark.UpdatedAt = tx.Now()
+	version := bookmark.Version
+	bookmark.Version++
 
-	// Perform basic field validation.
-	if err := bookmark.Validate(); err != nil {
+	// Perform basic field validation. The url is only validated when it is
+	// changed so bookmarks with urls saved under older rules can be updated.
+	if err := bookmark.ValidateExceptUrl(); err != nil {
 		return bookmark, err
+	} else if upd.Url != nil {
+		if err := core.ValidateBookmarkUrl(bookmark.Url); err != nil {
+			return bookmark, err
+		}
 	}
+	bookmark.CanonicalUrl = tx.CanonicalizeUrl(bookmark.Url)
 
-	// Execute update query.
-	if _, err := tx.ExecContext(ctx, `
+	// Execute update query. The version is checked again in case the
+	// bookmark changed since it was read.
+	result, err := tx.ExecContext(ctx, `
 		UPDATE bookmarks
 		SET name = ?,
 				description = ?,
 		  	url = ?,
+		    canonical_url = ?,
+		    status = ?,
+		    starred = ?,
+		    read_at = ?,
+		    version = ?,
 		    updated_at = ?
-		WHERE id = ?
+		WHERE id = ? AND version = ?
 	`,
 		bookmark.Name,
 		bookmark.Description,
 		bookmark.Url,
+		bookmark.CanonicalUrl,
+		bookmark.Status,
+		bookmark.Starred,
+		(*NullTime)(&bookmark.ReadAt),
+		bookmark.Version,
 		(*NullTime)(&bookmark.UpdatedAt),
 		id,
-	); err != nil {
+		version,
+	)
+	if err != nil {
 		// should we return inconsitent data or nil?
 		return bookmark, fmt.Errorf("db update bookmark: %w", FormatError(err))
+	} else if n, err := result.RowsAffected(); err != nil {
+		return bookmark, fmt.Errorf("db update bookmark: %w", FormatError(err))
+	} else if n == 0 {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// Record the update in the bookmark's revision history.
+	if err := createBookmarkRevision(ctx, tx, &core.BookmarkRevision{
+		BookmarkID: bookmark.ID,
+		UserID:     core.GetUserIDFromContext(ctx),
+		Changes:    changes,
+	}); err != nil {
+		return bookmark, fmt.Errorf("create bookmark revision: %w", err)
 	}
 
 	if upd.Name != nil {
@@ -336,24 +604,252 @@ func updateBookmark(ctx context.Context, tx *Tx, id int, upd core.BookmarkUpdate
 		}
 	}
 
+	if upd.Status != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkStatusChanged,
+			Payload: &core.EventTypeBookmarkStatusChangedPayload{
+				ID:        bookmark.ID,
+				Status:    bookmark.Status,
+				ReadAt:    bookmark.ReadAt,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark status event: %w", err)
+		}
+	}
+
+	if upd.Starred != nil {
+		if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+			Type: core.EventTypeBookmarkStarredChanged,
+			Payload: &core.EventTypeBookmarkStarredChangedPayload{
+				ID:        bookmark.ID,
+				Starred:   bookmark.Starred,
+				UpdatedAt: bookmark.UpdatedAt,
+			},
+		}); err != nil {
+			return bookmark, fmt.Errorf("publish bookmark starred event: %w", err)
+		}
+	}
+
 	return bookmark, nil
 }
 
+// updateBookmarksStatus sets the status of all bookmarks matching filter.
+// Bookmarks that already have the status are skipped. The sort & paging
+// fields of filter are ignored.
+func updateBookmarksStatus(ctx context.Context, tx *Tx, filter core.BookmarkFilter, status core.BookmarkStatus) (int, error) {
+	if err := status.Validate(); err != nil {
+		return 0, err
+	}
+
+	userID := core.GetUserIDFromContext(ctx)
+	where, args := formatBookmarkFilter(ctx, filter)
+	where, args = append(where, "status != ?"), append(args, status)
+	now := tx.Now()
+
+	// Record the change in the revision history of each bookmark while its
+	// previous status can still be read.
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO bookmark_revisions (
+		  bookmark_id,
+		  user_id,
+		  changes,
+		  created_at
+		)
+		SELECT id, ?, json_array(json_object('field', ?, 'old', status, 'new', ?)), ?
+		FROM bookmarks
+		WHERE `+strings.Join(where, " AND ")+`
+	`,
+		append([]interface{}{userID, core.BookmarkFieldStatus, status, (*NullTime)(&now)}, args...)...,
+	); err != nil {
+		return 0, fmt.Errorf("db insert bookmark revisions: %w", FormatError(err))
+	}
+
+	// Track when the bookmarks were read. Archiving keeps the read time.
+	readAt, readAtArgs := "read_at", []interface{}{}
+	switch status {
+	case core.BookmarkStatusRead:
+		readAt, readAtArgs = "?", []interface{}{(*NullTime)(&now)}
+	case core.BookmarkStatusUnread:
+		readAt = "NULL"
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE bookmarks
+		SET status = ?,
+		    read_at = `+readAt+`,
+		    version = version + 1,
+		    updated_at = ?
+		WHERE `+strings.Join(where, " AND ")+`
+		RETURNING id, read_at
+	`,
+		append(append(append([]interface{}{status}, readAtArgs...), (*NullTime)(&now)), args...)...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("db update bookmarks status: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	// Publish a status change of each bookmark to its owner, the current user.
+	var n int
+	for rows.Next() {
+		payload := &core.EventTypeBookmarkStatusChangedPayload{Status: status, UpdatedAt: now}
+		if err := rows.Scan(&payload.ID, (*NullTime)(&payload.ReadAt)); err != nil {
+			return 0, fmt.Errorf("db scan bookmark status row: %w", FormatError(err))
+		}
+		tx.PublishEvent(userID, core.Event{Type: core.EventTypeBookmarkStatusChanged, Payload: payload})
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("db bookmark status rows: %w", FormatError(err))
+	}
+	return n, nil
+}
+
 // deleteBookmark permanently deletes a bookmark by ID. Returns EUNAUTHORIZED if user
-// does not own the bookmark.
-func deleteBookmark(ctx context.Context, tx *Tx, id int) (*core.Bookmark, error) {
+// does not own the bookmark. Returns ErrPreconditionFailed if version is set
+// and does not match the bookmark.
+func deleteBookmark(ctx context.Context, tx *Tx, id int, version *int) (*core.Bookmark, error) {
 	// Verify object exists & the current user is the owner.
 	bookmark, err := findBookmarkByID(ctx, tx, id)
 	if err != nil {
 		return bookmark, err
 	} else if !core.CanEditBookmark(ctx, bookmark) {
 		return bookmark, bookmarkd.ErrUnauthorized
+	} else if version != nil && *version != bookmark.Version {
+		return bookmark, bookmarkd.ErrPreconditionFailed
 	}
 
 	// Remove row from database.
-	if _, err := tx.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = ?`, id); err != nil {
+	if result, err := tx.ExecContext(ctx, `DELETE FROM bookmarks WHERE id = ? AND version = ?`, id, bookmark.Version); err != nil {
 		return bookmark, fmt.Errorf("db delete bookmark: %w", FormatError(err))
+	} else if n, err := result.RowsAffected(); err != nil {
+		return bookmark, fmt.Errorf("db delete bookmark: %w", FormatError(err))
+	} else if n == 0 {
+		return bookmark, bookmarkd.ErrPreconditionFailed
+	}
+
+	// The row is gone so the event is published to the owner directly.
+	tx.PublishEvent(bookmark.UserID, core.Event{
+		Type:    core.EventTypeBookmarkRemoved,
+		Payload: &core.EventTypeBookmarkRemovedPayload{ID: id},
+	})
+
+	return bookmark, nil
+}
+
+// findDuplicateBookmarks returns groups of the current user's bookmarks that
+// share a canonical url.
+func findDuplicateBookmarks(ctx context.Context, tx *Tx) ([]*core.BookmarkDuplicates, error) {
+	userID := core.GetUserIDFromContext(ctx)
+
+	// Find canonical urls used by more than one bookmark.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT canonical_url
+		FROM bookmarks
+		WHERE user_id = ?
+		GROUP BY canonical_url
+		HAVING COUNT(*) > 1
+		ORDER BY canonical_url ASC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("db select duplicate bookmarks: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	urls := make([]string, 0)
+	for rows.Next() {
+		var canonicalUrl string
+		if err := rows.Scan(&canonicalUrl); err != nil {
+			return nil, fmt.Errorf("db scan duplicate bookmark row: %w", FormatError(err))
+		}
+		urls = append(urls, canonicalUrl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db duplicate bookmark rows: %w", FormatError(err))
+	}
+
+	// Fetch the bookmarks of each group.
+	duplicates := make([]*core.BookmarkDuplicates, 0, len(urls))
+	for _, canonicalUrl := range urls {
+		bookmarks, _, err := findBookmarks(ctx, tx, core.BookmarkFilter{CanonicalUrl: &canonicalUrl})
+		if err != nil {
+			return nil, fmt.Errorf("find bookmarks: %w", err)
+		}
+		duplicates = append(duplicates, &core.BookmarkDuplicates{
+			CanonicalUrl: canonicalUrl,
+			Bookmarks:    bookmarks,
+		})
+	}
+
+	return duplicates, nil
+}
+
+// canonicalizeBookmarkUrls sets the canonical url of the bookmarks created
+// before urls were canonicalized. Runs once, as part of migration 0000002.
+func canonicalizeBookmarkUrls(ctx context.Context, db *DB, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, url
+		FROM bookmarks
+		WHERE canonical_url = ''
+	`)
+	if err != nil {
+		return fmt.Errorf("db select bookmarks: %w", FormatError(err))
+	}
+	defer rows.Close()
+
+	urls := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var url string
+		if err := rows.Scan(&id, &url); err != nil {
+			return fmt.Errorf("db scan bookmark row: %w", FormatError(err))
+		}
+		urls[id] = url
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("db bookmark rows: %w", FormatError(err))
+	}
+	rows.Close()
+
+	for id, url := range urls {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE bookmarks
+			SET canonical_url = ?
+			WHERE id = ?
+		`, db.UrlCanonicalizer.Canonicalize(url), id); err != nil {
+			return fmt.Errorf("db update bookmark %d: %w", id, FormatError(err))
+		}
 	}
+	return nil
+}
+
+// revertBookmark restores the fields changed by a revision to their previous
+// values. Returns ENOTFOUND if the revision does not belong to the bookmark.
+func revertBookmark(ctx context.Context, tx *Tx, id int, revisionID int) (*core.Bookmark, error) {
+	revision, err := findBookmarkRevisionByID(ctx, tx, id, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the previous values as a regular update so the revert is checked
+	// for ownership, validated and recorded like any other change.
+	bookmark, err := updateBookmark(ctx, tx, id, revision.RevertUpdate())
+	if err != nil {
+		return bookmark, err
+	}
+
+	if err := publishBookmarkEvent(ctx, tx, id, core.Event{
+		Type: core.EventTypeBookmarkReverted,
+		Payload: &core.EventTypeBookmarkRevertedPayload{
+			ID:         bookmark.ID,
+			RevisionID: revision.ID,
+			UpdatedAt:  bookmark.UpdatedAt,
+		},
+	}); err != nil {
+		return bookmark, fmt.Errorf("publish bookmark reverted event: %w", err)
+	}
+
 	return bookmark, nil
 }
 
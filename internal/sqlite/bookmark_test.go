@@ -3,11 +3,13 @@ package sqlite_test
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
+	"bookmarkd/internal/mock"
 	"bookmarkd/internal/sqlite"
 	"bookmarkd/utils/require"
 )
@@ -26,7 +28,7 @@ func Test_BookmarkService_CreateBookmark(t *testing.T) {
 	// Ensure a bookmark can be created by a user & a membership for the user is automatically created.
 	t.Run("OK", func(t *testing.T) {
 		bookmark := &core.Bookmark{Name: "mybookmark", Description: "Description", Url: "https://mybookmark"}
-		err := b.CreateBookmark(userCtx, bookmark)
+		err := b.CreateBookmark(userCtx, bookmark, "")
 
 		require.Equal(t, err, nil)
 		require.Equal(t, bookmark.ID, 1)
@@ -41,7 +43,7 @@ func Test_BookmarkService_CreateBookmark(t *testing.T) {
 
 	// Ensure that creating a nameless bookmark returns an error.
 	t.Run("ErrNameRequired", func(t *testing.T) {
-		err := b.CreateBookmark(userCtx, &core.Bookmark{})
+		err := b.CreateBookmark(userCtx, &core.Bookmark{}, "")
 
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
@@ -49,36 +51,46 @@ func Test_BookmarkService_CreateBookmark(t *testing.T) {
 
 	// Ensure that creating a bookmark with a long name returns an error.
 	t.Run("ErrNameTooLong", func(t *testing.T) {
-		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: strings.Repeat("X", core.MaxBookmarkNameLen+1)})
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: strings.Repeat("X", core.MaxBookmarkNameLen+1)}, "")
 
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
 	})
 
 	t.Run("ErrDescriptionTooLong", func(t *testing.T) {
-		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark", Description: strings.Repeat("X", core.MaxBookmarkDescriptionLen+1)})
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark", Description: strings.Repeat("X", core.MaxBookmarkDescriptionLen+1)}, "")
 
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
 	})
 
 	t.Run("ErrUrlRequired", func(t *testing.T) {
-		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark"})
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark"}, "")
 
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
 	})
 
 	t.Run("ErrUrlTooLong", func(t *testing.T) {
-		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark", Url: strings.Repeat("X", core.MaxBookmarkUrlLen+1)})
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark", Url: strings.Repeat("X", core.MaxBookmarkUrlLen+1)}, "")
 
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
 	})
 
+	// Ensure unsafe schemes are rejected & the offending field is identified.
+	t.Run("ErrUrlScheme", func(t *testing.T) {
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "bookmark", Url: "javascript:alert(1)"}, "")
+
+		var verr *bookmarkd.ValidationError
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+		require.Equal(t, errors.As(err, &verr), true)
+		require.Equal(t, verr.Field, "url")
+	})
+
 	// Ensure user is logged in when creating a bookmark.
 	t.Run("ErrUserRequired", func(t *testing.T) {
-		err := b.CreateBookmark(context.Background(), &core.Bookmark{})
+		err := b.CreateBookmark(context.Background(), &core.Bookmark{}, "")
 
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
@@ -95,7 +107,7 @@ func Test_BookmarkService_UpdateBookmark(t *testing.T) {
 
 	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
 	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
-	bookmark := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME", Description: "", Url: "http://bookmark1`"})
+	bookmark := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME", Description: "", Url: "http://bookmark1"})
 
 	// Ensure a bookmark name can be updated.
 	t.Run("OK", func(t *testing.T) {
@@ -105,7 +117,84 @@ func Test_BookmarkService_UpdateBookmark(t *testing.T) {
 
 		require.Equal(t, err, nil)
 		require.Equal(t, uu.Name, "mybookmark2")
+		require.Equal(t, uu.Version, 2)
+	})
+
+	// Ensure an update against an old version is rejected.
+	t.Run("ErrPreconditionFailed", func(t *testing.T) {
+		newName, version := "mybookmark3", 1
+		_, err := b.UpdateBookmark(userCtx, bookmark.ID, core.BookmarkUpdate{Name: &newName, Version: &version})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrPreconditionFailed), true)
+
+		_, err = b.DeleteBookmark(userCtx, bookmark.ID, &version)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrPreconditionFailed), true)
+
+		version = 2
+		uu, err := b.UpdateBookmark(userCtx, bookmark.ID, core.BookmarkUpdate{Name: &newName, Version: &version})
+		require.Equal(t, err, nil)
+		require.Equal(t, uu.Version, 3)
 	})
+
+	// Ensure a bookmark saved with a url that is no longer valid can be
+	// updated as long as its url is left alone.
+	t.Run("LegacyUrl", func(t *testing.T) {
+		legacy := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "LEGACY", Url: "http://bookmark2"})
+		MustExec(t, ctx, db, `UPDATE bookmarks SET url = 'file:///etc/passwd' WHERE name = 'LEGACY'`)
+
+		starred := true
+		uu, err := b.UpdateBookmark(userCtx, legacy.ID, core.BookmarkUpdate{Starred: &starred})
+		require.Equal(t, err, nil)
+		require.Equal(t, uu.Starred, true)
+		require.Equal(t, uu.Url, "file:///etc/passwd")
+
+		url := "file:///etc/shadow"
+		_, err = b.UpdateBookmark(userCtx, legacy.ID, core.BookmarkUpdate{Url: &url})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+	})
+}
+
+// Ensure the length limits of the schema match the limits checked by
+// core.Bookmark.Validate(), so rows written through raw SQL are limited alike.
+func Test_BookmarkService_LengthLimits(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	ctx := context.Background()
+
+	user := MustCreateUser(t, ctx, sqlite.NewUserStore(db), &core.User{Username: "NAME0"})
+
+	// Writes a bookmark with a field of n characters & returns the error.
+	write := func(t *testing.T, query, field string, n int) error {
+		t.Helper()
+		values := map[string]string{"name": "NAME", "description": "", "url": "https://example.com"}
+		values[field] = strings.Repeat("é", n)
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.Equal(t, err, nil)
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, query, user.ID, values["name"], values["description"], values["url"]); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	for _, tt := range []struct {
+		field string
+		max   int
+	}{
+		{"name", core.MaxBookmarkNameLen},
+		{"description", core.MaxBookmarkDescriptionLen},
+		{"url", core.MaxBookmarkUrlLen},
+	} {
+		t.Run(tt.field, func(t *testing.T) {
+			insert := `INSERT INTO bookmarks (user_id, name, description, url, created_at, updated_at) VALUES (?, ?, ?, ?, '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z')`
+			require.Equal(t, write(t, insert, tt.field, tt.max), nil)
+			require.NotEqual(t, write(t, insert, tt.field, tt.max+1), nil)
+
+			update := `UPDATE bookmarks SET name = ?2, description = ?3, url = ?4 WHERE user_id = ?1`
+			require.Equal(t, write(t, update, tt.field, tt.max), nil)
+			require.NotEqual(t, write(t, update, tt.field, tt.max+1), nil)
+		})
+	}
 }
 
 func Test_BookmarkService_FindBookmarks(t *testing.T) {
@@ -118,9 +207,9 @@ func Test_BookmarkService_FindBookmarks(t *testing.T) {
 
 	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
 	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
-	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Description: "", Url: "http://bookmark1`"})
-	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME2", Description: "", Url: "http://bookmark2`"})
-	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME3", Description: "", Url: "http://bookmark3`"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Description: "", Url: "http://bookmark1"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME2", Description: "", Url: "http://bookmark2"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME3", Description: "", Url: "http://bookmark3"})
 
 	// Ensure all bookmarks that are owned by user can be fetched.
 	t.Run("Ok", func(t *testing.T) {
@@ -145,12 +234,12 @@ func TestBookmarkService_DeleteBookmark(t *testing.T) {
 
 	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
 	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
-	deleteMe := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Description: "", Url: "http://bookmark1`"})
-	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME2", Description: "", Url: "http://bookmark2`"})
+	deleteMe := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Description: "", Url: "http://bookmark1"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME2", Description: "", Url: "http://bookmark2"})
 
 	// Ensure a bookmark can be deleted by the owner.
 	t.Run("OK", func(t *testing.T) {
-		bookmark, err := b.DeleteBookmark(userCtx, deleteMe.ID)
+		bookmark, err := b.DeleteBookmark(userCtx, deleteMe.ID, nil)
 		require.Equal(t, err, nil)
 
 		_, err = b.FindBookmarkByID(userCtx, bookmark.ID)
@@ -158,10 +247,474 @@ func TestBookmarkService_DeleteBookmark(t *testing.T) {
 	})
 }
 
+func Test_BookmarkService_BookmarkRevisions(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	b := sqlite.NewBookmarkStore(db)
+	ctx := context.Background()
+
+	user0 := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	user1 := MustCreateUser(t, ctx, u, &core.User{Username: "NAME1"})
+	_, ctx0 := MustCreateSession(t, ctx, s, user0.ID)
+	_, ctx1 := MustCreateSession(t, ctx, s, user1.ID)
+	bookmark := MustCreateBookmark(t, ctx0, b, &core.Bookmark{Name: "NAME", Description: "DESC", Url: "http://bookmark1"})
+
+	var events []core.Event
+	db.EventService = &mock.EventService{
+		PublishEventFn: func(userID string, event core.Event) { events = append(events, event) },
+	}
+
+	// Ensure every update records the old & new values of the changed fields.
+	t.Run("RecordsRevision", func(t *testing.T) {
+		newName, newUrl := "NAME2", "http://bookmark2"
+		_, err := b.UpdateBookmark(ctx0, bookmark.ID, core.BookmarkUpdate{Name: &newName, Url: &newUrl})
+		require.Equal(t, err, nil)
+
+		revisions, n, err := b.FindBookmarkRevisions(ctx0, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+		require.Equal(t, revisions[0].UserID, user0.ID)
+		require.Equal(t, revisions[0].CreatedAt.IsZero(), false)
+		require.Equal(t, len(revisions[0].Changes), 2)
+		require.Equal(t, revisions[0].Changes[0], core.BookmarkFieldChange{Field: core.BookmarkFieldName, Old: "NAME", New: "NAME2"})
+		require.Equal(t, revisions[0].Changes[1], core.BookmarkFieldChange{Field: core.BookmarkFieldUrl, Old: "http://bookmark1", New: "http://bookmark2"})
+	})
+
+	// Ensure a revision can be reverted & the revert is recorded.
+	t.Run("Revert", func(t *testing.T) {
+		revisions, _, err := b.FindBookmarkRevisions(ctx0, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+
+		events = nil
+		other, err := b.RevertBookmark(ctx0, bookmark.ID, revisions[0].ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Name, "NAME")
+		require.Equal(t, other.Url, "http://bookmark1")
+		require.Equal(t, other.Description, "DESC")
+
+		_, n, err := b.FindBookmarkRevisions(ctx0, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 2)
+
+		require.Equal(t, len(events), 3)
+		require.Equal(t, events[2].Type, core.EventTypeBookmarkReverted)
+	})
+
+	// Ensure revisions are only visible to the bookmark owner.
+	t.Run("ErrNotFound", func(t *testing.T) {
+		_, n, err := b.FindBookmarkRevisions(ctx1, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+
+		_, err = b.RevertBookmark(ctx1, bookmark.ID, 1)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+}
+
+func Test_BookmarkService_DuplicateBookmarks(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	b := sqlite.NewBookmarkStore(db)
+	ctx := context.Background()
+
+	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
+	original := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Url: "https://example.com/page?utm_source=feed"})
+
+	// Ensure the canonical url is stored alongside the url.
+	t.Run("CanonicalUrl", func(t *testing.T) {
+		require.Equal(t, original.Url, "https://example.com/page?utm_source=feed")
+		require.Equal(t, original.CanonicalUrl, "https://example.com/page")
+	})
+
+	// Ensure a duplicate is rejected when requested.
+	t.Run("Reject", func(t *testing.T) {
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "NAME2", Url: "http://EXAMPLE.com/page/"}, core.DuplicateModeReject)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBookmarkDuplicate), true)
+	})
+
+	// Ensure a duplicate is merged into the existing bookmark when requested.
+	t.Run("Merge", func(t *testing.T) {
+		bookmark := &core.Bookmark{Name: "NAME2", Description: "DESC", Url: "https://example.com/page#top"}
+		err := b.CreateBookmark(userCtx, bookmark, core.DuplicateModeMerge)
+		require.Equal(t, err, nil)
+		require.Equal(t, bookmark.ID, original.ID)
+		require.Equal(t, bookmark.Name, "NAME2")
+		require.Equal(t, bookmark.Description, "DESC")
+		require.Equal(t, bookmark.Url, original.Url)
+
+		_, n, err := b.FindBookmarks(userCtx, core.BookmarkFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+	})
+
+	// Ensure duplicates are created by default & reported.
+	t.Run("Report", func(t *testing.T) {
+		MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME3", Url: "https://example.com/page"})
+		MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME4", Url: "https://example.com/other"})
+
+		duplicates, err := b.FindDuplicateBookmarks(userCtx)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(duplicates), 1)
+		require.Equal(t, duplicates[0].CanonicalUrl, "https://example.com/page")
+		require.Equal(t, len(duplicates[0].Bookmarks), 2)
+	})
+
+	// Ensure an unknown mode is rejected.
+	t.Run("ErrUnknownMode", func(t *testing.T) {
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "NAME5", Url: "https://example.com/"}, "skip")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+}
+
+// Ensure bookmarks created before urls were canonicalized are canonicalized
+// by the migration adding canonical urls & found as duplicates.
+func Test_BookmarkService_CanonicalUrlMigration(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "db")
+
+	// Create a bookmark in a database from before canonical urls.
+	legacy := sqlite.NewDB(dsn)
+	legacy.SkipMigrations = true
+	if err := legacy.Open(); err != nil {
+		t.Fatal(err)
+	}
+	target := 1
+	if _, err := legacy.Migrate(ctx, core.MigrateOptions{Target: &target}); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := legacy.BeginTx(ctx, nil)
+	require.Equal(t, err, nil)
+	for _, query := range []string{
+		`INSERT INTO users (id, username, seed, created_at, updated_at) VALUES ('USER0', 'NAME0', 'SEED', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z')`,
+		`INSERT INTO bookmarks (user_id, name, url, created_at, updated_at) VALUES ('USER0', 'NAME1', 'https://Example.com/page/?utm_source=x', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z')`,
+	} {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	require.Equal(t, tx.Commit(), nil)
+	MustCloseDB(t, legacy)
+
+	db := sqlite.NewDB(dsn)
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer MustCloseDB(t, db)
+	s := sqlite.NewSessionStore(db)
+	b := sqlite.NewBookmarkStore(db)
+	_, userCtx := MustCreateSession(t, ctx, s, "USER0")
+
+	original, err := b.FindBookmarkByID(userCtx, 1)
+	require.Equal(t, err, nil)
+	require.Equal(t, original.CanonicalUrl, "https://example.com/page")
+
+	t.Run("Reject", func(t *testing.T) {
+		err := b.CreateBookmark(userCtx, &core.Bookmark{Name: "NAME2", Url: "https://example.com/page"}, core.DuplicateModeReject)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBookmarkDuplicate), true)
+	})
+
+	t.Run("Report", func(t *testing.T) {
+		MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME3", Url: "https://example.com/page"})
+
+		duplicates, err := b.FindDuplicateBookmarks(userCtx)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(duplicates), 1)
+		require.Equal(t, len(duplicates[0].Bookmarks), 2)
+		require.Equal(t, duplicates[0].Bookmarks[0].ID, original.ID)
+	})
+
+	// Ensure bookmarks are only canonicalized by the migration, not whenever
+	// the database is opened.
+	t.Run("Once", func(t *testing.T) {
+		MustExec(t, ctx, db, `UPDATE bookmarks SET canonical_url = '' WHERE id = 1`)
+
+		other := sqlite.NewDB(dsn)
+		if err := other.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer MustCloseDB(t, other)
+
+		bookmark, err := sqlite.NewBookmarkStore(other).FindBookmarkByID(userCtx, 1)
+		require.Equal(t, err, nil)
+		require.Equal(t, bookmark.CanonicalUrl, "")
+	})
+}
+
+func Test_BookmarkService_BookmarkStatus(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	b := sqlite.NewBookmarkStore(db)
+	ctx := context.Background()
+
+	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
+	bookmark1 := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Url: "http://bookmark1"})
+	bookmark2 := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME2", Url: "http://bookmark2"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME3", Url: "http://bookmark3", Status: core.BookmarkStatusRead})
+
+	// Ensure new bookmarks are unread by default.
+	t.Run("DefaultUnread", func(t *testing.T) {
+		require.Equal(t, bookmark1.Status, core.BookmarkStatusUnread)
+		require.Equal(t, bookmark1.ReadAt.IsZero(), true)
+		require.Equal(t, bookmark1.Starred, false)
+	})
+
+	// Ensure bookmarks can be starred & filtered by the flag.
+	t.Run("Starred", func(t *testing.T) {
+		starred := true
+		other, err := b.UpdateBookmark(userCtx, bookmark1.ID, core.BookmarkUpdate{Starred: &starred})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Starred, true)
+
+		a, n, err := b.FindBookmarks(userCtx, core.BookmarkFilter{Starred: &starred})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+		require.Equal(t, a[0].ID, bookmark1.ID)
+	})
+
+	// Ensure all unread bookmarks can be marked read at once.
+	t.Run("MarkAllRead", func(t *testing.T) {
+		n, err := b.UpdateBookmarksStatus(userCtx, core.BookmarkFilter{}, core.BookmarkStatusRead)
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 2)
+
+		status := core.BookmarkStatusRead
+		a, n, err := b.FindBookmarks(userCtx, core.BookmarkFilter{Status: &status})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 3)
+		require.Equal(t, a[0].ReadAt.IsZero(), false)
+	})
+
+	// Ensure archiving keeps the read time & marking unread clears it.
+	t.Run("ReadAt", func(t *testing.T) {
+		archived, unread := core.BookmarkStatusArchived, core.BookmarkStatusUnread
+		other, err := b.UpdateBookmark(userCtx, bookmark1.ID, core.BookmarkUpdate{Status: &archived})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.ReadAt.IsZero(), false)
+
+		other, err = b.UpdateBookmark(userCtx, bookmark1.ID, core.BookmarkUpdate{Status: &unread})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.ReadAt.IsZero(), true)
+	})
+
+	// Ensure an unknown status is rejected.
+	t.Run("ErrUnknownStatus", func(t *testing.T) {
+		status := core.BookmarkStatus("later")
+		_, err := b.UpdateBookmark(userCtx, bookmark1.ID, core.BookmarkUpdate{Status: &status})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+	})
+
+	// Ensure bookmarks saved with a url that is no longer valid can be marked
+	// & their previous status is recorded.
+	t.Run("LegacyUrl", func(t *testing.T) {
+		MustExec(t, ctx, db, `UPDATE bookmarks SET url = 'file:///etc/passwd' WHERE name = 'NAME2'`)
+
+		n, err := b.UpdateBookmarksStatus(userCtx, core.BookmarkFilter{}, core.BookmarkStatusUnread)
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 2)
+
+		other, err := b.FindBookmarkByID(userCtx, bookmark2.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Status, core.BookmarkStatusUnread)
+		require.Equal(t, other.ReadAt.IsZero(), true)
+		require.Equal(t, other.Version, 3)
+
+		revisions, n, err := b.FindBookmarkRevisions(userCtx, core.BookmarkRevisionFilter{BookmarkID: &bookmark2.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 2)
+		require.Equal(t, len(revisions[0].Changes), 1)
+		require.Equal(t, revisions[0].Changes[0], core.BookmarkFieldChange{Field: core.BookmarkFieldStatus, Old: "read", New: "unread"})
+	})
+}
+
 // MustCreateBookmark creates a bookmark in the database. Fatal on error.
+func Test_BookmarkService_BookmarkPagination(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	b := sqlite.NewBookmarkStore(db)
+	ctx := context.Background()
+
+	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "golang", Url: "http://bookmark1"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "banana", Url: "http://bookmark2", Description: "golang"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "cherry", Url: "http://bookmark3"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "apple", Url: "http://golang4"})
+	MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "100%", Url: "http://bookmark5"})
+
+	names := func(a []*core.Bookmark) string {
+		var s []string
+		for _, bookmark := range a {
+			s = append(s, bookmark.Name)
+		}
+		return strings.Join(s, ",")
+	}
+
+	// Ensure bookmarks can be sorted by name in either direction.
+	t.Run("Sort", func(t *testing.T) {
+		a, _, err := b.FindBookmarks(userCtx, core.BookmarkFilter{Sort: core.SortName})
+		require.Equal(t, err, nil)
+		require.Equal(t, names(a), "100%,apple,banana,cherry,golang")
+
+		a, _, err = b.FindBookmarks(userCtx, core.BookmarkFilter{Sort: core.SortName, Direction: core.SortDesc})
+		require.Equal(t, err, nil)
+		require.Equal(t, names(a), "golang,cherry,banana,apple,100%")
+	})
+
+	// Ensure cursors walk forwards & backwards through the list.
+	t.Run("Cursor", func(t *testing.T) {
+		filter := core.BookmarkFilter{Sort: core.SortName, Limit: 2}
+		a, n, err := b.FindBookmarks(userCtx, filter)
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 5)
+		require.Equal(t, names(a), "100%,apple")
+		next, prev := filter.Cursors(a)
+		require.Equal(t, prev, "")
+
+		filter.Cursor = next
+		a, _, err = b.FindBookmarks(userCtx, filter)
+		require.Equal(t, err, nil)
+		require.Equal(t, names(a), "banana,cherry")
+		next, prev = filter.Cursors(a)
+
+		filter.Cursor = next
+		a, _, err = b.FindBookmarks(userCtx, filter)
+		require.Equal(t, err, nil)
+		require.Equal(t, names(a), "golang")
+		next, _ = filter.Cursors(a)
+		require.Equal(t, next, "")
+
+		filter.Cursor = prev
+		a, _, err = b.FindBookmarks(userCtx, filter)
+		require.Equal(t, err, nil)
+		require.Equal(t, names(a), "100%,apple")
+	})
+
+	// Ensure a malformed cursor is rejected.
+	t.Run("ErrInvalidCursor", func(t *testing.T) {
+		_, _, err := b.FindBookmarks(userCtx, core.BookmarkFilter{Cursor: "!"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+
+	// Ensure searches match name, description & url ordered by relevance.
+	t.Run("Search", func(t *testing.T) {
+		search := "GOLANG"
+		a, n, err := b.FindBookmarks(userCtx, core.BookmarkFilter{Search: &search, Sort: core.SortRelevance})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 3)
+		require.Equal(t, names(a), "golang,banana,apple")
+	})
+
+	// Ensure LIKE wildcards in the search are matched literally.
+	t.Run("SearchWildcard", func(t *testing.T) {
+		search := "0%"
+		a, _, err := b.FindBookmarks(userCtx, core.BookmarkFilter{Search: &search})
+		require.Equal(t, err, nil)
+		require.Equal(t, names(a), "100%")
+	})
+}
+
+func Test_BookmarkService_BatchBookmarks(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	b := sqlite.NewBookmarkStore(db)
+	ctx := context.Background()
+
+	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
+	bookmark1 := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME1", Url: "http://bookmark1"})
+	bookmark2 := MustCreateBookmark(t, userCtx, b, &core.Bookmark{Name: "NAME2", Url: "http://bookmark2"})
+
+	var events []core.Event
+	db.EventService = &mock.EventService{
+		PublishEventFn: func(userID string, event core.Event) { events = append(events, event) },
+	}
+
+	newName := "NAME3"
+	operations := []core.BookmarkOp{
+		{Op: core.BookmarkOpCreate, Bookmark: &core.Bookmark{Name: "NAME4", Url: "http://bookmark4"}},
+		{Op: core.BookmarkOpUpdate, ID: 100, Update: &core.BookmarkUpdate{Name: &newName}},
+		{Op: core.BookmarkOpUpdate, ID: bookmark1.ID, Update: &core.BookmarkUpdate{Name: &newName}},
+	}
+
+	// Ensure a failing operation rolls back an atomic batch & publishes nothing.
+	t.Run("Atomic", func(t *testing.T) {
+		events = nil
+		result, err := b.BatchBookmarks(userCtx, &core.BookmarkBatch{Operations: operations})
+		require.Equal(t, err, nil)
+		require.Equal(t, result.Committed, false)
+		require.Equal(t, result.Results[0].Status, core.BookmarkOpStatusRolledBack)
+		require.Equal(t, result.Results[1].Status, core.BookmarkOpStatusFailed)
+		require.Equal(t, errors.Is(result.Results[1].Err, bookmarkd.ErrNotFound), true)
+		require.Equal(t, result.Results[2].Status, core.BookmarkOpStatusSkipped)
+		require.Equal(t, len(events), 0)
+
+		_, n, err := b.FindBookmarks(userCtx, core.BookmarkFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 2)
+	})
+
+	// Ensure a best effort batch applies every operation that succeeds &
+	// publishes their events after commit.
+	t.Run("BestEffort", func(t *testing.T) {
+		events = nil
+		batch := &core.BookmarkBatch{
+			Mode:       core.BatchModeBestEffort,
+			Operations: append(operations, core.BookmarkOp{Op: core.BookmarkOpDelete, ID: bookmark2.ID}),
+		}
+		result, err := b.BatchBookmarks(userCtx, batch)
+		require.Equal(t, err, nil)
+		require.Equal(t, result.Committed, true)
+		require.Equal(t, result.Results[0].Status, core.BookmarkOpStatusOK)
+		require.Equal(t, result.Results[0].Bookmark.Name, "NAME4")
+		require.Equal(t, result.Results[1].Status, core.BookmarkOpStatusFailed)
+		require.Equal(t, result.Results[2].Status, core.BookmarkOpStatusOK)
+		require.Equal(t, result.Results[2].Bookmark.Name, "NAME3")
+		require.Equal(t, result.Results[3].Status, core.BookmarkOpStatusOK)
+
+		require.Equal(t, len(events), 3)
+		require.Equal(t, events[0].Type, core.EventTypeBookmarkAdded)
+		require.Equal(t, events[1].Type, core.EventTypeBookmarkNameChanged)
+		require.Equal(t, events[2].Type, core.EventTypeBookmarkRemoved)
+
+		_, err = b.FindBookmarkByID(userCtx, bookmark2.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	// Ensure an unknown operation only fails that operation.
+	t.Run("ErrUnknownOp", func(t *testing.T) {
+		result, err := b.BatchBookmarks(userCtx, &core.BookmarkBatch{
+			Mode:       core.BatchModeBestEffort,
+			Operations: []core.BookmarkOp{{Op: "tag"}},
+		})
+		require.Equal(t, err, nil)
+		require.Equal(t, errors.Is(result.Results[0].Err, bookmarkd.ErrInvalidInput), true)
+	})
+
+	// Ensure empty batches & unknown modes are rejected.
+	t.Run("ErrBadRequest", func(t *testing.T) {
+		_, err := b.BatchBookmarks(userCtx, &core.BookmarkBatch{})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+
+		_, err = b.BatchBookmarks(userCtx, &core.BookmarkBatch{Mode: "some", Operations: operations})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+}
+
 func MustCreateBookmark(tb testing.TB, ctx context.Context, bookmarkStore core.BookmarkStore, bookmark *core.Bookmark) *core.Bookmark {
 	tb.Helper()
-	if err := bookmarkStore.CreateBookmark(ctx, bookmark); err != nil {
+	if err := bookmarkStore.CreateBookmark(ctx, bookmark, ""); err != nil {
 		tb.Fatal(err)
 	}
 	return bookmark
@@ -0,0 +1,26 @@
+package sqlite_test
+
+import (
+	"testing"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/internal/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.TestStores(t, func(tb testing.TB, events core.EventService, now func() time.Time) *storetest.Stores {
+		db := MustOpenDB(tb)
+		tb.Cleanup(func() { MustCloseDB(tb, db) })
+		db.EventService = events
+		db.Now = now
+
+		return &storetest.Stores{
+			UserStore:           sqlite.NewUserStore(db),
+			SessionStore:        sqlite.NewSessionStore(db),
+			BookmarkStore:       sqlite.NewBookmarkStore(db),
+			IdempotencyKeyStore: sqlite.NewIdempotencyKeyStore(db, time.Hour),
+		}
+	})
+}
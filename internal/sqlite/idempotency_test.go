@@ -0,0 +1,125 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
+)
+
+func Test_IdempotencyKeyStore(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	k := sqlite.NewIdempotencyKeyStore(db, time.Hour)
+	ctx := context.Background()
+
+	user0 := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	user1 := MustCreateUser(t, ctx, u, &core.User{Username: "NAME1"})
+	_, ctx0 := MustCreateSession(t, ctx, s, user0.ID)
+	_, ctx1 := MustCreateSession(t, ctx, s, user1.ID)
+
+	// Ensure a key is reserved once & returned with its response afterwards.
+	t.Run("OK", func(t *testing.T) {
+		key := &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP1"}
+		other, err := k.ReserveIdempotencyKey(ctx0, key)
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+
+		other, err = k.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP1"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Completed, false)
+
+		key.StatusCode, key.Body = 201, []byte("BODY")
+		key.Header = map[string][]string{"Content-Type": {"application/json"}}
+		require.Equal(t, k.CompleteIdempotencyKey(ctx0, key), nil)
+
+		other, err = k.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP2"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Completed, true)
+		require.Equal(t, other.Fingerprint, "FP1")
+		require.Equal(t, other.StatusCode, 201)
+		require.Equal(t, string(other.Body), "BODY")
+		require.Equal(t, other.Header["Content-Type"][0], "application/json")
+	})
+
+	// Ensure keys are scoped per user.
+	t.Run("PerUser", func(t *testing.T) {
+		other, err := k.ReserveIdempotencyKey(ctx1, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP1"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+	})
+
+	// Ensure a deleted key can be reserved again.
+	t.Run("Delete", func(t *testing.T) {
+		require.Equal(t, k.DeleteIdempotencyKey(ctx0, "KEY1"), nil)
+
+		other, err := k.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP3"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+	})
+
+	// Ensure keys can be reused once they expire.
+	t.Run("Expired", func(t *testing.T) {
+		db.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		defer func() { db.Now = time.Now }()
+
+		other, err := k.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP4"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		_, err := k.ReserveIdempotencyKey(ctx, &core.IdempotencyKey{Key: "KEY1"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+}
+
+// Ensure concurrent requests with the same key reserve it exactly once & the
+// others are given the reserved key instead of an error.
+func Test_IdempotencyKeyStore_ConcurrentReserve(t *testing.T) {
+	db := sqlite.NewDB(filepath.Join(t.TempDir(), "db"))
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer MustCloseDB(t, db)
+	u := sqlite.NewUserStore(db)
+	s := sqlite.NewSessionStore(db)
+	k := sqlite.NewIdempotencyKeyStore(db, time.Hour)
+	ctx := context.Background()
+
+	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	_, userCtx := MustCreateSession(t, ctx, s, user.ID)
+
+	const n = 16
+	var wg sync.WaitGroup
+	others, errs := make([]*core.IdempotencyKey, n), make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			others[i], errs[i] = k.ReserveIdempotencyKey(userCtx, &core.IdempotencyKey{Key: "KEY1", Fingerprint: "FP1"})
+		}()
+	}
+	wg.Wait()
+
+	var reserved int
+	for i := range n {
+		require.Equal(t, errs[i], nil)
+		if others[i] == nil {
+			reserved++
+		} else {
+			require.Equal(t, others[i].Fingerprint, "FP1")
+			require.Equal(t, others[i].Completed, false)
+		}
+	}
+	require.Equal(t, reserved, 1)
+}
@@ -0,0 +1,9 @@
+CREATE TABLE bookmark_revisions (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	bookmark_id INTEGER NOT NULL REFERENCES bookmarks (id) ON DELETE CASCADE,
+	user_id     INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	changes     TEXT NOT NULL,
+	created_at  TEXT NOT NULL
+);
+
+CREATE INDEX bookmark_revisions_bookmark_id_idx ON bookmark_revisions (bookmark_id);
@@ -0,0 +1,3 @@
+DROP INDEX bookmarks_user_id_canonical_url_idx;
+
+ALTER TABLE bookmarks DROP COLUMN canonical_url;
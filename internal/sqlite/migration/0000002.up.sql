@@ -0,0 +1,6 @@
+ALTER TABLE bookmarks ADD COLUMN canonical_url TEXT NOT NULL DEFAULT "";
+
+-- Existing bookmarks are canonicalized by the Go code of this migration, see
+-- canonicalizeBookmarkUrls().
+
+CREATE INDEX bookmarks_user_id_canonical_url_idx ON bookmarks (user_id, canonical_url);
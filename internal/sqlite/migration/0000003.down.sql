@@ -0,0 +1,2 @@
+DROP TRIGGER bookmarks_length_insert_trg;
+DROP TRIGGER bookmarks_length_update_trg;
@@ -0,0 +1,14 @@
+-- TEXT columns have no length limit in sqlite. Enforce the limits checked by
+-- core.Bookmark.Validate() in the schema as well so rows written outside of
+-- the application are rejected too.
+CREATE TRIGGER bookmarks_length_insert_trg BEFORE INSERT ON bookmarks
+WHEN length(NEW.name) > 255 OR length(NEW.description) > 255 OR length(NEW.url) > 8192
+BEGIN
+	SELECT RAISE(ABORT, 'bookmark field too long');
+END;
+
+CREATE TRIGGER bookmarks_length_update_trg BEFORE UPDATE ON bookmarks
+WHEN length(NEW.name) > 255 OR length(NEW.description) > 255 OR length(NEW.url) > 8192
+BEGIN
+	SELECT RAISE(ABORT, 'bookmark field too long');
+END;
@@ -0,0 +1,5 @@
+DROP INDEX bookmarks_user_id_status_idx;
+
+ALTER TABLE bookmarks DROP COLUMN read_at;
+ALTER TABLE bookmarks DROP COLUMN starred;
+ALTER TABLE bookmarks DROP COLUMN status;
@@ -0,0 +1,5 @@
+ALTER TABLE bookmarks ADD COLUMN status TEXT NOT NULL DEFAULT "unread";
+ALTER TABLE bookmarks ADD COLUMN starred INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE bookmarks ADD COLUMN read_at TEXT;
+
+CREATE INDEX bookmarks_user_id_status_idx ON bookmarks (user_id, status);
@@ -0,0 +1,15 @@
+CREATE TABLE idempotency_keys (
+	user_id     TEXT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	key         TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	completed   INTEGER NOT NULL DEFAULT 0,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	header      TEXT NOT NULL DEFAULT '{}',
+	body        BLOB,
+	created_at  TEXT NOT NULL,
+	expires_at  TEXT NOT NULL,
+
+	PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
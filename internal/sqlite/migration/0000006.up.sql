@@ -0,0 +1 @@
+ALTER TABLE bookmarks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
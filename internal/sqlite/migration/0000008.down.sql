@@ -0,0 +1,9 @@
+DROP INDEX users_updated_at_idx;
+DROP INDEX users_created_at_idx;
+
+DROP INDEX sessions_user_id_updated_at_idx;
+DROP INDEX sessions_user_id_created_at_idx;
+
+DROP INDEX bookmarks_user_id_name_idx;
+DROP INDEX bookmarks_user_id_updated_at_idx;
+DROP INDEX bookmarks_user_id_created_at_idx;
@@ -0,0 +1,11 @@
+-- Back the cursor pagination of the lists with an index on each sort column,
+-- so a page is read starting at the cursor instead of sorting every row.
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks (user_id, created_at, id);
+CREATE INDEX bookmarks_user_id_updated_at_idx ON bookmarks (user_id, updated_at, id);
+CREATE INDEX bookmarks_user_id_name_idx ON bookmarks (user_id, name, id);
+
+CREATE INDEX sessions_user_id_created_at_idx ON sessions (user_id, created_at, id);
+CREATE INDEX sessions_user_id_updated_at_idx ON sessions (user_id, updated_at, id);
+
+CREATE INDEX users_created_at_idx ON users (created_at, id);
+CREATE INDEX users_updated_at_idx ON users (updated_at, id);
@@ -0,0 +1,596 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReplicaClient represents a destination a database is replicated to.
+//
+// Replicas are organized in generations. Each generation starts with a
+// snapshot of the database followed by WAL segments numbered from zero. A
+// snapshot with index N contains the changes of all segments before N, so a
+// database is restored by applying the segments from N onwards to it.
+type ReplicaClient interface {
+	// Returns the IDs of all generations.
+	Generations(ctx context.Context) ([]string, error)
+
+	// Returns the snapshots & WAL segments of a generation, ordered by index.
+	Snapshots(ctx context.Context, generation string) ([]*ReplicaFile, error)
+	WALSegments(ctx context.Context, generation string) ([]*ReplicaFile, error)
+
+	// Writes the contents of r as a snapshot or WAL segment. Size is set on
+	// info once written.
+	WriteSnapshot(ctx context.Context, info *ReplicaFile, r io.Reader) error
+	WriteWALSegment(ctx context.Context, info *ReplicaFile, r io.Reader) error
+
+	// Returns a reader for the contents of a snapshot or WAL segment.
+	OpenSnapshot(ctx context.Context, generation string, index int) (io.ReadCloser, error)
+	OpenWALSegment(ctx context.Context, generation string, index int) (io.ReadCloser, error)
+
+	// Removes a snapshot, a WAL segment or a whole generation.
+	DeleteSnapshot(ctx context.Context, generation string, index int) error
+	DeleteWALSegment(ctx context.Context, generation string, index int) error
+	DeleteGeneration(ctx context.Context, generation string) error
+}
+
+// ReplicaFile describes a snapshot or WAL segment of a replica.
+type ReplicaFile struct {
+	Generation string    `json:"generation"`
+	Index      int       `json:"index"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// errWALReset is returned by sync when frames were checkpointed before they
+// were shipped. The replica cannot be continued & a new generation must start.
+var errWALReset = errors.New("wal reset before frames were replicated")
+
+// Replicator continuously ships the WAL of a database to a ReplicaClient.
+//
+// SQLite copies WAL frames into the database & restarts the WAL during
+// checkpoints, so the database must be opened with ManualCheckpoint. The
+// replicator then checkpoints itself after each sync, once the frames have
+// been shipped.
+type Replicator struct {
+	db *DB
+
+	// Destination of the replica.
+	Client ReplicaClient
+
+	// Interval between syncs of the WAL.
+	SyncInterval time.Duration
+
+	// Interval between snapshots of the database. Each snapshot bounds the
+	// number of segments to apply on restore.
+	SnapshotInterval time.Duration
+
+	// Duration snapshots & WAL segments are kept. The newest snapshot is
+	// always kept. Zero keeps everything.
+	Retention time.Duration
+
+	// Serializes syncs & snapshots.
+	mu sync.Mutex
+
+	// Dedicated connection holding the write lock while the WAL is read.
+	conn *sql.Conn
+
+	// Current generation, the index of the next WAL segment & the position
+	// in the WAL up to which frames have been shipped.
+	generation string
+	index      int
+	pos        walPos
+
+	// Background loop.
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+}
+
+// NewReplicator returns a new instance of Replicator shipping db to client.
+func NewReplicator(db *DB, client ReplicaClient) *Replicator {
+	r := &Replicator{
+		db:               db,
+		Client:           client,
+		SyncInterval:     1 * time.Second,
+		SnapshotInterval: 24 * time.Hour,
+		Retention:        7 * 24 * time.Hour,
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r
+}
+
+// Open starts a new generation with a snapshot of the database & starts
+// syncing in the background.
+func (r *Replicator) Open() (err error) {
+	if r.db.DSN == ":memory:" {
+		return fmt.Errorf("cannot replicate in-memory database")
+	} else if !r.db.ManualCheckpoint {
+		return fmt.Errorf("replication requires a database opened with ManualCheckpoint")
+	} else if r.SyncInterval <= 0 {
+		return fmt.Errorf("sync interval required")
+	}
+
+	if r.conn, err = r.db.db.Conn(r.ctx); err != nil {
+		return err
+	}
+	if err := r.Snapshot(r.ctx); err != nil {
+		return fmt.Errorf("initial snapshot: %w", err)
+	}
+
+	r.wg.Add(1)
+	go func() { defer r.wg.Done(); r.monitor() }()
+	return nil
+}
+
+// Close stops the background loop & ships the remaining WAL frames.
+func (r *Replicator) Close() error {
+	r.cancel()
+	r.wg.Wait()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.Sync(context.Background())
+	if e := r.conn.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// Generation returns the ID of the current generation.
+func (r *Replicator) Generation() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation
+}
+
+// monitor runs in a goroutine and syncs & snapshots the database until the
+// replicator is closed.
+func (r *Replicator) monitor() {
+	syncTicker := time.NewTicker(r.SyncInterval)
+	defer syncTicker.Stop()
+
+	var snapshotC <-chan time.Time
+	if r.SnapshotInterval > 0 {
+		snapshotTicker := time.NewTicker(r.SnapshotInterval)
+		defer snapshotTicker.Stop()
+		snapshotC = snapshotTicker.C
+	}
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-syncTicker.C:
+			if err := r.Sync(r.ctx); err != nil && r.ctx.Err() == nil {
+				log.Printf("replica sync error: %s", err)
+			}
+		case <-snapshotC:
+			if err := r.Snapshot(r.ctx); err != nil && r.ctx.Err() == nil {
+				log.Printf("replica snapshot error: %s", err)
+			}
+		}
+	}
+}
+
+// Sync ships the transactions committed since the last sync as a WAL segment
+// & checkpoints the database afterwards.
+func (r *Replicator) Sync(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.sync(ctx); errors.Is(err, errWALReset) {
+		log.Printf("replica: %s, starting new generation", err)
+		if err := r.snapshot(ctx, true); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return r.checkpoint(ctx)
+}
+
+// Snapshot writes a snapshot of the database & removes snapshots, WAL
+// segments & generations older than the retention period.
+func (r *Replicator) Snapshot(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	newGeneration := r.generation == ""
+	if !newGeneration {
+		if err := r.sync(ctx); errors.Is(err, errWALReset) {
+			newGeneration = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := r.snapshot(ctx, newGeneration); err != nil {
+		return err
+	} else if err := r.checkpoint(ctx); err != nil {
+		return err
+	}
+
+	if err := r.prune(ctx); err != nil {
+		return fmt.Errorf("prune replica: %w", err)
+	}
+	return nil
+}
+
+// lock acquires the write lock on the dedicated connection so no frames are
+// appended to or checkpointed from the WAL while it is read. The lock is only
+// held while reading, never while writing to the client, so slow replicas
+// do not block writes.
+func (r *Replicator) lock(ctx context.Context) (rollback func(), err error) {
+	if _, err := r.conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return nil, fmt.Errorf("db begin: %w", FormatError(err))
+	}
+	return func() { r.conn.ExecContext(context.Background(), `ROLLBACK`) }, nil
+}
+
+// checkpoint copies the shipped frames into the database. The WAL restarts
+// on the next write once all frames have been checkpointed, so nothing is
+// checkpointed if frames were committed after the last sync read the WAL.
+// Those are shipped & checkpointed by the next sync instead.
+func (r *Replicator) checkpoint(ctx context.Context) error {
+	rollback, err := r.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer rollback()
+
+	if frames, _, err := r.readFrames(); err != nil {
+		return err
+	} else if len(frames) > 0 {
+		return nil
+	}
+
+	if _, err := r.db.db.ExecContext(ctx, `PRAGMA wal_checkpoint(PASSIVE);`); err != nil {
+		return fmt.Errorf("db checkpoint: %w", FormatError(err))
+	}
+	return nil
+}
+
+// sync ships the committed frames after r.pos as the next WAL segment.
+func (r *Replicator) sync(ctx context.Context) error {
+	frames, next, err := r.readSegment(ctx)
+	if err != nil {
+		return err
+	} else if len(frames) == 0 {
+		r.pos = next
+		return nil
+	}
+
+	info := &ReplicaFile{Generation: r.generation, Index: r.index, CreatedAt: r.db.Now().UTC()}
+	if err := r.Client.WriteWALSegment(ctx, info, bytes.NewReader(frames)); err != nil {
+		return fmt.Errorf("write wal segment: %w", err)
+	}
+	r.index, r.pos = r.index+1, next
+	return nil
+}
+
+// readSegment returns the committed frames after r.pos & the position
+// following them, holding the write lock while the WAL is read.
+func (r *Replicator) readSegment(ctx context.Context) (frames []byte, next walPos, err error) {
+	rollback, err := r.lock(ctx)
+	if err != nil {
+		return nil, next, err
+	}
+	defer rollback()
+
+	return r.readFrames()
+}
+
+// readFrames returns the committed frames after r.pos & the position
+// following them. Must be called with the write lock held.
+func (r *Replicator) readFrames() (frames []byte, next walPos, err error) {
+	path := r.db.DSN + "-wal"
+	hdr, err := readWALHeader(path)
+	if err != nil {
+		return nil, next, err
+	} else if hdr == nil {
+		return nil, r.pos, nil
+	}
+
+	pos := r.pos
+	if hdr.Salt1 != pos.Salt1 || hdr.Salt2 != pos.Salt2 {
+		// SQLite increments the first salt each time the WAL restarts. Any
+		// other value means the WAL restarted more than once since the last
+		// sync and the frames written in between were never shipped.
+		if pos != (walPos{}) && hdr.Salt1 != pos.Salt1+1 {
+			return nil, next, errWALReset
+		}
+		pos = walPos{Salt1: hdr.Salt1, Salt2: hdr.Salt2, Offset: walHeaderSize, Checksum: hdr.checksum}
+	}
+	return readWALFrames(path, hdr, pos)
+}
+
+// snapshot writes the database, including the committed frames of the WAL,
+// as a snapshot at the current index. Starts a new generation first if
+// requested.
+func (r *Replicator) snapshot(ctx context.Context, newGeneration bool) error {
+	if newGeneration {
+		generation, err := newGenerationID()
+		if err != nil {
+			return err
+		}
+		r.generation, r.index = generation, 0
+	}
+
+	f, pos, err := r.copyDatabase(ctx)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	info := &ReplicaFile{Generation: r.generation, Index: r.index, CreatedAt: r.db.Now().UTC()}
+	if err := r.Client.WriteSnapshot(ctx, info, f); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	r.pos = pos
+	return nil
+}
+
+// copyDatabase copies the database, including the committed frames of the
+// WAL, to a temporary file holding the write lock. Returns the file, rewound
+// to the start, & the position in the WAL the copy includes.
+func (r *Replicator) copyDatabase(ctx context.Context) (_ *os.File, pos walPos, err error) {
+	rollback, err := r.lock(ctx)
+	if err != nil {
+		return nil, pos, err
+	}
+	defer rollback()
+
+	f, err := os.CreateTemp("", "bookmarkd-snapshot-*")
+	if err != nil {
+		return nil, pos, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	src, err := os.Open(r.db.DSN)
+	if err != nil {
+		return nil, pos, err
+	}
+	defer src.Close()
+	if _, err := io.Copy(f, src); err != nil {
+		return nil, pos, err
+	}
+
+	// Pages committed to the WAL but not yet checkpointed are only present
+	// in the WAL, so apply them to the copy.
+	path := r.db.DSN + "-wal"
+	if hdr, err := readWALHeader(path); err != nil {
+		return nil, pos, err
+	} else if hdr != nil {
+		frames, next, err := readWALFrames(path, hdr, walPos{Salt1: hdr.Salt1, Salt2: hdr.Salt2, Offset: walHeaderSize, Checksum: hdr.checksum})
+		if err != nil {
+			return nil, pos, err
+		} else if err := applyWALFrames(f, hdr.PageSize, frames); err != nil {
+			return nil, pos, err
+		}
+		pos = next
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, pos, err
+	}
+	return f, pos, nil
+}
+
+// prune removes snapshots older than the retention period, except the newest,
+// along with the WAL segments which are no longer needed to restore from the
+// remaining snapshots. Generations other than the current one are removed
+// entirely once all of their files are older than the retention period.
+func (r *Replicator) prune(ctx context.Context) error {
+	if r.Retention <= 0 {
+		return nil
+	}
+	cutoff := r.db.Now().Add(-r.Retention)
+
+	generations, err := r.Client.Generations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, generation := range generations {
+		if generation == r.generation {
+			continue
+		}
+		updatedAt, err := generationUpdatedAt(ctx, r.Client, generation)
+		if err != nil {
+			return err
+		} else if updatedAt.Before(cutoff) {
+			if err := r.Client.DeleteGeneration(ctx, generation); err != nil {
+				return err
+			}
+		}
+	}
+
+	snapshots, err := r.Client.Snapshots(ctx, r.generation)
+	if err != nil {
+		return err
+	}
+	for len(snapshots) > 1 && snapshots[0].CreatedAt.Before(cutoff) {
+		if err := r.Client.DeleteSnapshot(ctx, r.generation, snapshots[0].Index); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	segments, err := r.Client.WALSegments(ctx, r.generation)
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if segment.Index >= snapshots[0].Index {
+			break
+		}
+		if err := r.Client.DeleteWALSegment(ctx, r.generation, segment.Index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generationUpdatedAt returns the creation time of the newest file of a generation.
+func generationUpdatedAt(ctx context.Context, client ReplicaClient, generation string) (time.Time, error) {
+	snapshots, err := client.Snapshots(ctx, generation)
+	if err != nil {
+		return time.Time{}, err
+	}
+	segments, err := client.WALSegments(ctx, generation)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t time.Time
+	for _, file := range append(snapshots, segments...) {
+		if file.CreatedAt.After(t) {
+			t = file.CreatedAt
+		}
+	}
+	return t, nil
+}
+
+// newGenerationID returns a random ID for a generation.
+func newGenerationID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// RestoreReplica reconstructs the database as of target from the replica &
+// writes it to dst, which must not exist. The latest state is restored if
+// target is zero. The server does not need to be stopped as dst is a new file.
+func RestoreReplica(ctx context.Context, client ReplicaClient, dst string, target time.Time) error {
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("%s already exists", dst)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	snapshot, err := findRestoreSnapshot(ctx, client, target)
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".restore"
+	if err := restoreReplica(ctx, client, tmp, snapshot, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := ValidateBackup(ctx, tmp); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("validate restored database: %w", err)
+	}
+	return os.Rename(tmp, dst)
+}
+
+// findRestoreSnapshot returns the newest snapshot created at or before target,
+// across all generations.
+func findRestoreSnapshot(ctx context.Context, client ReplicaClient, target time.Time) (*ReplicaFile, error) {
+	generations, err := client.Generations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot *ReplicaFile
+	for _, generation := range generations {
+		snapshots, err := client.Snapshots(ctx, generation)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range snapshots {
+			if !target.IsZero() && s.CreatedAt.After(target) {
+				continue
+			}
+			if snapshot == nil || s.CreatedAt.After(snapshot.CreatedAt) {
+				snapshot = s
+			}
+		}
+	}
+
+	if snapshot == nil {
+		return nil, fmt.Errorf("no snapshot available to restore from")
+	}
+	return snapshot, nil
+}
+
+// restoreReplica writes snapshot to path & applies the WAL segments of its
+// generation which were created at or before target.
+func restoreReplica(ctx context.Context, client ReplicaClient, path string, snapshot *ReplicaFile, target time.Time) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rc, err := client.OpenSnapshot(ctx, snapshot.Generation, snapshot.Index)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, rc)
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("copy snapshot: %w", err)
+	}
+
+	pageSize, err := databasePageSize(f)
+	if err != nil {
+		return err
+	}
+
+	segments, err := client.WALSegments(ctx, snapshot.Generation)
+	if err != nil {
+		return err
+	}
+	index := snapshot.Index
+	for _, segment := range segments {
+		if segment.Index < index {
+			continue
+		} else if !target.IsZero() && segment.CreatedAt.After(target) {
+			break
+		} else if segment.Index != index {
+			return fmt.Errorf("wal segment %d missing from generation %s", index, snapshot.Generation)
+		}
+
+		rc, err := client.OpenWALSegment(ctx, segment.Generation, segment.Index)
+		if err != nil {
+			return err
+		}
+		frames, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if err := applyWALFrames(f, pageSize, frames); err != nil {
+			return fmt.Errorf("apply wal segment %d: %w", segment.Index, err)
+		}
+		index++
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
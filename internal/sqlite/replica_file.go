@@ -0,0 +1,190 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// File extensions of snapshots & WAL segments.
+const (
+	snapshotExt   = ".snapshot"
+	walSegmentExt = ".wal"
+)
+
+// Ensure client implements interface.
+var _ ReplicaClient = (*FileReplicaClient)(nil)
+
+// FileReplicaClient represents a replica in a local or mounted directory.
+//
+// Files are laid out as generations/<generation>/{snapshots,wal}/<index> with
+// the index in hex. The creation time of a file is stored as its mtime.
+type FileReplicaClient struct {
+	// Root directory of the replica.
+	Dir string
+}
+
+// NewFileReplicaClient returns a new instance of FileReplicaClient writing to dir.
+func NewFileReplicaClient(dir string) *FileReplicaClient {
+	return &FileReplicaClient{Dir: dir}
+}
+
+func (c *FileReplicaClient) generationsDir() string {
+	return filepath.Join(c.Dir, "generations")
+}
+
+func (c *FileReplicaClient) snapshotsDir(generation string) string {
+	return filepath.Join(c.generationsDir(), generation, "snapshots")
+}
+
+func (c *FileReplicaClient) walDir(generation string) string {
+	return filepath.Join(c.generationsDir(), generation, "wal")
+}
+
+// replicaPath returns the path of a file within dir named by its index.
+func replicaPath(dir string, index int, ext string) string {
+	return filepath.Join(dir, fmt.Sprintf("%016x%s", index, ext))
+}
+
+// Generations returns the IDs of all generations.
+func (c *FileReplicaClient) Generations(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(c.generationsDir())
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	generations := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			generations = append(generations, entry.Name())
+		}
+	}
+	return generations, nil
+}
+
+// Snapshots returns the snapshots of a generation, ordered by index.
+func (c *FileReplicaClient) Snapshots(ctx context.Context, generation string) ([]*ReplicaFile, error) {
+	return c.files(c.snapshotsDir(generation), generation, snapshotExt)
+}
+
+// WALSegments returns the WAL segments of a generation, ordered by index.
+func (c *FileReplicaClient) WALSegments(ctx context.Context, generation string) ([]*ReplicaFile, error) {
+	return c.files(c.walDir(generation), generation, walSegmentExt)
+}
+
+func (c *FileReplicaClient) files(dir, generation, ext string) ([]*ReplicaFile, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []*ReplicaFile{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	files := make([]*ReplicaFile, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		// Ignore files which are not named by an index, e.g. partial writes.
+		index, err := strconv.ParseInt(strings.TrimSuffix(name, ext), 16, 64)
+		if err != nil {
+			continue
+		}
+
+		fi, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &ReplicaFile{Generation: generation, Index: int(index), Size: fi.Size(), CreatedAt: fi.ModTime().UTC()})
+	}
+
+	slices.SortFunc(files, func(a, b *ReplicaFile) int { return a.Index - b.Index })
+	return files, nil
+}
+
+// WriteSnapshot writes the contents of r as a snapshot.
+func (c *FileReplicaClient) WriteSnapshot(ctx context.Context, info *ReplicaFile, r io.Reader) error {
+	return c.write(replicaPath(c.snapshotsDir(info.Generation), info.Index, snapshotExt), info, r)
+}
+
+// WriteWALSegment writes the contents of r as a WAL segment.
+func (c *FileReplicaClient) WriteWALSegment(ctx context.Context, info *ReplicaFile, r io.Reader) error {
+	return c.write(replicaPath(c.walDir(info.Generation), info.Index, walSegmentExt), info, r)
+}
+
+// write writes r to a temporary file which is renamed to path once synced, so
+// a partially written file is never mistaken for a complete one.
+func (c *FileReplicaClient) write(path string, info *ReplicaFile, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return err
+	} else if err := f.Sync(); err != nil {
+		return err
+	} else if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chtimes(tmp, time.Time{}, info.CreatedAt); err != nil {
+		return err
+	} else if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	info.Size = n
+	return nil
+}
+
+// OpenSnapshot returns a reader for the contents of a snapshot.
+func (c *FileReplicaClient) OpenSnapshot(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	return os.Open(replicaPath(c.snapshotsDir(generation), index, snapshotExt))
+}
+
+// OpenWALSegment returns a reader for the contents of a WAL segment.
+func (c *FileReplicaClient) OpenWALSegment(ctx context.Context, generation string, index int) (io.ReadCloser, error) {
+	return os.Open(replicaPath(c.walDir(generation), index, walSegmentExt))
+}
+
+// DeleteSnapshot removes a snapshot.
+func (c *FileReplicaClient) DeleteSnapshot(ctx context.Context, generation string, index int) error {
+	if err := os.Remove(replicaPath(c.snapshotsDir(generation), index, snapshotExt)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteWALSegment removes a WAL segment.
+func (c *FileReplicaClient) DeleteWALSegment(ctx context.Context, generation string, index int) error {
+	if err := os.Remove(replicaPath(c.walDir(generation), index, walSegmentExt)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteGeneration removes a generation with all of its files.
+func (c *FileReplicaClient) DeleteGeneration(ctx context.Context, generation string) error {
+	if generation == "" {
+		return fmt.Errorf("generation required")
+	}
+	return os.RemoveAll(filepath.Join(c.generationsDir(), generation))
+}
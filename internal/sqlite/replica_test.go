@@ -0,0 +1,186 @@
+package sqlite_test
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bookmarkd/internal/core"
+	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
+)
+
+func Test_Replicator(t *testing.T) {
+	ctx := context.Background()
+
+	db := sqlite.NewDB(filepath.Join(t.TempDir(), "db"))
+	db.ManualCheckpoint = true
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	db.Now = func() time.Time { return now }
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer MustCloseDB(t, db)
+
+	client := sqlite.NewFileReplicaClient(t.TempDir())
+	r := sqlite.NewReplicator(db, client)
+	r.SyncInterval = time.Hour
+	r.SnapshotInterval = 0
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// Create a user per minute, syncing after each, so every minute of the
+	// replica holds one more user.
+	userStore := sqlite.NewUserStore(db)
+	usernames := []string{"jane", "john", "joe"}
+	for _, username := range usernames {
+		now = now.Add(time.Minute)
+		MustCreateUser(t, ctx, userStore, &core.User{Username: username})
+		if err := r.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Restores the replica as of target & returns the number of users.
+	restore := func(t *testing.T, target time.Time) int {
+		t.Helper()
+		dst := filepath.Join(t.TempDir(), "db")
+		if err := sqlite.RestoreReplica(ctx, client, dst, target); err != nil {
+			t.Fatal(err)
+		}
+
+		other := sqlite.NewDB(dst)
+		if err := other.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer MustCloseDB(t, other)
+
+		_, n, err := sqlite.NewUserStore(other).FindUsers(ctx, core.UserFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	t.Run("Latest", func(t *testing.T) {
+		require.Equal(t, restore(t, time.Time{}), 3)
+	})
+
+	t.Run("PointInTime", func(t *testing.T) {
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i <= len(usernames); i++ {
+			require.Equal(t, restore(t, start.Add(time.Duration(i)*time.Minute)), i)
+		}
+	})
+
+	// Ensure restores after a snapshot apply only the segments written after it.
+	t.Run("Snapshot", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		if err := r.Snapshot(ctx); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+		MustCreateUser(t, ctx, userStore, &core.User{Username: "jill"})
+		if err := r.Sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		snapshots, err := client.Snapshots(ctx, r.Generation())
+		require.Equal(t, err, nil)
+		require.Equal(t, len(snapshots), 2)
+		require.Equal(t, snapshots[1].Index, 3)
+
+		require.Equal(t, restore(t, now.Add(-time.Minute)), 3)
+		require.Equal(t, restore(t, now), 4)
+	})
+
+	// Ensure old snapshots & the segments before the oldest remaining
+	// snapshot are removed.
+	t.Run("Retention", func(t *testing.T) {
+		now = now.Add(r.Retention)
+		if err := r.Snapshot(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		snapshots, err := client.Snapshots(ctx, r.Generation())
+		require.Equal(t, err, nil)
+		require.Equal(t, len(snapshots), 1)
+
+		segments, err := client.WALSegments(ctx, r.Generation())
+		require.Equal(t, err, nil)
+		require.Equal(t, len(segments), 0)
+
+		require.Equal(t, restore(t, time.Time{}), 4)
+	})
+}
+
+// Ensure the database can be written to while a segment is being shipped.
+func Test_Replicator_SlowClient(t *testing.T) {
+	ctx := context.Background()
+
+	db := sqlite.NewDB(filepath.Join(t.TempDir(), "db"))
+	db.ManualCheckpoint = true
+	if err := db.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer MustCloseDB(t, db)
+
+	client := &BlockingReplicaClient{FileReplicaClient: sqlite.NewFileReplicaClient(t.TempDir())}
+	r := sqlite.NewReplicator(db, client)
+	r.SyncInterval = time.Hour
+	r.SnapshotInterval = 0
+	if err := r.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	userStore := sqlite.NewUserStore(db)
+	MustCreateUser(t, ctx, userStore, &core.User{Username: "jane"})
+
+	started := make(chan struct{})
+	client.started, client.unblock = started, make(chan struct{})
+	errc := make(chan error)
+	go func() { errc <- r.Sync(ctx) }()
+	<-started
+
+	err := userStore.CreateUser(ctx, &core.User{Username: "john", Seed: "random_seed"})
+	close(client.unblock)
+	require.Equal(t, err, nil)
+	require.Equal(t, <-errc, nil)
+
+	// Ensure the frames written while shipping are shipped by the next sync.
+	require.Equal(t, r.Sync(ctx), nil)
+	dst := filepath.Join(t.TempDir(), "db")
+	if err := sqlite.RestoreReplica(ctx, client, dst, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	other := sqlite.NewDB(dst)
+	if err := other.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer MustCloseDB(t, other)
+
+	_, n, err := sqlite.NewUserStore(other).FindUsers(ctx, core.UserFilter{})
+	require.Equal(t, err, nil)
+	require.Equal(t, n, 2)
+}
+
+// BlockingReplicaClient is a replica client which blocks writing WAL
+// segments until unblock is closed, once started is set.
+type BlockingReplicaClient struct {
+	*sqlite.FileReplicaClient
+	started, unblock chan struct{}
+}
+
+func (c *BlockingReplicaClient) WriteWALSegment(ctx context.Context, info *sqlite.ReplicaFile, r io.Reader) error {
+	if c.started != nil {
+		close(c.started)
+		c.started = nil
+		<-c.unblock
+	}
+	return c.FileReplicaClient.WriteWALSegment(ctx, info, r)
+}
@@ -3,7 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -154,6 +156,10 @@ func findSessionByRefreshToken(ctx context.Context, tx *Tx, token string) (*core
 }
 
 func findSessions(ctx context.Context, tx *Tx, filter core.SessionFilter) (_ []*core.Session, n int, err error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
 	// Build WHERE clause. Each part of the clause is AND-ed together to further
 	// restrict the results. Placeholders are added to "args" and are used
 	// to avoid SQL injection.
@@ -166,23 +172,41 @@ func findSessions(ctx context.Context, tx *Tx, filter core.SessionFilter) (_ []*
 	if v := filter.UserID; v != nil {
 		where, args = append(where, "user_id = ?"), append(args, *v)
 	}
+	if v := filter.RefreshToken; v != nil {
+		where, args = append(where, "refresh_token = ?"), append(args, *v)
+	}
+
+	// Page through the results using the sort column and cursor.
+	column := "created_at"
+	if filter.Sort == core.SortUpdated {
+		column = "updated_at"
+	}
+	keyset, keysetArgs, orderBy, reverse, err := FormatKeyset(column, filter.Descending(), filter.Cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Count the matching sessions separately, so the page itself is read
+	// from the index of the sort column starting at the cursor.
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sessions WHERE `+strings.Join(where, " AND "), args...).Scan(&n); err != nil {
+		return nil, 0, fmt.Errorf("db count sessions: %w", FormatError(err))
+	}
 
-	// Execute the query with WHERE clause and LIMIT/OFFSET injected.
+	// Execute the query with the cursor & LIMIT/OFFSET injected.
 	rows, err := tx.QueryContext(ctx, `
-		SELECT 
-		    id,
-		    user_id,
-		    refresh_token,
-		    expires_at,
-		    created_at,
-		    updated_at,
-		    COUNT(*) OVER()
+		SELECT
+		  id,
+		  user_id,
+		  refresh_token,
+		  expires_at,
+		  created_at,
+		  updated_at
 		FROM sessions
-		WHERE `+strings.Join(where, " AND ")+`
-		ORDER BY id ASC
+		WHERE `+strings.Join(append(where, keyset), " AND ")+`
+		`+orderBy+`
 		`+FormatLimitOffset(filter.Limit, filter.Offset)+`
 	`,
-		args...,
+		append(args, keysetArgs...)...,
 	)
 	if err != nil {
 		return nil, n, fmt.Errorf("db select user session: %w", FormatError(err))
@@ -201,7 +225,6 @@ func findSessions(ctx context.Context, tx *Tx, filter core.SessionFilter) (_ []*
 			&expiry,
 			(*NullTime)(&session.CreatedAt),
 			(*NullTime)(&session.UpdatedAt),
-			&n,
 		); err != nil {
 			return nil, 0, fmt.Errorf("db scan user session row: %w", FormatError(err))
 		}
@@ -218,6 +241,10 @@ func findSessions(ctx context.Context, tx *Tx, filter core.SessionFilter) (_ []*
 		return nil, 0, fmt.Errorf("db scan user session rows: %w", FormatError(err))
 	}
 
+	if reverse {
+		slices.Reverse(sessions)
+	}
+
 	return sessions, n, nil
 }
 
@@ -273,8 +300,12 @@ func createSession(ctx context.Context, tx *Tx, session *core.Session) error {
 func refreshSession(ctx context.Context, tx *Tx, refreshToken string) (*core.Session, error) {
 
 	s, err := findSessionByRefreshToken(ctx, tx, refreshToken)
-	if err != nil {
+	if errors.Is(err, bookmarkd.ErrNotFound) {
+		return nil, bookmarkd.ErrUnauthorized
+	} else if err != nil {
 		return nil, fmt.Errorf("find session by refresh token: %w", err)
+	} else if !s.ExpiresAt.After(tx.Now()) {
+		return nil, fmt.Errorf("session expired: %w", bookmarkd.ErrUnauthorized)
 	}
 
 	rt, err := uuid.NewRandom()
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
@@ -49,10 +50,12 @@ func TestUserStore_RefreshSession(t *testing.T) {
 	ctx := context.Background()
 
 	user := MustCreateUser(t, ctx, u, &core.User{Username: "NAME0"})
+	other := MustCreateUser(t, ctx, u, &core.User{Username: "NAME1"})
 	session, userCtx := MustCreateSession(t, ctx, s, user.ID)
+	otherSession, _ := MustCreateSession(t, ctx, s, other.ID)
 
 	t.Run("OK", func(t *testing.T) {
-		upd, err := s.RefreshSession(userCtx, "refresh_token")
+		upd, err := s.RefreshSession(userCtx, session.RefreshToken)
 
 		require.Equal(t, err, nil)
 		require.Equal(t, upd.ID, session.ID)
@@ -60,13 +63,38 @@ func TestUserStore_RefreshSession(t *testing.T) {
 		require.Equal(t, upd.ExpiresAt.IsZero(), false)
 	})
 
-	t.Run("ErrInvalid", func(t *testing.T) {
-		upd, err := s.RefreshSession(userCtx, "refresh_token")
+	// Ensure a refresh token can only be used once.
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		_, err := s.RefreshSession(userCtx, session.RefreshToken)
+
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	t.Run("ErrUnauthorized unknown token", func(t *testing.T) {
+		_, err := s.RefreshSession(userCtx, "refresh_token")
+
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	// Ensure the token only refreshes the session it belongs to.
+	t.Run("OtherUser", func(t *testing.T) {
+		upd, err := s.RefreshSession(userCtx, otherSession.RefreshToken)
 
 		require.Equal(t, err, nil)
-		require.Equal(t, upd.ID, session.ID)
-		require.NotEqual(t, upd.RefreshToken, session.RefreshToken)
-		require.Equal(t, upd.ExpiresAt.IsZero(), false)
+		require.Equal(t, upd.ID, otherSession.ID)
+		require.Equal(t, upd.UserID, other.ID)
+	})
+
+	t.Run("ErrUnauthorized expired", func(t *testing.T) {
+		expired, _ := MustCreateSession(t, ctx, s, user.ID)
+
+		now := db.Now
+		db.Now = func() time.Time { return now().Add(15 * 24 * time.Hour) }
+		defer func() { db.Now = now }()
+
+		_, err := s.RefreshSession(userCtx, expired.RefreshToken)
+
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
 	})
 }
 
@@ -4,23 +4,33 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
-	"embed"
 	"fmt"
-	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
-	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 
 	"bookmarkd"
 	"bookmarkd/internal/core"
+	"bookmarkd/internal/metrics"
 )
 
-//go:embed migration/*.sql
-var migrationFS embed.FS
+// manualCheckpointDriver is the name of a driver whose connections never
+// checkpoint the WAL on their own.
+const manualCheckpointDriver = "sqlite3_manual_checkpoint"
+
+func init() {
+	sql.Register(manualCheckpointDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec(`PRAGMA wal_autocheckpoint = 0;`, nil)
+			return err
+		},
+	})
+}
 
 // DB represents the database connection.
 type DB struct {
@@ -32,15 +42,29 @@ type DB struct {
 	// cancel background context
 	cancel func()
 
+	// tracks background goroutines, waited on by Close()
+	wg sync.WaitGroup
+
 	// Datasource name.
 	DSN string
 
 	// Destination for events to be published.
 	EventService core.EventService
 
+	// Canonicalizes bookmark urls for duplicate detection.
+	UrlCanonicalizer *core.UrlCanonicalizer
+
 	// Returns the current time. Defaults to time.Now().
 	// Can be mocked for tests.
 	Now func() time.Time
+
+	// If true, pending migrations are not run when the database is opened.
+	// Used to inspect a database without changing its schema.
+	SkipMigrations bool
+
+	// If true, SQLite does not checkpoint the WAL automatically. Required by
+	// the Replicator so frames are not checkpointed before they are shipped.
+	ManualCheckpoint bool
 }
 
 // NewDB returns a new instance of DB associated with the given datasource name.
@@ -49,7 +73,8 @@ func NewDB(dsn string) *DB {
 		DSN: dsn,
 		Now: time.Now,
 
-		EventService: core.NopEventService(),
+		EventService:     core.NopEventService(),
+		UrlCanonicalizer: core.NewUrlCanonicalizer(core.DefaultUrlStripParams),
 	}
 
 	db.ctx, db.cancel = context.WithCancel(context.Background())
@@ -72,7 +97,11 @@ func (db *DB) Open() (err error) {
 	}
 
 	// Connect to the database.
-	if db.db, err = sql.Open("sqlite3", db.DSN); err != nil {
+	driver := "sqlite3"
+	if db.ManualCheckpoint {
+		driver = manualCheckpointDriver
+	}
+	if db.db, err = sql.Open(driver, db.DSN); err != nil {
 		return err
 	}
 
@@ -88,83 +117,24 @@ func (db *DB) Open() (err error) {
 	}
 
 	// Run migrations.
-	if err := db.migrate(); err != nil {
-		return fmt.Errorf("migrate: %w", err)
+	if !db.SkipMigrations {
+		if err := db.migrate(db.ctx); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
 	}
 
 	// Monitor stats in background goroutine.
-	go db.monitor()
+	db.wg.Add(1)
+	go func() { defer db.wg.Done(); db.monitor() }()
 
 	return nil
 }
 
-// migrate sets up migration tracking and executes pending migration files.
-//
-// Migration files are embedded in the sqlite/migration folder and are executed
-// in lexigraphical order.
-//
-// Once a migration is run, its name is stored in the 'migrations' table so it
-// is not re-executed. Migrations run in a transaction to prevent partial
-// migrations.
-func (db *DB) migrate() error {
-	// Ensure the 'migrations' table exists so we don't duplicate migrations.
-	if _, err := db.db.Exec(`CREATE TABLE IF NOT EXISTS migrations (name TEXT PRIMARY KEY);`); err != nil {
-		return fmt.Errorf("cannot create migrations table: %w", err)
-	}
-
-	// Read migration files from our embedded file system.
-	// This uses Go 1.16's 'embed' package.
-	names, err := fs.Glob(migrationFS, "migration/*.sql")
-	if err != nil {
-		return err
-	}
-	sort.Strings(names)
-
-	// Loop over all migration files and execute them in order.
-	for _, name := range names {
-		if err := db.migrateFile(name); err != nil {
-			return fmt.Errorf("migration error: name=%q err=%w", name, err)
-		}
-	}
-	return nil
-}
-
-// migrate runs a single migration file within a transaction. On success, the
-// migration file name is saved to the "migrations" table to prevent re-running.
-func (db *DB) migrateFile(name string) error {
-	tx, err := db.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	// Ensure migration has not already been run.
-	var n int
-	if err := tx.QueryRow(`SELECT COUNT(*) FROM migrations WHERE name = ?`, name).Scan(&n); err != nil {
-		return err
-	} else if n != 0 {
-		return nil // already run migration, skip
-	}
-
-	// Read and execute migration file.
-	if buf, err := fs.ReadFile(migrationFS, name); err != nil {
-		return err
-	} else if _, err := tx.Exec(string(buf)); err != nil {
-		return err
-	}
-
-	// Insert record into migrations to prevent re-running migration.
-	if _, err := tx.Exec(`INSERT INTO migrations (name) VALUES (?)`, name); err != nil {
-		return err
-	}
-
-	return tx.Commit()
-}
-
-// Close closes the database connection.
+// Close stops the background goroutines & closes the database connection.
 func (db *DB) Close() error {
-	// Cancel background context.
+	// Cancel background context & wait for goroutines using the connection.
 	db.cancel()
+	db.wg.Wait()
 
 	// Close database.
 	if db.db != nil {
@@ -173,6 +143,15 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// Ping verifies the database can still be queried.
+func (db *DB) Ping(ctx context.Context) error {
+	var n int
+	if err := db.db.QueryRowContext(ctx, `SELECT 1`).Scan(&n); err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	return nil
+}
+
 // BeginTx starts a transaction and returns a wrapper Tx type. This type
 // provides a reference to the database and a fixed timestamp at the start of
 // the transaction. The timestamp allows us to mock time during tests as well.
@@ -210,39 +189,137 @@ func (db *DB) monitor() {
 
 // updateStats updates the metrics for the database.
 func (db *DB) updateStats(ctx context.Context) error {
-	tx, err := db.BeginTx(ctx, nil)
+	stats, err := db.Stats(ctx)
 	if err != nil {
 		return err
 	}
+
+	metrics.Users.Set(float64(stats.Users))
+	metrics.DisabledUsers.Set(float64(stats.DisabledUsers))
+	metrics.Sessions.Set(float64(stats.Sessions))
+	metrics.Bookmarks.Set(float64(stats.Bookmarks))
+	metrics.Revisions.Set(float64(stats.Revisions))
+
+	return nil
+}
+
+// Stats represents row counts of the database.
+type Stats struct {
+	Users         int `json:"users"`
+	DisabledUsers int `json:"disabledUsers"`
+	Sessions      int `json:"sessions"`
+	Bookmarks     int `json:"bookmarks"`
+	Revisions     int `json:"revisions"`
+}
+
+// Stats returns the current row counts of the database.
+func (db *DB) Stats(ctx context.Context) (*Stats, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 	defer tx.Rollback()
 
-	var n int
-	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users;`).Scan(&n); err != nil {
-		return fmt.Errorf("user count: %w", err)
+	var stats Stats
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(disabled_at) FROM users;`).Scan(&stats.Users, &stats.DisabledUsers); err != nil {
+		return nil, fmt.Errorf("user count: %w", err)
 	}
-	// update metrix table in database: userCountGauge
 
-	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks;`).Scan(&n); err != nil {
-		return fmt.Errorf("bookmark count: %w", err)
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sessions;`).Scan(&stats.Sessions); err != nil {
+		return nil, fmt.Errorf("session count: %w", err)
 	}
-	// update metrics table in database: bookmarksCountGauge
 
-	return nil
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmarks;`).Scan(&stats.Bookmarks); err != nil {
+		return nil, fmt.Errorf("bookmark count: %w", err)
+	}
+
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM bookmark_revisions;`).Scan(&stats.Revisions); err != nil {
+		return nil, fmt.Errorf("revision count: %w", err)
+	}
+
+	return &stats, nil
 }
 
 // Tx wraps the SQL Tx object to provide a timestamp at the start of the transaction.
+//
+// Events published within the transaction are held back until it commits so
+// listeners never see changes which are later rolled back.
 type Tx struct {
 	*sql.Tx
 	db  *DB
 	now time.Time
+
+	// Events waiting for the transaction to commit.
+	events []txEvent
+
+	// Number of buffered events when each open savepoint was created.
+	savepoints map[string]int
+}
+
+// txEvent represents an event buffered by a transaction.
+type txEvent struct {
+	userID string
+	event  core.Event
 }
 
 func (t Tx) Now() time.Time {
 	return t.now
 }
 
-func (t Tx) PublishEvent(userID string, event core.Event) {
-	t.db.EventService.PublishEvent(userID, event)
+func (t Tx) CanonicalizeUrl(url string) string {
+	return t.db.UrlCanonicalizer.Canonicalize(url)
+}
+
+// PublishEvent queues event to be published to userID once the transaction
+// commits. The event is discarded if the transaction is rolled back.
+func (t *Tx) PublishEvent(userID string, event core.Event) {
+	t.events = append(t.events, txEvent{userID: userID, event: event})
+}
+
+// Commit commits the transaction & publishes the events queued within it.
+func (t *Tx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+
+	events := t.events
+	t.events = nil
+	for _, e := range events {
+		t.db.EventService.PublishEvent(e.userID, e.event)
+	}
+	return nil
+}
+
+// Savepoint starts a savepoint within the transaction. Changes made after the
+// savepoint can be undone with RollbackTo() without aborting the transaction.
+func (t *Tx) Savepoint(ctx context.Context, name string) error {
+	if _, err := t.ExecContext(ctx, `SAVEPOINT `+name); err != nil {
+		return fmt.Errorf("db savepoint: %w", FormatError(err))
+	}
+	if t.savepoints == nil {
+		t.savepoints = make(map[string]int)
+	}
+	t.savepoints[name] = len(t.events)
+	return nil
+}
+
+// RollbackTo undoes the changes made since the savepoint was created,
+// including any events queued since then, and ends the savepoint.
+func (t *Tx) RollbackTo(ctx context.Context, name string) error {
+	if _, err := t.ExecContext(ctx, `ROLLBACK TO `+name); err != nil {
+		return fmt.Errorf("db rollback to savepoint: %w", FormatError(err))
+	}
+	t.events = t.events[:t.savepoints[name]]
+	return t.Release(ctx, name)
+}
+
+// Release ends the savepoint, keeping the changes made since it was created.
+func (t *Tx) Release(ctx context.Context, name string) error {
+	if _, err := t.ExecContext(ctx, `RELEASE `+name); err != nil {
+		return fmt.Errorf("db release savepoint: %w", FormatError(err))
+	}
+	delete(t.savepoints, name)
+	return nil
 }
 
 // NullTime represents a helper wrapper for time.Time. It automatically converts
@@ -271,17 +348,62 @@ func (n *NullTime) Value() (driver.Value, error) {
 
 // FormatLimitOffset returns a SQL string for a given limit & offset.
 // Clauses are only added if limit and/or offset are greater than zero.
+// SQLite requires a LIMIT with an OFFSET so a negative, unbounded limit is
+// used when only an offset is given.
 func FormatLimitOffset(limit, offset int) string {
 	if limit > 0 && offset > 0 {
 		return fmt.Sprintf(`LIMIT %d OFFSET %d`, limit, offset)
 	} else if limit > 0 {
 		return fmt.Sprintf(`LIMIT %d`, limit)
 	} else if offset > 0 {
-		return fmt.Sprintf(`OFFSET %d`, offset)
+		return fmt.Sprintf(`LIMIT -1 OFFSET %d`, offset)
 	}
 	return ""
 }
 
+// FormatKeyset returns the clauses used to page through rows ordered by a
+// sort column and "id" using a cursor. Returns a WHERE condition with its
+// arguments, and an ORDER BY clause.
+//
+// When the cursor selects rows before its position the order is inverted so
+// the rows nearest the cursor are returned first. reverse is then true and
+// the caller must reverse the results to restore the requested order.
+func FormatKeyset(column string, desc bool, cursor string) (where string, args []interface{}, orderBy string, reverse bool, err error) {
+	return FormatKeysetExpr(column, column, nil, desc, cursor)
+}
+
+// FormatKeysetExpr is like FormatKeyset for a sort column computed by expr,
+// whose arguments are exprArgs. The WHERE condition compares expr as the
+// computed column can't be referenced there.
+func FormatKeysetExpr(column, expr string, exprArgs []interface{}, desc bool, cursor string) (where string, args []interface{}, orderBy string, reverse bool, err error) {
+	var c core.Cursor
+	if cursor != "" {
+		if c, err = core.DecodeCursor(cursor); err != nil {
+			return "", nil, "", false, err
+		}
+	}
+
+	dir, op := "ASC", ">"
+	if desc != c.Before {
+		dir, op = "DESC", "<"
+	}
+	orderBy = `ORDER BY ` + column + ` ` + dir + `, id ` + dir
+
+	if cursor == "" {
+		return "1 = 1", nil, orderBy, false, nil
+	}
+	where = `(` + expr + ` ` + op + ` ? OR (` + expr + ` = ? AND id ` + op + ` ?))`
+	args = append(append(append(args, exprArgs...), c.Key), exprArgs...)
+	return where, append(args, c.Key, c.ID), orderBy, c.Before, nil
+}
+
+// FormatLike returns a LIKE pattern matching values that contain s. Wildcard
+// characters in s are escaped with a backslash.
+func FormatLike(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + s + "%"
+}
+
 // FormatError returns err as a Bookmarkd error, if possible.
 // Otherwise returns the original error.
 func FormatError(err error) error {
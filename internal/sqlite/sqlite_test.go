@@ -1,12 +1,18 @@
 package sqlite_test
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"bookmarkd"
+	"bookmarkd/internal/core"
 	"bookmarkd/internal/sqlite"
+	"bookmarkd/utils/require"
 )
 
 var dump = flag.Bool("dump", false, "save work data")
@@ -16,6 +22,191 @@ func TestDB(t *testing.T) {
 	MustCloseDB(t, db)
 }
 
+func TestDB_Migrations(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Applied", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.NotEqual(t, len(migrations), 0)
+		require.Equal(t, migrations[0].Version, 0)
+		for _, m := range migrations {
+			require.Equal(t, m.Status, core.MigrationStatusApplied)
+			require.NotEqual(t, m.AppliedAt, nil)
+		}
+		require.Equal(t, db.CheckMigrations(ctx), nil)
+	})
+
+	// Ensure migrations are reported as pending if they are skipped.
+	t.Run("Pending", func(t *testing.T) {
+		db := sqlite.NewDB(":memory:")
+		db.SkipMigrations = true
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer MustCloseDB(t, db)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		for _, m := range migrations {
+			require.Equal(t, m.Status, core.MigrationStatusPending)
+		}
+		require.NotEqual(t, db.CheckMigrations(ctx), nil)
+	})
+
+	// Ensure all migrations can be reverted & applied again.
+	t.Run("Down", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+
+		target := -1
+		steps, err := db.Migrate(ctx, core.MigrateOptions{Target: &target})
+		require.Equal(t, err, nil)
+		require.Equal(t, len(steps), len(migrations))
+		require.Equal(t, steps[0].Direction, core.MigrationDown)
+		require.Equal(t, steps[0].Version, migrations[len(migrations)-1].Version)
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.Equal(t, err, nil)
+		var n int
+		require.Equal(t, tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&n), nil)
+		require.Equal(t, n, 0)
+		tx.Rollback()
+
+		steps, err = db.Migrate(ctx, core.MigrateOptions{})
+		require.Equal(t, err, nil)
+		require.Equal(t, len(steps), len(migrations))
+		require.Equal(t, steps[0].Direction, core.MigrationUp)
+	})
+
+	// Ensure migrating to a version reverts the later migrations only.
+	t.Run("Target", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		target := 5
+		_, err := db.Migrate(ctx, core.MigrateOptions{Target: &target})
+		require.Equal(t, err, nil)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		for _, m := range migrations {
+			if m.Version <= target {
+				require.Equal(t, m.Status, core.MigrationStatusApplied)
+			} else {
+				require.Equal(t, m.Status, core.MigrationStatusPending)
+			}
+		}
+
+		target = 9999999
+		_, err = db.Migrate(ctx, core.MigrateOptions{Target: &target})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+	})
+
+	// Ensure a dry run reports the steps without changing the database.
+	t.Run("DryRun", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		target := -1
+		steps, err := db.Migrate(ctx, core.MigrateOptions{Target: &target, DryRun: true})
+		require.Equal(t, err, nil)
+		require.NotEqual(t, len(steps), 0)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		for _, m := range migrations {
+			require.Equal(t, m.Status, core.MigrationStatusApplied)
+		}
+	})
+
+	// Ensure edited migration files are reported.
+	t.Run("Modified", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		MustExec(t, ctx, db, `UPDATE migrations SET checksum = 'x' WHERE version = 1`)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, migrations[1].Status, core.MigrationStatusModified)
+	})
+
+	// Ensure a database migrated by a newer version is not opened.
+	t.Run("ErrDatabaseNewer", func(t *testing.T) {
+		dsn := filepath.Join(t.TempDir(), "db")
+		db := sqlite.NewDB(dsn)
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		MustExec(t, ctx, db, `INSERT INTO migrations (version, checksum) VALUES (9999999, '')`)
+		MustCloseDB(t, db)
+
+		db = sqlite.NewDB(dsn)
+		err := db.Open()
+		require.Equal(t, errors.Is(err, bookmarkd.ErrDatabaseNewer), true)
+
+		// The status is still reported without migrating.
+		db = sqlite.NewDB(dsn)
+		db.SkipMigrations = true
+		if err := db.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer MustCloseDB(t, db)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, migrations[len(migrations)-1].Status, core.MigrationStatusUnknown)
+		require.Equal(t, errors.Is(db.CheckMigrations(ctx), bookmarkd.ErrDatabaseNewer), true)
+	})
+
+	// Ensure migrations recorded by file name are converted to versions.
+	t.Run("Legacy", func(t *testing.T) {
+		db := MustOpenDB(t)
+		defer MustCloseDB(t, db)
+
+		MustExec(t, ctx, db, `DROP TABLE migrations; CREATE TABLE migrations (name TEXT PRIMARY KEY); INSERT INTO migrations (name) VALUES ('migration/0000000.sql'), ('migration/0000001.sql');`)
+
+		migrations, err := db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, migrations[0].Status, core.MigrationStatusApplied)
+		require.Equal(t, migrations[1].Status, core.MigrationStatusApplied)
+		require.Equal(t, migrations[2].Status, core.MigrationStatusPending)
+
+		// Only the migrations after the recorded ones are run.
+		target := 1
+		steps, err := db.Migrate(ctx, core.MigrateOptions{Target: &target})
+		require.Equal(t, err, nil)
+		require.Equal(t, len(steps), 0)
+
+		migrations, err = db.Migrations(ctx)
+		require.Equal(t, err, nil)
+		require.Equal(t, migrations[1].Status, core.MigrationStatusApplied)
+		require.Equal(t, migrations[1].AppliedAt, (*time.Time)(nil))
+	})
+}
+
+func TestDB_Stats(t *testing.T) {
+	db := MustOpenDB(t)
+	defer MustCloseDB(t, db)
+
+	ctx := context.Background()
+	us := sqlite.NewUserStore(db)
+	user := MustCreateUser(t, ctx, us, &core.User{Username: "jane"})
+	_, ctx0 := MustCreateSession(t, ctx, sqlite.NewSessionStore(db), user.ID)
+	MustCreateBookmark(t, ctx0, sqlite.NewBookmarkStore(db), &core.Bookmark{Name: "Go", Url: "https://go.dev", Status: core.BookmarkStatusUnread})
+
+	stats, err := db.Stats(ctx)
+	require.Equal(t, err, nil)
+	require.Equal(t, *stats, sqlite.Stats{Users: 1, Sessions: 1, Bookmarks: 1})
+}
+
 func MustOpenDB(tb testing.TB) *sqlite.DB {
 	tb.Helper()
 
@@ -35,6 +226,22 @@ func MustOpenDB(tb testing.TB) *sqlite.DB {
 	return db
 }
 
+// MustExec executes query in a transaction. Fatal on error.
+func MustExec(tb testing.TB, ctx context.Context, db *sqlite.DB, query string) {
+	tb.Helper()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		tb.Fatal(err)
+	} else if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+}
+
 func MustCloseDB(tb testing.TB, db *sqlite.DB) {
 	tb.Helper()
 	if err := db.Close(); err != nil {
@@ -3,7 +3,9 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -158,6 +160,10 @@ func findUserByUsername(ctx context.Context, tx *Tx, username string) (*core.Use
 // findUsers returns a list of users matching a filter. Also returns a count of
 // total matching users which may differ if filter.Limit is set.
 func findUsers(ctx context.Context, tx *Tx, filter core.UserFilter) (_ []*core.User, n int, err error) {
+	if err := filter.Validate(); err != nil {
+		return nil, 0, err
+	}
+
 	// Build WHERE clause.
 	where, args := []string{"1 = 1"}, []interface{}{}
 	if v := filter.ID; v != nil {
@@ -167,20 +173,39 @@ func findUsers(ctx context.Context, tx *Tx, filter core.UserFilter) (_ []*core.U
 		where, args = append(where, "username = ?"), append(args, *v)
 	}
 
-	// Execute query to fetch user rows.
+	// Page through the results using the sort column and cursor.
+	column := "created_at"
+	switch filter.Sort {
+	case core.SortUpdated:
+		column = "updated_at"
+	case core.SortUsername:
+		column = "username"
+	}
+	keyset, keysetArgs, orderBy, reverse, err := FormatKeyset(column, filter.Descending(), filter.Cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Count the matching users separately, so the page itself is read
+	// from the index of the sort column starting at the cursor.
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+strings.Join(where, " AND "), args...).Scan(&n); err != nil {
+		return nil, 0, fmt.Errorf("db count users: %w", FormatError(err))
+	}
+
+	// Execute the query with the cursor & LIMIT/OFFSET injected.
 	rows, err := tx.QueryContext(ctx, `
-		SELECT 
-		    id,
-		    username,
-		    seed,
-		    created_at,
-		    updated_at,
-		    COUNT(*) OVER()
+		SELECT
+		  id,
+		  username,
+		  seed,
+		  disabled_at,
+		  created_at,
+		  updated_at
 		FROM users
-		WHERE `+strings.Join(where, " AND ")+`
-		ORDER BY id ASC
+		WHERE `+strings.Join(append(where, keyset), " AND ")+`
+		`+orderBy+`
 		`+FormatLimitOffset(filter.Limit, filter.Offset),
-		args...,
+		append(args, keysetArgs...)...,
 	)
 	if err != nil {
 		return nil, n, fmt.Errorf("db select users: %w", FormatError(err))
@@ -191,23 +216,32 @@ func findUsers(ctx context.Context, tx *Tx, filter core.UserFilter) (_ []*core.U
 	users := make([]*core.User, 0)
 	for rows.Next() {
 		var user core.User
+		var disabledAt time.Time
 		if err := rows.Scan(
 			&user.ID,
 			&user.Username,
 			&user.Seed,
+			(*NullTime)(&disabledAt),
 			(*NullTime)(&user.CreatedAt),
 			(*NullTime)(&user.UpdatedAt),
-			&n,
 		); err != nil {
 			return nil, 0, fmt.Errorf("db scan users: %w", FormatError(err))
 		}
 
+		if !disabledAt.IsZero() {
+			user.DisabledAt = &disabledAt
+		}
+
 		users = append(users, &user)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, 0, fmt.Errorf("db users rows: %w", FormatError(err))
 	}
 
+	if reverse {
+		slices.Reverse(users)
+	}
+
 	return users, n, nil
 }
 
@@ -272,6 +306,23 @@ func updateUser(ctx context.Context, tx *Tx, id string, upd core.UserUpdate) (*c
 		user.Username = *v
 	}
 
+	// Changing the seed or disabling the user ends all of their sessions.
+	revoke := false
+	if v := upd.Seed; v != nil {
+		if *v == "" {
+			return user, fmt.Errorf("%w: seed required", bookmarkd.ErrInvalidInput)
+		}
+		user.Seed, revoke = *v, true
+	}
+	if v := upd.Disabled; v != nil && *v != user.Disabled() {
+		if *v {
+			now := tx.Now()
+			user.DisabledAt, revoke = &now, true
+		} else {
+			user.DisabledAt = nil
+		}
+	}
+
 	// Set last updated date to current time.
 	user.UpdatedAt = tx.Now()
 
@@ -279,16 +330,26 @@ func updateUser(ctx context.Context, tx *Tx, id string, upd core.UserUpdate) (*c
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE users
 		SET username = ?,
+		    seed = ?,
+		    disabled_at = ?,
 		    updated_at = ?
 		WHERE id = ?
 	`,
 		user.Username,
+		user.Seed,
+		(*NullTime)(user.DisabledAt),
 		(*NullTime)(&user.UpdatedAt),
 		id,
 	); err != nil {
 		return user, fmt.Errorf("db update user: %w", FormatError(err))
 	}
 
+	if revoke {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, id); err != nil {
+			return user, fmt.Errorf("db delete user sessions: %w", FormatError(err))
+		}
+	}
+
 	return user, nil
 }
 
@@ -101,6 +101,40 @@ func Test_UserStore_UpdateUser(t *testing.T) {
 		require.AssertError(t, err)
 		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
 	})
+
+	// Ensure resetting the seed revokes the user's sessions.
+	t.Run("Seed", func(t *testing.T) {
+		_, ctx1 := MustCreateSession(t, context.Background(), ss, user1.ID)
+
+		seed := "new_seed"
+		uu, err := s.UpdateUser(ctx1, user1.ID, core.UserUpdate{Seed: &seed})
+		require.Equal(t, err, nil)
+		require.Equal(t, uu.Seed, "new_seed")
+
+		_, n, err := ss.FindSessions(context.Background(), core.SessionFilter{UserID: &user1.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+	})
+
+	// Ensure disabling a user is persisted & revokes the user's sessions.
+	t.Run("Disabled", func(t *testing.T) {
+		_, ctx1 := MustCreateSession(t, context.Background(), ss, user1.ID)
+
+		disabled := true
+		uu, err := s.UpdateUser(ctx1, user1.ID, core.UserUpdate{Disabled: &disabled})
+		require.Equal(t, err, nil)
+		require.Equal(t, uu.Disabled(), true)
+
+		other, err := s.FindUserByID(context.Background(), user1.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.DisabledAt.Equal(*uu.DisabledAt), true)
+		require.Equal(t, len(other.Sessions), 0)
+
+		disabled = false
+		uu, err = s.UpdateUser(ctx1, user1.ID, core.UserUpdate{Disabled: &disabled})
+		require.Equal(t, err, nil)
+		require.Equal(t, uu.Disabled(), false)
+	})
 }
 
 func Test_UserStore_DeleteUser(t *testing.T) {
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WAL file format constants. See https://www.sqlite.org/fileformat.html#walformat
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+
+	// Magic numbers selecting the byte order of the checksums.
+	walMagicLittleEndian = 0x377f0682
+	walMagicBigEndian    = 0x377f0683
+)
+
+// walHeader represents the header of a WAL file.
+type walHeader struct {
+	PageSize int
+	Salt1    uint32
+	Salt2    uint32
+
+	// Byte order of the checksums & the checksum of the header, which seeds
+	// the checksum of the first frame.
+	order    binary.ByteOrder
+	checksum walChecksum
+}
+
+// walChecksum represents the cumulative checksum of a WAL file up to a frame.
+type walChecksum struct {
+	S0, S1 uint32
+}
+
+// add returns the checksum extended by b, which must be a multiple of 8 bytes.
+func (c walChecksum) add(order binary.ByteOrder, b []byte) walChecksum {
+	for i := 0; i+8 <= len(b); i += 8 {
+		c.S0 += order.Uint32(b[i:]) + c.S1
+		c.S1 += order.Uint32(b[i+4:]) + c.S0
+	}
+	return c
+}
+
+// readWALHeader reads & verifies the header of the WAL file at path. Returns
+// nil if the file does not exist or is too short to hold a header, which
+// means no frames have been written since the WAL was last reset.
+func readWALHeader(path string) (*walHeader, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(f, buf); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeWALHeader(buf)
+}
+
+// decodeWALHeader decodes & verifies a WAL header.
+func decodeWALHeader(buf []byte) (*walHeader, error) {
+	hdr := &walHeader{
+		PageSize: int(binary.BigEndian.Uint32(buf[8:])),
+		Salt1:    binary.BigEndian.Uint32(buf[16:]),
+		Salt2:    binary.BigEndian.Uint32(buf[20:]),
+	}
+
+	switch magic := binary.BigEndian.Uint32(buf[0:]); magic {
+	case walMagicLittleEndian:
+		hdr.order = binary.LittleEndian
+	case walMagicBigEndian:
+		hdr.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid wal magic: %x", magic)
+	}
+
+	hdr.checksum = walChecksum{}.add(hdr.order, buf[:24])
+	if hdr.checksum.S0 != binary.BigEndian.Uint32(buf[24:]) || hdr.checksum.S1 != binary.BigEndian.Uint32(buf[28:]) {
+		return nil, fmt.Errorf("invalid wal header checksum")
+	}
+	return hdr, nil
+}
+
+// walPos represents a position in the WAL directly after a commit frame.
+type walPos struct {
+	Salt1, Salt2 uint32
+	Offset       int64
+	Checksum     walChecksum
+}
+
+// readWALFrames reads the frames of the WAL file at path starting at pos,
+// which must use the salts of hdr. Only frames up to the last valid commit
+// frame are returned, along with the position after it. Frames of
+// transactions which have not committed are left for the next read.
+func readWALFrames(path string, hdr *walHeader, pos walPos) ([]byte, walPos, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, pos, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+		return nil, pos, err
+	}
+
+	var frames []byte
+	var n int
+	start, checksum := pos.Offset, pos.Checksum
+	frame := make([]byte, walFrameHeaderSize+hdr.PageSize)
+	for {
+		if _, err := io.ReadFull(f, frame); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return nil, pos, err
+		}
+
+		// Frames left over from before the WAL was reset carry old salts &
+		// frames of rolled back transactions fail the checksum.
+		if binary.BigEndian.Uint32(frame[8:]) != hdr.Salt1 || binary.BigEndian.Uint32(frame[12:]) != hdr.Salt2 {
+			break
+		}
+		checksum = checksum.add(hdr.order, frame[:8]).add(hdr.order, frame[walFrameHeaderSize:])
+		if checksum.S0 != binary.BigEndian.Uint32(frame[16:]) || checksum.S1 != binary.BigEndian.Uint32(frame[20:]) {
+			break
+		}
+
+		frames = append(frames, frame...)
+
+		// A non-zero database size marks the commit frame of a transaction.
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			n = len(frames)
+			pos.Offset, pos.Checksum = start+int64(n), checksum
+		}
+	}
+	return frames[:n], pos, nil
+}
+
+// applyWALFrames writes the pages of frames to the database file f. The
+// file is truncated to the database size recorded in each commit frame.
+func applyWALFrames(f *os.File, pageSize int, frames []byte) error {
+	size := walFrameHeaderSize + pageSize
+	if len(frames)%size != 0 {
+		return fmt.Errorf("wal frames not a multiple of the frame size")
+	}
+
+	for i := 0; i < len(frames); i += size {
+		frame := frames[i : i+size]
+		pgno := binary.BigEndian.Uint32(frame[0:])
+		if _, err := f.WriteAt(frame[walFrameHeaderSize:], int64(pgno-1)*int64(pageSize)); err != nil {
+			return err
+		}
+		if commit := binary.BigEndian.Uint32(frame[4:]); commit != 0 {
+			if err := f.Truncate(int64(commit) * int64(pageSize)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// databasePageSize returns the page size stored in the header of a database.
+func databasePageSize(f *os.File) (int, error) {
+	buf := make([]byte, 2)
+	if _, err := f.ReadAt(buf, 16); err != nil {
+		return 0, fmt.Errorf("read database header: %w", err)
+	}
+
+	// A page size of 65536 is stored as 1.
+	if size := int(binary.BigEndian.Uint16(buf)); size == 1 {
+		return 65536, nil
+	} else {
+		return size, nil
+	}
+}
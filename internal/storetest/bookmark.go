@@ -0,0 +1,360 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/utils/require"
+)
+
+// TestBookmarkStore tests the BookmarkStore returned by f.
+func TestBookmarkStore(t *testing.T, f StoresFactory) {
+	t.Run("CreateBookmark", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+		require.NotEqual(t, bookmark.ID, 0)
+		require.Equal(t, bookmark.UserID, user.ID)
+		require.Equal(t, bookmark.Status, core.BookmarkStatusUnread)
+		require.Equal(t, bookmark.Version, 1)
+		require.Equal(t, bookmark.CreatedAt, e.clock.Now())
+		require.Equal(t, bookmark.UpdatedAt, e.clock.Now())
+
+		other, err := e.BookmarkStore.FindBookmarkByID(ctx0, bookmark.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Name, "Go")
+		require.Equal(t, other.Url, "https://go.dev")
+		require.Equal(t, other.CanonicalUrl, bookmark.CanonicalUrl)
+		require.Equal(t, other.CreatedAt, bookmark.CreatedAt)
+
+		// The event is published to the owner.
+		requireEvents(t, e.events.Reset(), publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkAdded})
+	})
+
+	t.Run("CreateBookmark/Errors", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		err := e.BookmarkStore.CreateBookmark(context.Background(), &core.Bookmark{Name: "Go", Url: "https://go.dev"}, "")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+
+		err = e.BookmarkStore.CreateBookmark(ctx0, &core.Bookmark{Url: "https://go.dev"}, "")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+
+		err = e.BookmarkStore.CreateBookmark(ctx0, &core.Bookmark{Name: strings.Repeat("x", 256), Url: "https://go.dev"}, "")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+
+		require.Equal(t, len(e.events.Reset()), 0)
+	})
+
+	// Ensure bookmarks are only visible to & changeable by their owner.
+	t.Run("Ownership", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		_, ctx0 := e.mustLogin(t, user0)
+		_, ctx1 := e.mustLogin(t, user1)
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+		e.events.Reset()
+
+		_, err := e.BookmarkStore.FindBookmarkByID(ctx1, bookmark.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, n, err := e.BookmarkStore.FindBookmarks(ctx1, core.BookmarkFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+
+		_, n, err = e.BookmarkStore.FindBookmarks(context.Background(), core.BookmarkFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+
+		name := "Golang"
+		_, err = e.BookmarkStore.UpdateBookmark(ctx1, bookmark.ID, core.BookmarkUpdate{Name: &name})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, err = e.BookmarkStore.DeleteBookmark(ctx1, bookmark.ID, nil)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, n, err = e.BookmarkStore.FindBookmarkRevisions(ctx1, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+
+		// Nothing changed so nothing was published.
+		require.Equal(t, len(e.events.Reset()), 0)
+
+		other, err := e.BookmarkStore.FindBookmarkByID(ctx0, bookmark.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Name, "Go")
+	})
+
+	t.Run("UpdateBookmark", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+		e.events.Reset()
+		e.clock.Add(time.Minute)
+
+		name, status := "Golang", core.BookmarkStatusRead
+		other, err := e.BookmarkStore.UpdateBookmark(ctx0, bookmark.ID, core.BookmarkUpdate{Name: &name, Status: &status})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Name, "Golang")
+		require.Equal(t, other.Status, core.BookmarkStatusRead)
+		require.Equal(t, other.ReadAt, e.clock.Now())
+		require.Equal(t, other.Version, 2)
+		require.Equal(t, other.CreatedAt, bookmark.CreatedAt)
+		require.Equal(t, other.UpdatedAt, e.clock.Now())
+
+		requireEvents(t, e.events.Reset(), publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkNameChanged},
+			publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkStatusChanged})
+
+		revisions, n, err := e.BookmarkStore.FindBookmarkRevisions(ctx0, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+		require.Equal(t, revisions[0].CreatedAt, e.clock.Now())
+
+		// Updates of a stale version are rejected.
+		version := 1
+		_, err = e.BookmarkStore.UpdateBookmark(ctx0, bookmark.ID, core.BookmarkUpdate{Name: &name, Version: &version})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrPreconditionFailed), true)
+
+		_, err = e.BookmarkStore.UpdateBookmark(ctx0, bookmark.ID+1000, core.BookmarkUpdate{Name: &name})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		require.Equal(t, len(e.events.Reset()), 0)
+	})
+
+	t.Run("DeleteBookmark", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+		e.events.Reset()
+
+		version := 2
+		_, err := e.BookmarkStore.DeleteBookmark(ctx0, bookmark.ID, &version)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrPreconditionFailed), true)
+
+		_, err = e.BookmarkStore.DeleteBookmark(ctx0, bookmark.ID, nil)
+		require.Equal(t, err, nil)
+		requireEvents(t, e.events.Reset(), publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkRemoved})
+
+		_, err = e.BookmarkStore.FindBookmarkByID(ctx0, bookmark.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, err = e.BookmarkStore.DeleteBookmark(ctx0, bookmark.ID, nil)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	t.Run("FindBookmarks", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		for _, bookmark := range []*core.Bookmark{
+			{Name: "golang", Url: "http://bookmark1", Starred: true},
+			{Name: "banana", Url: "http://bookmark2", Description: "golang"},
+			{Name: "cherry", Url: "http://bookmark3", Status: core.BookmarkStatusRead},
+			{Name: "apple", Url: "http://golang4", Status: core.BookmarkStatusArchived, Starred: true},
+			{Name: "100%", Url: "http://bookmark5"},
+		} {
+			e.mustCreateBookmark(t, ctx0, bookmark)
+			e.clock.Add(time.Second)
+		}
+
+		find := func(t *testing.T, filter core.BookmarkFilter) (string, int) {
+			t.Helper()
+			a, n, err := e.BookmarkStore.FindBookmarks(ctx0, filter)
+			require.Equal(t, err, nil)
+			var names []string
+			for _, bookmark := range a {
+				names = append(names, bookmark.Name)
+			}
+			return strings.Join(names, ","), n
+		}
+
+		t.Run("Created", func(t *testing.T) {
+			names, n := find(t, core.BookmarkFilter{})
+			require.Equal(t, names, "golang,banana,cherry,apple,100%")
+			require.Equal(t, n, 5)
+
+			names, n = find(t, core.BookmarkFilter{Direction: core.SortDesc, Offset: 1, Limit: 2})
+			require.Equal(t, names, "apple,cherry")
+			require.Equal(t, n, 5)
+		})
+
+		t.Run("Status", func(t *testing.T) {
+			status := core.BookmarkStatusUnread
+			names, n := find(t, core.BookmarkFilter{Status: &status})
+			require.Equal(t, names, "golang,banana,100%")
+			require.Equal(t, n, 3)
+		})
+
+		t.Run("Starred", func(t *testing.T) {
+			starred := true
+			names, n := find(t, core.BookmarkFilter{Starred: &starred, Limit: 1})
+			require.Equal(t, names, "golang")
+			require.Equal(t, n, 2)
+		})
+
+		t.Run("Search", func(t *testing.T) {
+			search := "GOLANG"
+			names, n := find(t, core.BookmarkFilter{Search: &search, Sort: core.SortRelevance})
+			require.Equal(t, names, "golang,banana,apple")
+			require.Equal(t, n, 3)
+
+			search = "0%"
+			names, _ = find(t, core.BookmarkFilter{Search: &search})
+			require.Equal(t, names, "100%")
+		})
+
+		t.Run("Cursor", func(t *testing.T) {
+			filter := core.BookmarkFilter{Sort: core.SortName, Limit: 2}
+			a, n, err := e.BookmarkStore.FindBookmarks(ctx0, filter)
+			require.Equal(t, err, nil)
+			require.Equal(t, n, 5)
+			next, _ := filter.Cursors(a)
+
+			filter.Cursor = next
+			names, n := find(t, filter)
+			require.Equal(t, names, "banana,cherry")
+			require.Equal(t, n, 5)
+
+			_, _, err = e.BookmarkStore.FindBookmarks(ctx0, core.BookmarkFilter{Cursor: "!"})
+			require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+		})
+
+		// Ensure pages of search results follow their computed relevance.
+		t.Run("CursorRelevance", func(t *testing.T) {
+			search := "GOLANG"
+			filter := core.BookmarkFilter{Search: &search, Sort: core.SortRelevance, Limit: 1}
+			var names []string
+			for i := 0; i < 3; i++ {
+				a, n, err := e.BookmarkStore.FindBookmarks(ctx0, filter)
+				require.Equal(t, err, nil)
+				require.Equal(t, len(a), 1)
+				require.Equal(t, n, 3)
+				names = append(names, a[0].Name)
+				filter.Cursor, _ = filter.Cursors(a)
+			}
+			require.Equal(t, strings.Join(names, ","), "golang,banana,apple")
+		})
+	})
+
+	t.Run("DuplicateBookmarks", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev/?utm_source=x"})
+
+		err := e.BookmarkStore.CreateBookmark(ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev/"}, core.DuplicateModeReject)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBookmarkDuplicate), true)
+
+		merged := &core.Bookmark{Name: "Golang", Url: "https://go.dev/"}
+		err = e.BookmarkStore.CreateBookmark(ctx0, merged, core.DuplicateModeMerge)
+		require.Equal(t, err, nil)
+		require.Equal(t, merged.ID, bookmark.ID)
+		require.Equal(t, merged.Name, "Golang")
+
+		e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev/"})
+		duplicates, err := e.BookmarkStore.FindDuplicateBookmarks(ctx0)
+		require.Equal(t, err, nil)
+		require.Equal(t, len(duplicates), 1)
+		require.Equal(t, len(duplicates[0].Bookmarks), 2)
+	})
+
+	t.Run("UpdateBookmarksStatus", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		_, ctx0 := e.mustLogin(t, user0)
+		_, ctx1 := e.mustLogin(t, user1)
+		e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+		e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Rust", Url: "https://rust-lang.org", Status: core.BookmarkStatusRead})
+		e.mustCreateBookmark(t, ctx1, &core.Bookmark{Name: "Zig", Url: "https://ziglang.org"})
+		e.events.Reset()
+
+		// Only the current user's bookmarks which don't have the status change.
+		n, err := e.BookmarkStore.UpdateBookmarksStatus(ctx0, core.BookmarkFilter{}, core.BookmarkStatusRead)
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+		requireEvents(t, e.events.Reset(), publishedEvent{UserID: user0.ID, Type: core.EventTypeBookmarkStatusChanged})
+
+		status := core.BookmarkStatusUnread
+		_, n, err = e.BookmarkStore.FindBookmarks(ctx1, core.BookmarkFilter{Status: &status})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+	})
+
+	t.Run("RevertBookmark", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+
+		name := "Golang"
+		if _, err := e.BookmarkStore.UpdateBookmark(ctx0, bookmark.ID, core.BookmarkUpdate{Name: &name}); err != nil {
+			t.Fatal(err)
+		}
+		revisions, _, err := e.BookmarkStore.FindBookmarkRevisions(ctx0, core.BookmarkRevisionFilter{BookmarkID: &bookmark.ID})
+		require.Equal(t, err, nil)
+		e.events.Reset()
+
+		other, err := e.BookmarkStore.RevertBookmark(ctx0, bookmark.ID, revisions[0].ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Name, "Go")
+		requireEvents(t, e.events.Reset(), publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkNameChanged},
+			publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkReverted})
+
+		_, err = e.BookmarkStore.RevertBookmark(ctx0, bookmark.ID, revisions[0].ID+1000)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	// Ensure failed atomic batches change nothing & publish nothing.
+	t.Run("BatchBookmarks", func(t *testing.T) {
+		e := newEnv(t, f, true, true, true, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		bookmark := e.mustCreateBookmark(t, ctx0, &core.Bookmark{Name: "Go", Url: "https://go.dev"})
+		e.events.Reset()
+
+		result, err := e.BookmarkStore.BatchBookmarks(ctx0, &core.BookmarkBatch{Operations: []core.BookmarkOp{
+			{Op: core.BookmarkOpCreate, Bookmark: &core.Bookmark{Name: "Rust", Url: "https://rust-lang.org"}},
+			{Op: core.BookmarkOpDelete, ID: bookmark.ID + 1000},
+		}})
+		require.Equal(t, err, nil)
+		require.Equal(t, result.Committed, false)
+		require.Equal(t, result.Results[0].Status, core.BookmarkOpStatusRolledBack)
+		require.Equal(t, result.Results[1].Status, core.BookmarkOpStatusFailed)
+		require.Equal(t, len(e.events.Reset()), 0)
+
+		_, n, err := e.BookmarkStore.FindBookmarks(ctx0, core.BookmarkFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+
+		result, err = e.BookmarkStore.BatchBookmarks(ctx0, &core.BookmarkBatch{Mode: core.BatchModeBestEffort, Operations: []core.BookmarkOp{
+			{Op: core.BookmarkOpCreate, Bookmark: &core.Bookmark{Name: "Rust", Url: "https://rust-lang.org"}},
+			{Op: core.BookmarkOpDelete, ID: bookmark.ID + 1000},
+		}})
+		require.Equal(t, err, nil)
+		require.Equal(t, result.Committed, true)
+		requireEvents(t, e.events.Reset(), publishedEvent{UserID: user.ID, Type: core.EventTypeBookmarkAdded})
+	})
+}
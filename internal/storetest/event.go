@@ -0,0 +1,76 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/utils/require"
+)
+
+// TestEventService tests the EventService returned by f.
+func TestEventService(t *testing.T, f EventServiceFactory) {
+	t.Run("Subscribe/ErrUnauthorized", func(t *testing.T) {
+		s := f(t)
+
+		_, err := s.Subscribe(context.Background())
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	// Ensure events are only delivered to the subscriptions of their user.
+	t.Run("PublishEvent", func(t *testing.T) {
+		s := f(t)
+
+		sub0 := mustSubscribe(t, s, "USER0")
+		defer sub0.Close()
+		sub1 := mustSubscribe(t, s, "USER1")
+		defer sub1.Close()
+
+		s.PublishEvent("USER0", core.Event{Type: core.EventTypeBookmarkAdded})
+		s.PublishEvent("USER2", core.Event{Type: core.EventTypeBookmarkRemoved})
+
+		select {
+		case event := <-sub0.C():
+			require.Equal(t, event.Type, core.EventTypeBookmarkAdded)
+		case <-time.After(time.Second):
+			t.Fatal("expected event")
+		}
+
+		select {
+		case event := <-sub1.C():
+			t.Fatalf("unexpected event: %s", event.Type)
+		case <-time.After(10 * time.Millisecond):
+		}
+	})
+
+	// Ensure closing a subscription closes its channel.
+	t.Run("Close", func(t *testing.T) {
+		s := f(t)
+
+		sub := mustSubscribe(t, s, "USER0")
+		require.Equal(t, sub.Close(), nil)
+
+		select {
+		case _, ok := <-sub.C():
+			require.Equal(t, ok, false)
+		case <-time.After(time.Second):
+			t.Fatal("expected closed channel")
+		}
+
+		// Events published after closing are dropped.
+		s.PublishEvent("USER0", core.Event{Type: core.EventTypeBookmarkAdded})
+	})
+}
+
+// mustSubscribe subscribes to the events of userID. Fatal on error.
+func mustSubscribe(t *testing.T, s core.EventService, userID string) core.Subscription {
+	t.Helper()
+	sub, err := s.Subscribe(core.NewContextWithSession(context.Background(), core.SessionContext{UserID: userID}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
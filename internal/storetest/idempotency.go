@@ -0,0 +1,128 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/utils/require"
+)
+
+// TestIdempotencyKeyStore tests the IdempotencyKeyStore returned by f.
+func TestIdempotencyKeyStore(t *testing.T, f StoresFactory) {
+	ctx := context.Background()
+
+	// Ensure a key is reserved once & returned with its response afterwards.
+	t.Run("ReserveIdempotencyKey", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, true)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		key := &core.IdempotencyKey{Key: "KEY", Fingerprint: "FP0"}
+		other, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, key)
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+
+		other, err = e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY", Fingerprint: "FP0"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Completed, false)
+
+		key.StatusCode, key.Body = 201, []byte("BODY")
+		key.Header = map[string][]string{"Content-Type": {"application/json"}}
+		require.Equal(t, e.IdempotencyKeyStore.CompleteIdempotencyKey(ctx0, key), nil)
+
+		other, err = e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY", Fingerprint: "FP1"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Completed, true)
+		require.Equal(t, other.Fingerprint, "FP0")
+		require.Equal(t, other.StatusCode, 201)
+		require.Equal(t, string(other.Body), "BODY")
+		require.Equal(t, other.Header["Content-Type"][0], "application/json")
+	})
+
+	t.Run("ReserveIdempotencyKey/ErrUnauthorized", func(t *testing.T) {
+		e := newEnv(t, f, false, false, false, true)
+
+		_, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	// Ensure keys are scoped per user.
+	t.Run("PerUser", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, true)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		_, ctx0 := e.mustLogin(t, user0)
+		_, ctx1 := e.mustLogin(t, user1)
+
+		_, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, err, nil)
+
+		other, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx1, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+
+		// Keys of other users can't be completed.
+		err = e.IdempotencyKeyStore.CompleteIdempotencyKey(ctx1, &core.IdempotencyKey{Key: "OTHER"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	// Ensure a deleted key can be reserved again.
+	t.Run("DeleteIdempotencyKey", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, true)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		_, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, err, nil)
+		require.Equal(t, e.IdempotencyKeyStore.DeleteIdempotencyKey(ctx0, "KEY"), nil)
+
+		other, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+	})
+
+	// Ensure keys can be reused once they expire.
+	t.Run("Expired", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, true)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		_, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, err, nil)
+		e.clock.Add(24*time.Hour + time.Second)
+
+		other, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other == nil, true)
+	})
+
+	// Ensure expired keys are removed even if an existing key is returned.
+	t.Run("Expired/Existing", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, true)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		_, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY0"})
+		require.Equal(t, err, nil)
+		e.clock.Add(30 * time.Minute)
+		_, err = e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY1"})
+		require.Equal(t, err, nil)
+		e.clock.Add(30*time.Minute + time.Second)
+
+		other, err := e.IdempotencyKeyStore.ReserveIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY1"})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Key, "KEY1")
+
+		err = e.IdempotencyKeyStore.CompleteIdempotencyKey(ctx0, &core.IdempotencyKey{Key: "KEY0"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+}
@@ -0,0 +1,57 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"bookmarkd"
+	"bookmarkd/utils/require"
+)
+
+// TestRegistrationStore tests the RegistrationStore returned by f.
+func TestRegistrationStore(t *testing.T, f RegistrationStoreFactory) {
+	t.Run("StartRegistration", func(t *testing.T) {
+		s := f(t)
+
+		r, err := s.StartRegistration("jane")
+		require.Equal(t, err, nil)
+		require.NotEqual(t, r.ID, uuid.Nil)
+		require.NotEqual(t, r.Seed, "")
+
+		other, err := s.FindRegistrationByID(r.ID.String())
+		require.Equal(t, err, nil)
+		require.Equal(t, other.ID, r.ID)
+		require.Equal(t, other.Username, "jane")
+		require.Equal(t, other.Seed, r.Seed)
+		require.Equal(t, other.ExpiresAt.Equal(r.ExpiresAt), true)
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		s := f(t)
+
+		_, err := s.FindRegistrationByID(uuid.NewString())
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, err = s.FindRegistrationByID("NOT A UUID")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	t.Run("DeleteRegistration", func(t *testing.T) {
+		s := f(t)
+
+		r0, err := s.StartRegistration("jane")
+		require.Equal(t, err, nil)
+		r1, err := s.StartRegistration("john")
+		require.Equal(t, err, nil)
+
+		require.Equal(t, s.DeleteRegistration(r0.ID.String()), nil)
+
+		_, err = s.FindRegistrationByID(r0.ID.String())
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, err = s.FindRegistrationByID(r1.ID.String())
+		require.Equal(t, err, nil)
+	})
+}
@@ -0,0 +1,135 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/utils/require"
+)
+
+// TestSessionStore tests the SessionStore returned by f.
+func TestSessionStore(t *testing.T, f StoresFactory) {
+	ctx := context.Background()
+
+	t.Run("CreateSession", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+		session, _ := e.mustLogin(t, user)
+		require.NotEqual(t, session.ID, 0)
+		require.NotEqual(t, session.RefreshToken, "")
+		require.Equal(t, session.CreatedAt, e.clock.Now())
+		require.Equal(t, session.UpdatedAt, e.clock.Now())
+		require.Equal(t, session.ExpiresAt.After(session.CreatedAt), true)
+
+		other, err := e.SessionStore.FindSessionByID(ctx, session.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.UserID, user.ID)
+		require.Equal(t, other.RefreshToken, session.RefreshToken)
+		require.Equal(t, other.ExpiresAt, session.ExpiresAt)
+		require.Equal(t, other.User.Username, "jane")
+
+		other, err = e.SessionStore.FindSessionByUserID(ctx, user.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.ID, session.ID)
+	})
+
+	t.Run("ErrUserRequired", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		err := e.SessionStore.CreateSession(ctx, &core.Session{})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInternal), true)
+	})
+
+	t.Run("ErrNotFound", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		_, err := e.SessionStore.FindSessionByID(ctx, 1)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		user := e.mustCreateUser(t, "jane")
+		_, err = e.SessionStore.FindSessionByUserID(ctx, user.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	t.Run("FindSessions", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		session0, _ := e.mustLogin(t, user0)
+		e.clock.Add(time.Second)
+		e.mustLogin(t, user0)
+		e.mustLogin(t, user1)
+
+		// The count includes the sessions beyond the limit.
+		sessions, n, err := e.SessionStore.FindSessions(ctx, core.SessionFilter{UserID: &user0.ID, Limit: 1})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 2)
+		require.Equal(t, len(sessions), 1)
+		require.Equal(t, sessions[0].ID, session0.ID)
+
+		_, n, err = e.SessionStore.FindSessions(ctx, core.SessionFilter{})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 3)
+
+		_, _, err = e.SessionStore.FindSessions(ctx, core.SessionFilter{Offset: -1})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+
+	t.Run("RefreshSession", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+		session, ctx0 := e.mustLogin(t, user)
+		e.clock.Add(time.Hour)
+
+		other, err := e.SessionStore.RefreshSession(ctx0, session.RefreshToken)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.ID, session.ID)
+		require.NotEqual(t, other.RefreshToken, session.RefreshToken)
+		require.Equal(t, other.UpdatedAt, e.clock.Now())
+		require.Equal(t, other.ExpiresAt.After(session.ExpiresAt), true)
+
+		// The previous refresh token can't be used again.
+		_, err = e.SessionStore.RefreshSession(ctx0, session.RefreshToken)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	t.Run("RefreshSession/ErrExpired", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+		session, ctx0 := e.mustLogin(t, user)
+		e.clock.Add(session.ExpiresAt.Sub(session.CreatedAt))
+
+		_, err := e.SessionStore.RefreshSession(ctx0, session.RefreshToken)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+	})
+
+	t.Run("DeleteSession", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		session0, ctx0 := e.mustLogin(t, user0)
+		session1, _ := e.mustLogin(t, user1)
+
+		// Users can only delete their own sessions.
+		err := e.SessionStore.DeleteSession(ctx0, session1.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+
+		err = e.SessionStore.DeleteSession(ctx0, session0.ID)
+		require.Equal(t, err, nil)
+
+		_, err = e.SessionStore.FindSessionByID(ctx, session0.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, err = e.SessionStore.FindSessionByID(ctx, session1.ID)
+		require.Equal(t, err, nil)
+	})
+}
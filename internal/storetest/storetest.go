@@ -0,0 +1,179 @@
+// Package storetest provides conformance tests for implementations of the
+// core store interfaces. Every implementation is expected to behave the same
+// so the tests are run against each of them, e.g.
+//
+//	func TestStores(t *testing.T) {
+//		storetest.TestStores(t, func(tb testing.TB, events core.EventService, now func() time.Time) *storetest.Stores {
+//			...
+//		})
+//	}
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+)
+
+// Stores represents the stores of one implementation sharing a database.
+// Tests of stores which are nil are skipped.
+type Stores struct {
+	UserStore           core.UserStore
+	SessionStore        core.SessionStore
+	BookmarkStore       core.BookmarkStore
+	IdempotencyKeyStore core.IdempotencyKeyStore
+}
+
+// StoresFactory returns stores backed by a new, empty database. The stores
+// must publish their events to events & read the current time from now.
+// Idempotency keys must expire within a day.
+type StoresFactory func(tb testing.TB, events core.EventService, now func() time.Time) *Stores
+
+// RegistrationStoreFactory returns a new, empty registration store.
+type RegistrationStoreFactory func(tb testing.TB) core.RegistrationStore
+
+// EventServiceFactory returns a new event service without subscribers.
+type EventServiceFactory func(tb testing.TB) core.EventService
+
+// TestStores runs the tests of every store returned by f.
+func TestStores(t *testing.T, f StoresFactory) {
+	t.Run("UserStore", func(t *testing.T) { TestUserStore(t, f) })
+	t.Run("SessionStore", func(t *testing.T) { TestSessionStore(t, f) })
+	t.Run("BookmarkStore", func(t *testing.T) { TestBookmarkStore(t, f) })
+	t.Run("IdempotencyKeyStore", func(t *testing.T) { TestIdempotencyKeyStore(t, f) })
+}
+
+// env represents the stores under test along with the events they published
+// and the clock they read.
+type env struct {
+	*Stores
+	events *eventRecorder
+	clock  *clock
+}
+
+// newEnv returns stores backed by a new database. Skips the test if any of
+// the stores it requires are not implemented.
+func newEnv(t *testing.T, f StoresFactory, users, sessions, bookmarks, idempotencyKeys bool) *env {
+	t.Helper()
+
+	e := &env{
+		events: &eventRecorder{},
+		clock:  &clock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	e.Stores = f(t, e.events, e.clock.Now)
+
+	switch {
+	case users && e.UserStore == nil:
+		t.Skip("UserStore not implemented")
+	case sessions && e.SessionStore == nil:
+		t.Skip("SessionStore not implemented")
+	case bookmarks && e.BookmarkStore == nil:
+		t.Skip("BookmarkStore not implemented")
+	case idempotencyKeys && e.IdempotencyKeyStore == nil:
+		t.Skip("IdempotencyKeyStore not implemented")
+	}
+	return e
+}
+
+// mustCreateUser creates a user. Fatal on error.
+func (e *env) mustCreateUser(t *testing.T, username string) *core.User {
+	t.Helper()
+	user := &core.User{Username: username, Seed: "SEED"}
+	if err := e.UserStore.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// mustLogin creates a session for user & returns a context authenticated
+// with it. Fatal on error.
+func (e *env) mustLogin(t *testing.T, user *core.User) (*core.Session, context.Context) {
+	t.Helper()
+	session := &core.Session{UserID: user.ID}
+	if err := e.SessionStore.CreateSession(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	return session, core.NewContextWithSession(context.Background(), core.SessionContext{
+		UserID:    session.UserID,
+		SessionID: session.ID,
+		ExpiresAt: session.ExpiresAt,
+	})
+}
+
+// mustCreateBookmark creates a bookmark. Fatal on error.
+func (e *env) mustCreateBookmark(t *testing.T, ctx context.Context, bookmark *core.Bookmark) *core.Bookmark {
+	t.Helper()
+	if err := e.BookmarkStore.CreateBookmark(ctx, bookmark, ""); err != nil {
+		t.Fatal(err)
+	}
+	return bookmark
+}
+
+// clock represents a mock clock which only moves when advanced.
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// Now returns the current time of the clock.
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+// Add advances the clock by d.
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// publishedEvent represents an event published to a user.
+type publishedEvent struct {
+	UserID string
+	Type   string
+}
+
+// eventRecorder represents an event service which records published events.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []publishedEvent
+}
+
+func (r *eventRecorder) PublishEvent(userID string, event core.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, publishedEvent{UserID: userID, Type: event.Type})
+}
+
+func (r *eventRecorder) Subscribe(ctx context.Context) (core.Subscription, error) {
+	return nil, fmt.Errorf("subscribe: %w", bookmarkd.ErrInternal)
+}
+
+// Reset removes the recorded events & returns them.
+func (r *eventRecorder) Reset() []publishedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	return events
+}
+
+// requireEvents fails the test unless got contains exactly the events of want.
+func requireEvents(t *testing.T, got []publishedEvent, want ...publishedEvent) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("events: got %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("events: got %+v, want %+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"bookmarkd"
+	"bookmarkd/internal/core"
+	"bookmarkd/utils/require"
+)
+
+// TestUserStore tests the UserStore returned by f.
+func TestUserStore(t *testing.T, f StoresFactory) {
+	ctx := context.Background()
+
+	t.Run("CreateUser", func(t *testing.T) {
+		e := newEnv(t, f, true, false, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+		require.Equal(t, uuid.Validate(user.ID), nil)
+		require.Equal(t, user.CreatedAt, e.clock.Now())
+		require.Equal(t, user.UpdatedAt, e.clock.Now())
+
+		other, err := e.UserStore.FindUserByID(ctx, user.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Username, "jane")
+		require.Equal(t, other.Seed, "SEED")
+		require.Equal(t, other.CreatedAt, user.CreatedAt)
+		require.Equal(t, other.DisabledAt, (*time.Time)(nil))
+	})
+
+	t.Run("ErrUsernameRequired", func(t *testing.T) {
+		e := newEnv(t, f, true, false, false, false)
+
+		err := e.UserStore.CreateUser(ctx, &core.User{Seed: "SEED"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrInvalidInput), true)
+	})
+
+	t.Run("ErrUsersUsernameConflict", func(t *testing.T) {
+		e := newEnv(t, f, true, false, false, false)
+
+		e.mustCreateUser(t, "jane")
+		err := e.UserStore.CreateUser(ctx, &core.User{Username: "jane", Seed: "SEED"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUsersUsernameConflict), true)
+	})
+
+	t.Run("FindUser", func(t *testing.T) {
+		e := newEnv(t, f, true, false, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+
+		other, err := e.UserStore.FindUserByUsername(ctx, "jane")
+		require.Equal(t, err, nil)
+		require.Equal(t, other.ID, user.ID)
+
+		_, err = e.UserStore.FindUserByID(ctx, uuid.NewString())
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, err = e.UserStore.FindUserByUsername(ctx, "john")
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	t.Run("FindUsers", func(t *testing.T) {
+		e := newEnv(t, f, true, false, false, false)
+
+		for _, username := range []string{"jane", "john", "joe"} {
+			e.mustCreateUser(t, username)
+			e.clock.Add(time.Second)
+		}
+
+		// The count includes the users beyond the limit.
+		users, n, err := e.UserStore.FindUsers(ctx, core.UserFilter{Limit: 2})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 3)
+		require.Equal(t, len(users), 2)
+		require.Equal(t, users[0].Username, "jane")
+		require.Equal(t, users[1].Username, "john")
+
+		users, n, err = e.UserStore.FindUsers(ctx, core.UserFilter{Sort: core.SortUsername, Offset: 1})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 3)
+		require.Equal(t, len(users), 2)
+		require.Equal(t, users[0].Username, "joe")
+		require.Equal(t, users[1].Username, "john")
+
+		username := "john"
+		users, n, err = e.UserStore.FindUsers(ctx, core.UserFilter{Username: &username})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 1)
+		require.Equal(t, users[0].Username, "john")
+
+		_, _, err = e.UserStore.FindUsers(ctx, core.UserFilter{Sort: "seed"})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrBadRequest), true)
+	})
+
+	t.Run("UpdateUser", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+		e.clock.Add(time.Minute)
+
+		username := "janet"
+		other, err := e.UserStore.UpdateUser(ctx0, user.ID, core.UserUpdate{Username: &username})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Username, "janet")
+		require.Equal(t, other.CreatedAt, user.CreatedAt)
+		require.Equal(t, other.UpdatedAt, e.clock.Now())
+
+		other, err = e.UserStore.FindUserByID(ctx, user.ID)
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Username, "janet")
+	})
+
+	// Ensure users can only update themselves.
+	t.Run("UpdateUser/ErrUnauthorized", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		_, ctx1 := e.mustLogin(t, user1)
+
+		username := "janet"
+		_, err := e.UserStore.UpdateUser(ctx1, user0.ID, core.UserUpdate{Username: &username})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+
+		_, err = e.UserStore.UpdateUser(ctx1, uuid.NewString(), core.UserUpdate{Username: &username})
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+	})
+
+	// Ensure disabling a user ends their sessions.
+	t.Run("UpdateUser/Disabled", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user := e.mustCreateUser(t, "jane")
+		_, ctx0 := e.mustLogin(t, user)
+
+		disabled := true
+		other, err := e.UserStore.UpdateUser(ctx0, user.ID, core.UserUpdate{Disabled: &disabled})
+		require.Equal(t, err, nil)
+		require.Equal(t, other.Disabled(), true)
+		require.Equal(t, *other.DisabledAt, e.clock.Now())
+
+		_, n, err := e.SessionStore.FindSessions(ctx, core.SessionFilter{UserID: &user.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+	})
+
+	t.Run("DeleteUser", func(t *testing.T) {
+		e := newEnv(t, f, true, true, false, false)
+
+		user0 := e.mustCreateUser(t, "jane")
+		user1 := e.mustCreateUser(t, "john")
+		_, ctx0 := e.mustLogin(t, user0)
+
+		// Users can only delete themselves.
+		_, err := e.UserStore.DeleteUser(ctx0, user1.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrUnauthorized), true)
+
+		_, err = e.UserStore.DeleteUser(ctx0, user0.ID)
+		require.Equal(t, err, nil)
+
+		_, err = e.UserStore.FindUserByID(ctx, user0.ID)
+		require.Equal(t, errors.Is(err, bookmarkd.ErrNotFound), true)
+
+		_, n, err := e.SessionStore.FindSessions(ctx, core.SessionFilter{UserID: &user0.ID})
+		require.Equal(t, err, nil)
+		require.Equal(t, n, 0)
+
+		_, err = e.UserStore.FindUserByID(ctx, user1.ID)
+		require.Equal(t, err, nil)
+	})
+}
@@ -0,0 +1,111 @@
+// Package systemd implements the parts of the systemd service protocol used by
+// the server: socket activation & readiness notifications with watchdog
+// pings. Every function is a no-op if the process isn't run by systemd.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd.
+const listenFdsStart = 3
+
+// Notification states.
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Listeners returns the sockets passed by systemd socket activation, in the
+// order of the ListenStream= settings of the socket unit. Returns nil if no
+// sockets were passed to this process.
+func Listeners(getenv func(string) string) ([]net.Listener, error) {
+	if !forThisProcess(getenv("LISTEN_PID")) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(listenFdsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		// FileListener duplicates the descriptor so the file is closed
+		// either way.
+		f := os.NewFile(uintptr(listenFdsStart+i), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("socket %s: %w", name, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// Notify sends state to the service manager, e.g. Ready once the server
+// accepts requests. Returns false if the process isn't run by systemd with
+// Type=notify.
+func Notify(getenv func(string) string, state string) (bool, error) {
+	path := getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+
+	// Sockets starting with @ are in the abstract namespace.
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("dial notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("notify: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the time within which the service manager expects
+// a Watchdog notification before it restarts the process. Returns zero if the
+// watchdog is disabled.
+func WatchdogInterval(getenv func(string) string) (time.Duration, error) {
+	// The pid is optional, unlike the one of socket activation.
+	s := getenv("WATCHDOG_USEC")
+	if pid := getenv("WATCHDOG_PID"); s == "" || pid != "" && !forThisProcess(pid) {
+		return 0, nil
+	}
+
+	usec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid WATCHDOG_USEC %q", s)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// forThisProcess returns true if pid is the id of the current process. The
+// variables set by systemd are inherited by child processes, which must
+// ignore them.
+func forThisProcess(pid string) bool {
+	return pid != "" && pid == strconv.Itoa(os.Getpid())
+}
@@ -0,0 +1,97 @@
+package systemd_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"bookmarkd/internal/systemd"
+	"bookmarkd/utils/require"
+)
+
+func TestListeners(t *testing.T) {
+	// Ensure sockets passed to another process, e.g. the parent, are ignored.
+	t.Run("OtherProcess", func(t *testing.T) {
+		listeners, err := systemd.Listeners(mapenv(map[string]string{
+			"LISTEN_PID": strconv.Itoa(os.Getppid()),
+			"LISTEN_FDS": "1",
+		}))
+		require.Equal(t, err, nil)
+		require.Equal(t, len(listeners), 0)
+	})
+
+	t.Run("NotActivated", func(t *testing.T) {
+		listeners, err := systemd.Listeners(mapenv(nil))
+		require.Equal(t, err, nil)
+		require.Equal(t, len(listeners), 0)
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		_, err := systemd.Listeners(mapenv(map[string]string{
+			"LISTEN_PID": strconv.Itoa(os.Getpid()),
+			"LISTEN_FDS": "one",
+		}))
+		require.NotEqual(t, err, nil)
+	})
+}
+
+func TestNotify(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notify.sock")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		ok, err := systemd.Notify(mapenv(map[string]string{"NOTIFY_SOCKET": path}), systemd.Ready)
+		require.Equal(t, err, nil)
+		require.Equal(t, ok, true)
+
+		buf := make([]byte, 64)
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		require.Equal(t, err, nil)
+		require.Equal(t, string(buf[:n]), "READY=1")
+	})
+
+	t.Run("NotNotifyType", func(t *testing.T) {
+		ok, err := systemd.Notify(mapenv(nil), systemd.Ready)
+		require.Equal(t, err, nil)
+		require.Equal(t, ok, false)
+	})
+
+	t.Run("ErrNoSocket", func(t *testing.T) {
+		_, err := systemd.Notify(mapenv(map[string]string{"NOTIFY_SOCKET": filepath.Join(t.TempDir(), "missing.sock")}), systemd.Ready)
+		require.NotEqual(t, err, nil)
+	})
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		env     map[string]string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"Disabled", nil, 0, false},
+		{"OK", map[string]string{"WATCHDOG_USEC": "30000000"}, 30 * time.Second, false},
+		{"ThisProcess", map[string]string{"WATCHDOG_USEC": "1000", "WATCHDOG_PID": strconv.Itoa(os.Getpid())}, time.Millisecond, false},
+		{"OtherProcess", map[string]string{"WATCHDOG_USEC": "1000", "WATCHDOG_PID": strconv.Itoa(os.Getppid())}, 0, false},
+		{"ErrInvalid", map[string]string{"WATCHDOG_USEC": "-1"}, 0, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, err := systemd.WatchdogInterval(mapenv(tt.env))
+			require.Equal(t, err != nil, tt.wantErr)
+			require.Equal(t, interval, tt.want)
+		})
+	}
+}
+
+// mapenv returns a getenv function reading from m.
+func mapenv(m map[string]string) func(string) string {
+	return func(key string) string { return m[key] }
+}